@@ -0,0 +1,395 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AlertsConfig configures the threshold alerts
+type AlertsConfig struct {
+	Enabled  bool           `yaml:"enabled"`
+	Rules    []AlertRule    `yaml:"rules"`
+	Webhook  WebhookConfig  `yaml:"webhook"`
+	Telegram TelegramConfig `yaml:"telegram"`
+	Email    EmailConfig    `yaml:"email"`
+	Ntfy     NtfyConfig     `yaml:"ntfy"`
+	Pushover PushoverConfig `yaml:"pushover"`
+	Outputs  []OutputConfig `yaml:"outputs"` // LEDs and buzzers on GPIO pins
+
+	Remediations []RemediationConfig `yaml:"remediations"` // run only by the rules naming them
+}
+
+// AlertRule fires when a metric stays beyond a threshold for a while
+type AlertRule struct {
+	Name      string        `yaml:"name"`
+	Metric    string        `yaml:"metric"` // one of alertMetrics
+	Op        string        `yaml:"op"`     // ">" (default) or "<"
+	Threshold float64       `yaml:"threshold"`
+	For       time.Duration `yaml:"for"`     // how long the condition must hold
+	Actions   []string      `yaml:"actions"` // empty runs every notification and output
+}
+
+// alertMetrics are the values a rule can watch
+var alertMetrics = map[string]func(SystemStats) float64{
+	"cpu":  func(s SystemStats) float64 { return calculateAverage(s.CPUPercent) },
+	"mem":  func(s SystemStats) float64 { return s.MemPercent },
+	"swap": func(s SystemStats) float64 { return s.SwapPercent },
+	"disk": func(s SystemStats) float64 { return s.DiskPercent },
+	"temp": func(s SystemStats) float64 { return s.Temperature },
+	"throttled": func(s SystemStats) float64 {
+		if len(s.Throttle.Current()) > 0 {
+			return 1
+		}
+		return 0
+	},
+	"processes": func(s SystemStats) float64 { return float64(s.ProcessCount) },
+	"load":      func(s SystemStats) float64 { return s.Load.Load1 },
+}
+
+// notifyActions are the names the notification actions are registered
+// under, which an output can't take
+var notifyActions = map[string]bool{
+	"webhook": true, "telegram": true, "email": true, "ntfy": true, "pushover": true,
+}
+
+func defaultAlertRules() []AlertRule {
+	return []AlertRule{
+		{Name: "high-temp", Metric: "temp", Op: ">", Threshold: 80, For: time.Minute},
+		{Name: "disk-full", Metric: "disk", Op: ">", Threshold: 90},
+		{Name: "low-memory", Metric: "mem", Op: ">", Threshold: 95, For: 30 * time.Second},
+	}
+}
+
+func (c AlertsConfig) validate() error {
+	if (c.Telegram.Token == "") != (c.Telegram.ChatID == "") {
+		return fmt.Errorf("alerts.telegram needs both token and chat_id")
+	}
+	if c.Webhook.URL != "" {
+		if u, err := url.Parse(c.Webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("alerts.webhook.url must be an http(s) URL")
+		}
+	}
+	if err := c.Email.validate(); err != nil {
+		return err
+	}
+	if err := c.Ntfy.validate(); err != nil {
+		return err
+	}
+	if err := c.Pushover.validate(); err != nil {
+		return err
+	}
+
+	for _, out := range c.Outputs {
+		if err := out.validate(); err != nil {
+			return err
+		}
+		if notifyActions[out.Name] {
+			return fmt.Errorf("output name %q is reserved", out.Name)
+		}
+	}
+	for i, rem := range c.Remediations {
+		if err := rem.validate(); err != nil {
+			return err
+		}
+		if notifyActions[rem.Name] {
+			return fmt.Errorf("remediation name %q is reserved", rem.Name)
+		}
+		for _, out := range c.Outputs {
+			if out.Name == rem.Name {
+				return fmt.Errorf("remediation %q: an output has the same name", rem.Name)
+			}
+		}
+		for _, prev := range c.Remediations[:i] {
+			if prev.Name == rem.Name {
+				return fmt.Errorf("alerts.remediations: duplicate name %q", rem.Name)
+			}
+		}
+	}
+
+	actions := c.actionNames()
+	names := make(map[string]bool)
+	for i, r := range c.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("alerts.rules[%d]: name is required", i)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("alerts.rules: duplicate name %q", r.Name)
+		}
+		names[r.Name] = true
+		if _, ok := alertMetrics[r.Metric]; !ok {
+			return fmt.Errorf("alert %q: unknown metric %q (%s)", r.Name, r.Metric, strings.Join(alertMetricNames(), ", "))
+		}
+		if r.Op != "" && r.Op != ">" && r.Op != "<" {
+			return fmt.Errorf("alert %q: op must be > or <", r.Name)
+		}
+		if r.For < 0 {
+			return fmt.Errorf("alert %q: for must not be negative", r.Name)
+		}
+		for _, name := range r.Actions {
+			if !actions[name] {
+				return fmt.Errorf("alert %q: action %q is not configured", r.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// actionNames returns the names of the configured actions a rule can
+// list: the notifications that are set up, the outputs and the
+// remediations
+func (c AlertsConfig) actionNames() map[string]bool {
+	names := map[string]bool{
+		"webhook":  c.Webhook.URL != "",
+		"telegram": c.Telegram.Token != "",
+		"email":    c.Email.Host != "",
+		"ntfy":     c.Ntfy.Topic != "",
+		"pushover": c.Pushover.Token != "",
+	}
+	for _, out := range c.Outputs {
+		names[out.Name] = true
+	}
+	for _, rem := range c.Remediations {
+		names[rem.Name] = true
+	}
+	return names
+}
+
+func alertMetricNames() []string {
+	names := make([]string, 0, len(alertMetrics))
+	for name := range alertMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AlertEvent is passed to the actions when an alert fires or resolves
+type AlertEvent struct {
+	Rule     AlertRule
+	Value    float64
+	Since    time.Time // when the condition started to hold
+	Host     string
+	Resolved bool
+
+	Processes []ProcessInfo // of the sample, for the kill_top remediation
+}
+
+// Message describes the event in one line
+func (e AlertEvent) Message() string {
+	op := e.Rule.Op
+	if op == "" {
+		op = ">"
+	}
+	if e.Resolved {
+		return fmt.Sprintf("[%s] %s resolved: %s is %.1f", e.Host, e.Rule.Name, e.Rule.Metric, e.Value)
+	}
+	return fmt.Sprintf("[%s] %s: %s is %.1f (%s %g)", e.Host, e.Rule.Name, e.Rule.Metric, e.Value, op, e.Rule.Threshold)
+}
+
+// AlertAction is something done when an alert fires or resolves, such
+// as lighting an LED or calling a webhook. Actions are called from their
+// own goroutine and may block.
+type AlertAction interface {
+	Fire(evt AlertEvent)
+	Resolve(evt AlertEvent)
+}
+
+// AlertBreacher is an AlertAction that also wants every later sample in
+// which a firing alert still breaches its threshold
+type AlertBreacher interface {
+	Breach(evt AlertEvent)
+}
+
+// alertState tracks one rule between evaluations
+type alertState struct {
+	since  time.Time // zero while the condition does not hold
+	firing bool
+	value  float64
+}
+
+// AlertEngine evaluates the rules against every stats sample
+type AlertEngine struct {
+	rules   []AlertRule
+	states  []alertState
+	actions map[string]AlertAction
+	manual  map[string]bool // actions run only by the rules naming them
+	host    string
+}
+
+func newAlertEngine(cfg AlertsConfig) *AlertEngine {
+	e := &AlertEngine{
+		rules:   cfg.Rules,
+		states:  make([]alertState, len(cfg.Rules)),
+		actions: make(map[string]AlertAction),
+		manual:  make(map[string]bool),
+		host:    hostname(),
+	}
+	if cfg.Webhook.URL != "" {
+		e.AddAction("webhook", webhookAction{cfg.Webhook})
+	}
+	if cfg.Telegram.Token != "" {
+		e.AddAction("telegram", telegramAction{cfg.Telegram})
+	}
+	if cfg.Email.Host != "" {
+		e.AddAction("email", emailAction{cfg.Email})
+	}
+	if cfg.Ntfy.Topic != "" {
+		e.AddAction("ntfy", ntfyAction{cfg.Ntfy})
+	}
+	if cfg.Pushover.Token != "" {
+		e.AddAction("pushover", pushoverAction{cfg.Pushover})
+	}
+	for _, rem := range cfg.Remediations {
+		e.AddAction(rem.Name, newRemediation(rem))
+		e.manual[rem.Name] = true
+	}
+	return e
+}
+
+// carryOver takes over what old knew when the config is reloaded: the
+// state of the rules that are still there, the outputs, and the streaks
+// and cooldowns of the remediations that did not change. Alerts whose rule is
+// gone or changed are resolved.
+func (e *AlertEngine) carryOver(old *AlertEngine) {
+	for name, action := range old.actions {
+		switch action := action.(type) {
+		case *gpioOutput:
+			e.AddAction(name, action)
+		case *remediation:
+			if next, ok := e.actions[name].(*remediation); ok && reflect.DeepEqual(next.cfg, action.cfg) {
+				e.actions[name] = action
+			}
+		}
+	}
+
+	kept := make(map[string]bool)
+	for i, rule := range e.rules {
+		for j, prev := range old.rules {
+			if reflect.DeepEqual(rule, prev) {
+				e.states[i] = old.states[j]
+				kept[rule.Name] = true
+			}
+		}
+	}
+	for j, state := range old.states {
+		rule := old.rules[j]
+		if state.firing && !kept[rule.Name] {
+			old.dispatch(AlertEvent{Rule: rule, Value: state.value, Since: state.since, Host: old.host, Resolved: true})
+		}
+	}
+}
+
+// AddAction registers an action under name for use in the rules
+func (e *AlertEngine) AddAction(name string, action AlertAction) {
+	e.actions[name] = action
+}
+
+// Evaluate updates the rule states with stats, firing or resolving
+// alerts whose condition changed
+func (e *AlertEngine) Evaluate(stats SystemStats, now time.Time) {
+	for i, rule := range e.rules {
+		state := &e.states[i]
+		state.value = alertMetrics[rule.Metric](stats)
+
+		holds := state.value > rule.Threshold
+		if rule.Op == "<" {
+			holds = state.value < rule.Threshold
+		}
+
+		switch {
+		case holds && state.since.IsZero():
+			state.since = now
+			fallthrough
+		case holds:
+			if !state.firing && now.Sub(state.since) >= rule.For {
+				state.firing = true
+				e.dispatch(AlertEvent{Rule: rule, Value: state.value, Since: state.since, Host: e.host, Processes: stats.AllProcesses})
+			} else if state.firing {
+				e.breach(AlertEvent{Rule: rule, Value: state.value, Since: state.since, Host: e.host, Processes: stats.AllProcesses})
+			}
+		default:
+			if state.firing {
+				state.firing = false
+				e.dispatch(AlertEvent{Rule: rule, Value: state.value, Since: state.since, Host: e.host, Resolved: true, Processes: stats.AllProcesses})
+			}
+			state.since = time.Time{}
+		}
+	}
+}
+
+// dispatch logs evt and hands it to the actions of the rule
+func (e *AlertEngine) dispatch(evt AlertEvent) {
+	log.Printf("Alert: %s", evt.Message())
+
+	for _, name := range e.ruleActions(evt.Rule) {
+		action, ok := e.actions[name]
+		if !ok {
+			log.Printf("Alert %s: action %q is not configured", evt.Rule.Name, name)
+			continue
+		}
+		if evt.Resolved {
+			go action.Resolve(evt)
+		} else {
+			go action.Fire(evt)
+		}
+	}
+}
+
+// breach hands evt, a further sample of a firing alert, to the actions
+// of the rule that follow breaches
+func (e *AlertEngine) breach(evt AlertEvent) {
+	for _, name := range e.ruleActions(evt.Rule) {
+		if action, ok := e.actions[name].(AlertBreacher); ok {
+			go action.Breach(evt)
+		}
+	}
+}
+
+// ruleActions returns the names of the actions rule runs: the ones it
+// lists, or else every action that is not manual
+func (e *AlertEngine) ruleActions(rule AlertRule) []string {
+	names := rule.Actions
+	if len(names) == 0 {
+		for name := range e.actions {
+			if !e.manual[name] {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Firing returns the names of the alerts currently firing
+func (e *AlertEngine) Firing() []string {
+	var names []string
+	for i, state := range e.states {
+		if state.firing {
+			names = append(names, e.rules[i].Name)
+		}
+	}
+	return names
+}
+
+// MetricFiring reports whether an alert on metric is firing
+func (e *AlertEngine) MetricFiring(metric string) bool {
+	for i, state := range e.states {
+		if state.firing && e.rules[i].Metric == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// alertColor returns red when an alert on metric is firing and color
+// otherwise, for highlighting values in the views
+func (d *Dashboard) alertColor(metric, color string) string {
+	if d.alerts != nil && d.alerts.MetricFiring(metric) {
+		return "red"
+	}
+	return color
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apCommandTimeout bounds a mode switch, which restarts the interface
+const apCommandTimeout = 60 * time.Second
+
+// APConfig says how to switch the Wi-Fi interface between access point
+// and client mode. The commands, e.g. ap_toggle.sh, take precedence;
+// without them NetworkManager's hotspot is used.
+type APConfig struct {
+	APCommand     string `yaml:"ap_command"`     // shell command that enables AP mode
+	ClientCommand string `yaml:"client_command"` // shell command that returns to client mode
+	SSID          string `yaml:"ssid"`           // hotspot SSID for NetworkManager
+	Password      string `yaml:"password"`       // hotspot passphrase, 8 to 63 characters
+}
+
+func (c APConfig) validate() error {
+	if c.Password != "" && (len(c.Password) < 8 || len(c.Password) > 63) {
+		return fmt.Errorf("ap.password must have 8 to 63 characters")
+	}
+	return nil
+}
+
+// APStatus describes the Wi-Fi interface as reported by iw, which works
+// for hostapd and NetworkManager hotspots alike
+type APStatus struct {
+	AP       bool // interface type is AP
+	SSID     string
+	Channel  int
+	Stations int // associated clients in AP mode
+}
+
+// readAPStatus runs "iw dev <iface> info" and, in AP mode, counts the
+// stations of "iw dev <iface> station dump"
+func readAPStatus(iface string) (APStatus, error) {
+	var status APStatus
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "iw", "dev", iface, "info").Output()
+	if err != nil {
+		return status, toolError("iw", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "type":
+			status.AP = fields[1] == "AP"
+		case "ssid":
+			status.SSID = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "ssid"))
+		case "channel":
+			status.Channel, _ = strconv.Atoi(fields[1])
+		}
+	}
+	if !status.AP {
+		return status, nil
+	}
+
+	out, err = exec.CommandContext(ctx, "iw", "dev", iface, "station", "dump").Output()
+	if err != nil {
+		return status, nil
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "Station ") {
+			status.Stations++
+		}
+	}
+	return status, nil
+}
+
+// apRows shows the mode of the Wi-Fi interface at the top of the Wi-Fi
+// view
+func (d *Dashboard) apRows() []string {
+	if d.apErr != nil {
+		return []string{"Mode: " + truncateString(d.apErr.Error(), 21)}
+	}
+	s := d.apStatus
+	if !s.AP {
+		return []string{"Mode: [Client](fg:green)  [m: AP mode](fg:yellow)"}
+	}
+	return []string{
+		fmt.Sprintf("Mode: [AP](fg:magenta) ch %d, %d clients", s.Channel, s.Stations),
+		"AP SSID: " + truncateString(s.SSID, 18),
+		"[m: client mode](fg:yellow)",
+	}
+}
+
+// SwitchMode runs the configured command, or NetworkManager, to turn
+// iface into an access point or back into a client in a goroutine
+func (m *WifiManager) SwitchMode(cfg APConfig, iface string, toAP bool) {
+	m.mu.Lock()
+	if m.joining != "" {
+		m.mu.Unlock()
+		return
+	}
+	target := "client mode"
+	if toAP {
+		target = "AP mode"
+	}
+	m.joining = target
+	m.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), apCommandTimeout)
+		defer cancel()
+
+		var cmd *exec.Cmd
+		switch {
+		case toAP && cfg.APCommand != "":
+			cmd = exec.CommandContext(ctx, "sh", "-c", cfg.APCommand)
+		case !toAP && cfg.ClientCommand != "":
+			cmd = exec.CommandContext(ctx, "sh", "-c", cfg.ClientCommand)
+		case toAP:
+			args := []string{"device", "wifi", "hotspot", "ifname", iface, "ssid", cfg.SSID}
+			if cfg.Password != "" {
+				args = append(args, "password", cfg.Password)
+			}
+			cmd = exec.CommandContext(ctx, "nmcli", args...)
+		default:
+			// NetworkManager reconnects to a known network on its own
+			cmd = exec.CommandContext(ctx, "nmcli", "connection", "down", "Hotspot")
+		}
+
+		status := "Switched to " + target
+		if out, err := cmd.CombinedOutput(); err != nil {
+			status = "Switch failed: " + commandError(out, err)
+			log.Printf("Switching %s to %s failed: %s", iface, target, commandError(out, err))
+		} else {
+			log.Printf("Switched %s to %s", iface, target)
+		}
+		m.mu.Lock()
+		m.joining, m.status = "", status
+		m.mu.Unlock()
+	}()
+}
+
+// confirmModeSwitch asks before switching between AP and client mode,
+// since it drops the current Wi-Fi connection, and with it possibly the
+// SSH session of whoever is asking
+func (d *Dashboard) confirmModeSwitch() {
+	stats, _ := d.snapshot.Get()
+	iface := wifiInterface(stats)
+	toAP := !d.apStatus.AP
+
+	title, target := "Client mode", "Switch to client"
+	if toAP {
+		title, target = "AP mode", "Start access point"
+	}
+	message := []string{"Drops the Wi-Fi link", "of " + iface + ", SSH too"}
+	if toAP && d.config.AP.APCommand == "" {
+		message = append(message, "SSID: "+truncateString(d.config.AP.SSID, 18))
+	}
+	d.openMenu(&Menu{
+		title:   title,
+		message: message,
+		options: []menuOption{
+			{label: "Cancel"},
+			{label: target, action: func(d *Dashboard) {
+				d.wifiManager.SwitchMode(d.config.AP, iface, toAP)
+				d.UpdateStats()
+				d.Render()
+			}},
+		},
+	})
+}
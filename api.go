@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// apiStats is the body of GET /api/stats
+type apiStats struct {
+	Timestamp time.Time `json:"timestamp"`
+	Hostname  string    `json:"hostname"`
+	SystemStats
+}
+
+// writeJSON encodes v as the response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("API: failed to write response: %v", err)
+	}
+}
+
+// apiStatsHandler returns the latest SystemStats without the process list
+func (d *Dashboard) apiStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	stats, at := d.snapshot.Get()
+	if at.IsZero() {
+		http.Error(w, "no stats collected yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	stats.AllProcesses = nil
+	writeJSON(w, apiStats{Timestamp: at, Hostname: d.statsHost(), SystemStats: stats})
+}
+
+// apiProcessesHandler returns the process list. Optional query
+// parameters: sort=cpu|mem|pid|name and limit=N.
+func (d *Dashboard) apiProcessesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	stats, at := d.snapshot.Get()
+	if at.IsZero() {
+		http.Error(w, "no stats collected yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	mode := sortByCPU
+	switch r.URL.Query().Get("sort") {
+	case "", "cpu":
+	case "mem":
+		mode = sortByMemory
+	case "pid":
+		mode = sortByPID
+	case "name":
+		mode = sortByName
+	default:
+		http.Error(w, "sort must be cpu, mem, pid or name", http.StatusBadRequest)
+		return
+	}
+	procs := sortProcesses(stats.AllProcesses, mode)
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		if n < len(procs) {
+			procs = procs[:n]
+		}
+	}
+
+	writeJSON(w, procs)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Button actions that gpio.actions can assign to a button
+const (
+	actionNone     = "none"
+	actionUp       = "up"        // move the selection up, repeats while held
+	actionDown     = "down"      // move the selection down, repeats while held
+	actionPageUp   = "page_up"   // move the selection a screenful up, repeats while held
+	actionPageDown = "page_down" // move the selection a screenful down, repeats while held
+	actionNextView = "next_view" // switch to the next view
+	actionPrevView = "prev_view" // switch to the previous view
+	actionSelect   = "select"    // process detail, service/container menu, log filter
+	actionBack     = "back"      // leave the process detail, otherwise previous view
+	actionAction   = "action"    // kill process, service/container menu, AP mode, otherwise next view
+	actionKill     = "kill"      // signal or renice the selected process
+	actionToggle   = "toggle"    // process sort, log follow, kernel event filter
+	actionPause    = "pause"     // freeze the display, again to resume
+	actionPower    = "power"     // power menu
+	actionQuit     = "quit"      // exit the monitor
+	actionHelp     = "help"      // keys and buttons of the current view
+
+	// actionExecPrefix runs the rest of the action as a shell command
+	actionExecPrefix = "exec:"
+)
+
+// buttonActions are the action names accepted besides exec:
+var buttonActions = []string{
+	actionNone, actionUp, actionDown, actionPageUp, actionPageDown, actionNextView,
+	actionPrevView, actionSelect, actionBack, actionAction, actionKill, actionToggle, actionPause, actionPower,
+	actionQuit, actionHelp,
+}
+
+// commandTimeout bounds a custom command run from a button
+const commandTimeout = 30 * time.Second
+
+// defaultButtonActions returns the button semantics of the original HAT
+func defaultButtonActions() map[string]string {
+	return map[string]string{
+		"up":     actionUp,
+		"down":   actionDown,
+		"left":   actionBack,
+		"right":  actionNextView,
+		"a":      actionAction,
+		"b":      actionBack,
+		"x":      actionToggle,
+		"y":      actionNone,
+		"start":  actionPower,
+		"select": actionNone,
+		"l":      actionPageUp,
+		"r":      actionPageDown,
+		"center": actionSelect,
+	}
+}
+
+// validateButtonAction checks an action from gpio.actions
+func validateButtonAction(button, action string) error {
+	if strings.HasPrefix(action, actionExecPrefix) {
+		if strings.TrimSpace(strings.TrimPrefix(action, actionExecPrefix)) == "" {
+			return fmt.Errorf("gpio.actions.%s: exec: needs a command", button)
+		}
+		return nil
+	}
+	for _, a := range buttonActions {
+		if action == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("gpio.actions.%s: unknown action %q (%s, exec:<command>)",
+		button, action, strings.Join(buttonActions, ", "))
+}
+
+// buttonAction returns the action assigned to a button
+func (d *Dashboard) buttonAction(button string) string {
+	if action, ok := d.config.GPIO.Actions[button]; ok {
+		return action
+	}
+	return actionNone
+}
+
+// runButtonAction performs action for a button press. It reports false
+// when the monitor should exit.
+func (d *Dashboard) runButtonAction(action string) bool {
+	if strings.HasPrefix(action, actionExecPrefix) {
+		d.runCommand(strings.TrimSpace(strings.TrimPrefix(action, actionExecPrefix)))
+		return true
+	}
+
+	switch action {
+	case actionUp:
+		d.moveSelection(-1)
+	case actionDown:
+		d.moveSelection(1)
+	case actionPageUp:
+		d.moveSelection(-selectionPage)
+	case actionPageDown:
+		d.moveSelection(selectionPage)
+	case actionNextView:
+		d.switchView(1)
+	case actionPrevView:
+		d.switchView(-1)
+	case actionSelect:
+		d.selectItem()
+	case actionBack:
+		if d.currentView == viewProcess && d.detailPID != 0 {
+			d.closeProcessDetail()
+		} else {
+			d.switchView(-1)
+		}
+	case actionAction:
+		switch d.currentView {
+		case viewProcess:
+			d.openSignalMenu()
+		case viewServices:
+			d.confirmServiceAction()
+		case viewContainers:
+			d.confirmContainerAction()
+		case viewWifi:
+			d.confirmModeSwitch()
+		case viewCPUFreq:
+			d.chooseGovernor()
+		case viewControl:
+			d.toggleControl()
+		default:
+			d.switchView(1)
+		}
+	case actionKill:
+		if d.currentView == viewProcess {
+			d.openSignalMenu()
+		}
+	case actionToggle:
+		switch d.currentView {
+		case viewProcess:
+			d.cycleSortMode()
+		case viewGroups:
+			d.toggleGrouping()
+		case viewNetwork:
+			d.startSpeedTest()
+		case viewLAN:
+			d.rescanLAN()
+		case viewWifi:
+			d.rescanWifi()
+		case viewLogs:
+			d.toggleLogFollow()
+		case viewKernel:
+			d.toggleKernelFilter()
+		}
+	case actionPause:
+		d.togglePause()
+	case actionPower:
+		d.openPowerMenu()
+	case actionHelp:
+		d.openHelp()
+	case actionQuit:
+		return false
+	}
+	return true
+}
+
+// selectItem opens whatever belongs to the selected row of the view
+func (d *Dashboard) selectItem() {
+	switch d.currentView {
+	case viewProcess:
+		if d.detailPID == 0 {
+			d.openProcessDetail()
+		}
+	case viewServices:
+		d.confirmServiceAction()
+	case viewContainers:
+		d.confirmContainerAction()
+	case viewWifi:
+		d.chooseWifiNetwork()
+	case viewLogs:
+		d.chooseLogUnit()
+	case viewControl:
+		d.toggleControl()
+	}
+}
+
+// commandResult is a custom command that finished, for the event loop
+// to show
+type commandResult struct {
+	command string
+	out     []byte
+	err     error
+}
+
+// runCommand runs a custom shell command off the UI goroutine, so a slow
+// one does not freeze the keys and the display. The event loop shows the
+// first line it printed once it finishes.
+func (d *Dashboard) runCommand(command string) {
+	log.Printf("Running %q", command)
+	d.showMessage("Running", truncateString(command, 24))
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+		d.commandResults <- commandResult{command: command, out: out, err: err}
+	}()
+}
+
+// showCommandResult reports a finished custom command in a message box,
+// unless another menu was opened meanwhile
+func (d *Dashboard) showCommandResult(res commandResult) {
+	shown := d.menu == nil || d.menu.title == "Running"
+	if res.err != nil {
+		msg := commandError(res.out, res.err)
+		log.Printf("Command %q failed: %s", res.command, msg)
+		if shown {
+			d.showMessage("Error", truncateString(res.command, 24), truncateString(msg, 24))
+		}
+		return
+	}
+	log.Printf("Command %q done", res.command)
+	if !shown {
+		return
+	}
+
+	lines := []string{truncateString(res.command, 24)}
+	if first := strings.TrimSpace(strings.SplitN(string(res.out), "\n", 2)[0]); first != "" {
+		lines = append(lines, truncateString(first, 24))
+	}
+	d.showMessage("Done", lines...)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"raspi-monitor/pkg/collector"
+)
+
+const (
+	// cameraRefresh is how often the cameras are listed again while the
+	// Hardware view is shown; libcamera takes about a second to probe
+	cameraRefresh = 30 * time.Second
+	// cameraTimeout bounds one run of the listing tool
+	cameraTimeout = 10 * time.Second
+)
+
+// cameraListers are tried in order; the tools were renamed from
+// libcamera-* to rpicam-* in Raspberry Pi OS bookworm
+var cameraListers = []string{"rpicam-hello", "libcamera-hello"}
+
+// cameraSensorNode matches the device-tree nodes of the official camera
+// sensors, e.g. "imx708@1a" or "ov5647@36"
+var cameraSensorNode = regexp.MustCompile(`^(imx\d+|ov\d+\w*)@[0-9a-f]+$`)
+
+// cameraListLine matches one camera of "--list-cameras", e.g.
+// "0 : imx708 [4608x2592 10-bit RGGB] (/base/soc/i2c0mux/i2c@1/imx708@1a)"
+var cameraListLine = regexp.MustCompile(`^\s*(\d+)\s*:\s*(\S+)\s*\[(\d+x\d+)`)
+
+// CameraStatus says which cameras were found and who has them open
+type CameraStatus struct {
+	Cameras []string // e.g. "imx708 4608x2592"
+	Source  string   // tool or place the cameras were found with
+	Users   []cameraUser
+}
+
+// cameraUser is a process with a camera device open
+type cameraUser struct {
+	PID    int
+	Name   string
+	Device string // e.g. "video0"
+}
+
+// getCameraStatus lists the cameras with libcamera, or with vcgencmd or
+// the device tree when libcamera is not installed
+func getCameraStatus() CameraStatus {
+	var s CameraStatus
+	s.Users = cameraUsers()
+
+	for _, tool := range cameraListers {
+		if _, err := exec.LookPath(tool); err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), cameraTimeout)
+		// The cameras are printed on stdout, the libcamera log on stderr
+		out, err := exec.CommandContext(ctx, tool, "--list-cameras").Output()
+		cancel()
+		if err != nil && len(out) == 0 {
+			continue
+		}
+		s.Source = tool
+		for _, line := range strings.Split(string(out), "\n") {
+			if m := cameraListLine.FindStringSubmatch(line); m != nil {
+				s.Cameras = append(s.Cameras, m[2]+" "+m[3])
+			}
+		}
+		return s
+	}
+
+	// "supported=1 detected=1, libcamera interfaces=0" on the legacy stack
+	if out, err := collector.Vcgencmd(context.Background(), "get_camera"); err == nil && strings.Contains(out, "detected=") {
+		s.Source = "vcgencmd"
+		if !strings.Contains(out, "detected=0") {
+			s.Cameras = append(s.Cameras, "legacy camera")
+		}
+		return s
+	}
+
+	if sensors := deviceTreeCameras(); len(sensors) > 0 {
+		s.Source = "device tree"
+		s.Cameras = sensors
+	}
+	return s
+}
+
+// deviceTreeCameras finds sensor nodes enabled by a camera dtoverlay.
+// They only show that the overlay is loaded, not that a camera answers.
+func deviceTreeCameras() []string {
+	var sensors []string
+	filepath.Walk("/proc/device-tree", func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		name := info.Name()
+		if !cameraSensorNode.MatchString(name) {
+			return nil
+		}
+		if status := readDeviceTree(strings.TrimPrefix(path, "/proc/device-tree/") + "/status"); status != "" && status != "okay" {
+			return filepath.SkipDir
+		}
+		sensors = append(sensors, name[:strings.IndexByte(name, '@')]+" (overlay)")
+		return filepath.SkipDir
+	})
+	return sensors
+}
+
+// cameraDevices returns the V4L2 nodes of camera receivers: the CSI-2
+// receivers unicam (Pi 4 and older) and rp1-cfe (Pi 5), and USB webcams.
+// The codec and ISP nodes are left out.
+func cameraDevices() map[string]bool {
+	devices := make(map[string]bool)
+	dirs, _ := filepath.Glob("/sys/class/video4linux/video*")
+	for _, dir := range dirs {
+		name := readSysfs(filepath.Join(dir, "name"))
+		driver, _ := os.Readlink(filepath.Join(dir, "device", "driver"))
+		if strings.Contains(name, "unicam") || strings.Contains(name, "cfe") || filepath.Base(driver) == "uvcvideo" {
+			devices[filepath.Base(dir)] = true
+		}
+	}
+	return devices
+}
+
+// cameraUsers finds the processes with a camera node open. Processes of
+// other users are only found when running as root.
+func cameraUsers() []cameraUser {
+	devices := cameraDevices()
+	if len(devices) == 0 {
+		return nil
+	}
+	self := os.Getpid()
+	var users []cameraUser
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	seen := make(map[int]bool)
+	for _, fd := range fds {
+		target, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(target, "/dev/video") {
+			continue
+		}
+		device := strings.TrimPrefix(target, "/dev/")
+		pid, _ := strconv.Atoi(strings.Split(fd, "/")[2])
+		if !devices[device] || pid == self || seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		users = append(users, cameraUser{PID: pid, Name: readSysfs(fmt.Sprintf("/proc/%d/comm", pid)), Device: device})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].PID < users[j].PID })
+	return users
+}
+
+// refreshCamera lists the cameras again once per cameraRefresh
+func (d *Dashboard) refreshCamera() {
+	if !d.cameraAt.IsZero() && time.Since(d.cameraAt) < cameraRefresh {
+		return
+	}
+	d.camera, d.cameraAt = getCameraStatus(), time.Now()
+}
+
+// cameraRows shows the cameras at the bottom of the Hardware view
+func (d *Dashboard) cameraRows() []string {
+	d.refreshCamera()
+	c := d.camera
+
+	rows := []string{"", "[--Camera--](fg:yellow)"}
+	switch {
+	case c.Source == "":
+		rows = append(rows, "[No camera tools](fg:white)", " install rpicam-apps")
+	case len(c.Cameras) == 0:
+		rows = append(rows, "[Not detected](fg:red)", " check ribbon and dtoverlay")
+	default:
+		for i, camera := range c.Cameras {
+			rows = append(rows, fmt.Sprintf("%d: [%s](fg:cyan)", i, truncateString(camera, 25)))
+		}
+	}
+	if len(c.Users) == 0 {
+		return append(rows, "In use: [no](fg:green)")
+	}
+	for _, u := range c.Users {
+		rows = append(rows, fmt.Sprintf("[In use: %d %s](fg:yellow)", u.PID, truncateString(u.Name, 11)))
+	}
+	return rows
+}
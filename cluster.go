@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clusterPushLimit bounds the body of a push; the process list is not sent
+const clusterPushLimit = 1 << 20
+
+// ClusterConfig lists the other Pis shown on the Cluster view. Nodes are
+// polled through their JSON API; with accept set, agents can also push
+// their stats to POST /api/push on this monitor's api address.
+type ClusterConfig struct {
+	Nodes    []ClusterNode `yaml:"nodes"`
+	Accept   bool          `yaml:"accept"` // accept pushes from agents
+	Token    string        `yaml:"token"`  // shared secret agents send as a bearer token, empty accepts any
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// ClusterNode is a Pi whose API is polled
+type ClusterNode struct {
+	Name string `yaml:"name"` // shown on the view, defaults to the node's host name
+	URL  string `yaml:"url"`  // base URL of the node's api, e.g. http://pi2.local:8080
+}
+
+// AgentConfig pushes this Pi's stats to a central monitor
+type AgentConfig struct {
+	Server   string        `yaml:"server"` // base URL of the central monitor's api, empty disables it
+	Token    string        `yaml:"token"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+func (c ClusterConfig) validate() error {
+	for i, n := range c.Nodes {
+		if u, err := url.Parse(n.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("cluster.nodes[%d].url must be an http(s) URL, got %q", i, n.URL)
+		}
+	}
+	if c.Interval <= 0 || c.Timeout <= 0 {
+		return fmt.Errorf("cluster.interval and cluster.timeout must be positive")
+	}
+	return nil
+}
+
+func (c AgentConfig) validate() error {
+	if c.Server == "" {
+		return nil
+	}
+	if u, err := url.Parse(c.Server); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("agent.server must be an http(s) URL, got %q", c.Server)
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("agent.interval must be positive")
+	}
+	return nil
+}
+
+// NodeStatus is the latest sample of one cluster node
+type NodeStatus struct {
+	Name    string
+	Stats   SystemStats
+	Updated time.Time // when the sample arrived, zero before the first
+	Err     error     // why the last poll failed
+	Pushed  bool      // sent by an agent rather than polled
+}
+
+// ClusterMonitor polls the configured nodes and keeps what agents push
+type ClusterMonitor struct {
+	cfg    ClusterConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	nodes map[string]*NodeStatus // by polled URL, or host name for pushes
+	found map[string]foundNode   // monitors found over mDNS, by URL
+}
+
+// foundNode is a monitor found over mDNS and when it last answered
+type foundNode struct {
+	node ClusterNode
+	seen time.Time
+}
+
+func newClusterMonitor(cfg ClusterConfig) *ClusterMonitor {
+	return &ClusterMonitor{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		nodes:  make(map[string]*NodeStatus),
+		found:  make(map[string]foundNode),
+	}
+}
+
+// discovered adds the monitors an mDNS browse found, by instance name
+// and API URL. Monitors that stop answering are dropped once their
+// records would have expired.
+func (m *ClusterMonitor) discovered(found map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for name, u := range found {
+		m.found[u] = foundNode{node: ClusterNode{Name: name, URL: u}, seen: now}
+	}
+	for u, f := range m.found {
+		if now.Sub(f.seen) > mdnsTTL*time.Second {
+			delete(m.found, u)
+			delete(m.nodes, u)
+		}
+	}
+}
+
+// polled returns the configured nodes, then the ones found over mDNS by
+// name. The caller holds mu.
+func (m *ClusterMonitor) polled() []ClusterNode {
+	var found []ClusterNode
+	for u, f := range m.found {
+		if !m.configured(u) {
+			found = append(found, f.node)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+	return append(append([]ClusterNode(nil), m.cfg.Nodes...), found...)
+}
+
+// configured reports whether u is the URL of one of cluster.nodes
+func (m *ClusterMonitor) configured(u string) bool {
+	for _, n := range m.cfg.Nodes {
+		if n.URL == u {
+			return true
+		}
+	}
+	return false
+}
+
+// Nodes returns the polled nodes in config order, then the ones found
+// over mDNS and the agents by name
+func (m *ClusterMonitor) Nodes() []NodeStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var nodes, pushed []NodeStatus
+	for _, n := range m.polled() {
+		var status NodeStatus
+		if s, ok := m.nodes[n.URL]; ok {
+			status = *s
+		}
+		if n.Name != "" {
+			status.Name = n.Name
+		} else if status.Name == "" {
+			// Not reached yet, so the host name is not known
+			status.Name = n.URL
+			if u, err := url.Parse(n.URL); err == nil {
+				status.Name = u.Hostname()
+			}
+		}
+		nodes = append(nodes, status)
+	}
+	for _, s := range m.nodes {
+		if s.Pushed {
+			pushed = append(pushed, *s)
+		}
+	}
+	sort.Slice(pushed, func(i, j int) bool { return pushed[i].Name < pushed[j].Name })
+	return append(nodes, pushed...)
+}
+
+// Stale reports whether a node has not been heard from for three
+// intervals
+func (m *ClusterMonitor) Stale(n NodeStatus) bool {
+	return n.Updated.IsZero() || time.Since(n.Updated) > 3*m.cfg.Interval
+}
+
+// Run polls the nodes every interval until stop is closed
+func (m *ClusterMonitor) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.mu.Lock()
+		nodes := m.polled()
+		m.mu.Unlock()
+
+		var wg sync.WaitGroup
+		for _, n := range nodes {
+			wg.Add(1)
+			go func(n ClusterNode) {
+				defer wg.Done()
+				m.poll(n)
+			}(n)
+		}
+		wg.Wait()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches /api/stats of a node. A failed poll keeps the last
+// sample, which the view shows as stale.
+func (m *ClusterMonitor) poll(n ClusterNode) {
+	var body apiStats
+	resp, err := m.client.Get(strings.TrimSuffix(n.URL, "/") + "/api/stats")
+	if err == nil {
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("%s", resp.Status)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&body)
+		}
+		resp.Body.Close()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.found[n.URL]; !ok && !m.configured(n.URL) {
+		return // dropped from mDNS while polling
+	}
+	status, ok := m.nodes[n.URL]
+	if !ok {
+		status = &NodeStatus{}
+		m.nodes[n.URL] = status
+	}
+	status.Err = err
+	if err != nil {
+		return
+	}
+	status.Name = body.Hostname
+	status.Stats = body.SystemStats
+	status.Updated = time.Now()
+}
+
+// push stores a sample an agent sent
+func (m *ClusterMonitor) push(body apiStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodes[body.Hostname] = &NodeStatus{Name: body.Hostname, Stats: body.SystemStats, Updated: time.Now(), Pushed: true}
+}
+
+// clusterPushHandler takes the /api/stats body of an agent
+func (d *Dashboard) clusterPushHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if token := d.config.Cluster.Token; token != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	var body apiStats
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, clusterPushLimit)).Decode(&body); err != nil {
+		http.Error(w, "invalid stats: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Hostname == "" {
+		http.Error(w, "hostname is missing", http.StatusBadRequest)
+		return
+	}
+	d.cluster.push(body)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runAgent pushes the latest sample to agent.server every interval
+// until stop is closed. A failure is logged once until a push succeeds.
+func (d *Dashboard) runAgent(stop <-chan struct{}) {
+	cfg := d.config.Agent
+	endpoint := strings.TrimSuffix(cfg.Server, "/") + "/api/push"
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	failing := false
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		stats, at := d.snapshot.Get()
+		if at.IsZero() {
+			continue
+		}
+		stats.AllProcesses = nil
+		err := pushStats(endpoint, cfg.Token, apiStats{Timestamp: at, Hostname: hostname(), SystemStats: stats})
+		if err != nil && !failing {
+			log.Printf("Agent: push to %s failed: %v", cfg.Server, err)
+		} else if err == nil && failing {
+			log.Printf("Agent: pushing to %s again", cfg.Server)
+		}
+		failing = err != nil
+	}
+}
+
+func pushStats(endpoint, token string, body apiStats) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return doNotify(req)
+}
+
+// updateClusterView lists this Pi and the cluster nodes with the
+// averages and the hottest node on top
+func (d *Dashboard) updateClusterView(stats SystemStats) {
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+
+	if d.cluster == nil {
+		d.mainList.Rows = []string{"", "No cluster nodes", "", "List nodes under cluster:", "or let agents push here", "with cluster.accept."}
+		return
+	}
+
+	nodes := append([]NodeStatus{{Name: d.statsHost(), Stats: stats, Updated: time.Now()}}, d.cluster.Nodes()...)
+	up := 0
+	var cpu, mem float64
+	hottest := -1
+	for i, n := range nodes {
+		if d.cluster.Stale(n) {
+			continue
+		}
+		up++
+		cpu += calculateAverage(n.Stats.CPUPercent)
+		mem += n.Stats.MemPercent
+		if n.Stats.Temperature > 0 && (hottest < 0 || n.Stats.Temperature > nodes[hottest].Stats.Temperature) {
+			hottest = i
+		}
+	}
+
+	upColor := "green"
+	if up < len(nodes) {
+		upColor = "yellow"
+	}
+	rows := []string{fmt.Sprintf("Nodes: [%d/%d up](fg:%s)", up, len(nodes), upColor)}
+	if up > 0 {
+		rows = append(rows, fmt.Sprintf("Avg CPU %4.1f%%  Mem %4.1f%%", cpu/float64(up), mem/float64(up)))
+	}
+	if hottest >= 0 {
+		n := nodes[hottest]
+		rows = append(rows, fmt.Sprintf("Hottest %s %s", fitString(n.Name, 12), formatTemperature(n.Stats.Temperature)))
+	}
+	rows = append(rows, "", fmt.Sprintf("[%-8s %5s %5s %6s](fg:cyan)", "Node", "CPU", "Mem", "Temp"))
+
+	for _, n := range nodes {
+		name := fitString(n.Name, 8)
+		if d.cluster.Stale(n) {
+			reason := "no data"
+			if n.Err != nil {
+				reason = "offline"
+			} else if !n.Updated.IsZero() {
+				reason = formatAge(time.Since(n.Updated)) + " ago"
+			}
+			rows = append(rows, fmt.Sprintf("%-8s [%s](fg:red)", name, reason))
+			continue
+		}
+		s := n.Stats
+		color := "white"
+		if s.Temperature >= d.config.TempWarn && d.config.TempWarn > 0 {
+			color = "red"
+		}
+		rows = append(rows, fmt.Sprintf("%-8s %4.0f%% %4.0f%% [%6s](fg:%s)",
+			name, calculateAverage(s.CPUPercent), s.MemPercent, formatTemperature(s.Temperature), color))
+	}
+	d.mainList.Rows = rows
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Bounds of the update interval, whether configured or changed with +/-
+const (
+	minInterval = 500 * time.Millisecond
+	maxInterval = time.Minute
+)
+
+// intervalSteps are the intervals +/- step through
+var intervalSteps = []time.Duration{
+	500 * time.Millisecond, time.Second, 2 * time.Second, 5 * time.Second,
+	10 * time.Second, 15 * time.Second, 30 * time.Second, time.Minute,
+}
+
+// runCollector samples the stats every interval off the UI goroutine.
+// Enumerating the processes alone can take hundreds of milliseconds on
+// a Pi Zero, which used to freeze keys and buttons. A sample the event
+// loop has not picked up yet is replaced by the newer one.
+func (d *Dashboard) runCollector(interval time.Duration, stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// The systemd watchdog is pinged from this loop, so a Collect stuck
+	// on a hung /sys read gets the monitor restarted
+	var watchdog <-chan time.Time
+	if every := watchdogInterval(); every > 0 {
+		t := time.NewTicker(every)
+		defer t.Stop()
+		watchdog = t.C
+	}
+
+	var lastErr string
+	for {
+		select {
+		case <-stop:
+			return
+		case interval = <-d.intervalChanges:
+			ticker.Reset(interval)
+			continue
+		case <-watchdog:
+			if err := sdNotify("WATCHDOG=1"); err != nil {
+				log.Printf("Watchdog ping failed: %v", err)
+			}
+			continue
+		case <-ticker.C:
+		}
+
+		stats, err := d.collector.Collect(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return // exiting
+			}
+			// A remote host went away; keep the last sample and retry,
+			// logging the error once rather than at every tick
+			if err.Error() != lastErr {
+				lastErr = err.Error()
+				log.Printf("Stats collection failed: %v", err)
+			}
+			continue
+		}
+		if lastErr != "" {
+			lastErr = ""
+			log.Println("Stats collection resumed")
+		}
+		d.logins.Refresh()
+		d.containers.Refresh()
+
+		select {
+		case <-d.statsUpdates:
+		default:
+		}
+		d.statsUpdates <- stats
+	}
+}
+
+// changeInterval moves the update interval delta steps along
+// intervalSteps, a negative delta refreshing faster. A configured
+// interval between two steps snaps to the neighbouring one.
+func (d *Dashboard) changeInterval(delta int) {
+	i := 0
+	for i < len(intervalSteps)-1 && intervalSteps[i] < d.interval {
+		i++
+	}
+	if delta > 0 && intervalSteps[i] > d.interval {
+		i-- // intervalSteps[i] is already one step up
+	}
+	i += delta
+	if i < 0 {
+		i = 0
+	} else if i >= len(intervalSteps) {
+		i = len(intervalSteps) - 1
+	}
+	if intervalSteps[i] == d.interval {
+		return
+	}
+
+	d.interval = intervalSteps[i]
+	select {
+	case <-d.intervalChanges:
+	default:
+	}
+	d.intervalChanges <- d.interval
+	d.redraw()
+}
+
+// togglePause freezes the display on the current sample or resumes
+// with the latest one
+func (d *Dashboard) togglePause() {
+	d.paused = !d.paused
+	if r := d.replay(); r != nil {
+		r.SetPaused(d.paused)
+	}
+	if d.paused {
+		d.frozen, _ = d.snapshot.Get()
+		d.redraw()
+		return
+	}
+	d.UpdateStats()
+	d.Render()
+}
+
+// formatInterval prints an interval the way the title shows it, e.g.
+// "0.5s" or "30s"
+func formatInterval(interval time.Duration) string {
+	return fmt.Sprintf("%gs", interval.Seconds())
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandConfig is a widget showing the output of a shell command run
+// periodically in the background, e.g. "vcgencmd measure_clock arm" or
+// a script checking the health endpoint of an application
+type CommandConfig struct {
+	Name     string        `yaml:"name"`
+	Command  string        `yaml:"command"`
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Lines    int           `yaml:"lines"` // output lines shown, the first ones
+}
+
+func (c CommandConfig) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("commands: name is required")
+	}
+	if strings.TrimSpace(c.Command) == "" {
+		return fmt.Errorf("command %q: command is required", c.Name)
+	}
+	if c.Interval < 0 || c.Timeout < 0 || c.Lines < 0 {
+		return fmt.Errorf("command %q: interval, timeout and lines must not be negative", c.Name)
+	}
+	return nil
+}
+
+// withDefaults fills in the interval, timeout and line count left out
+func (c CommandConfig) withDefaults() CommandConfig {
+	if c.Interval == 0 {
+		c.Interval = 10 * time.Second
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5 * time.Second
+	}
+	if c.Lines == 0 {
+		c.Lines = 1
+	}
+	return c
+}
+
+// validateCommands checks the commands and that their names are unique
+func validateCommands(commands []CommandConfig) error {
+	names := make(map[string]bool)
+	for _, c := range commands {
+		if err := c.validate(); err != nil {
+			return err
+		}
+		if names[c.Name] {
+			return fmt.Errorf("command %q is defined twice", c.Name)
+		}
+		names[c.Name] = true
+	}
+	return nil
+}
+
+// commandIndex looks up a command by name, returning -1 when there is
+// none
+func commandIndex(commands []CommandConfig, name string) int {
+	for i, c := range commands {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// CommandWidget runs a command and keeps its last output. The commands
+// section runs in the background for the whole session; a command of a
+// custom view's own only runs while the view is shown.
+type CommandWidget struct {
+	cfg CommandConfig
+
+	mu      sync.Mutex
+	output  []string
+	err     string // why the last run failed
+	ranAt   time.Time
+	running bool
+}
+
+func newCommandWidget(cfg CommandConfig) *CommandWidget {
+	return &CommandWidget{cfg: cfg.withDefaults()}
+}
+
+// Run runs the command every interval until stop is closed
+func (c *CommandWidget) Run(stop <-chan struct{}) {
+	c.refresh()
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.refresh()
+		}
+	}
+}
+
+// refresh starts the command unless it is still running or ran within
+// the interval
+func (c *CommandWidget) refresh() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running || (!c.ranAt.IsZero() && time.Since(c.ranAt) < c.cfg.Interval) {
+		return
+	}
+	c.running = true
+	go c.run()
+}
+
+func (c *CommandWidget) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "sh", "-c", c.cfg.Command).CombinedOutput()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running, c.ranAt = false, time.Now()
+	c.output, c.err = nil, ""
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		c.err = fmt.Sprintf("timed out after %s", c.cfg.Timeout)
+	case err != nil:
+		c.err = commandError(out, err)
+	}
+	if c.err != "" {
+		log.Printf("Command widget %q failed: %s", c.cfg.Command, c.err)
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		c.output = append(c.output, strings.TrimRight(line, "\r"))
+	}
+}
+
+// rows shows the first lines of the last output, on the same row as the
+// title when one line is shown
+func (c *CommandWidget) rows(title string, width int) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var lines []string
+	color := ""
+	switch {
+	case c.err != "":
+		lines, color = []string{c.err}, "red"
+	case c.ranAt.IsZero():
+		lines, color = []string{"..."}, "white"
+	default:
+		lines = c.output
+		if len(lines) > c.cfg.Lines {
+			lines = lines[:c.cfg.Lines]
+		}
+	}
+
+	if title != "" && len(lines) == 1 && len(title)+2 < width {
+		line := fitString(lines[0], width-len(title)-2)
+		if color != "" {
+			line = fmt.Sprintf("[%s](fg:%s)", line, color)
+		}
+		return []string{fmt.Sprintf("[%s:](fg:cyan) %s", title, line)}
+	}
+	var rows []string
+	if title != "" {
+		rows = append(rows, fmt.Sprintf("[%s](fg:cyan)", fitString(title, width)))
+	}
+	for _, line := range lines {
+		line = fitString(line, width)
+		if color != "" {
+			line = fmt.Sprintf("[%s](fg:%s)", line, color)
+		}
+		rows = append(rows, line)
+	}
+	return rows
+}
+
+// namedCommand returns the widget of a command from the commands section
+func (d *Dashboard) namedCommand(name string) *CommandWidget {
+	for _, c := range d.commands {
+		if c.cfg.Name == name {
+			return c
+		}
+	}
+	return newCommandWidget(CommandConfig{Name: name}) // checked by validate
+}
+
+// inlineCommand returns the widget of a command given in a custom view,
+// created and run while the view is shown
+func (d *Dashboard) inlineCommand(key string, w WidgetConfig) *CommandWidget {
+	if d.inlineCommands == nil {
+		d.inlineCommands = make(map[string]*CommandWidget)
+	}
+	c, ok := d.inlineCommands[key]
+	if !ok {
+		c = newCommandWidget(CommandConfig{Command: w.Command, Interval: w.Interval, Timeout: w.Timeout, Lines: w.Lines})
+		d.inlineCommands[key] = c
+	}
+	c.refresh()
+	return c
+}
+
+// commandRows shows the commands section on the System view
+func (d *Dashboard) commandRows() []string {
+	if len(d.commands) == 0 {
+		return nil
+	}
+	rows := []string{"", "[--Commands--](fg:cyan)"}
+	for _, c := range d.commands {
+		rows = append(rows, c.rows(c.cfg.Name, viewContentWidth)...)
+	}
+	return rows
+}
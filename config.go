@@ -0,0 +1,567 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the runtime settings that used to be hard-coded constants.
+// It is loaded from ~/.config/raspi-monitor/config.yaml when present.
+type Config struct {
+	Interval    time.Duration    `yaml:"interval"`
+	LogFile     string           `yaml:"log_file"`
+	LogOutput   string           `yaml:"log_output"` // file, syslog or journald
+	DiskMount   string           `yaml:"disk_mount"`
+	DefaultView string           `yaml:"default_view"`
+	Prometheus  string           `yaml:"prometheus"`       // listen address, empty disables the exporter
+	API         string           `yaml:"api"`              // listen address of the JSON API, empty disables it
+	SDWriteWarn float64          `yaml:"sd_write_warn_gb"` // GB written per day that triggers a warning, 0 disables it
+	TempWarn    float64          `yaml:"temp_warn"`        // °C that counts as an overheat event, 0 disables them
+	GPIO        GPIOConfig       `yaml:"gpio"`
+	MQTT        MQTTConfig       `yaml:"mqtt"`
+	Influx      InfluxConfig     `yaml:"influxdb"`
+	Graphite    GraphiteConfig   `yaml:"graphite"`
+	Cluster     ClusterConfig    `yaml:"cluster"`
+	Agent       AgentConfig      `yaml:"agent"`
+	MDNS        MDNSConfig       `yaml:"mdns"`
+	Remote      RemoteConfig     `yaml:"remote"`
+	Report      ReportConfig     `yaml:"report"`
+	Record      RecordConfig     `yaml:"record"`
+	Replay      ReplayConfig     `yaml:"replay"`
+	Screenshot  ScreenshotConfig `yaml:"screenshot"`
+	Fan         FanConfig        `yaml:"fan"`
+	EnvSensors  EnvConfig        `yaml:"env_sensors"`
+	OneWire     OneWireConfig    `yaml:"one_wire"`
+	UPS         UPSConfig        `yaml:"ups"`
+	History     HistoryConfig    `yaml:"history"`
+	Alerts      AlertsConfig     `yaml:"alerts"`
+	Display     DisplayConfig    `yaml:"display"`
+	Internet    InternetConfig   `yaml:"internet"`
+	PublicIP    PublicIPConfig   `yaml:"public_ip"`
+	DNSNTP      DNSNTPConfig     `yaml:"dns_ntp"`
+	SpeedTest   SpeedTestConfig  `yaml:"speedtest"`
+	AP          APConfig         `yaml:"ap"`
+	Controls    []ControlConfig  `yaml:"controls"`
+	Theme       ThemeConfig      `yaml:"theme"`
+	Views       []ViewConfig     `yaml:"views"`
+	Commands    []CommandConfig  `yaml:"commands"`
+	Plugins     []PluginConfig   `yaml:"plugins"`
+	Keys        KeysConfig       `yaml:"keys"`
+}
+
+// GPIOConfig maps button names to BCM pin numbers and actions and sets
+// the press timings used by the button watcher.
+type GPIOConfig struct {
+	Enabled   bool              `yaml:"enabled"`
+	Chip      string            `yaml:"chip"`   // gpiochip name or path, "auto" detects the header
+	Offset    int               `yaml:"offset"` // added to the BCM numbers to get line offsets
+	Pins      map[string]int    `yaml:"pins"`
+	Actions   map[string]string `yaml:"actions"` // button name -> action
+	Debounce  time.Duration     `yaml:"debounce"`
+	LongPress time.Duration     `yaml:"long_press"`
+	Repeat    time.Duration     `yaml:"repeat"`
+}
+
+// defaultButtonPins returns the pin layout of the original button HAT
+func defaultButtonPins() map[string]int {
+	return map[string]int{
+		"up":     buttonUp,
+		"down":   buttonDown,
+		"left":   buttonLeft,
+		"right":  buttonRight,
+		"a":      buttonA,
+		"b":      buttonB,
+		"x":      buttonX,
+		"y":      buttonY,
+		"start":  buttonStart,
+		"select": buttonSelect,
+		"l":      buttonL,
+		"r":      buttonR,
+		"center": buttonCenter,
+	}
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Interval:    updateInterval,
+		LogFile:     "raspi-monitor.log",
+		LogOutput:   logOutputFile,
+		DiskMount:   "/",
+		DefaultView: "system",
+		SDWriteWarn: 10,
+		TempWarn:    80,
+		GPIO: GPIOConfig{
+			Enabled:   true,
+			Chip:      "auto",
+			Pins:      defaultButtonPins(),
+			Actions:   defaultButtonActions(),
+			Debounce:  30 * time.Millisecond,
+			LongPress: 800 * time.Millisecond,
+			Repeat:    150 * time.Millisecond,
+		},
+		Influx: InfluxConfig{Measurement: "raspi"},
+		Graphite: GraphiteConfig{
+			Protocol: graphiteProtocol,
+			Prefix:   "raspi",
+			Interval: 10 * time.Second,
+		},
+		Cluster:    ClusterConfig{Interval: 10 * time.Second, Timeout: 5 * time.Second},
+		Agent:      AgentConfig{Interval: 10 * time.Second},
+		MDNS:       MDNSConfig{Interval: time.Minute},
+		Remote:     RemoteConfig{Command: defaultRemoteCommand},
+		Report:     ReportConfig{Format: reportText},
+		Replay:     ReplayConfig{Speed: 60},
+		Screenshot: ScreenshotConfig{FontSize: 16},
+		MQTT: MQTTConfig{
+			TopicPrefix:     "raspi",
+			Discovery:       true,
+			DiscoveryPrefix: "homeassistant",
+		},
+		Fan: FanConfig{
+			Mode:       fanModeGPIO,
+			Pin:        18,
+			Hysteresis: 3,
+			Curve:      defaultFanCurve(),
+		},
+		History: HistoryConfig{
+			Path:       defaultHistoryPath(),
+			Resolution: 10 * time.Second,
+			Retention:  7 * 24 * time.Hour,
+		},
+		Alerts: AlertsConfig{
+			Enabled: true,
+			Rules:   defaultAlertRules(),
+			Email:   EmailConfig{Port: 587, TLS: smtpStartTLS},
+			Ntfy:    NtfyConfig{Server: "https://ntfy.sh"},
+		},
+		Display: DisplayConfig{
+			Backend:      displayTerminal,
+			Width:        240,
+			Height:       240,
+			FontSize:     8,
+			SPISpeed:     40000000,
+			DCPin:        25,
+			ResetPin:     27,
+			BacklightPin: 24,
+			Address:      0x3c,
+			PageInterval: 5 * time.Second,
+			Listen:       ":8090",
+		},
+		Internet: InternetConfig{
+			Enabled:  true,
+			URL:      "http://connectivitycheck.gstatic.com/generate_204",
+			DNSHost:  "one.one.one.one",
+			Interval: 30 * time.Second,
+			Timeout:  5 * time.Second,
+		},
+		PublicIP: PublicIPConfig{
+			URL:      "https://api.ipify.org",
+			Interval: 5 * time.Minute,
+			Timeout:  10 * time.Second,
+		},
+		DNSNTP: DNSNTPConfig{
+			Enabled:    true,
+			LookupHost: "one.one.one.one",
+			Interval:   time.Minute,
+			Timeout:    3 * time.Second,
+		},
+		EnvSensors: EnvConfig{
+			Device:   "/dev/i2c-1",
+			Interval: 10 * time.Second,
+		},
+		OneWire: OneWireConfig{
+			Enabled:  true,
+			Interval: 10 * time.Second,
+		},
+		UPS: UPSConfig{
+			Type:            upsPiSugar,
+			Device:          "/dev/i2c-1",
+			Server:          "127.0.0.1:8423",
+			PowerPin:        6,
+			Interval:        10 * time.Second,
+			ShutdownPercent: 10,
+			ShutdownCommand: "shutdown -h now",
+		},
+		SpeedTest: SpeedTestConfig{
+			DownloadURL: "https://speed.cloudflare.com/__down?bytes=1000000000",
+			UploadURL:   "https://speed.cloudflare.com/__up",
+			Duration:    10 * time.Second,
+		},
+		AP: APConfig{
+			SSID: "RaspberryPi_AP",
+		},
+		Theme: ThemeConfig{
+			Name: themeDefault,
+		},
+		Keys: KeysConfig{
+			Preset: keyPresetDefault,
+		},
+	}
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/raspi-monitor/config.yaml,
+// falling back to ~/.config when XDG_CONFIG_HOME is unset.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "raspi-monitor", "config.yaml")
+}
+
+// loadConfig reads the config file at path on top of the defaults.
+// A missing file is not an error; the defaults are returned as is.
+func loadConfig(path string) (*Config, error) {
+	cfg := defaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return defaultConfig(), fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	// Pins listed in the file override the defaults one by one
+	pins := defaultButtonPins()
+	for name, pin := range cfg.GPIO.Pins {
+		name = strings.ToLower(name)
+		if _, ok := pins[name]; !ok {
+			log.Printf("Config: ignoring unknown button %q", name)
+			continue
+		}
+		pins[name] = pin
+	}
+	cfg.GPIO.Pins = pins
+
+	// Actions work the same way, so a layout only lists what it changes
+	actions := defaultButtonActions()
+	for name, action := range cfg.GPIO.Actions {
+		name = strings.ToLower(name)
+		if _, ok := actions[name]; !ok {
+			log.Printf("Config: ignoring action for unknown button %q", name)
+			continue
+		}
+		actions[name] = strings.TrimSpace(action)
+	}
+	cfg.GPIO.Actions = actions
+
+	if err := cfg.validate(); err != nil {
+		return defaultConfig(), fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c *Config) validate() error {
+	if c.Interval < minInterval || c.Interval > maxInterval {
+		return fmt.Errorf("interval must be between %s and %s, got %s", minInterval, maxInterval, c.Interval)
+	}
+	switch c.LogOutput {
+	case logOutputFile, logOutputSyslog, logOutputJournald:
+	default:
+		return fmt.Errorf("unknown log_output %q (%s)", c.LogOutput, strings.Join(logOutputs, ", "))
+	}
+	if err := validateCommands(c.Commands); err != nil {
+		return err
+	}
+	if err := validateViews(c.Views, c.Commands); err != nil {
+		return err
+	}
+	if err := validatePlugins(c.Plugins); err != nil {
+		return err
+	}
+	if viewIndex(c.DefaultView) < 0 && customViewIndex(c.Views, c.DefaultView) < 0 {
+		return fmt.Errorf("unknown default_view %q", c.DefaultView)
+	}
+	if c.SDWriteWarn < 0 {
+		return fmt.Errorf("sd_write_warn_gb must not be negative")
+	}
+	if c.TempWarn < 0 {
+		return fmt.Errorf("temp_warn must not be negative")
+	}
+	if c.GPIO.Debounce < 0 || c.GPIO.LongPress <= 0 || c.GPIO.Repeat < 0 {
+		return fmt.Errorf("invalid gpio timings: debounce=%s long_press=%s repeat=%s",
+			c.GPIO.Debounce, c.GPIO.LongPress, c.GPIO.Repeat)
+	}
+	if c.GPIO.Offset < 0 {
+		return fmt.Errorf("gpio.offset must not be negative")
+	}
+	for button, action := range c.GPIO.Actions {
+		if err := validateButtonAction(button, action); err != nil {
+			return err
+		}
+	}
+	if c.MQTT.Broker != "" && (c.MQTT.TopicPrefix == "" || c.MQTT.DiscoveryPrefix == "") {
+		return fmt.Errorf("mqtt.topic_prefix and mqtt.discovery_prefix must not be empty")
+	}
+	if err := c.Influx.validate(); err != nil {
+		return err
+	}
+	if err := c.Graphite.validate(); err != nil {
+		return err
+	}
+	if err := c.Cluster.validate(); err != nil {
+		return err
+	}
+	if err := c.Agent.validate(); err != nil {
+		return err
+	}
+	if err := c.MDNS.validate(); err != nil {
+		return err
+	}
+	if err := c.Remote.validate(); err != nil {
+		return err
+	}
+	if err := c.Report.validate(); err != nil {
+		return err
+	}
+	if err := c.Record.validate(); err != nil {
+		return err
+	}
+	if err := c.Replay.validate(); err != nil {
+		return err
+	}
+	if err := c.Screenshot.validate(); err != nil {
+		return err
+	}
+	if c.Replay.Path != "" && c.Remote.Host != "" {
+		return fmt.Errorf("replay and remote.host cannot be used together")
+	}
+	if c.Fan.Enabled {
+		if err := c.Fan.validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.checkPinConflicts(); err != nil {
+		return err
+	}
+	if c.History.Enabled && (c.History.Resolution <= 0 || c.History.Retention <= 0) {
+		return fmt.Errorf("history.resolution and history.retention must be positive")
+	}
+	if c.History.Path == "" {
+		c.History.Path = defaultHistoryPath()
+	}
+	if err := c.Alerts.validate(); err != nil {
+		return err
+	}
+	if err := c.AP.validate(); err != nil {
+		return err
+	}
+	if err := c.SpeedTest.validate(); err != nil {
+		return err
+	}
+	if err := c.Internet.validate(); err != nil {
+		return err
+	}
+	if err := c.PublicIP.validate(); err != nil {
+		return err
+	}
+	if err := c.DNSNTP.validate(); err != nil {
+		return err
+	}
+	if err := c.EnvSensors.validate(); err != nil {
+		return err
+	}
+	if err := c.OneWire.validate(); err != nil {
+		return err
+	}
+	if c.UPS.Enabled {
+		if err := c.UPS.validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.Display.validate(); err != nil {
+		return err
+	}
+	if err := validateControls(c.Controls); err != nil {
+		return err
+	}
+	if err := c.Theme.validate(); err != nil {
+		return err
+	}
+	if err := c.Keys.validate(); err != nil {
+		return err
+	}
+	if c.DiskMount == "" {
+		c.DiskMount = "/"
+	}
+	return nil
+}
+
+// checkPinConflicts makes sure the buttons, the fan, the alert outputs,
+// the controls and the LCD control lines do not claim the same GPIO line
+func (c *Config) checkPinConflicts() error {
+	users := make(map[int]string)
+	claim := func(pin int, user string) error {
+		if other, ok := users[pin]; ok {
+			return fmt.Errorf("GPIO%d is used by both %s and %s", pin, other, user)
+		}
+		users[pin] = user
+		return nil
+	}
+
+	if c.GPIO.Enabled {
+		for name, pin := range c.GPIO.Pins {
+			if err := claim(pin, fmt.Sprintf("button %q", name)); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Fan.Enabled && c.Fan.Mode == fanModeGPIO {
+		if err := claim(c.Fan.Pin, "the fan"); err != nil {
+			return err
+		}
+	}
+	if c.UPS.Enabled && c.UPS.Type == upsX728 {
+		if err := claim(c.UPS.PowerPin, "the X728 power loss input"); err != nil {
+			return err
+		}
+	}
+	if c.Alerts.Enabled {
+		for _, out := range c.Alerts.Outputs {
+			if err := claim(out.Pin, fmt.Sprintf("output %q", out.Name)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, control := range c.Controls {
+		if err := claim(control.Pin, fmt.Sprintf("control %q", control.Name)); err != nil {
+			return err
+		}
+	}
+	if c.Display.spiPanel() {
+		pins := []int{c.Display.DCPin, c.Display.ResetPin, c.Display.BacklightPin}
+		for i, user := range []string{"the LCD DC line", "the LCD reset line", "the LCD backlight"} {
+			if pins[i] < 0 {
+				continue
+			}
+			if err := claim(pins[i], user); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// defaultViewIndex returns the view index selected by DefaultView
+func (c *Config) defaultViewIndex() int {
+	if i := viewIndex(c.DefaultView); i >= 0 {
+		return i
+	}
+	return viewSystem
+}
+
+// viewNameList returns the names accepted by default_view and --view
+func viewNameList() []string {
+	names := make([]string, len(views))
+	for i, v := range views {
+		names[i] = v.name
+	}
+	return names
+}
+
+// viewIndex looks up a view by name, returning -1 when there is none
+func viewIndex(name string) int {
+	for i, v := range views {
+		if strings.EqualFold(v.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// cliOptions holds the command-line flags. Flags that are set on the
+// command line take precedence over the config file.
+type cliOptions struct {
+	configPath string
+	interval   time.Duration
+	noGPIO     bool
+	view       string
+	logFile    string
+	logOutput  string
+	mount      string
+	prometheus string
+	api        string
+	display    string
+	theme      string
+	remote     string
+	record     string
+	replay     string
+	speed      float64
+}
+
+func parseFlags() *cliOptions {
+	opts := &cliOptions{}
+	flag.StringVar(&opts.configPath, "config", defaultConfigPath(), "path to the config file")
+	flag.DurationVar(&opts.interval, "interval", updateInterval, "update interval (e.g. 500ms, 2s)")
+	flag.BoolVar(&opts.noGPIO, "no-gpio", false, "disable GPIO button support")
+	flag.StringVar(&opts.view, "view", "system", "initial view: "+strings.Join(viewNameList(), ", "))
+	flag.StringVar(&opts.logFile, "log", "raspi-monitor.log", "log file path")
+	flag.StringVar(&opts.logOutput, "log-output", logOutputFile, "log output: "+strings.Join(logOutputs, ", "))
+	flag.StringVar(&opts.mount, "mount", "/", "mount point shown in the disk usage")
+	flag.StringVar(&opts.prometheus, "prometheus", "", "serve Prometheus metrics on this address (e.g. :9101)")
+	flag.StringVar(&opts.api, "api", "", "serve the JSON API on this address (e.g. :8080)")
+	flag.StringVar(&opts.display, "display", displayTerminal, "display backend: "+strings.Join(displayBackends, ", "))
+	flag.StringVar(&opts.theme, "theme", themeDefault, "color theme: "+strings.Join(themeNames(), ", "))
+	flag.StringVar(&opts.remote, "remote", "", "show the stats of another host: user@host over ssh, or the URL of its api")
+	flag.StringVar(&opts.record, "record", "", "append every stats sample to this file (.gz to compress)")
+	flag.StringVar(&opts.replay, "replay", "", "play back a recording instead of showing this Pi")
+	flag.Float64Var(&opts.speed, "replay-speed", 60, "recorded seconds played per second")
+	flag.Parse()
+	return opts
+}
+
+// apply overrides cfg with the flags given explicitly on the command line
+func (o *cliOptions) apply(cfg *Config) error {
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "interval":
+			cfg.Interval = o.interval
+		case "no-gpio":
+			cfg.GPIO.Enabled = !o.noGPIO
+		case "view":
+			cfg.DefaultView = o.view
+		case "log":
+			cfg.LogFile = o.logFile
+		case "log-output":
+			cfg.LogOutput = o.logOutput
+		case "mount":
+			cfg.DiskMount = o.mount
+		case "prometheus":
+			cfg.Prometheus = o.prometheus
+		case "api":
+			cfg.API = o.api
+		case "display":
+			cfg.Display.Backend = o.display
+		case "theme":
+			cfg.Theme.Name = o.theme
+		case "remote":
+			cfg.Remote.Host = o.remote
+		case "record":
+			cfg.Record.Path = o.record
+		case "replay":
+			cfg.Replay.Path = o.replay
+		case "replay-speed":
+			cfg.Replay.Speed = o.speed
+		}
+	})
+	return cfg.validate()
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"syscall"
+
+	gopsnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// ConnectionInfo is one socket shown in the connections view
+type ConnectionInfo struct {
+	Proto   string `json:"proto"` // tcp, tcp6, udp, udp6
+	Local   string `json:"local"`
+	Remote  string `json:"remote,omitempty"`
+	Status  string `json:"status"` // LISTEN or ESTABLISHED
+	PID     int32  `json:"pid,omitempty"`
+	Process string `json:"process,omitempty"`
+}
+
+// Listening reports whether the socket waits for peers
+func (c ConnectionInfo) Listening() bool {
+	return c.Status == "LISTEN"
+}
+
+// getConnections lists listening and established TCP/UDP sockets,
+// listeners first. Owning processes are only known for sockets of our
+// own user unless running as root.
+func getConnections(procs []ProcessInfo) []ConnectionInfo {
+	stats, err := gopsnet.Connections("inet")
+	if err != nil {
+		return nil
+	}
+
+	names := make(map[int32]string, len(procs))
+	for _, p := range procs {
+		names[p.PID] = p.Name
+	}
+
+	var conns []ConnectionInfo
+	for _, s := range stats {
+		c := ConnectionInfo{
+			Proto:   connProto(s.Family, s.Type),
+			Local:   connAddr(s.Laddr),
+			PID:     s.Pid,
+			Process: names[s.Pid],
+		}
+		if s.Raddr.IP != "" && s.Raddr.Port != 0 {
+			c.Remote = connAddr(s.Raddr)
+		}
+
+		switch {
+		case s.Status == "LISTEN":
+			c.Status = "LISTEN"
+		case s.Status == "ESTABLISHED":
+			c.Status = "ESTABLISHED"
+		case s.Type == syscall.SOCK_DGRAM && c.Remote == "":
+			c.Status = "LISTEN" // bound UDP socket
+		case s.Type == syscall.SOCK_DGRAM:
+			c.Status = "ESTABLISHED" // connected UDP socket
+		default:
+			continue // TIME_WAIT, SYN_SENT, ...
+		}
+		conns = append(conns, c)
+	}
+
+	sort.SliceStable(conns, func(i, j int) bool {
+		a, b := conns[i], conns[j]
+		if a.Listening() != b.Listening() {
+			return a.Listening()
+		}
+		if a.Listening() {
+			return connPort(a.Local) < connPort(b.Local)
+		}
+		return a.Remote < b.Remote
+	})
+	return conns
+}
+
+func connProto(family, sockType uint32) string {
+	proto := "tcp"
+	if sockType == syscall.SOCK_DGRAM {
+		proto = "udp"
+	}
+	if family == syscall.AF_INET6 {
+		proto += "6"
+	}
+	return proto
+}
+
+func connAddr(addr gopsnet.Addr) string {
+	return net.JoinHostPort(addr.IP, strconv.Itoa(int(addr.Port)))
+}
+
+// connPort returns the port of a host:port address
+func connPort(addr string) int {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+func (d *Dashboard) updateConnectionsView(stats SystemStats) {
+	if !d.reuseLists {
+		d.connList = getConnections(stats.AllProcesses)
+	}
+	total := len(d.connList)
+	if total == 0 {
+		d.mainList.Title = d.viewTitle("0/0")
+		d.mainList.Rows = []string{"", "No connections"}
+		return
+	}
+	if d.selectedConn >= total {
+		d.selectedConn = total - 1
+	}
+
+	listening := 0
+	for _, c := range d.connList {
+		if c.Listening() {
+			listening++
+		}
+	}
+	d.mainList.Title = d.viewTitle(fmt.Sprintf("%d/%d L:%d", d.selectedConn+1, total, listening))
+
+	rows := []string{"[Pr   Port/Peer      Process](fg:cyan)"}
+
+	// Leave room for the detail rows of the selected socket
+	visibleHeight := 20
+	startIdx := d.selectedConn - visibleHeight/2
+	if startIdx > total-visibleHeight {
+		startIdx = total - visibleHeight
+	}
+	if startIdx < 0 {
+		startIdx = 0
+	}
+	endIdx := startIdx + visibleHeight
+	if endIdx > total {
+		endIdx = total
+	}
+
+	for i := startIdx; i < endIdx; i++ {
+		c := d.connList[i]
+		target := fmt.Sprintf(":%d", connPort(c.Local))
+		color := "green"
+		if !c.Listening() {
+			target = c.Remote
+			color = "yellow"
+		}
+		process := c.Process
+		if process == "" {
+			process = "-"
+		}
+		line := fmt.Sprintf("%-4s %-14s %s", c.Proto, truncateString(target, 14), truncateString(process, 8))
+		if i == d.selectedConn {
+			rows = append(rows, fmt.Sprintf("[%s](bg:white,fg:black)", line))
+		} else {
+			rows = append(rows, fmt.Sprintf("[%s](fg:%s)", line, color))
+		}
+	}
+
+	c := d.connList[d.selectedConn]
+	rows = append(rows,
+		"",
+		fmt.Sprintf("[%s](fg:cyan) %s", c.Status, c.Proto),
+		"L: "+truncateString(c.Local, 25),
+	)
+	if c.Remote != "" {
+		rows = append(rows, "R: "+truncateString(c.Remote, 25))
+	}
+	if c.PID != 0 {
+		rows = append(rows, fmt.Sprintf("PID: %d %s", c.PID, truncateString(c.Process, 14)))
+	}
+	d.mainList.Rows = rows
+}
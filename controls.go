@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+)
+
+// ControlConfig is a relay, LED strip or pump switched by hand from the
+// Control view
+type ControlConfig struct {
+	Name      string `yaml:"name"`
+	Pin       int    `yaml:"pin"`
+	ActiveLow bool   `yaml:"active_low"` // most relay boards switch on a low level
+	Initial   bool   `yaml:"initial"`    // state set at start
+	Confirm   bool   `yaml:"confirm"`    // ask before switching
+}
+
+func validateControls(controls []ControlConfig) error {
+	names := make(map[string]bool)
+	for _, c := range controls {
+		if c.Name == "" {
+			return fmt.Errorf("controls: name is required")
+		}
+		if names[c.Name] {
+			return fmt.Errorf("control %q is defined twice", c.Name)
+		}
+		names[c.Name] = true
+		if c.Pin < 0 {
+			return fmt.Errorf("control %q: pin must not be negative", c.Name)
+		}
+	}
+	return nil
+}
+
+// controlOutput is a pin from the controls list. The line is held for
+// as long as the monitor runs and switched off when it exits.
+type controlOutput struct {
+	cfg       ControlConfig
+	line      *gpiocdev.Line // nil when the line could not be claimed
+	err       error          // why the line could not be claimed or set
+	on        bool
+	changedAt time.Time
+}
+
+// set switches the output, honouring active_low
+func (c *controlOutput) set(on bool) error {
+	if c.line == nil {
+		return c.err
+	}
+	if err := c.line.SetValue(outputLevel(on, c.cfg.ActiveLow)); err != nil {
+		c.err = err
+		return err
+	}
+	c.on, c.err, c.changedAt = on, nil, time.Now()
+	return nil
+}
+
+func (c *controlOutput) Close() {
+	if c.line == nil {
+		return
+	}
+	c.set(false)
+	c.line.Close()
+}
+
+// initControls claims the control pins and sets their initial state.
+// Pins that cannot be claimed stay in the list to show the error.
+func (d *Dashboard) initControls() []*controlOutput {
+	for _, cfg := range d.config.Controls {
+		c := &controlOutput{cfg: cfg}
+		c.line, c.err = requestOutputLine(cfg.Pin, outputLevel(cfg.Initial, cfg.ActiveLow))
+		if c.err != nil {
+			log.Printf("Warning: control %s disabled: %v", cfg.Name, c.err)
+		} else if err := c.set(cfg.Initial); err != nil {
+			log.Printf("Control %s: %v", cfg.Name, err)
+		}
+		d.controls = append(d.controls, c)
+	}
+	return d.controls
+}
+
+func (d *Dashboard) updateControlView(stats SystemStats) {
+	total := len(d.controls)
+	if total == 0 {
+		d.mainList.Title = d.viewTitle("")
+		d.mainList.Rows = []string{"", "No controls", "", "Add relays or LEDs under", "controls: in config.yaml."}
+		return
+	}
+	if d.selectedControl >= total {
+		d.selectedControl = total - 1
+	}
+	d.mainList.Title = d.viewTitle("[A:Switch]")
+
+	rows := []string{"[Name                 State](fg:cyan)"}
+
+	// Leave room for the detail rows of the selected control
+	visibleHeight := 17
+	startIdx := d.selectedControl - visibleHeight/2
+	if startIdx > total-visibleHeight {
+		startIdx = total - visibleHeight
+	}
+	if startIdx < 0 {
+		startIdx = 0
+	}
+	endIdx := startIdx + visibleHeight
+	if endIdx > total {
+		endIdx = total
+	}
+
+	for i := startIdx; i < endIdx; i++ {
+		c := d.controls[i]
+		state, color := "OFF", "white"
+		switch {
+		case c.line == nil:
+			state, color = "ERR", "red"
+		case c.on:
+			state, color = "ON", "green"
+		}
+		line := fmt.Sprintf("%-20s %5s", truncateString(c.cfg.Name, 20), state)
+		if i == d.selectedControl {
+			rows = append(rows, fmt.Sprintf("[%s](bg:white,fg:black)", line))
+		} else {
+			rows = append(rows, fmt.Sprintf("[%s](fg:%s)", line, color))
+		}
+	}
+
+	c := d.controls[d.selectedControl]
+	pin := fmt.Sprintf("GPIO%d", c.cfg.Pin)
+	if c.cfg.ActiveLow {
+		pin += " active-low"
+	}
+	rows = append(rows, "", fmt.Sprintf("[%s](fg:yellow)", truncateString(c.cfg.Name, 28)), "Pin:     "+pin)
+	if !c.changedAt.IsZero() {
+		rows = append(rows, "Changed: "+c.changedAt.Format("01-02 15:04"))
+	}
+	if c.err != nil {
+		rows = append(rows, fmt.Sprintf("[%s](fg:red)", truncateString(c.err.Error(), 28)))
+	}
+	d.mainList.Rows = rows
+}
+
+// toggleControl switches the selected control, asking first when the
+// control has confirm set
+func (d *Dashboard) toggleControl() {
+	if d.selectedControl < 0 || d.selectedControl >= len(d.controls) {
+		return
+	}
+	c := d.controls[d.selectedControl]
+	if c.line == nil {
+		d.showMessage("Error", truncateString(c.cfg.Name, 24), "GPIO line not available")
+		return
+	}
+	if !c.cfg.Confirm {
+		d.switchControl(c, !c.on)
+		return
+	}
+
+	label := "Switch on"
+	if c.on {
+		label = "Switch off"
+	}
+	on := !c.on
+	d.openMenu(&Menu{
+		title:   "Control",
+		message: []string{truncateString(c.cfg.Name, 24), fmt.Sprintf("GPIO%d is %s", c.cfg.Pin, onOff(c.on))},
+		options: []menuOption{
+			{label: "Cancel"},
+			{label: label, action: func(d *Dashboard) { d.switchControl(c, on) }},
+		},
+	})
+}
+
+func (d *Dashboard) switchControl(c *controlOutput, on bool) {
+	if err := c.set(on); err != nil {
+		log.Printf("Failed to switch control %s %s: %v", c.cfg.Name, onOff(on), err)
+		d.showMessage("Error", truncateString(c.cfg.Name, 24), truncateString(err.Error(), 24))
+		return
+	}
+	log.Printf("Control %s switched %s", c.cfg.Name, onOff(on))
+	d.redraw()
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
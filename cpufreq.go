@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const cpufreqDir = "/sys/devices/system/cpu/cpufreq"
+
+// cpuPolicy is one cpufreq policy, a group of cores that share a clock.
+// Every Pi has a single policy covering all cores.
+type cpuPolicy struct {
+	name      string // e.g. policy0
+	cpus      []int
+	governor  string
+	governors []string // scaling_available_governors
+	min, max  uint64   // scaling limits, kHz
+	hwMax     uint64   // cpuinfo_max_freq, kHz
+}
+
+// readKHz reads a cpufreq frequency attribute, 0 on errors
+func readKHz(path string) uint64 {
+	n, _ := strconv.ParseUint(readSysfs(path), 10, 64)
+	return n
+}
+
+// readCPUPolicies lists the cpufreq policies, empty when the kernel has
+// no cpufreq driver
+func readCPUPolicies() []cpuPolicy {
+	dirs, _ := filepath.Glob(filepath.Join(cpufreqDir, "policy*"))
+	sort.Strings(dirs)
+
+	var policies []cpuPolicy
+	for _, dir := range dirs {
+		p := cpuPolicy{
+			name:      filepath.Base(dir),
+			governor:  readSysfs(filepath.Join(dir, "scaling_governor")),
+			governors: strings.Fields(readSysfs(filepath.Join(dir, "scaling_available_governors"))),
+			min:       readKHz(filepath.Join(dir, "scaling_min_freq")),
+			max:       readKHz(filepath.Join(dir, "scaling_max_freq")),
+			hwMax:     readKHz(filepath.Join(dir, "cpuinfo_max_freq")),
+		}
+		for _, f := range strings.Fields(readSysfs(filepath.Join(dir, "affected_cpus"))) {
+			if cpu, err := strconv.Atoi(f); err == nil {
+				p.cpus = append(p.cpus, cpu)
+			}
+		}
+		policies = append(policies, p)
+	}
+	return policies
+}
+
+// coreFrequency returns the current clock of a core in kHz
+func coreFrequency(cpu int) uint64 {
+	return readKHz(fmt.Sprintf("/sys/devices/system/cpu/cpu%d/cpufreq/scaling_cur_freq", cpu))
+}
+
+// governorColor tells the governors apart by how hot they run
+func governorColor(governor string) string {
+	switch governor {
+	case "performance":
+		return "red"
+	case "powersave":
+		return "cyan"
+	}
+	return "green"
+}
+
+func (d *Dashboard) updateCPUFreqView(stats SystemStats) {
+	d.mainList.Title = d.viewTitle("[A:Governor]")
+
+	policies := readCPUPolicies()
+	if len(policies) == 0 {
+		d.mainList.Rows = []string{"", "No cpufreq policies", "", "The kernel has no CPU", "frequency scaling driver."}
+		return
+	}
+
+	var rows []string
+	for _, p := range policies {
+		if len(rows) > 0 {
+			rows = append(rows, "")
+		}
+		rows = append(rows,
+			fmt.Sprintf("[--%s--](fg:yellow)", p.name),
+			fmt.Sprintf("Governor: [%s](fg:%s)", p.governor, governorColor(p.governor)),
+			fmt.Sprintf("Range: %d-%d MHz", p.min/1000, p.max/1000),
+		)
+		if p.hwMax > p.max {
+			rows = append(rows, fmt.Sprintf("[Capped, HW max %d MHz](fg:yellow)", p.hwMax/1000))
+		}
+		for _, cpu := range p.cpus {
+			freq := coreFrequency(cpu)
+			percent := 0.0
+			if p.hwMax > 0 {
+				percent = float64(freq) / float64(p.hwMax) * 100
+			}
+			rows = append(rows, fmt.Sprintf("CPU%-2d %4d MHz %s", cpu, freq/1000, getBar(percent, 10)))
+		}
+	}
+	d.mainList.Rows = rows
+}
+
+// chooseGovernor offers the available governors, current one marked
+func (d *Dashboard) chooseGovernor() {
+	policies := readCPUPolicies()
+	if len(policies) == 0 || len(policies[0].governors) == 0 {
+		d.showMessage("Governor", "No cpufreq governors")
+		return
+	}
+
+	current := policies[0].governor
+	options := []menuOption{{label: "Cancel"}}
+	for _, gov := range policies[0].governors {
+		gov := gov
+		label := "  " + gov
+		if gov == current {
+			label = "* " + gov
+		}
+		options = append(options, menuOption{label: label, action: func(d *Dashboard) {
+			d.setGovernor(gov)
+		}})
+	}
+	d.openMenu(&Menu{
+		title:   "CPU governor",
+		message: []string{"performance: fast, hot", "powersave: slow, cool"},
+		options: options,
+	})
+}
+
+// setGovernor switches every policy to governor. Writing the sysfs
+// attribute needs root.
+func (d *Dashboard) setGovernor(governor string) {
+	for _, p := range readCPUPolicies() {
+		path := filepath.Join(cpufreqDir, p.name, "scaling_governor")
+		if err := os.WriteFile(path, []byte(governor), 0); err != nil {
+			log.Printf("Failed to set %s governor to %s: %v", p.name, governor, err)
+			msg := err.Error()
+			if os.IsPermission(err) {
+				msg = "needs root"
+			}
+			d.showMessage("Error", "Governor not changed", truncateString(msg, 24))
+			return
+		}
+	}
+	log.Printf("CPU governor set to %s", governor)
+	d.UpdateStats()
+	d.Render()
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// Widget types of the views section
+const (
+	widgetCPUGauge    = "cpu_gauge"
+	widgetMemGauge    = "mem_gauge"
+	widgetSwapGauge   = "swap_gauge"
+	widgetDiskGauge   = "disk_gauge"
+	widgetTempGauge   = "temp_gauge"
+	widgetCPUHistory  = "cpu_history"
+	widgetMemHistory  = "mem_history"
+	widgetTempHistory = "temp_history"
+	widgetNetHistory  = "net_history"
+	widgetNetRates    = "net_rates"
+	widgetProcTable   = "proc_table"
+	widgetSystemInfo  = "system_info"
+	widgetText        = "text"
+	widgetCommand     = "command"
+)
+
+var widgetTypes = []string{
+	widgetCPUGauge, widgetMemGauge, widgetSwapGauge, widgetDiskGauge, widgetTempGauge,
+	widgetCPUHistory, widgetMemHistory, widgetTempHistory, widgetNetHistory,
+	widgetNetRates, widgetProcTable, widgetSystemInfo, widgetText, widgetCommand,
+}
+
+const (
+	// viewContentWidth is the usable width of a view row
+	viewContentWidth = 28
+	// minWidgetWidth is the narrowest a widget sharing a row can be
+	minWidgetWidth = 8
+	// tempGaugeMax is the full scale of temp_gauge, where the firmware
+	// starts throttling
+	tempGaugeMax = 85.0
+)
+
+// ViewConfig is a view of the user's own, a grid of widgets. Each row
+// holds one or more widgets that share its width.
+type ViewConfig struct {
+	Name  string           `yaml:"name"` // used by default_view and --view
+	Title string           `yaml:"title"`
+	Rows  [][]WidgetConfig `yaml:"rows"`
+}
+
+// WidgetConfig is one widget of a custom view
+type WidgetConfig struct {
+	Type     string        `yaml:"type"`
+	Title    string        `yaml:"title"`    // label, or the text of a text widget
+	Lines    int           `yaml:"lines"`    // rows shown by proc_table and command
+	Name     string        `yaml:"name"`     // command widget: one from the commands section
+	Command  string        `yaml:"command"`  // command widget: a shell command of its own
+	Interval time.Duration `yaml:"interval"` // how often the command runs
+	Timeout  time.Duration `yaml:"timeout"`  // how long the command may take
+}
+
+func (c ViewConfig) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("views: name is required")
+	}
+	if i := viewIndex(c.Name); i >= 0 && i < viewCustom {
+		return fmt.Errorf("view %q: name is taken by a built-in view", c.Name)
+	}
+	if len(c.Rows) == 0 {
+		return fmt.Errorf("view %q: no rows", c.Name)
+	}
+	for _, row := range c.Rows {
+		if len(row) == 0 {
+			return fmt.Errorf("view %q: empty row", c.Name)
+		}
+		if len(row)*(minWidgetWidth+1)-1 > viewContentWidth {
+			return fmt.Errorf("view %q: at most %d widgets fit in a row", c.Name, (viewContentWidth+1)/(minWidgetWidth+1))
+		}
+		for _, w := range row {
+			if err := w.validate(); err != nil {
+				return fmt.Errorf("view %q: %w", c.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (w WidgetConfig) validate() error {
+	known := false
+	for _, t := range widgetTypes {
+		known = known || w.Type == t
+	}
+	if !known {
+		return fmt.Errorf("unknown widget type %q (%s)", w.Type, strings.Join(widgetTypes, ", "))
+	}
+	if w.Type == widgetCommand && (w.Name == "") == (strings.TrimSpace(w.Command) == "") {
+		return fmt.Errorf("command widget needs either a name or a command")
+	}
+	if w.Lines < 0 || w.Interval < 0 || w.Timeout < 0 {
+		return fmt.Errorf("%s: lines, interval and timeout must not be negative", w.Type)
+	}
+	return nil
+}
+
+// validateViews checks the custom views, that their names are unique
+// and that their command widgets name existing commands
+func validateViews(custom []ViewConfig, commands []CommandConfig) error {
+	names := make(map[string]bool)
+	for _, v := range custom {
+		if err := v.validate(); err != nil {
+			return err
+		}
+		for _, row := range v.Rows {
+			for _, w := range row {
+				if w.Name != "" && commandIndex(commands, w.Name) < 0 {
+					return fmt.Errorf("view %q: unknown command %q", v.Name, w.Name)
+				}
+			}
+		}
+		name := strings.ToLower(v.Name)
+		if names[name] {
+			return fmt.Errorf("view %q is defined twice", v.Name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// customViewIndex looks up a custom view by name, returning -1 when
+// there is none
+func customViewIndex(custom []ViewConfig, name string) int {
+	for i, v := range custom {
+		if strings.EqualFold(v.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// registerCustomViews appends the custom views after the built-in ones.
+// It must be called once, before the dashboard is created.
+func registerCustomViews(custom []ViewConfig) {
+	views = views[:viewCustom]
+	for _, v := range custom {
+		title := v.Title
+		if title == "" {
+			title = v.Name
+		}
+		views = append(views, view{name: v.Name, title: title})
+	}
+}
+
+func (d *Dashboard) updateCustomView(stats SystemStats) {
+	v := d.config.Views[d.currentView-viewCustom]
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+
+	var rows []string
+	for r, row := range v.Rows {
+		width := (viewContentWidth - (len(row) - 1)) / len(row)
+		cells := make([][]string, len(row))
+		height := 0
+		for c, w := range row {
+			cells[c] = d.widgetRows(w, fmt.Sprintf("%s/%d/%d", v.Name, r, c), stats, width)
+			if len(cells[c]) > height {
+				height = len(cells[c])
+			}
+		}
+		// Lay the widgets of a row side by side, padded to their width
+		for line := 0; line < height; line++ {
+			var b strings.Builder
+			for c := range row {
+				if c > 0 {
+					b.WriteString(" ")
+				}
+				text := ""
+				if line < len(cells[c]) {
+					text = cells[c][line]
+				}
+				b.WriteString(text)
+				if c < len(row)-1 {
+					b.WriteString(strings.Repeat(" ", width-markupLen(text)))
+				}
+			}
+			rows = append(rows, b.String())
+		}
+	}
+	d.mainList.Rows = rows
+}
+
+// markupLen returns the width of a row without its style markup
+func markupLen(s string) int {
+	return len(ui.ParseStyles(s, ui.Style{}))
+}
+
+// fitString truncates s to width, which may be smaller than two
+func fitString(s string, width int) string {
+	if width < 3 {
+		if len(s) > width {
+			return s[:width]
+		}
+		return s
+	}
+	return truncateString(s, width)
+}
+
+// gaugeRows is a label with the value and a bar of the full width
+func gaugeRows(label, value string, percent float64, color string, width int) []string {
+	pad := width - len(label) - len(value)
+	if pad < 1 {
+		pad = 1
+	}
+	return []string{
+		fmt.Sprintf("[%s](fg:%s)%s%s", label, color, strings.Repeat(" ", pad), value),
+		getBar(percent, width),
+	}
+}
+
+// historyRows is a label with the current value and a sparkline of the
+// full width
+func historyRows(label, value string, h *History, color string, width int) []string {
+	pad := width - len(label) - len(value)
+	if pad < 1 {
+		pad = 1
+	}
+	return []string{
+		fmt.Sprintf("[%s](fg:%s)%s%s", label, color, strings.Repeat(" ", pad), value),
+		getSparkline(h.Last(width), 0, color),
+	}
+}
+
+// widgetRows renders one widget width cells wide. key identifies the
+// widget for the ones keeping state between frames.
+func (d *Dashboard) widgetRows(w WidgetConfig, key string, stats SystemStats, width int) []string {
+	label := func(def string) string {
+		if w.Title != "" {
+			return fitString(w.Title, width)
+		}
+		return def
+	}
+
+	switch w.Type {
+	case widgetCPUGauge:
+		avg := calculateAverage(stats.CPUPercent)
+		return gaugeRows(label("CPU"), fmt.Sprintf("%.1f%%", avg), avg, d.alertColor("cpu", "cyan"), width)
+	case widgetMemGauge:
+		return gaugeRows(label("MEM"), fmt.Sprintf("%.1f%%", stats.MemPercent), stats.MemPercent, d.alertColor("mem", "yellow"), width)
+	case widgetSwapGauge:
+		return gaugeRows(label("Swap"), fmt.Sprintf("%.1f%%", stats.SwapPercent), stats.SwapPercent, "yellow", width)
+	case widgetDiskGauge:
+		return gaugeRows(label("DSK"), fmt.Sprintf("%.1f%%", stats.DiskPercent), stats.DiskPercent, d.alertColor("disk", "magenta"), width)
+	case widgetTempGauge:
+		return gaugeRows(label("Temp"), formatTemperature(stats.Temperature), stats.Temperature/tempGaugeMax*100, d.alertColor("temp", "white"), width)
+	case widgetCPUHistory:
+		return historyRows(label("CPU"), fmt.Sprintf("%.1f%%", calculateAverage(stats.CPUPercent)), d.cpuHistory, "cyan", width)
+	case widgetMemHistory:
+		return historyRows(label("MEM"), fmt.Sprintf("%.1f%%", stats.MemPercent), d.memHistory, "yellow", width)
+	case widgetTempHistory:
+		return historyRows(label("Temp"), formatTemperature(stats.Temperature), d.tempHistory, "red", width)
+	case widgetNetHistory:
+		return historyRows(label("Net"), formatRate(d.netSentRate+d.netRecvRate), d.netHistory, "green", width)
+	case widgetNetRates:
+		return []string{
+			fitString(fmt.Sprintf("Up:   %s", formatRate(d.netSentRate)), width),
+			fitString(fmt.Sprintf("Down: %s", formatRate(d.netRecvRate)), width),
+		}
+	case widgetProcTable:
+		return d.procTableRows(w, stats, width)
+	case widgetSystemInfo:
+		days, hours, minutes := formatUptime(stats.Uptime)
+		return []string{
+			fitString("Host: "+d.statsHost(), width),
+			fitString("IP: "+stats.IPAddress, width),
+			fitString(fmt.Sprintf("Up: %dd %dh %dm", days, hours, minutes), width),
+			fitString(fmt.Sprintf("Load: %.2f %.2f", stats.Load.Load1, stats.Load.Load5), width),
+		}
+	case widgetText:
+		return []string{fitString(w.Title, width)}
+	case widgetCommand:
+		if w.Name != "" {
+			return d.namedCommand(w.Name).rows(label(w.Name), width)
+		}
+		return d.inlineCommand(key, w).rows(label(""), width)
+	}
+	return nil
+}
+
+// procTableRows lists the busiest processes
+func (d *Dashboard) procTableRows(w WidgetConfig, stats SystemStats, width int) []string {
+	lines := w.Lines
+	if lines == 0 {
+		lines = 5
+	}
+	nameWidth := width - 12
+	if nameWidth < 3 {
+		nameWidth = 3
+	}
+	rows := []string{fmt.Sprintf("[%5s %-*s %5s](fg:cyan)", "PID", nameWidth, "Name", "CPU%")}
+	for _, p := range sortProcesses(stats.AllProcesses, sortByCPU) {
+		if len(rows) > lines {
+			break
+		}
+		rows = append(rows, fmt.Sprintf("%5d %-*s %5.1f", p.PID, nameWidth, fitString(p.Name, nameWidth), p.CPU))
+	}
+	return rows
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// diskUsageTimeout bounds statfs on a mount; a dead NFS server would
+// otherwise hang the UI
+const diskUsageTimeout = time.Second
+
+// pseudoFilesystems are kernel and virtual filesystems left out of the
+// disk view
+var pseudoFilesystems = map[string]bool{
+	"autofs": true, "binfmt_misc": true, "bpf": true, "cgroup": true,
+	"cgroup2": true, "configfs": true, "debugfs": true, "devpts": true,
+	"devtmpfs": true, "efivarfs": true, "fusectl": true, "hugetlbfs": true,
+	"mqueue": true, "nsfs": true, "overlay": true, "proc": true,
+	"pstore": true, "ramfs": true, "rpc_pipefs": true, "securityfs": true,
+	"squashfs": true, "sysfs": true, "tmpfs": true, "tracefs": true,
+}
+
+// FilesystemInfo is the usage of one mounted filesystem
+type FilesystemInfo struct {
+	Mount         string  `json:"mount"`
+	Device        string  `json:"device"`
+	FSType        string  `json:"fstype"`
+	Total         uint64  `json:"total"`
+	Used          uint64  `json:"used"`
+	Percent       float64 `json:"percent"`
+	InodesTotal   uint64  `json:"inodes_total"`
+	InodesUsed    uint64  `json:"inodes_used"`
+	InodesPercent float64 `json:"inodes_percent"`
+	Stale         bool    `json:"stale,omitempty"` // statfs did not answer in time
+}
+
+// staleMounts holds mounts whose statfs is still blocked, so that a hung
+// network mount does not pile up one goroutine per refresh
+var staleMounts sync.Map
+
+// getFilesystems lists the real filesystems, one entry per device
+func getFilesystems() []FilesystemInfo {
+	partitions, err := disk.Partitions(true)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var list []FilesystemInfo
+	for _, p := range partitions {
+		if pseudoFilesystems[p.Fstype] || seen[p.Device] {
+			continue
+		}
+		seen[p.Device] = true
+
+		fs := FilesystemInfo{Mount: p.Mountpoint, Device: p.Device, FSType: p.Fstype}
+		usage, ok := mountUsage(p.Mountpoint)
+		if !ok {
+			fs.Stale = true
+		} else if usage != nil {
+			fs.Total = usage.Total
+			fs.Used = usage.Used
+			fs.Percent = usage.UsedPercent
+			fs.InodesTotal = usage.InodesTotal
+			fs.InodesUsed = usage.InodesUsed
+			fs.InodesPercent = usage.InodesUsedPercent
+		}
+		list = append(list, fs)
+	}
+	return list
+}
+
+// mountUsage runs disk.Usage with a timeout. It returns false when the
+// mount did not answer, now or on an earlier call that is still blocked.
+func mountUsage(mount string) (*disk.UsageStat, bool) {
+	if _, blocked := staleMounts.Load(mount); blocked {
+		return nil, false
+	}
+
+	staleMounts.Store(mount, true)
+	done := make(chan *disk.UsageStat, 1)
+	go func() {
+		usage, err := disk.Usage(mount)
+		if err != nil {
+			usage = nil
+		}
+		staleMounts.Delete(mount)
+		done <- usage
+	}()
+
+	select {
+	case usage := <-done:
+		return usage, true
+	case <-time.After(diskUsageTimeout):
+		return nil, false
+	}
+}
+
+func (d *Dashboard) updateDiskView(stats SystemStats) {
+	if !d.reuseLists {
+		d.fsList = getFilesystems()
+	}
+	total := len(d.fsList)
+	if total == 0 {
+		d.mainList.Title = d.viewTitle("0/0")
+		d.mainList.Rows = []string{"", "No filesystems found"}
+		return
+	}
+	if d.selectedFS >= total {
+		d.selectedFS = total - 1
+	}
+	d.mainList.Title = d.viewTitle(fmt.Sprintf("%d/%d", d.selectedFS+1, total))
+
+	// Three rows per filesystem
+	visible := 9
+	startIdx := d.selectedFS - visible/2
+	if startIdx > total-visible {
+		startIdx = total - visible
+	}
+	if startIdx < 0 {
+		startIdx = 0
+	}
+	endIdx := startIdx + visible
+	if endIdx > total {
+		endIdx = total
+	}
+
+	var rows []string
+	for i := startIdx; i < endIdx; i++ {
+		fs := d.fsList[i]
+		name := fmt.Sprintf("%-18s %s", truncateString(fs.Mount, 18), truncateString(fs.FSType, 8))
+		if i == d.selectedFS {
+			rows = append(rows, fmt.Sprintf("[%s](bg:white,fg:black)", name))
+		} else {
+			rows = append(rows, fmt.Sprintf("[%s](fg:cyan)", name))
+		}
+
+		if fs.Stale {
+			rows = append(rows, "[not responding](fg:red)", "")
+			continue
+		}
+		rows = append(rows,
+			fmt.Sprintf("%s %5.1f%%", getBar(fs.Percent, 20), fs.Percent),
+			fmt.Sprintf("%s/%s  ino %.0f%%", formatBytes(fs.Used), formatBytes(fs.Total), fs.InodesPercent),
+		)
+	}
+
+	fs := d.fsList[d.selectedFS]
+	rows = append(rows, "", "Dev: "+truncateString(fs.Device, 23))
+	if fs.InodesTotal > 0 {
+		rows = append(rows, fmt.Sprintf("Inodes: %d/%d", fs.InodesUsed, fs.InodesTotal))
+	}
+	d.mainList.Rows = rows
+}
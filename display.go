@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// Display backends
+const (
+	displayTerminal    = "terminal"    // termui in the controlling terminal
+	displayFramebuffer = "framebuffer" // Linux framebuffer such as /dev/fb1
+	displayST7789      = "st7789"      // SPI panel driven through spidev
+	displayILI9341     = "ili9341"     // SPI panel driven through spidev
+	displaySSD1306     = "ssd1306"     // 128x64 I2C OLED
+	displaySH1106      = "sh1106"      // 128x64 I2C OLED with 132 columns of RAM
+	displayHTML        = "html"        // web page served on display.listen
+)
+
+// displayBackends lists the backends for messages and flag help
+var displayBackends = []string{
+	displayTerminal, displayFramebuffer, displayST7789, displayILI9341, displaySSD1306, displaySH1106, displayHTML,
+}
+
+// DisplayConfig selects where the dashboard is drawn
+type DisplayConfig struct {
+	Backend      string  `yaml:"backend"`
+	Device       string  `yaml:"device"` // framebuffer, spidev or i2c-dev device
+	Width        int     `yaml:"width"`
+	Height       int     `yaml:"height"`
+	Rotation     int     `yaml:"rotation"` // 0, 90, 180 or 270 for SPI panels
+	FontSize     float64 `yaml:"font_size"`
+	SPISpeed     int     `yaml:"spi_speed"`     // Hz
+	DCPin        int     `yaml:"dc_pin"`        // BCM pin of the data/command line
+	ResetPin     int     `yaml:"reset_pin"`     // -1 when not wired
+	BacklightPin int     `yaml:"backlight_pin"` // -1 when not wired
+	StatusBar    bool    `yaml:"status_bar"`    // time, host, IP and temperature under every view
+
+	// Screensaver settings
+	BlankAfter    time.Duration `yaml:"blank_after"`    // idle time before the screen blanks, 0 never
+	BacklightPath string        `yaml:"backlight_path"` // sysfs backlight switched off while blank
+
+	// OLED settings
+	Address      int           `yaml:"address"`       // I2C address of the OLED
+	PageInterval time.Duration `yaml:"page_interval"` // OLED page rotation, 0 keeps the page
+
+	// HTML settings
+	Listen string `yaml:"listen"` // address the html backend serves the screen on
+}
+
+func (c DisplayConfig) validate() error {
+	switch c.Backend {
+	case displayTerminal, displayFramebuffer, displayST7789, displayILI9341, displaySSD1306, displaySH1106, displayHTML:
+	default:
+		return fmt.Errorf("unknown display.backend %q (%s)", c.Backend, strings.Join(displayBackends, ", "))
+	}
+	if c.FontSize <= 0 {
+		return fmt.Errorf("display.font_size must be positive")
+	}
+	switch c.Rotation {
+	case 0, 90, 180, 270:
+	default:
+		return fmt.Errorf("display.rotation must be 0, 90, 180 or 270")
+	}
+	if c.BlankAfter < 0 {
+		return fmt.Errorf("display.blank_after must not be negative")
+	}
+	if c.Width < 0 || c.Height < 0 || c.SPISpeed < 0 {
+		return fmt.Errorf("display.width, height and spi_speed must not be negative")
+	}
+	if c.spiPanel() && c.DCPin < 0 {
+		return fmt.Errorf("display.dc_pin is required for %s", c.Backend)
+	}
+	if c.Backend == displayHTML && c.Listen == "" {
+		return fmt.Errorf("display.listen is required for %s", c.Backend)
+	}
+	if c.oled() {
+		if c.Rotation != 0 && c.Rotation != 180 {
+			return fmt.Errorf("display.rotation must be 0 or 180 for %s", c.Backend)
+		}
+		if c.Address <= 0 || c.Address > 0x7f {
+			return fmt.Errorf("display.address must be a 7-bit I2C address")
+		}
+		if c.PageInterval < 0 {
+			return fmt.Errorf("display.page_interval must not be negative")
+		}
+	}
+	return nil
+}
+
+// spiPanel reports whether the backend drives an SPI panel directly
+func (c DisplayConfig) spiPanel() bool {
+	return c.Backend == displayST7789 || c.Backend == displayILI9341
+}
+
+// oled reports whether the backend is a small I2C OLED showing stat pages
+func (c DisplayConfig) oled() bool {
+	return c.Backend == displaySSD1306 || c.Backend == displaySH1106
+}
+
+// Frame is what the views produced for one screen update
+type Frame struct {
+	Title    string
+	Rows     []string // termui style markup
+	Menu     *Menu    // open menu, nil when none
+	Stats    SystemStats
+	Overview *Overview // widgets beside the view, only built for a wide terminal
+	Status   string    // status bar, empty when display.status_bar is off
+}
+
+// DisplayBackend shows frames somewhere: the terminal, a pixel panel or
+// a web page. Events returns the key events of the backend, or nil when
+// it has no keyboard and only the buttons and signals drive the monitor.
+type DisplayBackend interface {
+	Render(frame Frame) error
+	Events() <-chan ui.Event
+	Close() error
+}
+
+// pagedDisplay is implemented by backends that show their own pages
+// instead of the views; the view buttons turn the pages
+type pagedDisplay interface {
+	Turn(delta int)
+}
+
+// newDisplay opens the backend selected by cfg.Backend. interval is the
+// update interval, which the html backend uses to reload the page.
+func newDisplay(cfg DisplayConfig, interval time.Duration) (DisplayBackend, error) {
+	switch {
+	case cfg.Backend == displayTerminal:
+		return newTerminalDisplay(cfg.StatusBar)
+	case cfg.Backend == displayHTML:
+		return newHTMLDisplay(interval), nil
+	case cfg.oled():
+		return newOLEDRenderer(cfg)
+	default:
+		return newLCDRenderer(cfg)
+	}
+}
+
+// terminalDisplay draws with termui in the controlling terminal. A
+// terminal wide enough gets an overview next to the view.
+type terminalDisplay struct {
+	list     *widgets.List
+	menuList *widgets.List
+	overview *overviewWidgets // nil on narrow terminals
+	status   *statusLine      // nil without the status bar
+}
+
+func newTerminalDisplay(statusBar bool) (*terminalDisplay, error) {
+	if err := ui.Init(); err != nil {
+		return nil, fmt.Errorf("initialize termui: %w", err)
+	}
+
+	t := &terminalDisplay{list: widgets.NewList(), menuList: widgets.NewList()}
+	if statusBar {
+		t.status = &statusLine{}
+	}
+	theme := currentTheme
+	t.list.TextStyle = ui.NewStyle(theme.term(theme.Text))
+	t.list.BorderStyle = ui.NewStyle(theme.term(theme.Border))
+	t.list.TitleStyle = ui.NewStyle(theme.term(theme.Title))
+
+	// Modal menu drawn over the list
+	t.menuList.TextStyle = ui.NewStyle(theme.term(theme.Text))
+	t.menuList.BorderStyle = ui.NewStyle(theme.term(theme.Menu))
+	t.menuList.TitleStyle = ui.NewStyle(theme.term(theme.Title))
+
+	// Fill the terminal from the start rather than after the first resize
+	t.Resize(ui.TerminalDimensions())
+	return t, nil
+}
+
+// Render redraws the whole screen. termui only flushes in ui.Render, so
+// clearing first does not flicker and removes a closed menu.
+func (t *terminalDisplay) Render(frame Frame) error {
+	ui.Clear()
+	t.list.Title = frame.Title
+	t.list.Rows = frame.Rows
+	ui.Render(t.list)
+	if t.overview != nil && frame.Overview != nil {
+		t.overview.update(frame.Overview)
+		ui.Render(t.overview.grid)
+	}
+	if t.status != nil {
+		t.status.text = frame.Status
+		ui.Render(t.status)
+	}
+	if frame.Menu != nil {
+		t.renderMenu(frame.Menu)
+	}
+	return nil
+}
+
+// renderMenu draws m centered over the list
+func (t *terminalDisplay) renderMenu(m *Menu) {
+	rows := m.rows()
+
+	rect := t.list.GetRect()
+	width := rect.Dx() - 4
+	height := len(rows) + 2
+	if height > rect.Dy() {
+		height = rect.Dy()
+	}
+	x := rect.Min.X + (rect.Dx()-width)/2
+	y := rect.Min.Y + (rect.Dy()-height)/2
+
+	t.menuList.Title = m.title
+	t.menuList.Rows = rows
+	t.menuList.SetRect(x, y, x+width, y+height)
+	ui.Render(t.menuList)
+}
+
+// Resize makes the list fill a terminal of the new size, or on a wide
+// terminal puts the view on the left and the overview beside it
+func (t *terminalDisplay) Resize(width, height int) {
+	if t.status != nil {
+		height--
+		t.status.SetRect(0, height, width, height+1)
+	}
+	if width < viewColumnWidth+overviewMinWidth || height < overviewMinHeight {
+		t.list.SetRect(0, 0, width, height)
+		t.overview = nil
+		return
+	}
+	t.list.SetRect(0, 0, viewColumnWidth, height)
+	t.overview = newOverviewWidgets(image.Rect(viewColumnWidth, 0, width, height))
+}
+
+// listRow returns the row of the list under the cell x, y: -1 for the
+// title on the top border, and false outside the list
+func (t *terminalDisplay) listRow(x, y int) (int, bool) {
+	rect := t.list.GetRect()
+	if x <= rect.Min.X || x >= rect.Max.X-1 || y < rect.Min.Y || y >= rect.Max.Y-1 {
+		return 0, false
+	}
+	return y - rect.Min.Y - 1, true
+}
+
+// Blank clears the terminal; the next Render redraws it
+func (t *terminalDisplay) Blank(blank bool) error {
+	if blank {
+		ui.Clear()
+		ui.Render()
+	}
+	return nil
+}
+
+// wide reports whether the overview is shown
+func (t *terminalDisplay) wide() bool {
+	return t.overview != nil
+}
+
+func (t *terminalDisplay) Events() <-chan ui.Event {
+	return ui.PollEvents()
+}
+
+func (t *terminalDisplay) Close() error {
+	ui.Close()
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// kmsgPath is the kernel ring buffer, readable unless dmesg_restrict is set
+const kmsgPath = "/dev/kmsg"
+
+// kernelEvent is a kind of kernel message worth highlighting
+type kernelEvent struct {
+	tag      string
+	color    string
+	patterns []string // matched case-insensitively
+}
+
+// kernelEvents are the messages that most often explain a misbehaving Pi
+var kernelEvents = []kernelEvent{
+	{"OOM", "red", []string{"out of memory", "oom-kill", "oom_reaper"}},
+	{"PWR", "red", []string{"undervoltage", "under-voltage"}},
+	{"FS", "red", []string{"ext4-fs error", "fat-fs", "i/o error", "remounting filesystem read-only", "mmc0: timeout"}},
+	{"USB", "yellow", []string{"usb disconnect"}},
+}
+
+// kernelEventOf returns the highlighted event msg belongs to, if any
+func kernelEventOf(msg string) (kernelEvent, bool) {
+	lower := strings.ToLower(msg)
+	for _, evt := range kernelEvents {
+		for _, p := range evt.patterns {
+			if strings.Contains(lower, p) {
+				return evt, true
+			}
+		}
+	}
+	return kernelEvent{}, false
+}
+
+// getKernelLog returns the recent kernel messages, oldest first. It falls
+// back to the kernel messages in the journal when /dev/kmsg is not readable.
+func getKernelLog() ([]LogEntry, error) {
+	entries, err := readKmsg()
+	if err == nil {
+		return entries, nil
+	}
+	if entries, jerr := readJournal("-k"); jerr == nil {
+		return entries, nil
+	}
+	return nil, err
+}
+
+// readKmsg reads every record currently in the ring buffer. Records look
+// like "6,1234,5678901,-;message" with the time in µs since boot.
+func readKmsg() ([]LogEntry, error) {
+	// The file is read through syscall so a non-blocking read ends with
+	// EAGAIN instead of waiting for the next message
+	fd, err := syscall.Open(kmsgPath, syscall.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", kmsgPath, err)
+	}
+	defer syscall.Close(fd)
+
+	var boot time.Time
+	if secs, err := host.BootTime(); err == nil {
+		boot = time.Unix(int64(secs), 0)
+	}
+
+	var entries []LogEntry
+	buf := make([]byte, 8192)
+	for {
+		n, err := syscall.Read(fd, buf)
+		if errors.Is(err, syscall.EPIPE) {
+			continue // the record was overwritten while reading
+		}
+		if err != nil || n <= 0 {
+			break
+		}
+
+		header, msg, ok := bytes.Cut(buf[:n], []byte(";"))
+		if !ok {
+			continue
+		}
+		if i := bytes.IndexByte(msg, '\n'); i >= 0 {
+			msg = msg[:i] // drop the key=value continuation lines
+		}
+		fields := strings.Split(string(header), ",")
+		if len(fields) < 3 {
+			continue
+		}
+		e := LogEntry{Unit: "kernel", Priority: 6, Message: string(msg)}
+		if p, err := strconv.Atoi(fields[0]); err == nil {
+			e.Priority = p & 7
+		}
+		if usec, err := strconv.ParseInt(fields[2], 10, 64); err == nil && !boot.IsZero() {
+			e.Time = boot.Add(time.Duration(usec) * time.Microsecond)
+		}
+		entries = append(entries, e)
+	}
+
+	if len(entries) > logEntries {
+		entries = entries[len(entries)-logEntries:]
+	}
+	return entries, nil
+}
+
+func (d *Dashboard) updateKernelView(stats SystemStats) {
+	entries, err := getKernelLog()
+	if err != nil {
+		d.mainList.Title = d.viewTitle("")
+		d.mainList.Rows = []string{"", "Kernel log not readable", "(root or dmesg_restrict=0)", "", truncateString(err.Error(), 27)}
+		return
+	}
+
+	// Count the highlighted events and format the rows
+	counts := make(map[string]int)
+	var lines []string
+	for _, e := range entries {
+		evt, important := kernelEventOf(e.Message)
+		if important {
+			counts[evt.tag]++
+		} else if d.kernelImportant {
+			continue
+		}
+
+		text := fmt.Sprintf("%s %s", e.Time.Format("15:04"), e.Message)
+		color := logColor(e.Priority)
+		if important {
+			text = evt.tag + " " + text
+			color = evt.color
+		}
+		text = strings.NewReplacer("[", "(", "]", ")").Replace(text)
+		for _, line := range wrapString(text, 27, 6) {
+			lines = append(lines, fmt.Sprintf("[%s](fg:%s)", line, color))
+		}
+	}
+
+	mode := "all"
+	if d.kernelImportant {
+		mode = "events"
+	}
+	d.mainList.Title = d.viewTitle(mode)
+
+	summary := make([]string, 0, len(kernelEvents))
+	for _, evt := range kernelEvents {
+		color := "green"
+		if counts[evt.tag] > 0 {
+			color = evt.color
+		}
+		summary = append(summary, fmt.Sprintf("[%s:%d](fg:%s)", evt.tag, counts[evt.tag], color))
+	}
+	rows := []string{strings.Join(summary, " ")}
+
+	if len(lines) == 0 {
+		d.mainList.Rows = append(rows, "", "No messages")
+		return
+	}
+	var visible []string
+	visible, d.kernelScroll = tailWindow(lines, d.kernelScroll, 27)
+	d.mainList.Rows = append(rows, visible...)
+}
+
+// scrollKernel moves the kernel log view delta lines down
+func (d *Dashboard) scrollKernel(delta int) {
+	d.kernelScroll -= delta
+	if d.kernelScroll < 0 {
+		d.kernelScroll = 0
+	}
+	d.UpdateStats()
+	d.Render()
+}
+
+// toggleKernelFilter switches between all messages and the highlighted events
+func (d *Dashboard) toggleKernelFilter() {
+	d.kernelImportant = !d.kernelImportant
+	d.kernelScroll = 0
+	d.UpdateStats()
+	d.Render()
+}
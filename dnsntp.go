@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDNSServers is how many resolvers are shown on the System view
+const maxDNSServers = 3
+
+// DNSNTPConfig sets up the resolver and clock checks on the System view.
+// Dead DNS and a clock that never synced look like every other network
+// problem, so both are shown explicitly.
+type DNSNTPConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	LookupHost string        `yaml:"lookup_host"` // name resolved through each DNS server
+	Interval   time.Duration `yaml:"interval"`
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+func (c DNSNTPConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.LookupHost == "" {
+		return fmt.Errorf("dns_ntp.lookup_host must be set")
+	}
+	if c.Interval <= 0 || c.Timeout <= 0 {
+		return fmt.Errorf("dns_ntp.interval and dns_ntp.timeout must be positive")
+	}
+	return nil
+}
+
+// DNSServerStatus is the result of one lookup through a resolver
+type DNSServerStatus struct {
+	Server  string
+	Latency time.Duration
+	Err     error
+}
+
+// NTPStatus is the clock synchronisation state from chrony or
+// systemd-timesyncd
+type NTPStatus struct {
+	Source    string // "chrony" or "timesyncd", empty when neither answered
+	Synced    bool
+	Server    string
+	Offset    time.Duration // local clock minus the reference
+	HasOffset bool
+}
+
+// DNSNTPMonitor runs the checks in the background and keeps the result
+// for the UI
+type DNSNTPMonitor struct {
+	cfg DNSNTPConfig
+
+	mu      sync.Mutex
+	dns     []DNSServerStatus
+	ntp     NTPStatus
+	checked time.Time
+}
+
+func newDNSNTPMonitor(cfg DNSNTPConfig) *DNSNTPMonitor {
+	return &DNSNTPMonitor{cfg: cfg}
+}
+
+// Status returns the results of the latest check; checked is zero
+// before the first one finished
+func (m *DNSNTPMonitor) Status() (dns []DNSServerStatus, ntp NTPStatus, checked time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dns, m.ntp, m.checked
+}
+
+// Run checks every interval until stop is closed
+func (m *DNSNTPMonitor) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.check()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *DNSNTPMonitor) check() {
+	var dns []DNSServerStatus
+	for _, server := range dnsServers() {
+		dns = append(dns, m.checkDNS(server))
+	}
+	ntp := readNTPStatus(m.cfg.Timeout)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ntp.Source != "" && ntp.Synced != m.ntp.Synced {
+		log.Printf("Clock synchronised: %v (%s)", ntp.Synced, ntp.Source)
+	}
+	m.dns, m.ntp, m.checked = dns, ntp, time.Now()
+}
+
+// checkDNS resolves the lookup host through server alone and times it
+func (m *DNSNTPMonitor) checkDNS(server string) DNSServerStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Timeout)
+	defer cancel()
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, net.JoinHostPort(server, "53"))
+		},
+	}
+	start := time.Now()
+	_, err := resolver.LookupHost(ctx, m.cfg.LookupHost)
+	return DNSServerStatus{Server: server, Latency: time.Since(start), Err: err}
+}
+
+// dnsServers lists the nameservers of resolv.conf. Behind the
+// systemd-resolved stub the upstream servers are used instead, since
+// the stub answers from its cache even when they are dead.
+func dnsServers() []string {
+	servers := resolvConfServers("/etc/resolv.conf")
+	if len(servers) == 1 && servers[0] == "127.0.0.53" {
+		if upstream := resolvConfServers("/run/systemd/resolve/resolv.conf"); len(upstream) > 0 {
+			servers = upstream
+		}
+	}
+	if len(servers) > maxDNSServers {
+		servers = servers[:maxDNSServers]
+	}
+	return servers
+}
+
+func resolvConfServers(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			// Drop the zone of link-local IPv6 servers, e.g. fe80::1%eth0
+			servers = append(servers, strings.SplitN(fields[1], "%", 2)[0])
+		}
+	}
+	return servers
+}
+
+// readNTPStatus asks chrony first and falls back to systemd-timesyncd
+func readNTPStatus(timeout time.Duration) NTPStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if out, err := exec.CommandContext(ctx, "chronyc", "-c", "tracking").Output(); err == nil {
+		if status, ok := parseChronyTracking(string(out)); ok {
+			return status
+		}
+	}
+
+	out, err := exec.CommandContext(ctx, "timedatectl", "show", "-p", "NTPSynchronized", "--value").Output()
+	if err != nil {
+		return NTPStatus{}
+	}
+	status := NTPStatus{Source: "timesyncd", Synced: strings.TrimSpace(string(out)) == "yes"}
+
+	// timesync-status only exists while systemd-timesyncd runs
+	out, err = exec.CommandContext(ctx, "timedatectl", "timesync-status").Output()
+	if err != nil {
+		return status
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Server":
+			// "162.159.200.1 (time.cloudflare.com)"
+			if i := strings.Index(value, "("); i >= 0 {
+				value = strings.TrimSuffix(value[i+1:], ")")
+			}
+			status.Server = value
+		case "Offset":
+			if offset, err := time.ParseDuration(strings.ReplaceAll(value, "+", "")); err == nil {
+				status.Offset, status.HasOffset = offset, true
+			}
+		}
+	}
+	return status
+}
+
+// parseChronyTracking reads the CSV of "chronyc -c tracking": reference
+// id, name, stratum, reference time, the system time offset in seconds
+// (positive when fast), ..., and the leap status last
+func parseChronyTracking(out string) (NTPStatus, bool) {
+	f := strings.Split(strings.TrimSpace(out), ",")
+	if len(f) < 14 {
+		return NTPStatus{}, false
+	}
+	status := NTPStatus{Source: "chrony", Server: f[1]}
+	stratum, _ := strconv.Atoi(f[2])
+	status.Synced = stratum > 0 && f[len(f)-1] != "Not synchronised"
+	if seconds, err := strconv.ParseFloat(f[4], 64); err == nil {
+		status.Offset = time.Duration(seconds * float64(time.Second))
+		status.HasOffset = true
+	}
+	return status, true
+}
+
+// formatOffset prints a clock offset with its sign in the largest
+// fitting unit, e.g. "+1.2ms" or "-3.4s"
+func formatOffset(offset time.Duration) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+	}
+	abs := math.Abs(offset.Seconds())
+	switch {
+	case abs < 1e-3:
+		return fmt.Sprintf("%s%.0fus", sign, abs*1e6)
+	case abs < 1:
+		return fmt.Sprintf("%s%.1fms", sign, abs*1e3)
+	}
+	return fmt.Sprintf("%s%.1fs", sign, abs)
+}
+
+// dnsNTPRows shows the resolvers and the clock on the System view
+func dnsNTPRows(m *DNSNTPMonitor) []string {
+	if m == nil {
+		return nil
+	}
+	dns, ntp, checked := m.Status()
+	if checked.IsZero() {
+		return []string{"DNS/NTP: checking..."}
+	}
+
+	var rows []string
+	if len(dns) == 0 {
+		rows = append(rows, "DNS: [no servers](fg:red)")
+	}
+	for _, s := range dns {
+		result := fmt.Sprintf("[%dms](fg:green)", s.Latency.Milliseconds())
+		switch {
+		case s.Err != nil:
+			result = "[FAIL](fg:red)"
+		case s.Latency > 500*time.Millisecond:
+			result = fmt.Sprintf("[%dms](fg:yellow)", s.Latency.Milliseconds())
+		}
+		rows = append(rows, fmt.Sprintf("DNS %-15s %s", truncateString(s.Server, 15), result))
+	}
+
+	switch {
+	case ntp.Source == "":
+		rows = append(rows, "NTP: [unknown](fg:yellow)")
+	case !ntp.Synced:
+		rows = append(rows, "NTP: [not synced](fg:red)")
+	default:
+		line := "NTP: [synced](fg:green)"
+		if ntp.HasOffset {
+			line += " " + formatOffset(ntp.Offset)
+		}
+		rows = append(rows, line)
+	}
+	if ntp.Server != "" {
+		rows = append(rows, "NTP server: "+truncateString(ntp.Server, 16))
+	}
+	return rows
+}
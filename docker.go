@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// dockerSocket is the Engine API socket, DOCKER_HOST=unix://... overrides it
+	dockerSocket = "/var/run/docker.sock"
+	// dockerTimeout bounds one API request; stopping a container waits
+	// for it to exit, so actions get longer
+	dockerTimeout       = 5 * time.Second
+	dockerActionTimeout = 30 * time.Second
+	// dockerStopWait is how long the daemon waits before killing a container
+	dockerStopWait = 10
+)
+
+// dockerClient talks to the Docker Engine API over its unix socket
+var dockerClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", dockerSocketPath())
+		},
+	},
+}
+
+func dockerSocketPath() string {
+	if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "unix://") {
+		return strings.TrimPrefix(host, "unix://")
+	}
+	return dockerSocket
+}
+
+// ContainerInfo is one container shown in the containers view
+type ContainerInfo struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Image    string  `json:"image"`
+	State    string  `json:"state"`  // running, exited, paused, ...
+	Status   string  `json:"status"` // e.g. "Up 2 hours"
+	CPU      float64 `json:"cpu_percent"`
+	MemUsage uint64  `json:"mem_usage"`
+	MemLimit uint64  `json:"mem_limit"`
+	NetRx    uint64  `json:"net_rx_bytes"`
+	NetTx    uint64  `json:"net_tx_bytes"`
+}
+
+// Running reports whether the container is up
+func (c ContainerInfo) Running() bool {
+	return c.State == "running"
+}
+
+// containerCPU is the CPU counter sample used for the next CPU%
+type containerCPU struct {
+	total  uint64
+	system uint64
+}
+
+// dockerContainer is an entry of GET /containers/json
+type dockerContainer struct {
+	ID     string   `json:"Id"`
+	Names  []string `json:"Names"`
+	Image  string   `json:"Image"`
+	State  string   `json:"State"`
+	Status string   `json:"Status"`
+}
+
+// dockerStats is the part of GET /containers/{id}/stats we use
+type dockerStats struct {
+	CPUStats struct {
+		CPUUsage struct {
+			TotalUsage uint64 `json:"total_usage"`
+		} `json:"cpu_usage"`
+		SystemUsage uint64 `json:"system_cpu_usage"`
+		OnlineCPUs  int    `json:"online_cpus"`
+	} `json:"cpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+}
+
+// dockerRequest calls the Engine API and decodes a JSON answer into out
+// when it is not nil
+func dockerRequest(method, path string, timeout time.Duration, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, "http://docker"+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := dockerClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1024)).Decode(&apiErr)
+		if apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+		return fmt.Errorf("%s", apiErr.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// getContainers lists all containers, running ones first, and fills in
+// the resource usage of the running ones. prev holds the CPU counters of
+// the last call and is updated for the next one.
+func getContainers(prev map[string]containerCPU) ([]ContainerInfo, error) {
+	var list []dockerContainer
+	if err := dockerRequest(http.MethodGet, "/containers/json?all=1", dockerTimeout, &list); err != nil {
+		return nil, err
+	}
+
+	containers := make([]ContainerInfo, 0, len(list))
+	seen := make(map[string]bool, len(list))
+	for _, dc := range list {
+		c := ContainerInfo{
+			ID:     dc.ID,
+			Image:  dc.Image,
+			State:  dc.State,
+			Status: dc.Status,
+		}
+		if len(dc.Names) > 0 {
+			c.Name = strings.TrimPrefix(dc.Names[0], "/")
+		}
+		if c.Running() {
+			readContainerStats(&c, prev)
+			seen[c.ID] = true
+		}
+		containers = append(containers, c)
+	}
+	for id := range prev {
+		if !seen[id] {
+			delete(prev, id)
+		}
+	}
+
+	sort.SliceStable(containers, func(i, j int) bool {
+		a, b := containers[i], containers[j]
+		if a.Running() != b.Running() {
+			return a.Running()
+		}
+		return a.Name < b.Name
+	})
+	return containers, nil
+}
+
+// readContainerStats fills in CPU, memory and network usage of c. The
+// one-shot stats have no previous CPU sample, so CPU% is computed
+// against our own last reading and is 0 on the first one.
+func readContainerStats(c *ContainerInfo, prev map[string]containerCPU) {
+	var s dockerStats
+	if err := dockerRequest(http.MethodGet, "/containers/"+c.ID+"/stats?stream=false&one-shot=true", dockerTimeout, &s); err != nil {
+		return
+	}
+
+	cur := containerCPU{total: s.CPUStats.CPUUsage.TotalUsage, system: s.CPUStats.SystemUsage}
+	if p, ok := prev[c.ID]; ok && cur.system > p.system && cur.total >= p.total {
+		cpus := s.CPUStats.OnlineCPUs
+		if cpus == 0 {
+			cpus = 1
+		}
+		c.CPU = float64(cur.total-p.total) / float64(cur.system-p.system) * float64(cpus) * 100
+	}
+	prev[c.ID] = cur
+
+	// Page cache is reclaimable, docker stats leaves it out as well
+	c.MemUsage = s.MemoryStats.Usage
+	cache := s.MemoryStats.Stats["inactive_file"] // cgroup v2
+	if cache == 0 {
+		cache = s.MemoryStats.Stats["cache"] // cgroup v1
+	}
+	if cache < c.MemUsage {
+		c.MemUsage -= cache
+	}
+	c.MemLimit = s.MemoryStats.Limit
+
+	for _, n := range s.Networks {
+		c.NetRx += n.RxBytes
+		c.NetTx += n.TxBytes
+	}
+}
+
+// ContainerStatus is the container list of the latest refresh
+type ContainerStatus struct {
+	Containers []ContainerInfo
+	Err        error
+	At         time.Time // zero before the first refresh
+}
+
+// ContainerMonitor keeps the containers for the Containers view.
+// runCollector refreshes it while the view is shown, so the Engine API
+// requests do not hold up the UI goroutine.
+type ContainerMonitor struct {
+	mu     sync.Mutex
+	status ContainerStatus
+	wanted bool                    // the view asked since the last refresh
+	prev   map[string]containerCPU // CPU counters for the next CPU%, used by Refresh only
+}
+
+// Status returns the result of the latest refresh and asks for the next
+// one
+func (m *ContainerMonitor) Status() ContainerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.wanted = true
+	return m.status
+}
+
+// Refresh lists the containers if the view asked for them since the
+// last refresh
+func (m *ContainerMonitor) Refresh() {
+	m.mu.Lock()
+	wanted := m.wanted
+	m.wanted = false
+	m.mu.Unlock()
+	if !wanted {
+		return
+	}
+
+	if m.prev == nil {
+		m.prev = make(map[string]containerCPU)
+	}
+	containers, err := getContainers(m.prev)
+
+	m.mu.Lock()
+	m.status = ContainerStatus{Containers: containers, Err: err, At: time.Now()}
+	m.mu.Unlock()
+}
+
+func (d *Dashboard) updateContainersView(stats SystemStats) {
+	if !d.reuseLists {
+		status := d.containers.Status()
+		switch {
+		case status.At.IsZero():
+			d.containerList = nil
+			d.mainList.Title = d.viewTitle("")
+			d.mainList.Rows = []string{"", "Loading..."}
+			return
+		case status.Err != nil:
+			d.containerList = nil
+			d.mainList.Title = d.viewTitle("")
+			d.mainList.Rows = []string{"", "Docker not running", "", truncateString(status.Err.Error(), 27)}
+			return
+		}
+		d.containerList = status.Containers
+	}
+	containers := d.containerList
+	total := len(containers)
+	if total == 0 {
+		d.mainList.Title = d.viewTitle("0/0")
+		d.mainList.Rows = []string{"", "No containers"}
+		return
+	}
+	if d.selectedContainer >= total {
+		d.selectedContainer = total - 1
+	}
+
+	running := 0
+	for _, c := range containers {
+		if c.Running() {
+			running++
+		}
+	}
+	d.mainList.Title = d.viewTitle(fmt.Sprintf("%d/%d R:%d", d.selectedContainer+1, total, running))
+
+	rows := []string{"[Name          CPU%    MEM](fg:cyan)"}
+
+	// Leave room for the detail rows of the selected container
+	visibleHeight := 19
+	startIdx := d.selectedContainer - visibleHeight/2
+	if startIdx > total-visibleHeight {
+		startIdx = total - visibleHeight
+	}
+	if startIdx < 0 {
+		startIdx = 0
+	}
+	endIdx := startIdx + visibleHeight
+	if endIdx > total {
+		endIdx = total
+	}
+
+	for i := startIdx; i < endIdx; i++ {
+		c := containers[i]
+		line := fmt.Sprintf("%-12s %s", truncateString(c.Name, 12), truncateString(c.State, 13))
+		color := "white"
+		if c.Running() {
+			line = fmt.Sprintf("%-12s %5.1f %6s", truncateString(c.Name, 12), c.CPU, formatBytes(c.MemUsage))
+			color = "green"
+		}
+		if i == d.selectedContainer {
+			rows = append(rows, fmt.Sprintf("[%s](bg:white,fg:black)", line))
+		} else {
+			rows = append(rows, fmt.Sprintf("[%s](fg:%s)", line, color))
+		}
+	}
+
+	c := containers[d.selectedContainer]
+	rows = append(rows,
+		"",
+		truncateString(c.Image, 27),
+		truncateString(c.Status, 27),
+	)
+	if c.Running() {
+		memPercent := 0.0
+		if c.MemLimit > 0 {
+			memPercent = float64(c.MemUsage) / float64(c.MemLimit) * 100
+		}
+		rows = append(rows,
+			fmt.Sprintf("MEM: %s/%s %.0f%%", formatBytes(c.MemUsage), formatBytes(c.MemLimit), memPercent),
+			fmt.Sprintf("NET: ↓%s ↑%s", formatBytes(c.NetRx), formatBytes(c.NetTx)),
+		)
+	}
+	rows = append(rows, "[Enter/A: actions](fg:yellow)")
+	d.mainList.Rows = rows
+}
+
+// confirmContainerAction asks what to do with the selected container
+func (d *Dashboard) confirmContainerAction() {
+	if d.selectedContainer < 0 || d.selectedContainer >= len(d.containerList) {
+		return
+	}
+	c := d.containerList[d.selectedContainer]
+
+	options := []menuOption{{label: "Cancel"}}
+	actions := []string{"start"}
+	if c.Running() {
+		actions = []string{"restart", "stop"}
+	}
+	for _, action := range actions {
+		action := action
+		options = append(options, menuOption{
+			label:  strings.ToUpper(action[:1]) + action[1:],
+			action: func(d *Dashboard) { d.runContainerAction(c, action) },
+		})
+	}
+
+	d.openMenu(&Menu{
+		title: "Container",
+		message: []string{
+			truncateString(c.Name, 24),
+			truncateString(c.Status, 24),
+		},
+		options: options,
+	})
+}
+
+// containerResult is a container action that finished, for the event
+// loop to show
+type containerResult struct {
+	name   string
+	action string
+	err    error
+}
+
+// runContainerAction starts, stops or restarts c off the UI goroutine,
+// since the daemon may take dockerStopWait seconds to stop it. The event
+// loop shows the result once it finishes.
+func (d *Dashboard) runContainerAction(c ContainerInfo, action string) {
+	path := fmt.Sprintf("/containers/%s/%s", c.ID, action)
+	if action != "start" {
+		path += fmt.Sprintf("?t=%d", dockerStopWait)
+	}
+	d.showMessage("Running", action, truncateString(c.Name, 24))
+	go func() {
+		err := dockerRequest(http.MethodPost, path, dockerActionTimeout, nil)
+		d.dockerResults <- containerResult{name: c.Name, action: action, err: err}
+	}()
+}
+
+// showContainerResult reports a finished container action in a message
+// box, unless another menu was opened meanwhile. The list catches up at
+// the next refresh.
+func (d *Dashboard) showContainerResult(res containerResult) {
+	shown := d.menu == nil || d.menu.title == "Running"
+	if res.err != nil {
+		log.Printf("Failed to %s container %s: %v", res.action, res.name, res.err)
+		if shown {
+			d.showMessage("Error", truncateString(res.err.Error(), 24))
+		}
+		return
+	}
+	log.Printf("Container %s: %s", res.name, res.action)
+	if shown {
+		d.showMessage("Done", fmt.Sprintf("%s done", res.action), truncateString(res.name, 24))
+	}
+}
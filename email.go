@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLS modes of the SMTP connection
+const (
+	smtpStartTLS = "starttls" // plain connection upgraded with STARTTLS, usually port 587
+	smtpTLS      = "tls"      // TLS from the start, usually port 465
+	smtpNoTLS    = "none"     // only for a relay on the local network
+)
+
+// EmailConfig sends alerts by e-mail through an SMTP server
+type EmailConfig struct {
+	Host     string   `yaml:"host"` // SMTP server, empty disables e-mail
+	Port     int      `yaml:"port"`
+	TLS      string   `yaml:"tls"` // starttls, tls or none
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+func (c EmailConfig) validate() error {
+	if c.Host == "" {
+		return nil
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("alerts.email.port must be between 1 and 65535")
+	}
+	if c.TLS != smtpStartTLS && c.TLS != smtpTLS && c.TLS != smtpNoTLS {
+		return fmt.Errorf("alerts.email.tls must be %s, %s or %s, got %q", smtpStartTLS, smtpTLS, smtpNoTLS, c.TLS)
+	}
+	if c.Password != "" && c.Username == "" {
+		return fmt.Errorf("alerts.email.password needs a username")
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("alerts.email.from: %w", err)
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("alerts.email.to needs at least one address")
+	}
+	for _, to := range c.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("alerts.email.to: %w", err)
+		}
+	}
+	return nil
+}
+
+type emailAction struct {
+	cfg EmailConfig
+}
+
+func (a emailAction) Fire(evt AlertEvent)    { a.send(evt) }
+func (a emailAction) Resolve(evt AlertEvent) { a.send(evt) }
+
+func (a emailAction) send(evt AlertEvent) {
+	if err := sendEmail(a.cfg, emailMessage(a.cfg, evt, time.Now())); err != nil {
+		log.Printf("E-mail: %v", err)
+	}
+}
+
+// emailMessage builds the mail for evt, with the one line message as
+// the subject and the details in the body
+func emailMessage(cfg EmailConfig, evt AlertEvent, now time.Time) []byte {
+	status := "FIRING"
+	if evt.Resolved {
+		status = "RESOLVED"
+	}
+	op := evt.Rule.Op
+	if op == "" {
+		op = ">"
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", status+" "+evt.Message()))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(&b, "Content-Transfer-Encoding: 8bit\r\n")
+	fmt.Fprintf(&b, "\r\n")
+	fmt.Fprintf(&b, "%s\r\n\r\n", evt.Message())
+	fmt.Fprintf(&b, "Status:    %s\r\n", status)
+	fmt.Fprintf(&b, "Host:      %s\r\n", evt.Host)
+	fmt.Fprintf(&b, "Alert:     %s\r\n", evt.Rule.Name)
+	fmt.Fprintf(&b, "Metric:    %s\r\n", evt.Rule.Metric)
+	fmt.Fprintf(&b, "Value:     %.1f\r\n", evt.Value)
+	fmt.Fprintf(&b, "Condition: %s %s %g\r\n", evt.Rule.Metric, op, evt.Rule.Threshold)
+	fmt.Fprintf(&b, "Since:     %s\r\n", evt.Since.Format(time.RFC1123Z))
+	return b.Bytes()
+}
+
+// sendEmail delivers msg to every recipient, giving up after
+// notifyTimeout like the other notifications
+func sendEmail(cfg EmailConfig, msg []byte) error {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+	dialer := &net.Dialer{Timeout: notifyTimeout}
+
+	var conn net.Conn
+	var err error
+	if cfg.TLS == smtpTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(notifyTimeout))
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if err := c.Hello(hostname()); err != nil {
+		return err
+	}
+	if cfg.TLS == smtpStartTLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS: %w", err)
+		}
+	}
+	if cfg.Username != "" {
+		// PlainAuth refuses to send the password without TLS, except
+		// to localhost
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	from, _ := mail.ParseAddress(cfg.From) // checked by validate
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range cfg.To {
+		rcpt, _ := mail.ParseAddress(to)
+		if err := c.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("%s: %w", rcpt.Address, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
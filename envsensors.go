@@ -0,0 +1,507 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// Supported ambient sensor chips
+const (
+	envBME280 = "bme280" // temperature, humidity, pressure
+	envBMP280 = "bmp280" // temperature, pressure
+	envBMP180 = "bmp180" // temperature, pressure
+	envSHT3x  = "sht3x"  // temperature, humidity
+)
+
+// Chip ids in register 0xd0 of the Bosch sensors
+var boschChipIDs = map[byte]string{
+	0x60: envBME280,
+	0x58: envBMP280,
+	0x55: envBMP180,
+}
+
+// envProbeAddresses are tried when no sensors are configured
+var envProbeAddresses = []int{0x76, 0x77, 0x44, 0x45}
+
+// EnvReading is an ambient sensor value reported with the stats
+type EnvReading = collector.EnvReading
+
+// EnvConfig sets up the I2C environmental sensors that many
+// monitoring HATs carry
+type EnvConfig struct {
+	Enabled  bool              `yaml:"enabled"`
+	Device   string            `yaml:"device"` // i2c-dev node, /dev/i2c-1 when empty
+	Interval time.Duration     `yaml:"interval"`
+	Sensors  []EnvSensorConfig `yaml:"sensors"` // empty probes 0x76, 0x77, 0x44 and 0x45
+}
+
+// EnvSensorConfig is one sensor on the bus
+type EnvSensorConfig struct {
+	Type    string `yaml:"type"` // bme280, bmp280, bmp180 or sht3x
+	Address int    `yaml:"address"`
+	Name    string `yaml:"name"` // shown instead of the type, e.g. "Case"
+}
+
+func (c EnvConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("env_sensors.interval must be positive")
+	}
+	for i, s := range c.Sensors {
+		switch s.Type {
+		case envBME280, envBMP280, envBMP180, envSHT3x:
+		default:
+			return fmt.Errorf("env_sensors.sensors[%d]: unknown type %q (%s, %s, %s, %s)",
+				i, s.Type, envBME280, envBMP280, envBMP180, envSHT3x)
+		}
+		if s.Address < 0x03 || s.Address > 0x77 {
+			return fmt.Errorf("env_sensors.sensors[%d]: address 0x%02x out of range", i, s.Address)
+		}
+	}
+	return nil
+}
+
+// i2cDevice is one address on an I2C bus, opened through i2c-dev
+type i2cDevice struct {
+	f *os.File
+}
+
+func openI2C(device string, addr int) (*i2cDevice, error) {
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open %s (dtparam=i2c_arm=on?): %w", device, err)
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), i2cSlave, uintptr(addr)); errno != 0 {
+		f.Close()
+		return nil, fmt.Errorf("select I2C address 0x%02x: %w", addr, errno)
+	}
+	return &i2cDevice{f: f}, nil
+}
+
+func (d *i2cDevice) write(data ...byte) error {
+	_, err := d.f.Write(data)
+	return err
+}
+
+// readReg reads n bytes starting at register reg
+func (d *i2cDevice) readReg(reg byte, n int) ([]byte, error) {
+	if err := d.write(reg); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if _, err := d.f.Read(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (d *i2cDevice) Close() error {
+	return d.f.Close()
+}
+
+// envSensor reads one chip
+type envSensor interface {
+	read() (EnvReading, error)
+	Close() error
+}
+
+// openEnvSensor opens and initializes the sensor described by cfg
+func openEnvSensor(device string, cfg EnvSensorConfig) (envSensor, error) {
+	dev, err := openI2C(device, cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	name := cfg.Name
+	if name == "" {
+		name = cfg.Type
+	}
+
+	var s envSensor
+	switch cfg.Type {
+	case envBME280, envBMP280:
+		s, err = newBME280(dev, name, cfg.Type == envBME280)
+	case envBMP180:
+		s, err = newBMP180(dev, name)
+	case envSHT3x:
+		s = &sht3x{dev: dev, name: name}
+	}
+	if err != nil {
+		dev.Close()
+		return nil, fmt.Errorf("%s at 0x%02x: %w", cfg.Type, cfg.Address, err)
+	}
+	return s, nil
+}
+
+// probeEnvSensors looks for the usual sensors at their default
+// addresses. Bosch chips tell their type in register 0xd0; an SHT3x is
+// assumed when a measurement at 0x44/0x45 succeeds.
+func probeEnvSensors(device string) []EnvSensorConfig {
+	var found []EnvSensorConfig
+	for _, addr := range envProbeAddresses {
+		dev, err := openI2C(device, addr)
+		if err != nil {
+			continue
+		}
+		if addr == 0x76 || addr == 0x77 {
+			if id, err := dev.readReg(0xd0, 1); err == nil {
+				if kind, ok := boschChipIDs[id[0]]; ok {
+					found = append(found, EnvSensorConfig{Type: kind, Address: addr})
+				}
+			}
+		} else if _, err := (&sht3x{dev: dev}).read(); err == nil {
+			found = append(found, EnvSensorConfig{Type: envSHT3x, Address: addr})
+		}
+		dev.Close()
+	}
+	return found
+}
+
+// bme280 drives a Bosch BME280 or BMP280 in forced mode
+type bme280 struct {
+	dev      *i2cDevice
+	name     string
+	humidity bool // BME280; the BMP280 has no humidity sensor
+
+	t1                                 float64
+	t2, t3                             float64
+	p1, p2, p3, p4, p5, p6, p7, p8, p9 float64
+	h1, h2, h3, h4, h5, h6             float64
+}
+
+func newBME280(dev *i2cDevice, name string, humidity bool) (*bme280, error) {
+	c, err := dev.readReg(0x88, 26)
+	if err != nil {
+		return nil, err
+	}
+	u16 := func(i int) float64 { return float64(uint16(c[i]) | uint16(c[i+1])<<8) }
+	s16 := func(i int) float64 { return float64(int16(uint16(c[i]) | uint16(c[i+1])<<8)) }
+	s := &bme280{
+		dev: dev, name: name, humidity: humidity,
+		t1: u16(0), t2: s16(2), t3: s16(4),
+		p1: u16(6), p2: s16(8), p3: s16(10), p4: s16(12), p5: s16(14),
+		p6: s16(16), p7: s16(18), p8: s16(20), p9: s16(22),
+		h1: float64(c[25]),
+	}
+	if humidity {
+		h, err := dev.readReg(0xe1, 7)
+		if err != nil {
+			return nil, err
+		}
+		s.h2 = float64(int16(uint16(h[0]) | uint16(h[1])<<8))
+		s.h3 = float64(h[2])
+		s.h4 = float64(int16(int8(h[3]))<<4 | int16(h[4]&0x0f))
+		s.h5 = float64(int16(int8(h[5]))<<4 | int16(h[4]>>4))
+		s.h6 = float64(int8(h[6]))
+	}
+	return s, nil
+}
+
+func (s *bme280) read() (EnvReading, error) {
+	if s.humidity {
+		if err := s.dev.write(0xf2, 0x01); err != nil { // humidity oversampling x1
+			return EnvReading{}, err
+		}
+	}
+	// Temperature and pressure oversampling x1, forced mode: one
+	// measurement, then back to sleep so the chip does not self-heat
+	if err := s.dev.write(0xf4, 0x25); err != nil {
+		return EnvReading{}, err
+	}
+	for i := 0; i < 10; i++ {
+		time.Sleep(5 * time.Millisecond)
+		status, err := s.dev.readReg(0xf3, 1)
+		if err != nil {
+			return EnvReading{}, err
+		}
+		if status[0]&0x08 == 0 {
+			break
+		}
+	}
+	d, err := s.dev.readReg(0xf7, 8)
+	if err != nil {
+		return EnvReading{}, err
+	}
+	adcP := float64(uint32(d[0])<<12 | uint32(d[1])<<4 | uint32(d[2])>>4)
+	adcT := float64(uint32(d[3])<<12 | uint32(d[4])<<4 | uint32(d[5])>>4)
+	adcH := float64(uint32(d[6])<<8 | uint32(d[7]))
+
+	// Floating point compensation from the BME280 datasheet, 8.1
+	v1 := (adcT/16384 - s.t1/1024) * s.t2
+	v2 := (adcT/131072 - s.t1/8192) * (adcT/131072 - s.t1/8192) * s.t3
+	tFine := v1 + v2
+	r := EnvReading{Name: s.name, Temperature: tFine / 5120}
+
+	v1 = tFine/2 - 64000
+	v2 = v1 * v1 * s.p6 / 32768
+	v2 += v1 * s.p5 * 2
+	v2 = v2/4 + s.p4*65536
+	v1 = (s.p3*v1*v1/524288 + s.p2*v1) / 524288
+	v1 = (1 + v1/32768) * s.p1
+	if v1 != 0 {
+		p := 1048576 - adcP
+		p = (p - v2/4096) * 6250 / v1
+		v1 = s.p9 * p * p / 2147483648
+		v2 = p * s.p8 / 32768
+		p += (v1 + v2 + s.p7) / 16
+		hPa := p / 100
+		r.Pressure = &hPa
+	}
+
+	if s.humidity {
+		h := tFine - 76800
+		h = (adcH - (s.h4*64 + s.h5/16384*h)) *
+			(s.h2 / 65536 * (1 + s.h6/67108864*h*(1+s.h3/67108864*h)))
+		h *= 1 - s.h1*h/524288
+		h = clamp(h, 0, 100)
+		r.Humidity = &h
+	}
+	return r, nil
+}
+
+func (s *bme280) Close() error { return s.dev.Close() }
+
+// bmp180 drives the older Bosch BMP180 (and BMP085)
+type bmp180 struct {
+	dev  *i2cDevice
+	name string
+
+	ac1, ac2, ac3      int64
+	ac4, ac5, ac6      int64
+	b1, b2, mb, mc, md int64
+}
+
+func newBMP180(dev *i2cDevice, name string) (*bmp180, error) {
+	c, err := dev.readReg(0xaa, 22)
+	if err != nil {
+		return nil, err
+	}
+	// Calibration words are big endian, AC4 to AC6 unsigned
+	s16 := func(i int) int64 { return int64(int16(uint16(c[i])<<8 | uint16(c[i+1]))) }
+	u16 := func(i int) int64 { return int64(uint16(c[i])<<8 | uint16(c[i+1])) }
+	return &bmp180{
+		dev: dev, name: name,
+		ac1: s16(0), ac2: s16(2), ac3: s16(4), ac4: u16(6), ac5: u16(8), ac6: u16(10),
+		b1: s16(12), b2: s16(14), mb: s16(16), mc: s16(18), md: s16(20),
+	}, nil
+}
+
+func (s *bmp180) read() (EnvReading, error) {
+	if err := s.dev.write(0xf4, 0x2e); err != nil { // temperature
+		return EnvReading{}, err
+	}
+	time.Sleep(5 * time.Millisecond)
+	d, err := s.dev.readReg(0xf6, 2)
+	if err != nil {
+		return EnvReading{}, err
+	}
+	ut := int64(d[0])<<8 | int64(d[1])
+
+	if err := s.dev.write(0xf4, 0x34); err != nil { // pressure, oversampling 0
+		return EnvReading{}, err
+	}
+	time.Sleep(5 * time.Millisecond)
+	d, err = s.dev.readReg(0xf6, 3)
+	if err != nil {
+		return EnvReading{}, err
+	}
+	up := (int64(d[0])<<16 | int64(d[1])<<8 | int64(d[2])) >> 8
+
+	// Integer compensation from the BMP180 datasheet, 3.5
+	x1 := (ut - s.ac6) * s.ac5 >> 15
+	if x1+s.md == 0 {
+		return EnvReading{}, fmt.Errorf("bad calibration data")
+	}
+	x2 := (s.mc << 11) / (x1 + s.md)
+	b5 := x1 + x2
+	r := EnvReading{Name: s.name, Temperature: float64((b5+8)>>4) / 10}
+
+	b6 := b5 - 4000
+	x1 = (s.b2 * (b6 * b6 >> 12)) >> 11
+	x2 = s.ac2 * b6 >> 11
+	b3 := ((s.ac1*4 + x1 + x2) + 2) / 4
+	x1 = s.ac3 * b6 >> 13
+	x2 = (s.b1 * (b6 * b6 >> 12)) >> 16
+	x3 := ((x1 + x2) + 2) >> 2
+	b4 := s.ac4 * (x3 + 32768) >> 15
+	if b4 == 0 {
+		return EnvReading{}, fmt.Errorf("bad calibration data")
+	}
+	b7 := (up - b3) * 50000
+	p := b7 * 2 / b4
+	x1 = (p >> 8) * (p >> 8)
+	x1 = (x1 * 3038) >> 16
+	x2 = (-7357 * p) >> 16
+	p += (x1 + x2 + 3791) >> 4
+	hPa := float64(p) / 100
+	r.Pressure = &hPa
+	return r, nil
+}
+
+func (s *bmp180) Close() error { return s.dev.Close() }
+
+// sht3x drives a Sensirion SHT30/31/35
+type sht3x struct {
+	dev  *i2cDevice
+	name string
+}
+
+func (s *sht3x) read() (EnvReading, error) {
+	// Single shot, high repeatability, no clock stretching
+	if err := s.dev.write(0x24, 0x00); err != nil {
+		return EnvReading{}, err
+	}
+	time.Sleep(16 * time.Millisecond)
+	d := make([]byte, 6)
+	if _, err := s.dev.f.Read(d); err != nil {
+		return EnvReading{}, err
+	}
+	if sensirionCRC(d[0:2]) != d[2] || sensirionCRC(d[3:5]) != d[5] {
+		return EnvReading{}, fmt.Errorf("CRC mismatch")
+	}
+	rawT := float64(uint16(d[0])<<8 | uint16(d[1]))
+	rawH := float64(uint16(d[3])<<8 | uint16(d[4]))
+	h := clamp(100*rawH/65535, 0, 100)
+	return EnvReading{Name: s.name, Temperature: -45 + 175*rawT/65535, Humidity: &h}, nil
+}
+
+func (s *sht3x) Close() error { return s.dev.Close() }
+
+// sensirionCRC is the CRC-8 of Sensirion sensors: polynomial 0x31,
+// initial value 0xff
+func sensirionCRC(data []byte) byte {
+	crc := byte(0xff)
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func clamp(v, lo, hi float64) float64 {
+	switch {
+	case v < lo:
+		return lo
+	case v > hi:
+		return hi
+	}
+	return v
+}
+
+// EnvMonitor reads the sensors in the background, since the chips need
+// several milliseconds per measurement
+type EnvMonitor struct {
+	cfg     EnvConfig
+	sensors []envSensor
+
+	mu       sync.Mutex
+	readings []EnvReading
+}
+
+// newEnvMonitor opens the configured sensors, or the ones found by
+// probing. Sensors that fail to open are logged and skipped.
+func newEnvMonitor(cfg EnvConfig) (*EnvMonitor, error) {
+	device := cfg.Device
+	if device == "" {
+		device = "/dev/i2c-1"
+	}
+	configs := cfg.Sensors
+	if len(configs) == 0 {
+		configs = probeEnvSensors(device)
+	}
+
+	m := &EnvMonitor{cfg: cfg}
+	for _, sc := range configs {
+		s, err := openEnvSensor(device, sc)
+		if err != nil {
+			log.Printf("Warning: env sensor skipped: %v", err)
+			continue
+		}
+		log.Printf("Env sensor %s at 0x%02x on %s", sc.Type, sc.Address, device)
+		m.sensors = append(m.sensors, s)
+	}
+	if len(m.sensors) == 0 {
+		return nil, fmt.Errorf("no sensors found on %s", device)
+	}
+	return m, nil
+}
+
+// Readings returns the latest value of every sensor that answered
+func (m *EnvMonitor) Readings() []EnvReading {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.readings
+}
+
+// Run reads the sensors every interval until stop is closed
+func (m *EnvMonitor) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	failed := make([]bool, len(m.sensors))
+	for {
+		var readings []EnvReading
+		for i, s := range m.sensors {
+			r, err := s.read()
+			if err != nil {
+				if !failed[i] {
+					log.Printf("Env sensor read failed: %v", err)
+				}
+				failed[i] = true
+				continue
+			}
+			failed[i] = false
+			readings = append(readings, r)
+		}
+		m.mu.Lock()
+		m.readings = readings
+		m.mu.Unlock()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close releases the I2C devices
+func (m *EnvMonitor) Close() {
+	for _, s := range m.sensors {
+		s.Close()
+	}
+}
+
+// formatEnv prints a reading compactly, e.g. "23.4°C 45% 1013hPa"
+func formatEnv(r EnvReading) string {
+	text := fmt.Sprintf("%.1f°C", r.Temperature)
+	if r.Humidity != nil {
+		text += fmt.Sprintf(" %.0f%%", *r.Humidity)
+	}
+	if r.Pressure != nil {
+		text += fmt.Sprintf(" %.0fhPa", *r.Pressure)
+	}
+	return text
+}
+
+// envRows shows the ambient readings under the CPU temperature
+func envRows(readings []EnvReading) []string {
+	rows := make([]string, 0, len(readings))
+	for _, r := range readings {
+		rows = append(rows, fmt.Sprintf("%s: %s", truncateString(r.Name, 7), formatEnv(r)))
+	}
+	return rows
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportTimeLayouts are the absolute time formats accepted by --from/--to
+var exportTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseExportTime accepts an absolute time in local time or a duration
+// meaning that long before now, e.g. "24h" or "30m"
+func parseExportTime(value string, now time.Time) (time.Time, error) {
+	if value == "" || value == "now" {
+		return now, nil
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(value, "-")); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range exportTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use e.g. 2024-05-01, \"2024-05-01 12:00\" or 24h)", value)
+}
+
+// runExport implements "raspi-monitor export", dumping the recorded
+// history as CSV or JSON. It returns the process exit code.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath(), "path to the config file")
+	dbPath := fs.String("db", "", "history database (default: history.path from the config)")
+	from := fs.String("from", "24h", "start time, absolute or a duration ago")
+	to := fs.String("to", "now", "end time, absolute or a duration ago")
+	format := fs.String("format", "csv", "output format: csv or json")
+	output := fs.String("output", "", "output file (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: raspi-monitor export [--from TIME] [--to TIME] [--format csv|json] [--output FILE]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := exportHistory(*configPath, *dbPath, *from, *to, *format, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func exportHistory(configPath, dbPath, from, to, format, output string) error {
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	now := time.Now()
+	fromTime, err := parseExportTime(from, now)
+	if err != nil {
+		return err
+	}
+	toTime, err := parseExportTime(to, now)
+	if err != nil {
+		return err
+	}
+
+	if dbPath == "" {
+		cfg, err := loadConfig(configPath)
+		if err != nil {
+			return err
+		}
+		dbPath = cfg.History.Path
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("no history database at %s (enable history in the config)", dbPath)
+	}
+
+	store, err := openHistoryStore(dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	samples, err := store.Query(fromTime, toTime)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if samples == nil {
+			samples = []HistorySample{}
+		}
+		return enc.Encode(samples)
+	}
+	return writeHistoryCSV(w, samples)
+}
+
+// writeHistoryCSV writes one row per sample with a header line
+func writeHistoryCSV(w io.Writer, samples []HistorySample) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "cpu_percent", "mem_percent", "swap_percent", "disk_percent", "temperature",
+		"net_up_bps", "net_down_bps", "disk_read_bps", "disk_write_bps", "throttled"})
+
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	for _, s := range samples {
+		cw.Write([]string{
+			s.Time.Format(time.RFC3339),
+			f(s.CPU), f(s.Mem), f(s.Swap), f(s.Disk), f(s.Temperature),
+			f(s.NetUp), f(s.NetDown), f(s.DiskRead), f(s.DiskWrite),
+			strconv.FormatUint(s.Throttled, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// Fan output modes
+const (
+	fanModeGPIO  = "gpio"  // software PWM on a header pin
+	fanModePWM   = "pwm"   // hardware PWM through /sys/class/pwm
+	fanModeHwmon = "hwmon" // pwmN attribute of a hwmon fan driver
+)
+
+// FanConfig configures the temperature controlled fan
+type FanConfig struct {
+	Enabled    bool       `yaml:"enabled"`
+	Mode       string     `yaml:"mode"`
+	Pin        int        `yaml:"pin"`         // BCM pin for gpio mode
+	PWMChip    int        `yaml:"pwm_chip"`    // pwmchipN for pwm mode
+	PWMChannel int        `yaml:"pwm_channel"` // pwmN for pwm mode
+	HwmonPath  string     `yaml:"hwmon_path"`  // e.g. /sys/class/hwmon/hwmon2/pwm1
+	Frequency  int        `yaml:"frequency"`   // Hz, 0 picks a default for the mode
+	Hysteresis float64    `yaml:"hysteresis"`  // °C the temperature must drop before slowing down
+	Curve      []FanPoint `yaml:"curve"`
+}
+
+// FanPoint maps a temperature to a fan duty in percent
+type FanPoint struct {
+	Temp float64 `yaml:"temp"`
+	Duty int     `yaml:"duty"`
+}
+
+// FanStatus is the fan state reported with the stats
+type FanStatus = collector.FanStatus
+
+func defaultFanCurve() []FanPoint {
+	return []FanPoint{
+		{Temp: 50, Duty: 0},
+		{Temp: 55, Duty: 40},
+		{Temp: 65, Duty: 70},
+		{Temp: 75, Duty: 100},
+	}
+}
+
+func (c FanConfig) validate() error {
+	switch c.Mode {
+	case fanModeGPIO, fanModePWM:
+	case fanModeHwmon:
+		if c.HwmonPath == "" {
+			return fmt.Errorf("fan.hwmon_path is required in hwmon mode")
+		}
+	default:
+		return fmt.Errorf("unknown fan.mode %q (gpio, pwm, hwmon)", c.Mode)
+	}
+	if len(c.Curve) == 0 {
+		return fmt.Errorf("fan.curve must have at least one point")
+	}
+	for _, p := range c.Curve {
+		if p.Duty < 0 || p.Duty > 100 {
+			return fmt.Errorf("fan.curve duty must be 0-100, got %d", p.Duty)
+		}
+	}
+	if c.Frequency < 0 || c.Hysteresis < 0 {
+		return fmt.Errorf("fan.frequency and fan.hysteresis must not be negative")
+	}
+	return nil
+}
+
+// fanDutyFor interpolates the duty for temp between the curve points.
+// Below the first point the first duty is used, above the last the last.
+func fanDutyFor(curve []FanPoint, temp float64) int {
+	if temp <= curve[0].Temp {
+		return curve[0].Duty
+	}
+	for i := 1; i < len(curve); i++ {
+		lo, hi := curve[i-1], curve[i]
+		if temp < hi.Temp {
+			frac := (temp - lo.Temp) / (hi.Temp - lo.Temp)
+			return lo.Duty + int(frac*float64(hi.Duty-lo.Duty)+0.5)
+		}
+	}
+	return curve[len(curve)-1].Duty
+}
+
+// fanOutput drives the fan at a duty cycle in percent
+type fanOutput interface {
+	SetDuty(percent int) error
+	Close() error
+}
+
+// FanController applies the fan curve to the measured temperature
+type FanController struct {
+	cfg   FanConfig
+	out   fanOutput
+	duty  int
+	ready bool
+}
+
+func newFanController(cfg FanConfig) (*FanController, error) {
+	cfg.Curve = append([]FanPoint(nil), cfg.Curve...)
+	sort.Slice(cfg.Curve, func(i, j int) bool { return cfg.Curve[i].Temp < cfg.Curve[j].Temp })
+
+	var out fanOutput
+	var err error
+	switch cfg.Mode {
+	case fanModeGPIO:
+		out, err = newGPIOFan(cfg.Pin, cfg.Frequency)
+	case fanModePWM:
+		out, err = newPWMFan(cfg.PWMChip, cfg.PWMChannel, cfg.Frequency)
+	case fanModeHwmon:
+		out, err = newHwmonFan(cfg.HwmonPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Fan control enabled (%s)", cfg.Mode)
+	return &FanController{cfg: cfg, out: out}, nil
+}
+
+// Update sets the fan speed for temp. The fan only slows down once the
+// temperature has dropped by the hysteresis, so it does not hunt around
+// a curve point. An unreadable temperature runs the fan at full speed.
+func (f *FanController) Update(temp float64) {
+	duty := 100
+	if temp > 0 {
+		duty = fanDutyFor(f.cfg.Curve, temp)
+		if f.ready && duty < f.duty && fanDutyFor(f.cfg.Curve, temp+f.cfg.Hysteresis) >= f.duty {
+			duty = f.duty
+		}
+	}
+	if f.ready && duty == f.duty {
+		return
+	}
+
+	if err := f.out.SetDuty(duty); err != nil {
+		log.Printf("Fan: set duty %d%%: %v", duty, err)
+		return
+	}
+	f.duty = duty
+	f.ready = true
+}
+
+// Status returns the current duty for the stats
+func (f *FanController) Status() *FanStatus {
+	return &FanStatus{Duty: f.duty, Mode: f.cfg.Mode}
+}
+
+func (f *FanController) Close() error {
+	return f.out.Close()
+}
+
+// gpioFan switches a header pin in software. Low frequencies are fine
+// for a 2-pin fan behind a transistor.
+type gpioFan struct {
+	line   *gpiocdev.Line
+	duty   atomic.Int32
+	period time.Duration
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newGPIOFan(pin, freq int) (*gpioFan, error) {
+	if freq == 0 {
+		freq = 25
+	}
+	line, err := requestOutputLine(pin, 0)
+	if err != nil {
+		return nil, fmt.Errorf("fan: %w", err)
+	}
+
+	f := &gpioFan{line: line, period: time.Second / time.Duration(freq), done: make(chan struct{})}
+	f.wg.Add(1)
+	go f.run()
+	return f, nil
+}
+
+func (f *gpioFan) run() {
+	defer f.wg.Done()
+	for {
+		duty := time.Duration(f.duty.Load())
+		on := f.period * duty / 100
+		switch {
+		case duty <= 0:
+			f.line.SetValue(0)
+		case duty >= 100:
+			f.line.SetValue(1)
+		default:
+			f.line.SetValue(1)
+			select {
+			case <-f.done:
+				return
+			case <-time.After(on):
+			}
+			f.line.SetValue(0)
+		}
+
+		select {
+		case <-f.done:
+			return
+		case <-time.After(f.period - on):
+		}
+	}
+}
+
+func (f *gpioFan) SetDuty(percent int) error {
+	f.duty.Store(int32(percent))
+	return nil
+}
+
+// Close leaves the fan running at full speed
+func (f *gpioFan) Close() error {
+	close(f.done)
+	f.wg.Wait()
+	f.line.SetValue(1)
+	return f.line.Close()
+}
+
+// pwmFan uses a hardware PWM channel, which needs dtoverlay=pwm
+type pwmFan struct {
+	dir    string
+	period int
+}
+
+func newPWMFan(chip, channel, freq int) (*pwmFan, error) {
+	if freq == 0 {
+		freq = 25000
+	}
+	chipDir := fmt.Sprintf("/sys/class/pwm/pwmchip%d", chip)
+	dir := filepath.Join(chipDir, fmt.Sprintf("pwm%d", channel))
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := writeSysfs(filepath.Join(chipDir, "export"), strconv.Itoa(channel)); err != nil {
+			return nil, fmt.Errorf("export PWM channel (dtoverlay=pwm?): %w", err)
+		}
+		// udev fixes up the permissions of the new directory shortly after
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	f := &pwmFan{dir: dir, period: int(time.Second) / freq}
+	writeSysfs(filepath.Join(dir, "duty_cycle"), "0")
+	if err := writeSysfs(filepath.Join(dir, "period"), strconv.Itoa(f.period)); err != nil {
+		return nil, err
+	}
+	if err := writeSysfs(filepath.Join(dir, "enable"), "1"); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *pwmFan) SetDuty(percent int) error {
+	return writeSysfs(filepath.Join(f.dir, "duty_cycle"), strconv.Itoa(f.period*percent/100))
+}
+
+// Close leaves the fan running at full speed
+func (f *pwmFan) Close() error {
+	return f.SetDuty(100)
+}
+
+// hwmonFan writes 0-255 to a pwmN attribute in manual mode
+type hwmonFan struct {
+	path       string
+	prevEnable string
+}
+
+func newHwmonFan(path string) (*hwmonFan, error) {
+	f := &hwmonFan{path: path}
+	if data, err := os.ReadFile(path + "_enable"); err == nil {
+		f.prevEnable = strings.TrimSpace(string(data))
+		if err := writeSysfs(path+"_enable", "1"); err != nil {
+			return nil, fmt.Errorf("switch %s to manual: %w", path, err)
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *hwmonFan) SetDuty(percent int) error {
+	return writeSysfs(f.path, strconv.Itoa(percent*255/100))
+}
+
+// Close hands the fan back to the driver's own control
+func (f *hwmonFan) Close() error {
+	if f.prevEnable == "" {
+		return f.SetDuty(100)
+	}
+	return writeSysfs(f.path+"_enable", f.prevEnable)
+}
+
+func writeSysfs(path, value string) error {
+	return os.WriteFile(path, []byte(value), 0644)
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// firewallRefresh is how often the rules are read again; nft and
+	// iptables walk the whole ruleset
+	firewallRefresh = 10 * time.Second
+	// firewallTimeout bounds one run of nft, iptables or fail2ban-client
+	firewallTimeout = 5 * time.Second
+)
+
+// FirewallChain is a base chain, i.e. one the kernel hands packets to
+type FirewallChain struct {
+	Table   string // e.g. "inet filter" or "filter"
+	Name    string
+	Policy  string // accept or drop, lower case
+	Rules   int
+	Dropped uint64 // packets dropped or rejected by counted rules and the policy
+}
+
+// Fail2banJail is the state of one fail2ban jail
+type Fail2banJail struct {
+	Name        string
+	Failed      int // failures within the find time
+	Banned      int // addresses banned now
+	TotalBanned int
+}
+
+// FirewallStatus is what the firewall view shows
+type FirewallStatus struct {
+	Backend  string // nftables or iptables, empty when neither could be read
+	Err      string // why the rules could not be read
+	Chains   []FirewallChain
+	Rules    int // all rules, including those of regular chains
+	Jails    []Fail2banJail
+	JailsErr string // why fail2ban could not be asked, empty when not installed
+}
+
+// dropped is the number of packets dropped by all chains
+func (s FirewallStatus) dropped() uint64 {
+	var total uint64
+	for _, c := range s.Chains {
+		total += c.Dropped
+	}
+	return total
+}
+
+// getFirewallStatus reads nftables, falling back to iptables, and the
+// fail2ban jails
+func getFirewallStatus() FirewallStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), firewallTimeout)
+	defer cancel()
+
+	status, err := readNftables(ctx)
+	if err != nil || len(status.Chains) == 0 {
+		// iptables-legacy rules are invisible to nft
+		if ipt, iptErr := readIptables(ctx); iptErr == nil {
+			status = ipt
+		} else if err != nil {
+			status.Err = err.Error()
+		}
+	}
+	status.Jails, status.JailsErr = readFail2ban(ctx)
+	return status
+}
+
+// firewallToolError explains why a firewall tool failed; reading the
+// rules needs CAP_NET_ADMIN
+func firewallToolError(tool string, out []byte, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		output := append(out, exitErr.Stderr...)
+		msg := strings.ToLower(string(output))
+		if strings.Contains(msg, "permission denied") || strings.Contains(msg, "not permitted") {
+			return fmt.Errorf("%s needs root", tool)
+		}
+		return fmt.Errorf("%s: %s", tool, commandError(output, err))
+	}
+	return toolError(tool, err)
+}
+
+// nftRuleset is the part of "nft -j list ruleset" we use. Each entry
+// holds exactly one of its fields.
+type nftRuleset struct {
+	Nftables []struct {
+		Chain *struct {
+			Family string `json:"family"`
+			Table  string `json:"table"`
+			Name   string `json:"name"`
+			Hook   string `json:"hook"`
+			Policy string `json:"policy"`
+		} `json:"chain"`
+		Rule *struct {
+			Family string                       `json:"family"`
+			Table  string                       `json:"table"`
+			Chain  string                       `json:"chain"`
+			Expr   []map[string]json.RawMessage `json:"expr"`
+		} `json:"rule"`
+	} `json:"nftables"`
+}
+
+func readNftables(ctx context.Context) (FirewallStatus, error) {
+	status := FirewallStatus{Backend: "nftables"}
+	cmd := exec.CommandContext(ctx, "nft", "-j", "list", "ruleset")
+	out, err := cmd.Output()
+	if err != nil {
+		return status, firewallToolError("nft", out, err)
+	}
+	var ruleset nftRuleset
+	if err := json.Unmarshal(out, &ruleset); err != nil {
+		return status, fmt.Errorf("nft: bad JSON output")
+	}
+
+	index := make(map[string]int)
+	for _, item := range ruleset.Nftables {
+		if c := item.Chain; c != nil && c.Hook != "" {
+			index[c.Family+" "+c.Table+" "+c.Name] = len(status.Chains)
+			policy := c.Policy
+			if policy == "" {
+				policy = "accept"
+			}
+			status.Chains = append(status.Chains, FirewallChain{
+				Table: c.Family + " " + c.Table, Name: c.Name, Policy: policy,
+			})
+		}
+	}
+	for _, item := range ruleset.Nftables {
+		r := item.Rule
+		if r == nil {
+			continue
+		}
+		status.Rules++
+		i, ok := index[r.Family+" "+r.Table+" "+r.Chain]
+		if !ok {
+			continue
+		}
+		status.Chains[i].Rules++
+
+		// Only rules with a counter statement say how often they matched
+		var packets uint64
+		drops := false
+		for _, expr := range r.Expr {
+			if raw, ok := expr["counter"]; ok {
+				var counter struct {
+					Packets uint64 `json:"packets"`
+				}
+				if json.Unmarshal(raw, &counter) == nil {
+					packets = counter.Packets
+				}
+			}
+			if _, ok := expr["drop"]; ok {
+				drops = true
+			}
+			if _, ok := expr["reject"]; ok {
+				drops = true
+			}
+		}
+		if drops {
+			status.Chains[i].Dropped += packets
+		}
+	}
+	return status, nil
+}
+
+// readIptables parses "iptables -L -n -v -x". Built-in chains carry
+// "(policy DROP 12 packets, 720 bytes)" in their header.
+func readIptables(ctx context.Context) (FirewallStatus, error) {
+	status := FirewallStatus{Backend: "iptables"}
+	out, err := exec.CommandContext(ctx, "iptables", "-L", "-n", "-v", "-x").Output()
+	if err != nil {
+		return status, firewallToolError("iptables", out, err)
+	}
+
+	current := -1 // index of the built-in chain being read
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 0:
+			continue
+		case fields[0] == "Chain":
+			current = -1
+			if len(fields) >= 5 && fields[2] == "(policy" {
+				c := FirewallChain{Table: "filter", Name: fields[1], Policy: strings.ToLower(fields[3])}
+				if c.Policy == "drop" {
+					c.Dropped, _ = strconv.ParseUint(fields[4], 10, 64)
+				}
+				current = len(status.Chains)
+				status.Chains = append(status.Chains, c)
+			}
+		case fields[0] == "pkts":
+			continue // column header
+		default:
+			status.Rules++
+			if current < 0 || len(fields) < 3 {
+				continue
+			}
+			status.Chains[current].Rules++
+			if fields[2] == "DROP" || fields[2] == "REJECT" {
+				packets, _ := strconv.ParseUint(fields[0], 10, 64)
+				status.Chains[current].Dropped += packets
+			}
+		}
+	}
+	return status, nil
+}
+
+// readFail2ban asks fail2ban-client for its jails. Nothing is returned
+// when fail2ban is not installed.
+func readFail2ban(ctx context.Context) ([]Fail2banJail, string) {
+	out, err := exec.CommandContext(ctx, "fail2ban-client", "status").CombinedOutput()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, ""
+	}
+	if err != nil {
+		return nil, firewallToolError("fail2ban", out, err).Error()
+	}
+
+	jails := []Fail2banJail{} // not nil, fail2ban runs
+	for _, name := range fail2banValue(string(out), "Jail list") {
+		jail := Fail2banJail{Name: name}
+		detail, err := exec.CommandContext(ctx, "fail2ban-client", "status", name).Output()
+		if err == nil {
+			jail.Failed = fail2banCount(string(detail), "Currently failed")
+			jail.Banned = fail2banCount(string(detail), "Currently banned")
+			jail.TotalBanned = fail2banCount(string(detail), "Total banned")
+		}
+		jails = append(jails, jail)
+	}
+	return jails, ""
+}
+
+// fail2banValue returns the comma separated list after "key:" in the
+// tree-like output of fail2ban-client
+func fail2banValue(out, key string) []string {
+	for _, line := range strings.Split(out, "\n") {
+		_, value, ok := strings.Cut(line, key+":")
+		if !ok {
+			continue
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	return nil
+}
+
+func fail2banCount(out, key string) int {
+	values := fail2banValue(out, key)
+	if len(values) == 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(values[0])
+	return n
+}
+
+// refreshFirewall re-reads the firewall once per firewallRefresh and
+// remembers the previous drop count for the recent drops
+func (d *Dashboard) refreshFirewall() {
+	if !d.firewallAt.IsZero() && time.Since(d.firewallAt) < firewallRefresh {
+		return
+	}
+	status := getFirewallStatus()
+	now := time.Now()
+	d.firewallRecent = 0
+	if !d.firewallAt.IsZero() && status.dropped() >= d.firewall.dropped() {
+		d.firewallRecent = status.dropped() - d.firewall.dropped()
+	}
+	d.firewallSince = now.Sub(d.firewallAt)
+	d.firewall, d.firewallAt = status, now
+}
+
+func (d *Dashboard) updateFirewallView(stats SystemStats) {
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+	if !d.reuseLists {
+		d.refreshFirewall()
+	}
+	s := d.firewall
+
+	var rows []string
+	switch {
+	case s.Err != "":
+		rows = append(rows, fmt.Sprintf("Firewall: [%s](fg:red)", truncateString(s.Err, 18)))
+	case len(s.Chains) == 0:
+		rows = append(rows, fmt.Sprintf("Firewall: [none](fg:yellow) (%s)", s.Backend))
+	default:
+		rows = append(rows,
+			fmt.Sprintf("Backend: %s", s.Backend),
+			fmt.Sprintf("Rules: %d", s.Rules),
+		)
+		recent := fmt.Sprintf("%d", d.firewallRecent)
+		if d.firewallRecent > 0 {
+			recent = fmt.Sprintf("[%d](fg:red)", d.firewallRecent)
+		}
+		rows = append(rows, fmt.Sprintf("Dropped: %d", s.dropped()))
+		if d.firewallSince > 0 && d.firewallSince < 2*firewallRefresh {
+			rows = append(rows, fmt.Sprintf("Recent: %s in %s", recent, formatAge(d.firewallSince)))
+		}
+
+		rows = append(rows, "", "[--Chains--](fg:cyan)", "[Chain     Policy Rules  Drop](fg:cyan)")
+		for _, c := range s.Chains {
+			line := fmt.Sprintf("%-9s %-6s %5d %5d", truncateString(c.Name, 9), c.Policy, c.Rules, c.Dropped)
+			// An input chain that accepts everything leaves every
+			// listening port open
+			if strings.EqualFold(c.Name, "input") && c.Policy == "accept" && c.Rules == 0 {
+				line = fmt.Sprintf("[%s](fg:yellow)", line)
+			}
+			rows = append(rows, line)
+		}
+	}
+
+	switch {
+	case s.JailsErr != "":
+		rows = append(rows, "", "[--fail2ban--](fg:magenta)", fmt.Sprintf("[%s](fg:red)", truncateString(s.JailsErr, 27)))
+	case s.Jails != nil:
+		rows = append(rows, "", "[--fail2ban--](fg:magenta)", "[Jail        Failed Ban Total](fg:cyan)")
+		for _, j := range s.Jails {
+			line := fmt.Sprintf("%-11s %6d %3d %5d", truncateString(j.Name, 11), j.Failed, j.Banned, j.TotalBanned)
+			if j.Banned > 0 {
+				line = fmt.Sprintf("[%s](fg:red)", line)
+			}
+			rows = append(rows, line)
+		}
+	}
+	d.mainList.Rows = rows
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// fbPanel writes frames to a Linux framebuffer device, e.g. the fb_st7789v
+// or fbtft driver of an SPI LCD HAT or the HDMI console on /dev/fb0
+type fbPanel struct {
+	f      *os.File
+	width  int
+	height int
+	stride int // bytes per line
+	bpp    int // 16 (RGB565) or 32 (XRGB8888)
+	buf    []byte
+	sys    string // sysfs directory of the device
+}
+
+// openFramebuffer opens device and reads its geometry from sysfs
+func openFramebuffer(device string) (*fbPanel, error) {
+	if device == "" {
+		device = "/dev/fb0"
+	}
+	if _, err := os.Stat(device); err != nil {
+		return nil, fmt.Errorf("%s not found (available: %s)", device, fbDevices())
+	}
+	sys := filepath.Join("/sys/class/graphics", filepath.Base(device))
+
+	// modes holds the visible size, e.g. "U:240x240p-0"; the virtual size
+	// is larger when the driver double buffers
+	var width, height int
+	_, mode, _ := strings.Cut(strings.SplitN(readSysfs(filepath.Join(sys, "modes")), "\n", 2)[0], ":")
+	if _, err := fmt.Sscanf(mode, "%dx%d", &width, &height); err != nil {
+		if _, err := fmt.Sscanf(readSysfs(filepath.Join(sys, "virtual_size")), "%d,%d", &width, &height); err != nil {
+			return nil, fmt.Errorf("%s: unknown framebuffer size", device)
+		}
+	}
+	bpp, _ := strconv.Atoi(readSysfs(filepath.Join(sys, "bits_per_pixel")))
+	if bpp != 16 && bpp != 32 {
+		return nil, fmt.Errorf("%s: %d bits per pixel is not supported", device, bpp)
+	}
+	stride, _ := strconv.Atoi(readSysfs(filepath.Join(sys, "stride")))
+	if stride == 0 {
+		stride = width * bpp / 8
+	}
+
+	f, err := os.OpenFile(device, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &fbPanel{
+		f:      f,
+		width:  width,
+		height: height,
+		stride: stride,
+		bpp:    bpp,
+		buf:    make([]byte, stride*height),
+		sys:    sys,
+	}, nil
+}
+
+// SetBacklight blanks the framebuffer, which switches the backlight off
+// on drivers such as fbtft
+func (p *fbPanel) SetBacklight(on bool) error {
+	value := "1" // FB_BLANK_NORMAL
+	if on {
+		value = "0"
+	}
+	return os.WriteFile(filepath.Join(p.sys, "blank"), []byte(value), 0644)
+}
+
+func (p *fbPanel) Bounds() image.Rectangle {
+	return image.Rect(0, 0, p.width, p.height)
+}
+
+// Draw converts img to the pixel format of the framebuffer and writes it
+func (p *fbPanel) Draw(img *image.RGBA) error {
+	for y := 0; y < p.height; y++ {
+		row := p.buf[y*p.stride:]
+		for x := 0; x < p.width; x++ {
+			c := img.RGBAAt(x, y)
+			if p.bpp == 16 {
+				v := rgb565(c.R, c.G, c.B)
+				row[x*2] = byte(v)
+				row[x*2+1] = byte(v >> 8)
+			} else {
+				row[x*4] = c.B
+				row[x*4+1] = c.G
+				row[x*4+2] = c.R
+				row[x*4+3] = 0xff
+			}
+		}
+	}
+	_, err := p.f.WriteAt(p.buf, 0)
+	return err
+}
+
+func (p *fbPanel) Close() error {
+	return p.f.Close()
+}
+
+// rgb565 packs a color into the 16-bit format of most small panels
+func rgb565(r, g, b uint8) uint16 {
+	return uint16(r&0xf8)<<8 | uint16(g&0xfc)<<3 | uint16(b>>3)
+}
+
+// fbDevices lists the framebuffer devices, for the error message when
+// the configured one is missing
+func fbDevices() string {
+	matches, _ := filepath.Glob("/dev/fb*")
+	if len(matches) == 0 {
+		return "none"
+	}
+	return strings.Join(matches, ", ")
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+)
+
+// gpioChip is the character device that exposes the 40-pin header lines
+// and gpioOffset is added to BCM numbers to get its line offsets. Both
+// are set once at startup by selectGPIOChip.
+var (
+	gpioChip   = "gpiochip0"
+	gpioOffset = 0
+)
+
+// headerChipLabels identify the chip driving the header: RP1 on the Pi 5,
+// the SoC pin controller on earlier models
+var headerChipLabels = []string{"pinctrl-rp1", "pinctrl-bcm2711", "pinctrl-bcm2835"}
+
+// gpioConsumer is the label shown by gpioinfo for lines we hold
+const gpioConsumer = "raspi-monitor"
+
+// buttonPollInterval is how often the watcher settles bouncing lines
+// and checks held buttons for long press and repeat
+const buttonPollInterval = 10 * time.Millisecond
+
+// PressKind tells apart the ways a button can be pressed
+type PressKind int
+
+const (
+	PressShort  PressKind = iota // released before the long press delay
+	PressLong                    // held for the long press delay
+	PressRepeat                  // still held after a long press
+)
+
+func (k PressKind) String() string {
+	switch k {
+	case PressShort:
+		return "short"
+	case PressLong:
+		return "long"
+	case PressRepeat:
+		return "repeat"
+	}
+	return "unknown"
+}
+
+// ButtonEvent is a debounced button press delivered to the event loop
+type ButtonEvent struct {
+	Button string
+	Kind   PressKind
+}
+
+// pendingEdge is a line level that has not been stable long enough yet
+type pendingEdge struct {
+	value int
+	at    time.Time
+}
+
+// heldButton tracks a pressed button for long press and repeat
+type heldButton struct {
+	since    time.Time
+	lastFire time.Time
+	long     bool
+}
+
+// selectGPIOChip sets the chip and offset used for every GPIO line. With
+// chip set to auto the chip is found by its label, since the header is
+// gpiochip4 on a Pi 5 with older kernels and gpiochip0 everywhere else.
+func selectGPIOChip(cfg GPIOConfig) {
+	gpioOffset = cfg.Offset
+	if cfg.Chip != "" && cfg.Chip != "auto" {
+		gpioChip = cfg.Chip
+		return
+	}
+	if name, label, ok := detectHeaderChip(); ok {
+		gpioChip = name
+		log.Printf("GPIO header found on %s (%s)", name, label)
+		return
+	}
+	log.Printf("No known GPIO header chip found, using %s", gpioChip)
+}
+
+// detectHeaderChip returns the first chip with one of headerChipLabels
+func detectHeaderChip() (name, label string, ok bool) {
+	for _, name := range gpiocdev.Chips() {
+		chip, err := gpiocdev.NewChip(name)
+		if err != nil {
+			continue
+		}
+		label := chip.Label
+		chip.Close()
+		for _, l := range headerChipLabels {
+			if label == l {
+				return name, label, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// InitGPIO requests the button lines from the GPIO character device.
+// The lines are pulled-up inputs with edge detection, so the kernel
+// reports presses as events instead of us polling every pin.
+func (d *Dashboard) InitGPIO() error {
+	log.Printf("Initializing GPIO pins via %s...", gpioChip)
+
+	edges := make(chan gpiocdev.LineEvent, 64)
+	d.buttonEdges = edges
+
+	offsets := make([]int, 0, len(d.config.GPIO.Pins))
+	d.buttonNames = make(map[int]string)
+	for name, pin := range d.config.GPIO.Pins {
+		offsets = append(offsets, pin+gpioOffset)
+		d.buttonNames[pin+gpioOffset] = name
+	}
+	sort.Ints(offsets)
+
+	lines, err := gpiocdev.RequestLines(gpioChip, offsets,
+		gpiocdev.WithConsumer(gpioConsumer),
+		gpiocdev.AsInput,
+		gpiocdev.WithPullUp,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventHandler(d.handleButtonEvent))
+	if err != nil {
+		return fmt.Errorf("request lines on %s: %w", gpioChip, err)
+	}
+
+	// Seed the button states from the current line levels
+	values := make([]int, len(offsets))
+	if err := lines.Values(values); err != nil {
+		lines.Close()
+		return fmt.Errorf("read initial line values: %w", err)
+	}
+
+	d.buttonMu.Lock()
+	for i, pin := range offsets {
+		d.lastButtonState[pin] = values[i]
+	}
+	d.buttonMu.Unlock()
+
+	d.buttonLines = lines
+	d.buttonEvents = make(chan ButtonEvent, 16)
+	d.gpioDone = make(chan struct{})
+	go d.watchButtons(edges, d.buttonEvents, d.gpioDone)
+
+	d.gpioEnabled = true
+	log.Println("GPIO ready - press buttons to test")
+	return nil
+}
+
+// handleButtonEvent is called from the gpiocdev event goroutine for
+// every edge on a button line. It only hands the edge to watchButtons.
+func (d *Dashboard) handleButtonEvent(evt gpiocdev.LineEvent) {
+	select {
+	case d.buttonEdges <- evt:
+	default:
+		log.Printf("Button edge queue full, dropping GPIO%d event", evt.Offset)
+	}
+}
+
+// watchButtons turns raw line edges into debounced button events. A line
+// level is only accepted once it has been stable for the debounce period.
+// Buttons are active low.
+func (d *Dashboard) watchButtons(edges <-chan gpiocdev.LineEvent, out chan<- ButtonEvent, done <-chan struct{}) {
+	cfg := d.config.GPIO
+	pending := make(map[int]pendingEdge)
+	held := make(map[int]*heldButton)
+
+	ticker := time.NewTicker(buttonPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case evt := <-edges:
+			value := 1 // HIGH = not pressed
+			if evt.Type == gpiocdev.LineEventFallingEdge {
+				value = 0 // LOW = pressed
+			}
+			pending[evt.Offset] = pendingEdge{value: value, at: time.Now()}
+		case now := <-ticker.C:
+			for pin, p := range pending {
+				if now.Sub(p.at) < cfg.Debounce {
+					continue
+				}
+				delete(pending, pin)
+
+				d.buttonMu.Lock()
+				changed := d.lastButtonState[pin] != p.value
+				d.lastButtonState[pin] = p.value
+				d.buttonMu.Unlock()
+				if !changed {
+					continue
+				}
+
+				if p.value == 0 {
+					held[pin] = &heldButton{since: now}
+					continue
+				}
+				if h := held[pin]; h != nil && !h.long {
+					d.emitButton(out, ButtonEvent{Button: d.buttonNames[pin], Kind: PressShort})
+				}
+				delete(held, pin)
+			}
+
+			for pin, h := range held {
+				switch {
+				case !h.long && now.Sub(h.since) >= cfg.LongPress:
+					h.long = true
+					h.lastFire = now
+					d.emitButton(out, ButtonEvent{Button: d.buttonNames[pin], Kind: PressLong})
+				case h.long && cfg.Repeat > 0 && now.Sub(h.lastFire) >= cfg.Repeat:
+					h.lastFire = now
+					d.emitButton(out, ButtonEvent{Button: d.buttonNames[pin], Kind: PressRepeat})
+				}
+			}
+		}
+	}
+}
+
+func (d *Dashboard) emitButton(out chan<- ButtonEvent, evt ButtonEvent) {
+	select {
+	case out <- evt:
+	default:
+		log.Printf("Button event queue full, dropping %s %s press", evt.Button, evt.Kind)
+	}
+}
+
+// CloseGPIO releases the requested button lines
+func (d *Dashboard) CloseGPIO() {
+	if d.buttonLines == nil {
+		return
+	}
+	close(d.gpioDone)
+	if err := d.buttonLines.Close(); err != nil {
+		log.Printf("Failed to release GPIO lines: %v", err)
+	}
+	d.buttonLines = nil
+	d.gpioEnabled = false
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+)
+
+// Output patterns for alert LEDs and buzzers
+const (
+	outputOn    = "on"    // steady while the alert fires
+	outputBlink = "blink" // toggles every interval while the alert fires
+	outputOnce  = "once"  // a single pulse of interval when the alert fires
+)
+
+// OutputConfig is a status LED or buzzer driven by the alerts
+type OutputConfig struct {
+	Name      string        `yaml:"name"` // action name used in the rules
+	Pin       int           `yaml:"pin"`
+	Mode      string        `yaml:"mode"`
+	Interval  time.Duration `yaml:"interval"`
+	ActiveLow bool          `yaml:"active_low"`
+}
+
+func (c OutputConfig) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("alerts.outputs: name is required")
+	}
+	switch c.Mode {
+	case "", outputOn, outputBlink, outputOnce:
+	default:
+		return fmt.Errorf("output %q: unknown mode %q (on, blink, once)", c.Name, c.Mode)
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("output %q: interval must not be negative", c.Name)
+	}
+	return nil
+}
+
+// requestOutputLine claims BCM pin as an output driven to value, on the
+// same chip and with the same consumer label as the button lines. The
+// line takes value as it is claimed, so an active-low relay does not
+// click on before it is set.
+func requestOutputLine(pin, value int) (*gpiocdev.Line, error) {
+	line, err := gpiocdev.RequestLine(gpioChip, pin+gpioOffset,
+		gpiocdev.WithConsumer(gpioConsumer),
+		gpiocdev.AsOutput(value))
+	if err != nil {
+		return nil, fmt.Errorf("request GPIO%d on %s: %w", pin, gpioChip, err)
+	}
+	return line, nil
+}
+
+// outputLevel is the physical level that switches an output on or off
+func outputLevel(on, activeLow bool) int {
+	if on != activeLow {
+		return 1
+	}
+	return 0
+}
+
+// gpioOutput is an AlertAction that drives a pin while alerts using it
+// are firing. Several alerts can share one output.
+type gpioOutput struct {
+	cfg  OutputConfig
+	line *gpiocdev.Line
+
+	mu     sync.Mutex
+	active map[string]bool // names of the firing alerts
+	stop   chan struct{}   // stops the blink goroutine, nil when idle
+	wg     sync.WaitGroup  // running blink goroutine
+}
+
+func newGPIOOutput(cfg OutputConfig) (*gpioOutput, error) {
+	if cfg.Mode == "" {
+		cfg.Mode = outputBlink
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 500 * time.Millisecond
+	}
+	line, err := requestOutputLine(cfg.Pin, outputLevel(false, cfg.ActiveLow))
+	if err != nil {
+		return nil, err
+	}
+	return &gpioOutput{cfg: cfg, line: line, active: make(map[string]bool)}, nil
+}
+
+// set switches the output, honouring active_low
+func (o *gpioOutput) set(on bool) {
+	if err := o.line.SetValue(outputLevel(on, o.cfg.ActiveLow)); err != nil {
+		log.Printf("Output %s: %v", o.cfg.Name, err)
+	}
+}
+
+func (o *gpioOutput) Fire(evt AlertEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	first := len(o.active) == 0
+	o.active[evt.Rule.Name] = true
+
+	switch o.cfg.Mode {
+	case outputOnce:
+		o.set(true)
+		time.AfterFunc(o.cfg.Interval, func() { o.set(false) })
+	case outputOn:
+		o.set(true)
+	case outputBlink:
+		if first {
+			o.stop = make(chan struct{})
+			o.wg.Add(1)
+			go o.blink(o.stop)
+		}
+	}
+}
+
+func (o *gpioOutput) Resolve(evt AlertEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.active, evt.Rule.Name)
+	if len(o.active) > 0 {
+		return
+	}
+	switch {
+	case o.stop != nil:
+		close(o.stop) // the blink goroutine switches the output off
+		o.stop = nil
+	case o.cfg.Mode == outputOn:
+		o.set(false)
+	}
+}
+
+func (o *gpioOutput) blink(stop <-chan struct{}) {
+	defer o.wg.Done()
+	ticker := time.NewTicker(o.cfg.Interval)
+	defer ticker.Stop()
+
+	on := true
+	o.set(on)
+	for {
+		select {
+		case <-stop:
+			o.set(false)
+			return
+		case <-ticker.C:
+			on = !on
+			o.set(on)
+		}
+	}
+}
+
+// Close switches the output off and releases the line
+func (o *gpioOutput) Close() error {
+	o.mu.Lock()
+	if o.stop != nil {
+		close(o.stop)
+		o.stop = nil
+	}
+	o.mu.Unlock()
+	o.wg.Wait()
+	o.set(false)
+	return o.line.Close()
+}
+
+// initAlertOutputs registers the configured LEDs and buzzers with the
+// alert engine. Outputs that cannot be claimed are skipped with a warning.
+func (d *Dashboard) initAlertOutputs() []*gpioOutput {
+	var outputs []*gpioOutput
+	for _, cfg := range d.config.Alerts.Outputs {
+		out, err := newGPIOOutput(cfg)
+		if err != nil {
+			log.Printf("Warning: alert output %s disabled: %v", cfg.Name, err)
+			continue
+		}
+		d.alerts.AddAction(cfg.Name, out)
+		outputs = append(outputs, out)
+	}
+	return outputs
+}
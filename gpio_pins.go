@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/warthog618/go-gpiocdev"
+)
+
+// Header pins that are not GPIOs
+const (
+	pin3V3    = -1
+	pin5V     = -2
+	pinGround = -3
+)
+
+// headerLayout maps the physical pins 1-40 to BCM numbers, or to one
+// of the power constants
+var headerLayout = [40]int{
+	pin3V3, pin5V, 2, pin5V, 3, pinGround, 4, 14, pinGround, 15,
+	17, 18, 27, pinGround, 22, 23, pin3V3, 24, 10, pinGround,
+	9, 25, 11, 8, pinGround, 7, 0, 1, 5, pinGround,
+	6, 12, 13, pinGround, 19, 16, 26, 20, pinGround, 21,
+}
+
+// HeaderPin is the state of one physical header pin
+type HeaderPin struct {
+	Physical int // 1-40
+	BCM      int // BCM number, or one of the power constants
+	Info     gpiocdev.LineInfo
+	Value    int // -1 when the line is held by another program
+}
+
+// readHeaderPins reads the line info of every header GPIO. Free lines
+// are requested as they are for a moment to read their level; lines held
+// by other programs keep an unknown level, except our own buttons.
+func (d *Dashboard) readHeaderPins() ([]HeaderPin, error) {
+	chip, err := gpiocdev.NewChip(gpioChip, gpiocdev.WithConsumer(gpioConsumer))
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", gpioChip, err)
+	}
+	defer chip.Close()
+
+	pins := make([]HeaderPin, len(headerLayout))
+	for i, bcm := range headerLayout {
+		pins[i] = HeaderPin{Physical: i + 1, BCM: bcm, Value: -1}
+		if bcm < 0 {
+			continue
+		}
+		offset := bcm + gpioOffset
+		info, err := chip.LineInfo(offset)
+		if err != nil {
+			continue
+		}
+		pins[i].Info = info
+		if info.Used {
+			d.buttonMu.Lock()
+			if v, ok := d.lastButtonState[offset]; ok && info.Consumer == gpioConsumer {
+				pins[i].Value = v
+			}
+			d.buttonMu.Unlock()
+			continue
+		}
+		if line, err := chip.RequestLine(offset, gpiocdev.AsIs); err == nil {
+			if v, err := line.Value(); err == nil {
+				pins[i].Value = v
+			}
+			line.Close()
+		}
+	}
+	return pins, nil
+}
+
+// pinPowerLabel names a power pin and gives its colour
+func pinPowerLabel(bcm int) (label, color string) {
+	switch bcm {
+	case pin3V3:
+		return "3V3", "yellow"
+	case pin5V:
+		return "5V", "red"
+	}
+	return "GND", "white"
+}
+
+func pinDirection(info gpiocdev.LineInfo) string {
+	switch info.Config.Direction {
+	case gpiocdev.LineDirectionInput:
+		return "in"
+	case gpiocdev.LineDirectionOutput:
+		return "out"
+	}
+	return "?"
+}
+
+func pinBias(info gpiocdev.LineInfo) string {
+	switch info.Config.Bias {
+	case gpiocdev.LineBiasPullUp:
+		return "up"
+	case gpiocdev.LineBiasPullDown:
+		return "down"
+	case gpiocdev.LineBiasDisabled:
+		return "off"
+	}
+	return ""
+}
+
+// pinRow is the list row of a pin without colours, and the colour of
+// the row
+func pinRow(p HeaderPin) (line, color string) {
+	if p.BCM < 0 {
+		label, color := pinPowerLabel(p.BCM)
+		return fmt.Sprintf("%2d  %s", p.Physical, label), color
+	}
+	value, color := "?", "white"
+	switch p.Value {
+	case 0:
+		value, color = "0", "cyan"
+	case 1:
+		value, color = "1", "green"
+	}
+	return fmt.Sprintf("%2d  %-6s %-3s %s %-4s %s", p.Physical, fmt.Sprintf("GPIO%d", p.BCM),
+		pinDirection(p.Info), value, pinBias(p.Info), truncateString(p.Info.Consumer, 6)), color
+}
+
+func (d *Dashboard) updateGPIOPinsView(stats SystemStats) {
+	if !d.reuseLists {
+		pins, err := d.readHeaderPins()
+		if err != nil {
+			d.mainList.Title = d.viewTitle("")
+			d.mainList.Rows = []string{"", "GPIO not available", "", truncateString(err.Error(), 28)}
+			return
+		}
+		d.pinList = pins
+	}
+
+	total := len(d.pinList)
+	if d.selectedPin >= total {
+		d.selectedPin = total - 1
+	}
+	d.mainList.Title = d.viewTitle(fmt.Sprintf("%d/%d", d.selectedPin+1, total))
+
+	rows := []string{"[Pin GPIO   Dir V Pull User](fg:cyan)"}
+
+	// Leave room for the detail rows of the selected pin
+	visibleHeight := 17
+	startIdx := d.selectedPin - visibleHeight/2
+	if startIdx > total-visibleHeight {
+		startIdx = total - visibleHeight
+	}
+	if startIdx < 0 {
+		startIdx = 0
+	}
+	endIdx := startIdx + visibleHeight
+	if endIdx > total {
+		endIdx = total
+	}
+
+	for i := startIdx; i < endIdx; i++ {
+		line, color := pinRow(d.pinList[i])
+		if i == d.selectedPin {
+			rows = append(rows, fmt.Sprintf("[%s](bg:white,fg:black)", line))
+		} else {
+			rows = append(rows, fmt.Sprintf("[%s](fg:%s)", line, color))
+		}
+	}
+
+	p := d.pinList[d.selectedPin]
+	rows = append(rows, "")
+	if p.BCM < 0 {
+		label, color := pinPowerLabel(p.BCM)
+		rows = append(rows, fmt.Sprintf("Pin %d: [%s](fg:%s)", p.Physical, label, color))
+		d.mainList.Rows = rows
+		return
+	}
+	consumer := p.Info.Consumer
+	if !p.Info.Used {
+		consumer = "free"
+	}
+	flags := []string{}
+	if p.Info.Config.ActiveLow {
+		flags = append(flags, "active-low")
+	}
+	switch p.Info.Config.Drive {
+	case gpiocdev.LineDriveOpenDrain:
+		flags = append(flags, "open-drain")
+	case gpiocdev.LineDriveOpenSource:
+		flags = append(flags, "open-source")
+	}
+	if p.Info.Config.EdgeDetection != gpiocdev.LineEdgeNone {
+		flags = append(flags, "edges")
+	}
+	rows = append(rows,
+		fmt.Sprintf("Pin %d  GPIO%d  %s", p.Physical, p.BCM, truncateString(p.Info.Name, 12)),
+		"User:  "+truncateString(consumer, 21),
+		"Flags: "+truncateString(strings.Join(flags, " "), 21),
+	)
+	d.mainList.Rows = rows
+}
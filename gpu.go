@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// GPUStats holds the VideoCore figures reported by vcgencmd
+type GPUStats struct {
+	Available   bool
+	Temperature float64
+	CoreClock   uint64 // Hz
+	V3DClock    uint64 // Hz
+	H264Clock   uint64 // Hz, 0 while the block is powered down
+	ISPClock    uint64 // Hz, 0 while the block is powered down
+	GPUMem      uint64 // bytes
+	ARMMem      uint64 // bytes
+}
+
+// getMemSplit parses "gpu=76M" into bytes
+func getMemSplit(name string) uint64 {
+	out, err := collector.Vcgencmd(context.Background(), "get_mem", name)
+	if err != nil {
+		return 0
+	}
+	value := collector.VcgencmdValue(out)
+	mult := uint64(1)
+	switch {
+	case strings.HasSuffix(value, "M"):
+		mult = 1024 * 1024
+	case strings.HasSuffix(value, "G"):
+		mult = 1024 * 1024 * 1024
+	}
+	n, _ := strconv.ParseUint(strings.TrimRight(value, "MG"), 10, 64)
+	return n * mult
+}
+
+// getGPUStats queries vcgencmd. It is only called while the GPU view
+// is shown since every value costs a firmware round trip.
+func getGPUStats() GPUStats {
+	ctx := context.Background()
+	if _, err := collector.Vcgencmd(ctx, "version"); err != nil {
+		return GPUStats{}
+	}
+	return GPUStats{
+		Available:   true,
+		Temperature: collector.VcgencmdTemperature(ctx),
+		CoreClock:   collector.MeasureClock(ctx, "core"),
+		V3DClock:    collector.MeasureClock(ctx, "v3d"),
+		H264Clock:   collector.MeasureClock(ctx, "h264"),
+		ISPClock:    collector.MeasureClock(ctx, "isp"),
+		GPUMem:      getMemSplit("gpu"),
+		ARMMem:      getMemSplit("arm"),
+	}
+}
+
+// formatClock shows a frequency in MHz, or "idle" for a gated clock
+func formatClock(hz uint64) string {
+	if hz == 0 {
+		return "[idle](fg:white)"
+	}
+	return fmt.Sprintf("%d MHz", hz/1000000)
+}
+
+func (d *Dashboard) updateGPUView(stats SystemStats) {
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+
+	gpu := getGPUStats()
+	if !gpu.Available {
+		d.mainList.Rows = []string{"", "vcgencmd not available", "", "GPU stats need Raspberry Pi", "firmware tools (vcgencmd)."}
+		return
+	}
+
+	gpuPercent := 0.0
+	if total := gpu.GPUMem + gpu.ARMMem; total > 0 {
+		gpuPercent = float64(gpu.GPUMem) / float64(total) * 100
+	}
+
+	d.mainList.Rows = []string{
+		"",
+		fmt.Sprintf("[Temp:](fg:cyan) %s", formatTemperature(gpu.Temperature)),
+		getBar(gpu.Temperature, 20),
+		"",
+		"[--Clocks--](fg:yellow)",
+		fmt.Sprintf("Core: %s", formatClock(gpu.CoreClock)),
+		fmt.Sprintf("V3D:  %s", formatClock(gpu.V3DClock)),
+		fmt.Sprintf("H264: %s", formatClock(gpu.H264Clock)),
+		fmt.Sprintf("ISP:  %s", formatClock(gpu.ISPClock)),
+		"",
+		"[--Memory Split--](fg:magenta)",
+		fmt.Sprintf("GPU: %4.0f MB", bytesToMB(gpu.GPUMem)),
+		fmt.Sprintf("ARM: %4.0f MB", bytesToMB(gpu.ARMMem)),
+		getBar(gpuPercent, 20),
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Protocols of the Graphite exporter
+const (
+	graphiteProtocol = "graphite" // plaintext protocol over TCP
+	statsdProtocol   = "statsd"   // gauges over UDP
+)
+
+const (
+	graphiteDialTimeout = 5 * time.Second
+	// statsdPacketSize keeps a datagram of several metrics under the
+	// MTU of any link
+	statsdPacketSize = 512
+)
+
+// GraphiteConfig sends the stats to Carbon or a StatsD daemon every
+// interval. Metrics are named <prefix>.<host>.<metric>, e.g.
+// raspi.mypi.cpu_percent or raspi.mypi.cpu.0.usage_percent.
+type GraphiteConfig struct {
+	Address  string        `yaml:"address"`  // host:port, empty disables it
+	Protocol string        `yaml:"protocol"` // graphite or statsd
+	Prefix   string        `yaml:"prefix"`
+	Interval time.Duration `yaml:"interval"` // flush interval
+}
+
+func (c GraphiteConfig) validate() error {
+	if c.Address == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		return fmt.Errorf("graphite.address: %w", err)
+	}
+	if c.Protocol != graphiteProtocol && c.Protocol != statsdProtocol {
+		return fmt.Errorf("graphite.protocol must be %s or %s, got %q", graphiteProtocol, statsdProtocol, c.Protocol)
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("graphite.interval must be positive")
+	}
+	return nil
+}
+
+// graphiteMetric is one numeric value and its dotted path
+type graphiteMetric struct {
+	path  string
+	value string
+}
+
+// graphiteName makes s usable as one component of a dotted path
+func graphiteName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// graphiteMetrics flattens points into paths. Tag values become path
+// components; the unit only describes the value and is left out, as are
+// string fields.
+func graphiteMetrics(base string, points []statPoint) []graphiteMetric {
+	var metrics []graphiteMetric
+	for _, p := range points {
+		path := base
+		if p.name != "" {
+			path += "." + graphiteName(p.name)
+		}
+		for _, t := range p.tags {
+			if t[0] != "unit" {
+				path += "." + graphiteName(t[1])
+			}
+		}
+		for _, f := range p.fields {
+			var value string
+			switch v := f.value.(type) {
+			case float64:
+				value = strconv.FormatFloat(v, 'f', -1, 64)
+			case uint64:
+				value = strconv.FormatUint(v, 10)
+			default:
+				continue
+			}
+			metrics = append(metrics, graphiteMetric{path: path + "." + graphiteName(f.key), value: value})
+		}
+	}
+	return metrics
+}
+
+// runGraphite flushes the latest sample every graphite.interval until
+// stop is closed. Failed flushes are logged and the sample is dropped.
+func (d *Dashboard) runGraphite(stop <-chan struct{}) {
+	cfg := d.config.Graphite
+	base := graphiteName(hostname())
+	if cfg.Prefix != "" {
+		base = strings.Trim(cfg.Prefix, ".") + "." + base
+	}
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	var prev SystemStats
+	var prevAt time.Time
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		stats, at := d.snapshot.Get()
+		if at.IsZero() || !at.After(prevAt) {
+			continue
+		}
+		seconds := 0.0
+		if !prevAt.IsZero() {
+			seconds = at.Sub(prevAt).Seconds()
+		}
+		metrics := graphiteMetrics(base, statPoints(stats, prev, seconds))
+		prev, prevAt = stats, at
+
+		var err error
+		if cfg.Protocol == statsdProtocol {
+			err = sendStatsd(cfg.Address, metrics)
+		} else {
+			err = sendGraphite(cfg.Address, metrics, at)
+		}
+		if err != nil {
+			log.Printf("Graphite: %v", err)
+		}
+	}
+}
+
+// sendGraphite writes the metrics to Carbon in the plaintext protocol
+func sendGraphite(addr string, metrics []graphiteMetric, at time.Time) error {
+	var b bytes.Buffer
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "%s %s %d\n", m.path, m.value, at.Unix())
+	}
+	conn, err := net.DialTimeout("tcp", addr, graphiteDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(graphiteDialTimeout))
+	_, err = conn.Write(b.Bytes())
+	return err
+}
+
+// sendStatsd sends the metrics as gauges, as many per datagram as fit
+// in statsdPacketSize
+func sendStatsd(addr string, metrics []graphiteMetric) error {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var packet bytes.Buffer
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+	for _, m := range metrics {
+		line := statsdGauge(m)
+		if packet.Len() > 0 && packet.Len()+1+len(line) > statsdPacketSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	return flush()
+}
+
+// statsdGauge formats m as a StatsD gauge. A signed value changes the
+// gauge by that much instead of setting it, so a negative one is sent as
+// a reset to 0 followed by the change, kept together in one datagram.
+func statsdGauge(m graphiteMetric) string {
+	if strings.HasPrefix(m.value, "-") {
+		return m.path + ":0|g\n" + m.path + ":" + m.value + "|g"
+	}
+	return m.path + ":" + m.value + "|g"
+}
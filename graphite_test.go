@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsdGauge(t *testing.T) {
+	tests := []struct {
+		name string
+		m    graphiteMetric
+		want string
+	}{
+		{"positive", graphiteMetric{"pi.temp", "48.3"}, "pi.temp:48.3|g"},
+		{"zero", graphiteMetric{"pi.load", "0"}, "pi.load:0|g"},
+		{"negative is reset first", graphiteMetric{"pi.plugin.outside", "-5.5"}, "pi.plugin.outside:0|g\npi.plugin.outside:-5.5|g"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statsdGauge(tt.m); got != tt.want {
+				t.Errorf("statsdGauge() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendStatsdNegativeGauge(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no UDP: %v", err)
+	}
+	defer conn.Close()
+
+	metrics := []graphiteMetric{{"pi.cpu", "12.5"}, {"pi.temp", "-3"}}
+	if err := sendStatsd(conn.LocalAddr().String(), metrics); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, statsdPacketSize)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pi.cpu:12.5|g\npi.temp:0|g\npi.temp:-3|g"
+	if got := string(buf[:n]); got != want {
+		t.Errorf("datagram = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ResourceGroup sums the processes of one user or cgroup
+type ResourceGroup struct {
+	Name   string
+	CPU    float64 // percent of one core, summed over the processes
+	Memory float64 // percent of RAM
+	Count  int
+}
+
+// cgroupUnit shortens a cgroup path to its systemd slice or unit, e.g.
+// "/system.slice/ssh.service" to "ssh.service" and
+// "/user.slice/user-1000.slice/session-2.scope" to "user-1000.slice".
+// Kernel threads live in the root cgroup "/".
+func cgroupUnit(cgroup string) string {
+	parts := strings.Split(strings.Trim(cgroup, "/"), "/")
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	if unit := path.Base(strings.Join(parts, "/")); unit != "." {
+		return unit
+	}
+	return "/"
+}
+
+// groupProcesses sums CPU and memory per user, or per systemd unit when
+// byCgroup is set, busiest group first
+func groupProcesses(procs []ProcessInfo, byCgroup bool) []ResourceGroup {
+	groups := make(map[string]*ResourceGroup)
+	for _, proc := range procs {
+		name := proc.Username
+		if byCgroup {
+			name = cgroupUnit(getProcessCgroup(proc.PID))
+		}
+		if name == "" {
+			name = "?"
+		}
+		g, ok := groups[name]
+		if !ok {
+			g = &ResourceGroup{Name: name}
+			groups[name] = g
+		}
+		g.CPU += proc.CPU
+		g.Memory += proc.Memory
+		g.Count++
+	}
+
+	list := make([]ResourceGroup, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, *g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CPU != list[j].CPU {
+			return list[i].CPU > list[j].CPU
+		}
+		return list[i].Memory > list[j].Memory
+	})
+	return list
+}
+
+// toggleGrouping switches the groups view between users and cgroups
+func (d *Dashboard) toggleGrouping() {
+	d.groupByCgroup = !d.groupByCgroup
+	d.selectedGroup = 0
+	d.UpdateStats()
+	d.Render()
+}
+
+func (d *Dashboard) updateGroupsView(stats SystemStats) {
+	if !d.reuseLists {
+		d.groupList = groupProcesses(stats.AllProcesses, d.groupByCgroup)
+	}
+	by := "user"
+	if d.groupByCgroup {
+		by = "cgroup"
+	}
+	total := len(d.groupList)
+	if total == 0 {
+		d.mainList.Title = d.viewTitle("0/0 " + by)
+		d.mainList.Rows = []string{"", "No processes"}
+		return
+	}
+	if d.selectedGroup >= total {
+		d.selectedGroup = total - 1
+	}
+	d.mainList.Title = d.viewTitle(fmt.Sprintf("%d/%d %s", d.selectedGroup+1, total, by))
+
+	rows := []string{
+		"[Name          CPU%  MEM%   N](fg:cyan)",
+		"---------------------------",
+	}
+
+	visibleHeight := 24
+	startIdx := d.selectedGroup - visibleHeight/2
+	if startIdx > total-visibleHeight {
+		startIdx = total - visibleHeight
+	}
+	if startIdx < 0 {
+		startIdx = 0
+	}
+	endIdx := startIdx + visibleHeight
+	if endIdx > total {
+		endIdx = total
+	}
+
+	for i := startIdx; i < endIdx; i++ {
+		g := d.groupList[i]
+		line := fmt.Sprintf("%-12s %5.1f %5.1f %3d", truncateString(g.Name, 12), g.CPU, g.Memory, g.Count)
+		if i == d.selectedGroup {
+			rows = append(rows, fmt.Sprintf("[%s](bg:white,fg:black)", line))
+		} else {
+			rows = append(rows, line)
+		}
+	}
+
+	rows = append(rows, "", "[s/X: user or cgroup](fg:yellow)")
+	d.mainList.Rows = rows
+}
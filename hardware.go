@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/host"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// HardwareInfo identifies the board and its software. None of it
+// changes while the monitor runs, so it is read once.
+type HardwareInfo struct {
+	Model      string // e.g. "Raspberry Pi 4 Model B Rev 1.4"
+	Revision   uint32 // board revision code, 0 when unknown
+	Serial     string
+	Firmware   string // build date of the VideoCore firmware, e.g. "Mar 17 2023"
+	FirmwareID string // short commit hash of the firmware
+	Bootloader string // EEPROM bootloader build date, Pi 4 and later, e.g. "2023/01/11"
+	Kernel     string
+	OS         string
+	Arch       string
+	HAT        *HATInfo // nil without a HAT ID EEPROM
+	Overlays   []string // dtoverlay lines of config.txt, e.g. "i2c-rtc,ds3231"
+}
+
+// HATInfo is the ID EEPROM of a HAT, which the firmware copies to
+// /proc/device-tree/hat at boot
+type HATInfo struct {
+	Product   string
+	Vendor    string
+	ProductID string // e.g. "0x0001"
+	Version   string // e.g. "0x0002"
+}
+
+// configTxtPaths are where config.txt lives before and since Raspberry
+// Pi OS bookworm
+var configTxtPaths = []string{"/boot/firmware/config.txt", "/boot/config.txt"}
+
+// Fields of the new-style revision codes
+var (
+	revisionSoCs   = []string{"BCM2835", "BCM2836", "BCM2837", "BCM2711", "BCM2712"}
+	revisionMakers = []string{"Sony UK", "Egoman", "Embest", "Sony Japan", "Embest", "Stadium"}
+)
+
+// revisionNewStyle is set in every revision code since the Pi 2
+const revisionNewStyle = 1 << 23
+
+// RevisionSoC returns the processor encoded in the revision code
+func (h HardwareInfo) RevisionSoC() string {
+	if h.Revision&revisionNewStyle == 0 {
+		return ""
+	}
+	if i := int(h.Revision >> 12 & 0xf); i < len(revisionSoCs) {
+		return revisionSoCs[i]
+	}
+	return ""
+}
+
+// RevisionMaker returns the manufacturer encoded in the revision code
+func (h HardwareInfo) RevisionMaker() string {
+	if h.Revision&revisionNewStyle == 0 {
+		return ""
+	}
+	if i := int(h.Revision >> 16 & 0xf); i < len(revisionMakers) {
+		return revisionMakers[i]
+	}
+	return ""
+}
+
+// RevisionMemory returns the memory size in MB encoded in the revision
+// code, 0 for old-style codes
+func (h HardwareInfo) RevisionMemory() int {
+	if h.Revision&revisionNewStyle == 0 {
+		return 0
+	}
+	return 256 << (h.Revision >> 20 & 7)
+}
+
+// readDeviceTree reads a device-tree string property without its NUL
+func readDeviceTree(name string) string {
+	data, err := os.ReadFile("/proc/device-tree/" + name)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(string(data), "\x00\n ")
+}
+
+// cpuinfoFields returns the "Key : value" lines of /proc/cpuinfo that
+// are not per core, like Revision and Serial
+func cpuinfoFields() map[string]string {
+	fields := make(map[string]string)
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return fields
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok {
+			fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+// getHardwareInfo reads the device tree, /proc/cpuinfo and vcgencmd
+func getHardwareInfo() HardwareInfo {
+	cpuinfo := cpuinfoFields()
+	h := HardwareInfo{
+		Model:  readDeviceTree("model"),
+		Serial: readDeviceTree("serial-number"),
+	}
+	if h.Model == "" {
+		h.Model = cpuinfo["Model"]
+	}
+	if h.Serial == "" {
+		h.Serial = cpuinfo["Serial"]
+	}
+	if rev, err := strconv.ParseUint(cpuinfo["Revision"], 16, 32); err == nil {
+		h.Revision = uint32(rev)
+	} else if data, err := os.ReadFile("/proc/device-tree/system/linux,revision"); err == nil && len(data) == 4 {
+		h.Revision = binary.BigEndian.Uint32(data)
+	}
+
+	ctx := context.Background()
+	// "Mar 17 2023 10:52:00", "Copyright ...", "version 82f3750a... (clean) ..."
+	if out, err := collector.Vcgencmd(ctx, "version"); err == nil {
+		lines := strings.Split(out, "\n")
+		if date := strings.Fields(lines[0]); len(date) >= 3 {
+			h.Firmware = strings.Join(date[:3], " ")
+		}
+		for _, line := range lines[1:] {
+			if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "version" {
+				h.FirmwareID = fields[1]
+				if len(h.FirmwareID) > 8 {
+					h.FirmwareID = h.FirmwareID[:8]
+				}
+			}
+		}
+	}
+	// "2023/01/11 17:40:52", "version 8ba17717... (release)", ...
+	if out, err := collector.Vcgencmd(ctx, "bootloader_version"); err == nil {
+		if fields := strings.Fields(out); len(fields) > 0 {
+			h.Bootloader = fields[0]
+		}
+	}
+
+	if info, err := host.InfoWithContext(ctx); err == nil {
+		h.Kernel = info.KernelVersion
+		h.Arch = info.KernelArch
+		h.OS = strings.TrimSpace(info.Platform + " " + info.PlatformVersion)
+	}
+	if name := osPrettyName(); name != "" {
+		h.OS = name
+	}
+	h.HAT = readHAT()
+	h.Overlays = configOverlays()
+	return h
+}
+
+// readHAT reads the HAT ID EEPROM as exposed by the firmware
+func readHAT() *HATInfo {
+	product := readDeviceTree("hat/product")
+	vendor := readDeviceTree("hat/vendor")
+	if product == "" && vendor == "" {
+		return nil
+	}
+	return &HATInfo{
+		Product:   product,
+		Vendor:    vendor,
+		ProductID: readDeviceTree("hat/product_id"),
+		Version:   readDeviceTree("hat/product_ver"),
+	}
+}
+
+// configOverlays lists the dtoverlay lines of config.txt. Overlays under
+// a conditional section other than [all], e.g. [pi4], are marked with
+// it since they may not apply to this board.
+func configOverlays() []string {
+	for _, path := range configTxtPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var overlays []string
+		section := "all"
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if i := strings.IndexByte(line, '#'); i >= 0 {
+				line = strings.TrimSpace(line[:i])
+			}
+			if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+				section = strings.Trim(line, "[]")
+				continue
+			}
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || strings.TrimSpace(key) != "dtoverlay" || strings.TrimSpace(value) == "" {
+				continue
+			}
+			overlay := strings.TrimSpace(value)
+			if section != "all" {
+				overlay += " [" + section + "]"
+			}
+			overlays = append(overlays, overlay)
+		}
+		return overlays
+	}
+	return nil
+}
+
+// osPrettyName returns PRETTY_NAME of /etc/os-release, e.g. "Debian
+// GNU/Linux 12 (bookworm)"
+func osPrettyName() string {
+	data, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "PRETTY_NAME=") {
+			return strings.Trim(strings.TrimPrefix(line, "PRETTY_NAME="), `"`)
+		}
+	}
+	return ""
+}
+
+func (d *Dashboard) updateHardwareView(stats SystemStats) {
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+	if d.hardware == nil {
+		h := getHardwareInfo()
+		d.hardware = &h
+	}
+	h := d.hardware
+
+	valueOr := func(s string) string {
+		if s == "" {
+			return "[unknown](fg:white)"
+		}
+		return truncateString(s, 18)
+	}
+
+	rows := []string{"[--Board--](fg:yellow)"}
+	if h.Model != "" {
+		rows = append(rows, fmt.Sprintf("[%s](fg:cyan)", truncateString(h.Model, 28)))
+	}
+	if h.Revision != 0 {
+		rows = append(rows, fmt.Sprintf("Revision: %x", h.Revision))
+	}
+	if soc := h.RevisionSoC(); soc != "" {
+		rows = append(rows, fmt.Sprintf("SoC:      %s", soc))
+	}
+	if maker := h.RevisionMaker(); maker != "" {
+		rows = append(rows, fmt.Sprintf("Maker:    %s", maker))
+	}
+	memory := formatBytes(stats.MemTotal) + " usable"
+	if mb := h.RevisionMemory(); mb > 0 {
+		memory = fmt.Sprintf("%s (%s usable)", formatBytes(uint64(mb)<<20), formatBytes(stats.MemTotal))
+	}
+	rows = append(rows,
+		fmt.Sprintf("Memory:   %s", memory),
+		fmt.Sprintf("Serial:   %s", valueOr(h.Serial)),
+		"",
+		"[--Firmware--](fg:yellow)",
+		fmt.Sprintf("Firmware: %s", valueOr(h.Firmware)),
+	)
+	if h.FirmwareID != "" {
+		rows = append(rows, fmt.Sprintf("          %s", h.FirmwareID))
+	}
+	rows = append(rows,
+		fmt.Sprintf("EEPROM:   %s", valueOr(h.Bootloader)),
+		"",
+		"[--Software--](fg:yellow)",
+		fmt.Sprintf("Kernel:   %s", valueOr(h.Kernel)),
+		fmt.Sprintf("Arch:     %s", valueOr(h.Arch)),
+		"OS:",
+		" "+truncateString(h.OS, 27),
+	)
+	rows = append(rows, "", "[--HAT--](fg:yellow)")
+	if h.HAT == nil {
+		rows = append(rows, "[No HAT EEPROM](fg:white)")
+	} else {
+		rows = append(rows,
+			fmt.Sprintf("[%s](fg:cyan)", truncateString(h.HAT.Product, 28)),
+			fmt.Sprintf("Vendor:   %s", valueOr(h.HAT.Vendor)),
+			fmt.Sprintf("ID:       %s v%s", h.HAT.ProductID, h.HAT.Version),
+		)
+	}
+	rows = append(rows, fmt.Sprintf("Overlays: %d in config.txt", len(h.Overlays)))
+	for _, overlay := range h.Overlays {
+		rows = append(rows, " "+truncateString(overlay, 27))
+	}
+	rows = append(rows, d.cameraRows()...)
+	d.mainList.Rows = rows
+}
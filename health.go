@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// healthRefresh is how often the card identity and SMART data are
+	// read again; they change slowly and smartctl is not cheap
+	healthRefresh = time.Minute
+	// smartctlTimeout bounds one smartctl run; USB bridges can be slow
+	smartctlTimeout = 10 * time.Second
+	// healthMinUptime is how long the system must run before the daily
+	// write estimate is trusted for the warning
+	healthMinUptime = time.Hour
+)
+
+// sdManufacturers maps the CID manufacturer id of SD cards to a name
+var sdManufacturers = map[uint64]string{
+	0x02: "Toshiba",
+	0x03: "SanDisk",
+	0x1b: "Samsung",
+	0x27: "Phison",
+	0x28: "Lexar",
+	0x41: "Kingston",
+	0x74: "Transcend",
+}
+
+// DeviceHealth is the identity and wear information of one block device
+type DeviceHealth struct {
+	Name         string
+	Model        string
+	Serial       string
+	Date         string // manufacturing date of SD cards
+	LifeUsed     int    // percent of rated endurance used, -1 when unknown
+	PowerOnHours int
+	TotalWritten uint64 // lifetime bytes written as reported by SMART
+	Temperature  int
+	SmartPassed  *bool // nil when SMART is not available
+}
+
+// readSysfs returns the trimmed contents of a sysfs attribute
+func readSysfs(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// getDeviceHealth reads the CID registers of SD/eMMC devices from sysfs
+// and asks smartctl about everything else
+func getDeviceHealth(name string) DeviceHealth {
+	h := DeviceHealth{Name: name, LifeUsed: -1}
+	if strings.HasPrefix(name, "mmcblk") {
+		readMMCHealth(&h)
+	} else {
+		readSmartctl(&h)
+	}
+	return h
+}
+
+func readMMCHealth(h *DeviceHealth) {
+	dir := "/sys/block/" + h.Name + "/device/"
+	h.Model = readSysfs(dir + "name")
+	h.Serial = readSysfs(dir + "serial")
+	h.Date = readSysfs(dir + "date")
+	if id, err := strconv.ParseUint(strings.TrimPrefix(readSysfs(dir+"manfid"), "0x"), 16, 64); err == nil {
+		if vendor, ok := sdManufacturers[id]; ok {
+			h.Model = vendor + " " + h.Model
+		}
+	}
+
+	// eMMC 5.0+ reports wear in 10% steps as "0x01 0x02" (type A, type B);
+	// SD cards have no such register
+	if fields := strings.Fields(readSysfs(dir + "life_time")); len(fields) > 0 {
+		worst := 0
+		for _, f := range fields {
+			if v, err := strconv.ParseInt(strings.TrimPrefix(f, "0x"), 16, 64); err == nil && int(v) > worst {
+				worst = int(v)
+			}
+		}
+		if worst > 0 {
+			h.LifeUsed = worst * 10
+		}
+	}
+}
+
+// smartctlOutput is the part of "smartctl -j -a" output we use
+type smartctlOutput struct {
+	ModelName    string `json:"model_name"`
+	SerialNumber string `json:"serial_number"`
+	SmartStatus  *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours int `json:"hours"`
+	} `json:"power_on_time"`
+	LogicalBlockSize uint64 `json:"logical_block_size"`
+	NVMeLog          *struct {
+		PercentageUsed   int    `json:"percentage_used"`
+		DataUnitsWritten uint64 `json:"data_units_written"`
+	} `json:"nvme_smart_health_information_log"`
+	ATAAttributes *struct {
+		Table []struct {
+			ID    int `json:"id"`
+			Value int `json:"value"`
+			Raw   struct {
+				Value uint64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+}
+
+// readSmartctl fills h from smartctl. Reading SMART data needs root;
+// without it, or without smartctl installed, the fields stay empty.
+func readSmartctl(h *DeviceHealth) {
+	ctx, cancel := context.WithTimeout(context.Background(), smartctlTimeout)
+	defer cancel()
+
+	// smartctl sets exit status bits for disk problems, so the output is
+	// parsed even when the command "fails"
+	out, _ := exec.CommandContext(ctx, "smartctl", "-j", "-a", "/dev/"+h.Name).Output()
+	var smart smartctlOutput
+	if len(out) == 0 || json.Unmarshal(out, &smart) != nil {
+		return
+	}
+
+	h.Model = smart.ModelName
+	h.Serial = smart.SerialNumber
+	h.Temperature = smart.Temperature.Current
+	h.PowerOnHours = smart.PowerOnTime.Hours
+	if smart.SmartStatus != nil {
+		passed := smart.SmartStatus.Passed
+		h.SmartPassed = &passed
+	}
+
+	if smart.NVMeLog != nil {
+		h.LifeUsed = smart.NVMeLog.PercentageUsed
+		h.TotalWritten = smart.NVMeLog.DataUnitsWritten * 512000 // units of 1000 sectors
+	}
+	if smart.ATAAttributes != nil {
+		blockSize := smart.LogicalBlockSize
+		if blockSize == 0 {
+			blockSize = 512
+		}
+		for _, attr := range smart.ATAAttributes.Table {
+			switch attr.ID {
+			case 177, 231, 233: // wear leveling count / SSD life left, normalized remaining
+				if h.LifeUsed < 0 {
+					h.LifeUsed = 100 - attr.Value
+				}
+			case 241: // total LBAs written
+				h.TotalWritten = attr.Raw.Value * blockSize
+			}
+		}
+	}
+}
+
+// refreshDeviceHealth re-reads the device health once per healthRefresh
+func (d *Dashboard) refreshDeviceHealth(devices []DiskIOStats) {
+	if time.Since(d.healthAt) < healthRefresh && len(d.healthCache) == len(devices) {
+		return
+	}
+	health := make(map[string]DeviceHealth, len(devices))
+	for _, dev := range devices {
+		health[dev.Name] = getDeviceHealth(dev.Name)
+	}
+	d.healthCache = health
+	d.healthAt = time.Now()
+}
+
+// dailyWrite estimates the bytes written per day from the writes since boot
+func dailyWrite(written, uptime uint64) float64 {
+	if uptime == 0 {
+		return 0
+	}
+	return float64(written) / float64(uptime) * 86400
+}
+
+// checkWriteRate logs once when a device writes more than the configured
+// daily volume, and again after it drops back below
+func (d *Dashboard) checkWriteRate(stats SystemStats) {
+	limit := d.config.SDWriteWarn * 1e9
+	if limit <= 0 || time.Duration(stats.Uptime)*time.Second < healthMinUptime {
+		return
+	}
+	for _, dev := range stats.DiskIO {
+		high := dailyWrite(dev.WriteBytes, stats.Uptime) > limit
+		if high != d.writeWarned[dev.Name] {
+			if high {
+				log.Printf("Warning: %s writes %s/day, above %.0f GB/day", dev.Name,
+					formatBytes(uint64(dailyWrite(dev.WriteBytes, stats.Uptime))), d.config.SDWriteWarn)
+			}
+			d.writeWarned[dev.Name] = high
+		}
+	}
+}
+
+func (d *Dashboard) updateHealthView(stats SystemStats) {
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+	if len(stats.DiskIO) == 0 {
+		d.mainList.Rows = []string{"", "No block devices found"}
+		return
+	}
+	d.refreshDeviceHealth(stats.DiskIO)
+
+	var rows []string
+	for _, dev := range stats.DiskIO {
+		h := d.healthCache[dev.Name]
+		rows = append(rows, fmt.Sprintf("[--%s--](fg:cyan)", dev.Name))
+		if h.Model != "" {
+			rows = append(rows, truncateString(h.Model, 27))
+		}
+		if h.Date != "" {
+			rows = append(rows, fmt.Sprintf("Made: %s", h.Date))
+		}
+
+		daily := dailyWrite(dev.WriteBytes, stats.Uptime)
+		dailyText := fmt.Sprintf("%s/day", formatBytes(uint64(daily)))
+		if d.writeWarned[dev.Name] {
+			dailyText = fmt.Sprintf("[%s HIGH](fg:red)", dailyText)
+		}
+		rows = append(rows,
+			fmt.Sprintf("Boot W: %s", formatBytes(dev.WriteBytes)),
+			fmt.Sprintf("Rate: %s", dailyText),
+		)
+
+		if h.LifeUsed >= 0 {
+			rows = append(rows, fmt.Sprintf("Life used: %d%%", h.LifeUsed), getBar(float64(h.LifeUsed), 20))
+		}
+		if h.TotalWritten > 0 {
+			rows = append(rows, fmt.Sprintf("Total W: %s", formatBytes(h.TotalWritten)))
+		}
+		if h.PowerOnHours > 0 {
+			rows = append(rows, fmt.Sprintf("Power on: %dh", h.PowerOnHours))
+		}
+		if h.Temperature > 0 {
+			rows = append(rows, fmt.Sprintf("Temp: %d°C", h.Temperature))
+		}
+		switch {
+		case h.SmartPassed == nil && !strings.HasPrefix(dev.Name, "mmcblk"):
+			rows = append(rows, "SMART: n/a (smartctl/root)")
+		case h.SmartPassed != nil && *h.SmartPassed:
+			rows = append(rows, "SMART: [PASSED](fg:green)")
+		case h.SmartPassed != nil:
+			rows = append(rows, "SMART: [FAILED](fg:red)")
+		}
+		rows = append(rows, "")
+	}
+	d.mainList.Rows = rows
+}
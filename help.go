@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// helpKeys are the key actions that work on every view
+var helpKeys = [][2]string{
+	{keyNextView, "next view"},
+	{keyPrevView, "previous view"},
+	{keyUp, "up"},
+	{keyDown, "down"},
+	{keyPageUp, "page up"},
+	{keyPageDown, "page down"},
+	{keyTop, "top"},
+	{keyBottom, "bottom"},
+	{keySelect, "open selected"},
+	{keyBack, "back"},
+	{keyPause, "pause"},
+	{keyFaster, "faster"},
+	{keySlower, "slower"},
+	{keyPower, "power menu"},
+	{keyReport, "save report"},
+	{keyScreen, "save screenshot"},
+	{keyHelp, "this help"},
+	{keyQuit, "quit"},
+}
+
+// viewHelpKeys are the key actions of single views
+var viewHelpKeys = map[int][][2]string{
+	viewProcess: {{keyKill, "signal/renice"}, {keySort, "sort"}, {keySearch, "search"}},
+	viewGroups:  {{keySort, "group by cgroup"}},
+	viewNetwork: {{keySpeedTest, "speed test"}, {keyProcNet, "per process"}},
+	viewLAN:     {{keyRescan, "rescan"}},
+	viewWifi:    {{keyRescan, "rescan"}, {keyMode, "AP/client mode"}},
+	viewCPUFreq: {{keyGovernor, "governor"}},
+	viewLogs:    {{keyFollow, "follow"}, {keyUnit, "unit filter"}},
+	viewKernel:  {{keyFollow, "events only"}},
+}
+
+// openHelp shows the keys and the button mapping of the current view.
+// Up/down scroll it on screens too small for all of it.
+func (d *Dashboard) openHelp() {
+	d.openMenu(&Menu{
+		title:   "Help: " + views[d.currentView].title,
+		message: d.helpRows(),
+		options: []menuOption{{label: "OK"}},
+		scroll:  true,
+	})
+}
+
+func (d *Dashboard) helpRows() []string {
+	rows := []string{"[Keys](fg:cyan)"}
+	for _, k := range helpKeys {
+		if keys := d.keysFor(k[0]); keys != "" {
+			rows = append(rows, helpRow(keys, k[1]))
+		}
+	}
+	var viewRows []string
+	for _, k := range viewHelpKeys[d.currentView] {
+		if keys := d.keysFor(k[0]); keys != "" {
+			viewRows = append(viewRows, helpRow(keys, k[1]))
+		}
+	}
+	if len(viewRows) > 0 {
+		rows = append(rows, "", fmt.Sprintf("[%s view](fg:cyan)", truncateString(views[d.currentView].title, 19)))
+		rows = append(rows, viewRows...)
+	}
+
+	if !d.gpioEnabled {
+		return rows
+	}
+	rows = append(rows, "", "[Buttons](fg:cyan)")
+	buttons := make([]string, 0, len(d.config.GPIO.Pins))
+	for button := range d.config.GPIO.Pins {
+		buttons = append(buttons, button)
+	}
+	sort.Strings(buttons)
+	var hold []string
+	for _, button := range buttons {
+		action := d.buttonAction(button)
+		if action == actionSelect {
+			hold = append(hold, button)
+		}
+		if desc := d.actionHelp(action); desc != "" {
+			rows = append(rows, helpRow(button, desc))
+		}
+	}
+	for _, button := range hold {
+		rows = append(rows, helpRow("hold "+button, "this help"))
+	}
+	return rows
+}
+
+// helpRow lines up a key or button with what it does
+func helpRow(key, desc string) string {
+	return truncateString(fmt.Sprintf("%-8s %s", key, desc), 24)
+}
+
+// actionHelp describes what a button action does on the current view,
+// following runButtonAction. It is empty when the button does nothing.
+func (d *Dashboard) actionHelp(action string) string {
+	if strings.HasPrefix(action, actionExecPrefix) {
+		return "run " + strings.TrimSpace(strings.TrimPrefix(action, actionExecPrefix))
+	}
+
+	switch action {
+	case actionUp, actionDown:
+		return action
+	case actionPageUp:
+		return "page up"
+	case actionPageDown:
+		return "page down"
+	case actionNextView:
+		return "next view"
+	case actionPrevView:
+		return "previous view"
+	case actionSelect:
+		return map[int]string{
+			viewProcess:    "details",
+			viewServices:   "service menu",
+			viewContainers: "container menu",
+			viewWifi:       "connect",
+			viewLogs:       "unit filter",
+			viewControl:    "switch output",
+		}[d.currentView]
+	case actionBack:
+		if d.currentView == viewProcess && d.detailPID != 0 {
+			return "close details"
+		}
+		return "previous view"
+	case actionAction:
+		if desc, ok := map[int]string{
+			viewProcess:    "signal menu",
+			viewServices:   "service menu",
+			viewContainers: "container menu",
+			viewWifi:       "AP/client mode",
+			viewCPUFreq:    "governor",
+			viewControl:    "switch output",
+		}[d.currentView]; ok {
+			return desc
+		}
+		return "next view"
+	case actionKill:
+		if d.currentView == viewProcess {
+			return "signal menu"
+		}
+	case actionToggle:
+		return map[int]string{
+			viewProcess: "sort",
+			viewGroups:  "group by cgroup",
+			viewNetwork: "speed test",
+			viewLAN:     "rescan",
+			viewWifi:    "rescan",
+			viewLogs:    "follow",
+			viewKernel:  "events only",
+		}[d.currentView]
+	case actionPause:
+		return "pause"
+	case actionPower:
+		return "power menu"
+	case actionHelp:
+		return "this help"
+	case actionQuit:
+		return "quit"
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package main
+
+import "fmt"
+
+// sparkLevels are the block characters used to draw sparklines, lowest first
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// History is a fixed-size ring buffer of metric samples
+type History struct {
+	samples []float64
+	next    int
+	full    bool
+}
+
+func NewHistory(size int) *History {
+	return &History{samples: make([]float64, size)}
+}
+
+// Add appends a sample, overwriting the oldest one when the buffer is full
+func (h *History) Add(v float64) {
+	h.samples[h.next] = v
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Len returns the number of stored samples
+func (h *History) Len() int {
+	if h.full {
+		return len(h.samples)
+	}
+	return h.next
+}
+
+// Values returns the stored samples from oldest to newest
+func (h *History) Values() []float64 {
+	if !h.full {
+		return append([]float64(nil), h.samples[:h.next]...)
+	}
+	values := make([]float64, 0, len(h.samples))
+	values = append(values, h.samples[h.next:]...)
+	return append(values, h.samples[:h.next]...)
+}
+
+// Last returns up to n of the newest samples, oldest first
+func (h *History) Last(n int) []float64 {
+	values := h.Values()
+	if len(values) > n {
+		values = values[len(values)-n:]
+	}
+	return values
+}
+
+// recordHistory appends the current sample of every tracked metric.
+// Refreshes triggered by key presses arrive faster than the update
+// interval and are skipped so the history keeps an even time base.
+func (d *Dashboard) recordHistory(stats SystemStats) {
+	now := d.sampleTime()
+	elapsed := now.Sub(d.lastSample)
+	if !d.lastSample.IsZero() && elapsed < d.interval/2 {
+		return
+	}
+
+	// seconds stays zero for the first sample, which has no rates yet
+	var seconds float64
+	if !d.lastSample.IsZero() {
+		seconds = elapsed.Seconds()
+		d.netSentRate = counterRate(stats.NetSent, d.prevNetSent, seconds)
+		d.netRecvRate = counterRate(stats.NetRecv, d.prevNetRecv, seconds)
+	}
+	d.recordInterfaceRates(stats.Interfaces, seconds)
+	d.recordDiskIO(stats.DiskIO, seconds)
+	d.prevNetSent = stats.NetSent
+	d.prevNetRecv = stats.NetRecv
+	d.lastSample = now
+
+	d.cpuHistory.Add(calculateAverage(stats.CPUPercent))
+	d.memHistory.Add(stats.MemPercent)
+	d.swapHistory.Add(stats.SwapPercent)
+	d.tempHistory.Add(stats.Temperature)
+	d.tempTracker.Add(now, stats.Temperature)
+	d.netHistory.Add(d.netSentRate + d.netRecvRate)
+	d.sentHistory.Add(d.netSentRate)
+	d.recvHistory.Add(d.netRecvRate)
+
+	d.voltRange.Update(stats.CoreVolts)
+	d.armClockRange.Update(float64(stats.ARMClock))
+	d.coreClockRange.Update(float64(stats.CoreClock))
+}
+
+// counterRate returns the per-second increase of a byte counter. A
+// counter that went backwards was reset, e.g. when an interface went
+// away, and yields zero instead of a wrapped uint64.
+func counterRate(cur, prev uint64, seconds float64) float64 {
+	if cur < prev || seconds <= 0 {
+		return 0
+	}
+	return float64(cur-prev) / seconds
+}
+
+// getSparkline draws values as a row of block characters. A max of zero
+// scales the line to the largest value.
+func getSparkline(values []float64, max float64, color string) string {
+	if max <= 0 {
+		for _, v := range values {
+			if v > max {
+				max = v
+			}
+		}
+	}
+
+	spark := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if max > 0 {
+			level = int(v / max * float64(len(sparkLevels)-1))
+		}
+		if level < 0 {
+			level = 0
+		}
+		if level >= len(sparkLevels) {
+			level = len(sparkLevels) - 1
+		}
+		spark[i] = sparkLevels[level]
+	}
+	return fmt.Sprintf("[%s](fg:%s)", string(spark), color)
+}
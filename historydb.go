@@ -0,0 +1,217 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// HistoryConfig configures the on-disk metrics history
+type HistoryConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Path       string        `yaml:"path"`
+	Resolution time.Duration `yaml:"resolution"` // time between stored samples
+	Retention  time.Duration `yaml:"retention"`  // samples older than this are deleted
+}
+
+// historyPruneInterval is how often expired samples are deleted
+const historyPruneInterval = time.Hour
+
+// HistorySample is one stored row of the metrics history
+type HistorySample struct {
+	Time        time.Time `json:"time"`
+	CPU         float64   `json:"cpu_percent"`
+	Mem         float64   `json:"mem_percent"`
+	Swap        float64   `json:"swap_percent"`
+	Disk        float64   `json:"disk_percent"`
+	Temperature float64   `json:"temperature"`
+	NetUp       float64   `json:"net_up_bps"`
+	NetDown     float64   `json:"net_down_bps"`
+	DiskRead    float64   `json:"disk_read_bps"`
+	DiskWrite   float64   `json:"disk_write_bps"`
+	Throttled   uint64    `json:"throttled"`
+}
+
+// historyColumns lists the sample columns in table order
+const historyColumns = "ts, cpu, mem, swap, disk, temp, net_up, net_down, disk_read, disk_write, throttled"
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS samples (
+	ts         INTEGER PRIMARY KEY, -- unix seconds
+	cpu        REAL,
+	mem        REAL,
+	swap       REAL,
+	disk       REAL,
+	temp       REAL,
+	net_up     REAL,
+	net_down   REAL,
+	disk_read  REAL,
+	disk_write REAL,
+	throttled  INTEGER
+)`
+
+// defaultHistoryPath returns $XDG_DATA_HOME/raspi-monitor/history.db,
+// falling back to ~/.local/share when XDG_DATA_HOME is unset.
+func defaultHistoryPath() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "history.db"
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "raspi-monitor", "history.db")
+}
+
+// HistoryStore keeps sampled metrics in a SQLite database
+type HistoryStore struct {
+	db *sql.DB
+}
+
+// openHistoryStore opens or creates the database at path
+func openHistoryStore(path string) (*HistoryStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// One connection avoids "database is locked" between our own writers
+	db.SetMaxOpenConns(1)
+
+	// WAL keeps readers (export, other instances) from blocking the writer
+	for _, stmt := range []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=5000", historySchema} {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("init %s: %w", path, err)
+		}
+	}
+	return &HistoryStore{db: db}, nil
+}
+
+// Insert stores s, replacing a sample with the same timestamp
+func (h *HistoryStore) Insert(s HistorySample) error {
+	_, err := h.db.Exec("INSERT OR REPLACE INTO samples ("+historyColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		s.Time.Unix(), s.CPU, s.Mem, s.Swap, s.Disk, s.Temperature,
+		s.NetUp, s.NetDown, s.DiskRead, s.DiskWrite, s.Throttled)
+	return err
+}
+
+// Prune deletes the samples taken before t
+func (h *HistoryStore) Prune(t time.Time) (int64, error) {
+	res, err := h.db.Exec("DELETE FROM samples WHERE ts < ?", t.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Query returns the samples taken in [from, to], oldest first
+func (h *HistoryStore) Query(from, to time.Time) ([]HistorySample, error) {
+	rows, err := h.db.Query("SELECT "+historyColumns+" FROM samples WHERE ts >= ? AND ts <= ? ORDER BY ts",
+		from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []HistorySample
+	for rows.Next() {
+		var s HistorySample
+		var ts int64
+		if err := rows.Scan(&ts, &s.CPU, &s.Mem, &s.Swap, &s.Disk, &s.Temperature,
+			&s.NetUp, &s.NetDown, &s.DiskRead, &s.DiskWrite, &s.Throttled); err != nil {
+			return nil, err
+		}
+		s.Time = time.Unix(ts, 0)
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
+
+func (h *HistoryStore) Close() error {
+	return h.db.Close()
+}
+
+// historySampleFrom builds a sample from two stats snapshots; the rates
+// are computed over the time between them
+func historySampleFrom(stats, prev SystemStats, at, prevAt time.Time) HistorySample {
+	s := HistorySample{
+		Time:        at,
+		CPU:         calculateAverage(stats.CPUPercent),
+		Mem:         stats.MemPercent,
+		Swap:        stats.SwapPercent,
+		Disk:        stats.DiskPercent,
+		Temperature: stats.Temperature,
+		Throttled:   stats.Throttle.Flags,
+	}
+	if prevAt.IsZero() {
+		return s
+	}
+
+	seconds := at.Sub(prevAt).Seconds()
+	rate := func(cur, old uint64) float64 {
+		if cur < old {
+			return 0
+		}
+		return float64(cur-old) / seconds
+	}
+	s.NetUp = rate(stats.NetSent, prev.NetSent)
+	s.NetDown = rate(stats.NetRecv, prev.NetRecv)
+
+	prevDisks := make(map[string]DiskIOStats, len(prev.DiskIO))
+	for _, dev := range prev.DiskIO {
+		prevDisks[dev.Name] = dev
+	}
+	for _, dev := range stats.DiskIO {
+		if old, ok := prevDisks[dev.Name]; ok {
+			s.DiskRead += rate(dev.ReadBytes, old.ReadBytes)
+			s.DiskWrite += rate(dev.WriteBytes, old.WriteBytes)
+		}
+	}
+	return s
+}
+
+// runHistoryStore writes a sample every resolution until stop is closed
+// and deletes expired samples once an hour
+func (d *Dashboard) runHistoryStore(store *HistoryStore, stop <-chan struct{}) {
+	cfg := d.config.History
+	ticker := time.NewTicker(cfg.Resolution)
+	defer ticker.Stop()
+
+	lastPrune := time.Time{}
+	var prev SystemStats
+	var prevAt time.Time
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		stats, at := d.snapshot.Get()
+		if at.IsZero() || !at.After(prevAt) {
+			continue
+		}
+		if err := store.Insert(historySampleFrom(stats, prev, at, prevAt)); err != nil {
+			log.Printf("History: %v", err)
+		}
+		prev, prevAt = stats, at
+
+		if time.Since(lastPrune) >= historyPruneInterval {
+			if n, err := store.Prune(time.Now().Add(-cfg.Retention)); err != nil {
+				log.Printf("History: prune: %v", err)
+			} else if n > 0 {
+				log.Printf("History: pruned %d samples", n)
+			}
+			lastPrune = time.Now()
+		}
+	}
+}
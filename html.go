@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image/color"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// htmlPageStyle lays out the list like the terminal: a 30 column box
+// with the menu centered on top
+const htmlPageStyle = `body{background:%[3]s;margin:1em;font:14px/1.25 monospace}
+.screen{position:relative;display:inline-block;min-width:30ch;border:1px solid %[1]s;padding:0 1ch}
+.menu{position:absolute;left:2ch;right:2ch;top:30%%;background:%[3]s;border:1px solid %[2]s;padding:0 1ch}
+.title{margin-top:-.65em;background:%[3]s;display:inline-block;padding:0 .5ch}
+pre{margin:0;white-space:pre}`
+
+// htmlDisplay keeps the last frame as a web page, served on
+// display.listen by startHTTPServers. The page reloads itself every
+// update interval.
+type htmlDisplay struct {
+	refresh int // seconds
+
+	mu   sync.RWMutex
+	page []byte
+}
+
+func newHTMLDisplay(interval time.Duration) *htmlDisplay {
+	return &htmlDisplay{refresh: int(math.Max(1, math.Ceil(interval.Seconds())))}
+}
+
+func (h *htmlDisplay) Render(frame Frame) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<!DOCTYPE html><html><head><meta charset=\"utf-8\">"+
+		"<meta http-equiv=\"refresh\" content=\"%d\"><title>%s</title><style>", h.refresh, html.EscapeString(frame.Title))
+	t := currentTheme
+	fmt.Fprintf(&b, htmlPageStyle, cssColor(t.rgb(t.Border)), cssColor(t.rgb(t.Menu)), cssColor(t.rgb("black")))
+	b.WriteString("</style></head><body><div class=\"screen\">")
+	writeHTMLList(&b, frame.Title, frame.Rows)
+	if frame.Menu != nil {
+		b.WriteString("<div class=\"menu\">")
+		writeHTMLList(&b, frame.Menu.title, frame.Menu.rows())
+		b.WriteString("</div>")
+	}
+	b.WriteString("</div>")
+	if frame.Status != "" {
+		b.WriteString("<pre>")
+		writeHTMLRow(&b, frame.Status)
+		b.WriteString("</pre>")
+	}
+	b.WriteString("</body></html>\n")
+
+	h.mu.Lock()
+	h.page = b.Bytes()
+	h.mu.Unlock()
+	return nil
+}
+
+// writeHTMLList writes a title and rows with their termui colors as spans
+func writeHTMLList(b *bytes.Buffer, title string, rows []string) {
+	fmt.Fprintf(b, "<div class=\"title\" style=\"color:%s\">%s</div><pre>",
+		cssColor(currentTheme.rgb(currentTheme.Title)), html.EscapeString(title))
+	for _, row := range rows {
+		writeHTMLRow(b, row)
+		b.WriteByte('\n')
+	}
+	b.WriteString("</pre>")
+}
+
+// writeHTMLRow writes one row of termui markup as colored spans
+func writeHTMLRow(b *bytes.Buffer, row string) {
+	var style ui.Style
+	open := false
+	for _, cell := range ui.ParseStyles(row, ui.NewStyle(currentTheme.term(currentTheme.Text))) {
+		if !open || cell.Style != style {
+			if open {
+				b.WriteString("</span>")
+			}
+			style, open = cell.Style, true
+			fmt.Fprintf(b, "<span style=\"color:%s", cssColor(lcdColor(style.Fg, currentTheme.rgb(currentTheme.Text))))
+			if style.Bg != ui.ColorClear {
+				fmt.Fprintf(b, ";background:%s", cssColor(lcdColor(style.Bg, currentTheme.rgb("black"))))
+			}
+			b.WriteString("\">")
+		}
+		b.WriteString(html.EscapeString(string(cell.Rune)))
+	}
+	if open {
+		b.WriteString("</span>")
+	}
+}
+
+func cssColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// ServeHTTP returns the page of the last frame
+func (h *htmlDisplay) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	page := h.page
+	h.mu.RUnlock()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// Events returns nil; the page is read only
+func (h *htmlDisplay) Events() <-chan ui.Event {
+	return nil
+}
+
+func (h *htmlDisplay) Close() error {
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// startHTTPServers starts one HTTP server per configured listen address.
+// Endpoints configured on the same address share a server.
+func (d *Dashboard) startHTTPServers() []*http.Server {
+	muxes := make(map[string]*http.ServeMux)
+	handle := func(addr, pattern string, handler http.HandlerFunc) {
+		if addr == "" {
+			return
+		}
+		mux, ok := muxes[addr]
+		if !ok {
+			mux = http.NewServeMux()
+			muxes[addr] = mux
+		}
+		mux.HandleFunc(pattern, handler)
+	}
+
+	handle(d.config.Prometheus, "/metrics", d.prometheusHandler)
+	handle(d.config.API, "/api/stats", d.apiStatsHandler)
+	handle(d.config.API, "/api/processes", d.apiProcessesHandler)
+	handle(d.config.API, "/ws", d.wsHandler)
+	if d.cluster != nil && d.config.Cluster.Accept {
+		handle(d.config.API, "/api/push", d.clusterPushHandler)
+	}
+	if h, ok := d.display.(*htmlDisplay); ok {
+		handle(d.config.Display.Listen, "/", h.ServeHTTP)
+	}
+
+	servers := make([]*http.Server, 0, len(muxes))
+	for addr, mux := range muxes {
+		srv := &http.Server{Addr: addr, Handler: mux}
+		go func() {
+			log.Printf("HTTP server listening on %s", srv.Addr)
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("HTTP server on %s stopped: %v", srv.Addr, err)
+			}
+		}()
+		servers = append(servers, srv)
+	}
+	return servers
+}
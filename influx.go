@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InfluxConfig writes the stats in InfluxDB line protocol at every
+// update interval, to the v2 HTTP API or a UDP listener
+type InfluxConfig struct {
+	URL         string            `yaml:"url"`    // http(s)://host:8086, or udp://host:8089; empty disables it
+	Org         string            `yaml:"org"`    // HTTP API only
+	Bucket      string            `yaml:"bucket"` // HTTP API only
+	Token       string            `yaml:"token"`  // HTTP API only
+	Measurement string            `yaml:"measurement"`
+	Tags        map[string]string `yaml:"tags"` // added to every point besides host, e.g. location: office
+}
+
+func (c InfluxConfig) validate() error {
+	if c.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("influxdb.url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		if c.Bucket == "" {
+			return fmt.Errorf("influxdb.bucket must be set for the HTTP API")
+		}
+	case "udp":
+		if _, _, err := net.SplitHostPort(u.Host); err != nil {
+			return fmt.Errorf("influxdb.url: %w", err)
+		}
+	default:
+		return fmt.Errorf("influxdb.url must start with http://, https:// or udp://, got %q", c.URL)
+	}
+	if c.Measurement == "" {
+		return fmt.Errorf("influxdb.measurement must not be empty")
+	}
+	return nil
+}
+
+// statPoint is a group of values measured together, like one line of
+// the line protocol
+type statPoint struct {
+	name   string // suffix of the measurement, empty for the main one
+	tags   [][2]string
+	fields []statField
+}
+
+type statField struct {
+	key   string
+	value interface{} // float64, uint64 or string
+}
+
+// statPoints turns a sample into points. Rates are left out until a
+// previous sample taken seconds before is known.
+func statPoints(stats, prev SystemStats, seconds float64) []statPoint {
+	main := statPoint{fields: []statField{
+		{"cpu_percent", calculateAverage(stats.CPUPercent)},
+		{"mem_percent", stats.MemPercent},
+		{"swap_percent", stats.SwapPercent},
+		{"disk_percent", stats.DiskPercent},
+		{"temperature", stats.Temperature},
+		{"load1", stats.Load.Load1},
+		{"load5", stats.Load.Load5},
+		{"load15", stats.Load.Load15},
+		{"uptime", stats.Uptime},
+		{"processes", stats.ProcessCount},
+	}}
+	if seconds > 0 {
+		main.fields = append(main.fields,
+			statField{"net_sent_bytes_per_sec", counterRate(stats.NetSent, prev.NetSent, seconds)},
+			statField{"net_recv_bytes_per_sec", counterRate(stats.NetRecv, prev.NetRecv, seconds)})
+	}
+	if stats.Fan != nil {
+		main.fields = append(main.fields, statField{"fan_duty", float64(stats.Fan.Duty)})
+	}
+	if b := stats.Battery; b != nil {
+		main.fields = append(main.fields, statField{"battery_percent", b.Percent})
+	}
+	if stats.IPAddress != "" {
+		main.fields = append(main.fields, statField{"ip", stats.IPAddress})
+	}
+
+	points := []statPoint{main}
+	for i, pct := range stats.CPUPercent {
+		points = append(points, statPoint{
+			name:   "cpu",
+			tags:   [][2]string{{"cpu", strconv.Itoa(i)}},
+			fields: []statField{{"usage_percent", pct}},
+		})
+	}
+	for _, m := range stats.Plugins {
+		tags := [][2]string{{"plugin", m.Plugin}, {"name", m.Name}}
+		if m.Unit != "" {
+			tags = append(tags, [2]string{"unit", m.Unit})
+		}
+		points = append(points, statPoint{name: "plugin", tags: tags, fields: []statField{{"value", m.Value}}})
+	}
+	return points
+}
+
+var (
+	influxNameEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxKeyEscaper  = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxStrEscaper  = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+// influxLine formats a point in line protocol, e.g.
+// raspi,host=pi cpu_percent=12.5,uptime=3600i 1700000000000000000
+func influxLine(measurement string, tags [][2]string, p statPoint, at time.Time) string {
+	var b strings.Builder
+	name := measurement
+	if p.name != "" {
+		name += "_" + p.name
+	}
+	b.WriteString(influxNameEscaper.Replace(name))
+	for _, set := range [][][2]string{tags, p.tags} {
+		for _, t := range set {
+			if t[1] == "" {
+				continue // empty tag values are not allowed
+			}
+			fmt.Fprintf(&b, ",%s=%s", influxKeyEscaper.Replace(t[0]), influxKeyEscaper.Replace(t[1]))
+		}
+	}
+	for i, f := range p.fields {
+		sep := ","
+		if i == 0 {
+			sep = " "
+		}
+		b.WriteString(sep + influxKeyEscaper.Replace(f.key) + "=")
+		switch v := f.value.(type) {
+		case float64:
+			b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+		case uint64:
+			b.WriteString(strconv.FormatUint(v, 10) + "i")
+		case string:
+			b.WriteString(`"` + influxStrEscaper.Replace(v) + `"`)
+		}
+	}
+	fmt.Fprintf(&b, " %d", at.UnixNano())
+	return b.String()
+}
+
+// influxTags returns the host tag and the configured ones, sorted by
+// key as InfluxDB prefers. A configured host tag replaces the host name.
+func influxTags(cfg InfluxConfig) [][2]string {
+	all := map[string]string{"host": hostname()}
+	for k, v := range cfg.Tags {
+		all[k] = v
+	}
+	tags := make([][2]string, 0, len(all))
+	for k, v := range all {
+		tags = append(tags, [2]string{k, v})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i][0] < tags[j][0] })
+	return tags
+}
+
+// runInflux writes the latest sample at every update interval until
+// stop is closed. Failed writes are logged and the sample is dropped.
+func (d *Dashboard) runInflux(stop <-chan struct{}) {
+	cfg := d.config.Influx
+	tags := influxTags(cfg)
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	var prev SystemStats
+	var prevAt time.Time
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		stats, at := d.snapshot.Get()
+		if at.IsZero() || !at.After(prevAt) {
+			continue
+		}
+		seconds := 0.0
+		if !prevAt.IsZero() {
+			seconds = at.Sub(prevAt).Seconds()
+		}
+		var lines []string
+		for _, p := range statPoints(stats, prev, seconds) {
+			lines = append(lines, influxLine(cfg.Measurement, tags, p, at))
+		}
+		prev, prevAt = stats, at
+
+		if err := writeInflux(cfg, lines); err != nil {
+			log.Printf("InfluxDB: %v", err)
+		}
+	}
+}
+
+// writeInflux sends lines to the v2 write API, or one datagram per line
+// to a UDP listener so none is cut at the MTU
+func writeInflux(cfg InfluxConfig, lines []string) error {
+	u, _ := url.Parse(cfg.URL) // checked by validate
+	if u.Scheme == "udp" {
+		conn, err := net.Dial("udp", u.Host)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		for _, line := range lines {
+			if _, err := conn.Write([]byte(line + "\n")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v2/write"
+	u.RawQuery = url.Values{"org": {cfg.Org}, "bucket": {cfg.Bucket}, "precision": {"ns"}}.Encode()
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewBufferString(strings.Join(lines, "\n")+"\n"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+cfg.Token)
+	}
+	return doNotify(req)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Results of the internet connectivity check
+const (
+	internetOK       = "OK"
+	internetDegraded = "Degraded"
+	internetOffline  = "Offline"
+)
+
+// InternetConfig sets up the connectivity check shown on the System
+// view. An interface having an IP address does not mean it reaches the
+// internet, so a URL that answers 204 is fetched and a name resolved.
+type InternetConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	URL      string        `yaml:"url"`      // must answer 204 No Content
+	DNSHost  string        `yaml:"dns_host"` // resolved to test DNS
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+func (c InternetConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("internet.url must be an http(s) URL, got %q", c.URL)
+	}
+	if c.DNSHost == "" {
+		return fmt.Errorf("internet.dns_host must be set")
+	}
+	if c.Interval <= 0 || c.Timeout <= 0 {
+		return fmt.Errorf("internet.interval and internet.timeout must be positive")
+	}
+	return nil
+}
+
+// InternetStatus is the outcome of the latest connectivity check
+type InternetStatus struct {
+	State   string    // one of the internet constants, empty before the first check
+	Reason  string    // why the state is not OK
+	Checked time.Time // when the check ran
+	LastOK  time.Time // last check that passed, zero if none did
+}
+
+// InternetMonitor runs the check in the background and keeps the
+// result for the UI
+type InternetMonitor struct {
+	cfg    InternetConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	status InternetStatus
+}
+
+func newInternetMonitor(cfg InternetConfig) *InternetMonitor {
+	return &InternetMonitor{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// A captive portal answers with a redirect to its login page
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Status returns the result of the latest check
+func (m *InternetMonitor) Status() InternetStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Run checks every interval until stop is closed
+func (m *InternetMonitor) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.check()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check resolves the DNS host and fetches the URL. Both passing is OK,
+// both failing Offline and anything in between Degraded, which includes
+// a captive portal answering the URL with its own page.
+func (m *InternetMonitor) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Timeout)
+	defer cancel()
+
+	_, dnsErr := net.DefaultResolver.LookupHost(ctx, m.cfg.DNSHost)
+
+	httpOK, reason := false, ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.cfg.URL, nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = m.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			httpOK = resp.StatusCode == http.StatusNoContent
+			if !httpOK {
+				reason = fmt.Sprintf("captive portal? (HTTP %d)", resp.StatusCode)
+			}
+		}
+	}
+	if err != nil {
+		reason = "HTTP check failed"
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev := m.status.State
+	m.status.Checked = now
+	switch {
+	case dnsErr == nil && httpOK:
+		m.status.State, m.status.Reason = internetOK, ""
+		m.status.LastOK = now
+	case dnsErr != nil && !httpOK:
+		m.status.State, m.status.Reason = internetOffline, "DNS and HTTP failed"
+	case dnsErr != nil:
+		m.status.State, m.status.Reason = internetDegraded, "DNS lookup failed"
+	default:
+		m.status.State, m.status.Reason = internetDegraded, reason
+	}
+	if m.status.State != prev {
+		log.Printf("Internet: %s %s", m.status.State, m.status.Reason)
+	}
+}
+
+// internetRows shows the check result on the System view
+func internetRows(m *InternetMonitor) []string {
+	if m == nil {
+		return nil
+	}
+	status := m.Status()
+	if status.State == "" {
+		return []string{"Internet: checking..."}
+	}
+
+	color := "green"
+	switch status.State {
+	case internetDegraded:
+		color = "yellow"
+	case internetOffline:
+		color = "red"
+	}
+	rows := []string{fmt.Sprintf("Internet: [%s](fg:%s)", status.State, color)}
+	if status.State != internetOK {
+		rows = append(rows, truncateString(status.Reason, 27))
+	}
+	lastOK := "never"
+	if !status.LastOK.IsZero() {
+		lastOK = formatAge(time.Since(status.LastOK)) + " ago"
+	}
+	return append(rows, "Last OK: "+lastOK)
+}
+
+// formatAge prints a duration coarsely, e.g. "45s", "12m" or "3h"
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
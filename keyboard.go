@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Special keys on the last row of the on-screen keyboard
+const (
+	keyShift     = "Aa"
+	keySpace     = "Sp"
+	keyBackspace = "Del"
+	keyDone      = "OK"
+)
+
+// keyboardRows is the layout of the on-screen keyboard. Letters are
+// shown in upper case while shift is on.
+var keyboardRows = [][]string{
+	strings.Split("abcdefghij", ""),
+	strings.Split("klmnopqrst", ""),
+	strings.Split("uvwxyz0123", ""),
+	strings.Split("456789-_.@", ""),
+	strings.Split("!#$%&*+=?/", ""),
+	{keyShift, keySpace, keyBackspace, keyDone},
+}
+
+// keyboard is an on-screen keyboard shown in a Menu so text such as a
+// Wi-Fi passphrase can be typed with the GPIO buttons alone
+type keyboard struct {
+	text     string
+	row, col int
+	shift    bool
+	done     func(d *Dashboard, text string) // called with the text on OK
+}
+
+// openKeyboard asks for a line of text. done runs when OK is chosen;
+// cancelling closes the keyboard without calling it.
+func (d *Dashboard) openKeyboard(title string, done func(d *Dashboard, text string)) {
+	d.openMenu(&Menu{title: title, keyboard: &keyboard{done: done}})
+}
+
+// label returns key as it is currently typed
+func (k *keyboard) label(key string) string {
+	if k.shift && len(key) == 1 {
+		return strings.ToUpper(key)
+	}
+	return key
+}
+
+// move moves the cursor, wrapping around the edges. Moving between
+// rows of different length keeps the cursor within the new row.
+func (k *keyboard) move(drow, dcol int) {
+	n := len(keyboardRows)
+	k.row = ((k.row+drow)%n + n) % n
+	cols := len(keyboardRows[k.row])
+	if k.col >= cols {
+		k.col = cols - 1
+	}
+	k.col = ((k.col+dcol)%cols + cols) % cols
+}
+
+// press types the key under the cursor. It reports true for OK.
+func (k *keyboard) press() bool {
+	switch key := keyboardRows[k.row][k.col]; key {
+	case keyShift:
+		k.shift = !k.shift
+	case keySpace:
+		k.text += " "
+	case keyBackspace:
+		k.backspace()
+	case keyDone:
+		return true
+	default:
+		k.text += k.label(key)
+	}
+	return false
+}
+
+func (k *keyboard) backspace() {
+	if k.text != "" {
+		_, size := utf8.DecodeLastRuneInString(k.text)
+		k.text = k.text[:len(k.text)-size]
+	}
+}
+
+// rows draws the typed text and the keys with the cursor highlighted
+func (k *keyboard) rows() []string {
+	text := k.text
+	if n := utf8.RuneCountInString(text); n > 23 {
+		text = "…" + string([]rune(text)[n-22:])
+	}
+	rows := []string{"> " + text + "_", ""}
+	for r, keys := range keyboardRows {
+		cells := make([]string, len(keys))
+		for c, key := range keys {
+			label := k.label(key)
+			if r == k.row && c == k.col {
+				cells[c] = fmt.Sprintf("[%s](bg:white,fg:black)", label)
+			} else {
+				cells[c] = label
+			}
+		}
+		sep := " "
+		if r == len(keyboardRows)-1 {
+			sep = "  "
+		}
+		rows = append(rows, strings.Join(cells, sep))
+	}
+	return rows
+}
+
+// finishKeyboard closes the keyboard and hands the text over
+func (d *Dashboard) finishKeyboard() {
+	k := d.menu.keyboard
+	d.closeMenu()
+	k.done(d, k.text)
+}
+
+// handleKeyboardKey handles keyboard input while the on-screen keyboard
+// is open. Typed characters go straight into the text.
+func (d *Dashboard) handleKeyboardKey(id string) {
+	k := d.menu.keyboard
+	switch id {
+	case "<Up>":
+		k.move(-1, 0)
+	case "<Down>":
+		k.move(1, 0)
+	case "<Left>":
+		k.move(0, -1)
+	case "<Right>":
+		k.move(0, 1)
+	case "<Enter>":
+		if k.press() {
+			d.finishKeyboard()
+			return
+		}
+	case "<Backspace>", "<C-<Backspace>>":
+		k.backspace()
+	case "<Space>":
+		k.text += " "
+	case "<Escape>":
+		d.closeMenu()
+		return
+	default:
+		if utf8.RuneCountInString(id) != 1 {
+			return
+		}
+		k.text += id
+	}
+	d.Render()
+}
+
+// handleKeyboardButton handles GPIO buttons while the on-screen keyboard
+// is open. The buttons named left and right move the cursor sideways
+// whatever their action; up/down move it between rows, select/action
+// press the key and back deletes a character, or cancels when held.
+func (d *Dashboard) handleKeyboardButton(evt ButtonEvent) {
+	k := d.menu.keyboard
+	switch evt.Button {
+	case "left":
+		k.move(0, -1)
+		d.Render()
+		return
+	case "right":
+		k.move(0, 1)
+		d.Render()
+		return
+	}
+
+	switch d.buttonAction(evt.Button) {
+	case actionUp:
+		k.move(-1, 0)
+	case actionDown:
+		k.move(1, 0)
+	case actionSelect, actionAction:
+		if evt.Kind != PressShort {
+			return
+		}
+		if k.press() {
+			d.finishKeyboard()
+			return
+		}
+	case actionBack:
+		switch evt.Kind {
+		case PressLong:
+			d.closeMenu()
+			return
+		case PressRepeat:
+			return
+		}
+		k.backspace()
+	default:
+		return
+	}
+	d.Render()
+}
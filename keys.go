@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Key actions that keys.bindings can assign to a key. Keys act on the
+// current view like the buttons, but back and select keep their
+// keyboard meaning.
+const (
+	keyNone      = "none"
+	keyUp        = "up"
+	keyDown      = "down"
+	keyPageUp    = "page_up"
+	keyPageDown  = "page_down"
+	keyTop       = "top"    // first row of the list, or the oldest log lines
+	keyBottom    = "bottom" // last row of the list, or the newest log lines
+	keyNextView  = "next_view"
+	keyPrevView  = "prev_view"
+	keySelect    = "select" // process detail, service/container menu, Wi-Fi, unit filter
+	keyBack      = "back"   // close the process detail or clear the filter
+	keyKill      = "kill"
+	keySort      = "sort"   // process sort, grouping
+	keySearch    = "search" // process filter
+	keyFollow    = "follow" // log follow, kernel event filter
+	keySpeedTest = "speedtest"
+	keyProcNet   = "procnet"
+	keyRescan    = "rescan" // LAN and Wi-Fi
+	keyMode      = "mode"   // AP/client mode
+	keyGovernor  = "governor"
+	keyUnit      = "unit" // log unit filter
+	keyPause     = "pause"
+	keyFaster    = "faster"
+	keySlower    = "slower"
+	keyPower     = "power"
+	keyReport    = "report"     // diagnostic report file
+	keyScreen    = "screenshot" // current view to a file
+	keyHelp      = "help"
+	keyQuit      = "quit"
+
+	keyPresetDefault = "default"
+	keyPresetVim     = "vim"
+)
+
+// keyActions are the action names accepted in keys.bindings
+var keyActions = []string{
+	keyNone, keyUp, keyDown, keyPageUp, keyPageDown, keyTop, keyBottom,
+	keyNextView, keyPrevView, keySelect, keyBack, keyKill, keySort, keySearch, keyFollow, keySpeedTest,
+	keyProcNet, keyRescan, keyMode, keyGovernor, keyUnit, keyPause, keyFaster,
+	keySlower, keyPower, keyReport, keyScreen, keyHelp, keyQuit,
+}
+
+const (
+	// selectionPage moves a screenful of the process list, keeping the
+	// last row of the old one in view
+	selectionPage = processPageRows - 1
+	// selectionEnd is a move long enough to reach either end of any list
+	selectionEnd = 1 << 20
+)
+
+// KeysConfig picks the key preset and remaps single keys. Keys are
+// named like termui names them: "j", "G", "<Up>", "<Enter>", "<Space>",
+// "<C-n>", "<F2>"; several characters in a row, like "gg", make a
+// sequence.
+type KeysConfig struct {
+	Preset   string            `yaml:"preset"`   // default or vim
+	Bindings map[string]string `yaml:"bindings"` // key -> action, none unbinds it
+}
+
+// defaultKeyBindings returns the keys of the default preset
+func defaultKeyBindings() map[string]string {
+	return map[string]string{
+		"<Up>":        keyUp,
+		"<Down>":      keyDown,
+		"<PageUp>":    keyPageUp,
+		"<PageDown>":  keyPageDown,
+		"<Home>":      keyTop,
+		"<End>":       keyBottom,
+		"<Tab>":       keyNextView,
+		"<Enter>":     keySelect,
+		"<Escape>":    keyBack,
+		"<Backspace>": keyBack,
+		"k":           keyKill,
+		"s":           keySort,
+		"/":           keySearch,
+		"f":           keyFollow,
+		"t":           keySpeedTest,
+		"n":           keyProcNet,
+		"r":           keyRescan,
+		"m":           keyMode,
+		"g":           keyGovernor,
+		"u":           keyUnit,
+		"<Space>":     keyPause,
+		"+":           keyFaster,
+		"=":           keyFaster,
+		"-":           keySlower,
+		"p":           keyPower,
+		"d":           keyReport,
+		"w":           keyScreen,
+		"?":           keyHelp,
+		"q":           keyQuit,
+	}
+}
+
+// vimKeyBindings are laid over the default preset by the vim one. The
+// signal menu moves to K and the governor menu to c, as k and g now
+// navigate.
+var vimKeyBindings = map[string]string{
+	"j":  keyDown,
+	"k":  keyUp,
+	"h":  keyPrevView,
+	"l":  keyNextView,
+	"gg": keyTop,
+	"G":  keyBottom,
+	"K":  keyKill,
+	"c":  keyGovernor,
+	"g":  keyNone,
+}
+
+// bindings returns the keys of the preset with the remapped ones applied
+func (c KeysConfig) bindings() map[string]string {
+	keys := defaultKeyBindings()
+	if c.Preset == keyPresetVim {
+		for key, action := range vimKeyBindings {
+			keys[key] = action
+		}
+	}
+	for key, action := range c.Bindings {
+		keys[key] = action
+	}
+	for key, action := range keys {
+		if action == keyNone {
+			delete(keys, key)
+		}
+	}
+	return keys
+}
+
+func (c KeysConfig) validate() error {
+	if c.Preset != keyPresetDefault && c.Preset != keyPresetVim {
+		return fmt.Errorf("keys.preset must be %s or %s, got %q", keyPresetDefault, keyPresetVim, c.Preset)
+	}
+	for key, action := range c.Bindings {
+		if key == "" || key == "<C-c>" {
+			return fmt.Errorf("keys.bindings: %q cannot be remapped", key)
+		}
+		known := false
+		for _, a := range keyActions {
+			known = known || action == a
+		}
+		if !known {
+			return fmt.Errorf("keys.bindings.%s: unknown action %q (%s)", key, action, strings.Join(keyActions, ", "))
+		}
+	}
+	// A key starting a sequence only waits for the rest of it
+	keys := c.bindings()
+	for key := range keys {
+		if !isKeySequence(key) {
+			continue
+		}
+		for i := 1; i < len(key); i++ {
+			if _, ok := keys[key[:i]]; ok {
+				return fmt.Errorf("keys.bindings: %q cannot be used, %q starts with it", key[:i], key)
+			}
+		}
+	}
+	return nil
+}
+
+// isKeySequence reports whether key is several characters typed in a
+// row rather than one key
+func isKeySequence(key string) bool {
+	return !strings.HasPrefix(key, "<") && utf8.RuneCountInString(key) > 1
+}
+
+// keyAction returns the action of a key press. A key that begins a
+// sequence is held back until the next key; a key that does not
+// continue it starts over.
+func (d *Dashboard) keyAction(id string) string {
+	seq := d.keyPrefix + id
+	d.keyPrefix = ""
+	if action, ok := d.keys[seq]; ok {
+		return action
+	}
+	if utf8.RuneCountInString(id) == 1 {
+		for key := range d.keys {
+			if isKeySequence(key) && len(key) > len(seq) && strings.HasPrefix(key, seq) {
+				d.keyPrefix = seq
+				return keyNone
+			}
+		}
+	}
+	if seq != id {
+		return d.keyAction(id)
+	}
+	return keyNone
+}
+
+// handleKey runs the action bound to a key. It reports false when the
+// monitor should exit.
+func (d *Dashboard) handleKey(id string) bool {
+	switch d.keyAction(id) {
+	case keyQuit:
+		return false
+	case keyPower:
+		d.openPowerMenu()
+	case keyReport:
+		d.saveReport()
+	case keyScreen:
+		d.saveScreenshot()
+	case keyHelp:
+		d.openHelp()
+	case keyKill:
+		if d.currentView == viewProcess {
+			d.openSignalMenu()
+		}
+	case keySort:
+		if d.currentView == viewProcess {
+			d.cycleSortMode()
+		} else if d.currentView == viewGroups {
+			d.toggleGrouping()
+		}
+	case keySearch:
+		if d.currentView == viewProcess {
+			d.startSearch()
+		}
+	case keyFollow:
+		if d.currentView == viewLogs {
+			d.toggleLogFollow()
+		} else if d.currentView == viewKernel {
+			d.toggleKernelFilter()
+		}
+	case keySpeedTest:
+		if d.currentView == viewNetwork {
+			d.startSpeedTest()
+		}
+	case keyProcNet:
+		if d.currentView == viewNetwork {
+			d.toggleProcessNet()
+		}
+	case keyRescan:
+		if d.currentView == viewLAN {
+			d.rescanLAN()
+		} else if d.currentView == viewWifi {
+			d.rescanWifi()
+		}
+	case keyMode:
+		if d.currentView == viewWifi {
+			d.confirmModeSwitch()
+		}
+	case keyGovernor:
+		if d.currentView == viewCPUFreq {
+			d.chooseGovernor()
+		}
+	case keyUnit:
+		if d.currentView == viewLogs {
+			d.chooseLogUnit()
+		}
+	case keySelect:
+		d.selectItem()
+	case keyBack:
+		if d.currentView == viewProcess && d.detailPID != 0 {
+			d.closeProcessDetail()
+		} else if d.currentView == viewProcess && d.processFilter != "" {
+			d.processFilter = ""
+			d.UpdateStats()
+			d.Render()
+		}
+	case keyPause:
+		d.togglePause()
+	case keyFaster:
+		if r := d.replay(); r != nil {
+			r.changeSpeed(1)
+			d.redraw()
+		} else {
+			d.changeInterval(-1)
+		}
+	case keySlower:
+		if r := d.replay(); r != nil {
+			r.changeSpeed(-1)
+			d.redraw()
+		} else {
+			d.changeInterval(1)
+		}
+	case keyNextView:
+		d.switchView(1)
+	case keyPrevView:
+		d.switchView(-1)
+	case keyUp:
+		d.moveSelection(-1)
+	case keyDown:
+		d.moveSelection(1)
+	case keyPageUp:
+		d.moveSelection(-selectionPage)
+	case keyPageDown:
+		d.moveSelection(selectionPage)
+	case keyTop:
+		d.moveSelection(-selectionEnd)
+	case keyBottom:
+		d.moveSelection(selectionEnd)
+	}
+	return true
+}
+
+// keysFor lists the keys bound to an action the way the help shows
+// them, e.g. "Up/k"
+func (d *Dashboard) keysFor(action string) string {
+	var names []string
+	for key, a := range d.keys {
+		if a == action {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(key, "<"), ">"))
+		}
+	}
+	// Named keys like Enter first, then the characters
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) > 1 != (len(names[j]) > 1) {
+			return len(names[i]) > 1
+		}
+		return names[i] < names[j]
+	})
+	return strings.Join(names, "/")
+}
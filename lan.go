@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	lanMaxHosts    = 1024 // larger subnets are only swept around our own address
+	lanProbes      = 64   // probes in flight at once
+	lanARPWait     = 2 * time.Second
+	lanLookupLimit = time.Second // per reverse DNS lookup
+)
+
+// ouiFiles are the IEEE vendor lists shipped by ieee-data, arp-scan and
+// nmap, tried in order
+var ouiFiles = []string{
+	"/usr/share/ieee-data/oui.txt",
+	"/usr/share/arp-scan/ieee-oui.txt",
+	"/usr/share/nmap/nmap-mac-prefixes",
+}
+
+// knownOUIs names common vendors when no OUI list is installed
+var knownOUIs = map[string]string{
+	"B827EB": "Raspberry Pi",
+	"DCA632": "Raspberry Pi",
+	"E45F01": "Raspberry Pi",
+	"D83ADD": "Raspberry Pi",
+	"28CDC1": "Raspberry Pi",
+	"2CCF67": "Raspberry Pi",
+}
+
+// dnsmasqLeases is where dnsmasq, the usual DHCP server of a Pi access
+// point, records client hostnames
+const dnsmasqLeases = "/var/lib/misc/dnsmasq.leases"
+
+// LANDevice is a host found on a local subnet
+type LANDevice struct {
+	IP       string
+	MAC      string
+	Vendor   string
+	Hostname string
+	Iface    string
+}
+
+// LANScanner sweeps the local subnets in the background
+type LANScanner struct {
+	mu       sync.Mutex
+	devices  []LANDevice
+	scanning bool
+	at       time.Time // when the last scan finished
+	vendors  map[string]string
+}
+
+// Devices returns the last scan result, whether a scan is running and
+// when the last one finished
+func (s *LANScanner) Devices() ([]LANDevice, bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.devices, s.scanning, s.at
+}
+
+// Start scans in a goroutine. It does nothing while a scan is running.
+func (s *LANScanner) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scanning {
+		return
+	}
+	s.scanning = true
+	if s.vendors == nil {
+		s.vendors = loadOUIs()
+	}
+	vendors := s.vendors
+
+	go func() {
+		devices := scanLAN(vendors)
+		log.Printf("LAN scan found %d devices", len(devices))
+
+		s.mu.Lock()
+		s.devices = devices
+		s.scanning = false
+		s.at = time.Now()
+		s.mu.Unlock()
+	}()
+}
+
+// scanLAN sends a UDP packet to every address of the local subnets so
+// the kernel resolves them with ARP, then reads the neighbour table.
+// This needs no raw sockets and thus no root.
+func scanLAN(vendors map[string]string) []LANDevice {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, lanProbes)
+	for _, subnet := range localSubnets() {
+		for _, ip := range subnetHosts(subnet) {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(ip net.IP) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				// The discard port; the reply does not matter, only the ARP
+				// request the kernel sends before the packet
+				if conn, err := net.Dial("udp4", net.JoinHostPort(ip.String(), "9")); err == nil {
+					conn.Write([]byte{0})
+					conn.Close()
+				}
+			}(ip)
+		}
+	}
+	wg.Wait()
+	time.Sleep(lanARPWait)
+
+	devices := readARPTable()
+	leases := readDHCPLeases()
+	for i := range devices {
+		dev := &devices[i]
+		dev.Vendor = macVendor(vendors, dev.MAC)
+		dev.Hostname = leases[dev.MAC]
+	}
+
+	// Reverse DNS for the rest, in parallel since each may time out
+	for i := range devices {
+		if devices[i].Hostname != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(dev *LANDevice) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), lanLookupLimit)
+			defer cancel()
+			if names, err := net.DefaultResolver.LookupAddr(ctx, dev.IP); err == nil && len(names) > 0 {
+				dev.Hostname = strings.TrimSuffix(names[0], ".")
+			}
+		}(&devices[i])
+	}
+	wg.Wait()
+
+	sort.Slice(devices, func(i, j int) bool {
+		a, b := net.ParseIP(devices[i].IP).To4(), net.ParseIP(devices[j].IP).To4()
+		if a == nil || b == nil {
+			return devices[i].IP < devices[j].IP
+		}
+		return binary.BigEndian.Uint32(a) < binary.BigEndian.Uint32(b)
+	})
+	return devices
+}
+
+// localSubnets returns the IPv4 networks of the interfaces that are up
+func localSubnets() []*net.IPNet {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var subnets []*net.IPNet
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				subnets = append(subnets, ipNet)
+			}
+		}
+	}
+	return subnets
+}
+
+// subnetHosts lists the host addresses of subnet without our own. Of
+// subnets with more than lanMaxHosts addresses only the block of that
+// size around our address is returned.
+func subnetHosts(subnet *net.IPNet) []net.IP {
+	ones, bits := subnet.Mask.Size()
+	if bits != 32 || ones >= 31 {
+		return nil
+	}
+	self := binary.BigEndian.Uint32(subnet.IP.To4())
+	mask := binary.BigEndian.Uint32(net.IP(subnet.Mask).To4())
+	first, last := self&mask+1, self|^mask-1
+	if last-first+1 > lanMaxHosts {
+		first = self&^(lanMaxHosts-1) + 1
+		last = first + lanMaxHosts - 3
+	}
+
+	hosts := make([]net.IP, 0, last-first+1)
+	for n := first; n <= last; n++ {
+		if n == self {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, n)
+		hosts = append(hosts, ip)
+	}
+	return hosts
+}
+
+// readARPTable returns the resolved entries of /proc/net/arp
+func readARPTable() []LANDevice {
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var devices []LANDevice
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// IP address  HW type  Flags  HW address  Mask  Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[2] == "0x0" || fields[3] == "00:00:00:00:00:00" {
+			continue
+		}
+		devices = append(devices, LANDevice{IP: fields[0], MAC: strings.ToLower(fields[3]), Iface: fields[5]})
+	}
+	return devices
+}
+
+// readDHCPLeases maps MAC addresses to the hostnames in the dnsmasq
+// lease file
+func readDHCPLeases() map[string]string {
+	leases := make(map[string]string)
+	data, err := os.ReadFile(dnsmasqLeases)
+	if err != nil {
+		return leases
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		// expiry MAC IP hostname client-id
+		fields := strings.Fields(line)
+		if len(fields) >= 4 && fields[3] != "*" {
+			leases[strings.ToLower(fields[1])] = fields[3]
+		}
+	}
+	return leases
+}
+
+// loadOUIs reads the first vendor list found, keyed by the upper case
+// hex prefix like "B827EB"
+func loadOUIs() map[string]string {
+	for _, path := range ouiFiles {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		vendors := make(map[string]string)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			// ieee-data: "B8-27-EB   (hex)\t\tRaspberry Pi Foundation",
+			// arp-scan and nmap: "B827EB\tRaspberry Pi Foundation"
+			if prefix, rest, ok := strings.Cut(line, "(hex)"); ok {
+				vendors[strings.ReplaceAll(strings.TrimSpace(prefix), "-", "")] = strings.TrimSpace(rest)
+				continue
+			}
+			fields := strings.SplitN(line, "\t", 2)
+			if len(fields) == 2 && len(fields[0]) == 6 {
+				vendors[strings.ToUpper(fields[0])] = strings.TrimSpace(fields[1])
+			}
+		}
+		f.Close()
+		if len(vendors) > 0 {
+			return vendors
+		}
+	}
+	return knownOUIs
+}
+
+// macVendor looks up the vendor of mac. Locally administered addresses,
+// e.g. the randomized MACs of phones, have no vendor.
+func macVendor(vendors map[string]string, mac string) string {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) < 3 {
+		return ""
+	}
+	if hw[0]&0x02 != 0 {
+		return "(random)"
+	}
+	return vendors[fmt.Sprintf("%02X%02X%02X", hw[0], hw[1], hw[2])]
+}
+
+// rescanLAN starts a scan from the LAN view
+func (d *Dashboard) rescanLAN() {
+	d.lanScanner.Start()
+	d.UpdateStats()
+	d.Render()
+}
+
+func (d *Dashboard) updateLANView(stats SystemStats) {
+	devices, scanning, at := d.lanScanner.Devices()
+	if at.IsZero() && !scanning {
+		// First visit
+		d.lanScanner.Start()
+		scanning = true
+	}
+	if !d.reuseLists {
+		d.lanList = devices
+	}
+
+	state := ""
+	switch {
+	case scanning:
+		state = "scanning"
+	case !at.IsZero():
+		state = at.Format("15:04")
+	}
+
+	total := len(d.lanList)
+	if total == 0 {
+		d.mainList.Title = d.viewTitle("0/0 " + state)
+		d.mainList.Rows = []string{"", "No devices found yet", "", "[r/X: scan again](fg:yellow)"}
+		return
+	}
+	if d.selectedLAN >= total {
+		d.selectedLAN = total - 1
+	}
+	d.mainList.Title = d.viewTitle(fmt.Sprintf("%d/%d %s", d.selectedLAN+1, total, state))
+
+	rows := []string{"[IP              Name](fg:cyan)"}
+
+	// Leave room for the detail rows of the selected device
+	visibleHeight := 20
+	startIdx := d.selectedLAN - visibleHeight/2
+	if startIdx > total-visibleHeight {
+		startIdx = total - visibleHeight
+	}
+	if startIdx < 0 {
+		startIdx = 0
+	}
+	endIdx := startIdx + visibleHeight
+	if endIdx > total {
+		endIdx = total
+	}
+
+	for i := startIdx; i < endIdx; i++ {
+		dev := d.lanList[i]
+		name := dev.Hostname
+		if name == "" {
+			name = dev.Vendor
+		}
+		line := fmt.Sprintf("%-15s %-11s", dev.IP, truncateString(name, 11))
+		if i == d.selectedLAN {
+			rows = append(rows, fmt.Sprintf("[%s](bg:white,fg:black)", line))
+		} else {
+			rows = append(rows, line)
+		}
+	}
+
+	dev := d.lanList[d.selectedLAN]
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	rows = append(rows,
+		"",
+		"MAC:    "+dev.MAC,
+		"Vendor: "+truncateString(orDash(dev.Vendor), 19),
+		"Host:   "+truncateString(orDash(dev.Hostname), 19),
+		"Iface:  "+dev.Iface,
+		"[r/X: scan again](fg:yellow)",
+	)
+	d.mainList.Rows = rows
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// Wide terminals, e.g. over SSH, show an overview of gauges, network
+// sparklines and a process table next to the view. The view keeps the
+// 30 column width it is written for.
+const (
+	viewColumnWidth   = 30
+	overviewMinWidth  = 40 // columns needed beside the view
+	overviewMinHeight = 16
+)
+
+// Overview is what the widgets next to the view show
+type Overview struct {
+	Gauges    []OverviewGauge
+	SentRate  float64   // bytes per second
+	RecvRate  float64   // bytes per second
+	Sent      []float64 // rate history, oldest first
+	Recv      []float64
+	Processes []ProcessInfo // busiest first
+}
+
+// OverviewGauge is one usage gauge of the overview
+type OverviewGauge struct {
+	Title   string
+	Percent float64
+	Label   string
+	Color   string // colour name, red while an alert on the metric fires
+}
+
+// overview collects the data of the overview widgets
+func (d *Dashboard) overview(stats SystemStats) *Overview {
+	avgCPU := calculateAverage(stats.CPUPercent)
+	return &Overview{
+		Gauges: []OverviewGauge{
+			{"CPU", avgCPU, fmt.Sprintf("%.1f%%  load %.2f  %s", avgCPU, stats.Load.Load1, formatTemperature(stats.Temperature)),
+				d.alertColor("cpu", "cyan")},
+			{"Memory", stats.MemPercent, fmt.Sprintf("%.1f%%  %s / %s", stats.MemPercent, formatBytes(stats.MemUsed), formatBytes(stats.MemTotal)),
+				d.alertColor("mem", "yellow")},
+			{"Disk " + d.config.DiskMount, stats.DiskPercent, fmt.Sprintf("%.1f%%", stats.DiskPercent),
+				d.alertColor("disk", "magenta")},
+		},
+		SentRate:  d.netSentRate,
+		RecvRate:  d.netRecvRate,
+		Sent:      d.sentHistory.Values(),
+		Recv:      d.recvHistory.Values(),
+		Processes: sortProcesses(stats.AllProcesses, sortByCPU),
+	}
+}
+
+// overviewWidgets draws an Overview with termui widgets in a grid
+type overviewWidgets struct {
+	grid    *ui.Grid
+	gauges  []*widgets.Gauge
+	sent    *widgets.Sparkline
+	recv    *widgets.Sparkline
+	network *widgets.SparklineGroup
+	table   *widgets.Table
+}
+
+// newOverviewWidgets lays out the widgets in rect: a row of gauges, the
+// network sparklines below and the process table in the rest
+func newOverviewWidgets(rect image.Rectangle) *overviewWidgets {
+	theme := currentTheme
+	o := &overviewWidgets{grid: ui.NewGrid(), table: widgets.NewTable()}
+
+	gaugeCols := make([]interface{}, 3)
+	for i := range gaugeCols {
+		g := widgets.NewGauge()
+		g.BorderStyle = ui.NewStyle(theme.term(theme.Border))
+		g.TitleStyle = ui.NewStyle(theme.term(theme.Title))
+		g.LabelStyle = ui.NewStyle(theme.term(theme.Text))
+		o.gauges = append(o.gauges, g)
+		gaugeCols[i] = ui.NewCol(1.0/3, g)
+	}
+
+	o.sent, o.recv = widgets.NewSparkline(), widgets.NewSparkline()
+	o.sent.LineColor, o.recv.LineColor = theme.term("yellow"), theme.term("green")
+	o.sent.TitleStyle = ui.NewStyle(theme.term(theme.Text))
+	o.recv.TitleStyle = ui.NewStyle(theme.term(theme.Text))
+	o.network = widgets.NewSparklineGroup(o.sent, o.recv)
+	o.network.Title = "Network"
+	o.network.BorderStyle = ui.NewStyle(theme.term(theme.Border))
+	o.network.TitleStyle = ui.NewStyle(theme.term(theme.Title))
+
+	o.table.RowSeparator = false
+	o.table.TextStyle = ui.NewStyle(theme.term(theme.Text))
+	o.table.RowStyles[0] = ui.NewStyle(theme.term("cyan"))
+	o.table.BorderStyle = ui.NewStyle(theme.term(theme.Border))
+	o.table.TitleStyle = ui.NewStyle(theme.term(theme.Title))
+
+	// Gauges need three lines, the sparklines get a third of the rest
+	height := float64(rect.Dy())
+	gaugeRatio := 3 / height
+	networkRatio := (1 - gaugeRatio) / 3
+	o.grid.SetRect(rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y)
+	o.grid.Set(
+		ui.NewRow(gaugeRatio, gaugeCols...),
+		ui.NewRow(networkRatio, ui.NewCol(1, o.network)),
+		ui.NewRow(1-gaugeRatio-networkRatio, ui.NewCol(1, o.table)),
+	)
+
+	// The grid sizes the widgets while drawing; draw once off screen so
+	// update knows how much fits
+	o.table.Rows = [][]string{processTableHeader}
+	o.grid.Draw(ui.NewBuffer(o.grid.GetRect()))
+	return o
+}
+
+var processTableHeader = []string{"PID", "Name", "CPU%", "MEM%", "User"}
+
+// update copies the data into the widgets
+func (o *overviewWidgets) update(ov *Overview) {
+	for i, g := range o.gauges {
+		if i >= len(ov.Gauges) {
+			break
+		}
+		gauge := ov.Gauges[i]
+		g.Title = gauge.Title
+		g.Percent = int(gauge.Percent)
+		g.Label = gauge.Label
+		g.BarColor = currentTheme.term(gauge.Color)
+	}
+
+	// Both lines share one scale so they can be compared
+	width := o.network.Inner.Dx()
+	sent, recv := lastValues(ov.Sent, width), lastValues(ov.Recv, width)
+	peak := 1.0
+	for _, v := range append(append([]float64(nil), sent...), recv...) {
+		if v > peak {
+			peak = v
+		}
+	}
+	o.sent.Data, o.sent.MaxVal, o.sent.Title = sent, peak, "Up   "+formatRate(ov.SentRate)
+	o.recv.Data, o.recv.MaxVal, o.recv.Title = recv, peak, "Down "+formatRate(ov.RecvRate)
+
+	// PID, CPU%, MEM% and user get fixed columns, the name the rest
+	inner := o.table.Inner.Dx()
+	nameWidth := inner - 7 - 7 - 7 - 10
+	if nameWidth < 8 {
+		nameWidth = 8
+	}
+	o.table.ColumnWidths = []int{7, nameWidth, 7, 7, 10}
+	o.table.Rows = [][]string{processTableHeader}
+	for _, p := range ov.Processes {
+		if len(o.table.Rows) >= o.table.Inner.Dy() {
+			break
+		}
+		name := truncateString(p.Name, nameWidth-1)
+		if color := processStateColor(p.Status); color != "" {
+			name = fmt.Sprintf("[%s](fg:%s)", name, color)
+		}
+		o.table.Rows = append(o.table.Rows, []string{
+			strconv.Itoa(int(p.PID)), name,
+			fmt.Sprintf("%.1f", p.CPU), fmt.Sprintf("%.1f", p.Memory),
+			truncateString(p.Username, 9),
+		})
+	}
+	o.table.Title = fmt.Sprintf("Processes (%d)", len(ov.Processes))
+}
+
+// lastValues returns the last n values
+func lastValues(values []float64, n int) []float64 {
+	if n < 0 {
+		n = 0
+	}
+	if len(values) > n {
+		return values[len(values)-n:]
+	}
+	return values
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	ui "github.com/gizak/termui/v3"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// lcdPanel is a pixel display the dashboard image is copied to
+type lcdPanel interface {
+	Bounds() image.Rectangle
+	Draw(img *image.RGBA) error
+	Close() error
+}
+
+// lcdPalette maps the termui colors used in the views to panel colors,
+// replaced by applyTheme
+var lcdPalette = themes[themeDefault].palette()
+
+func lcdColor(c ui.Color, fallback color.RGBA) color.RGBA {
+	if rgba, ok := lcdPalette[c]; ok {
+		return rgba
+	}
+	return fallback
+}
+
+// lcdRenderer draws the list and menu of the dashboard as pixels in the
+// same character grid termui uses, so the views need no changes
+type lcdRenderer struct {
+	panel    lcdPanel
+	face     font.Face
+	img      *image.RGBA
+	cellW    int
+	cellH    int
+	baseline int // from the top of a cell
+}
+
+func newLCDRenderer(cfg DisplayConfig) (*lcdRenderer, error) {
+	var panel lcdPanel
+	var err error
+	if cfg.spiPanel() {
+		panel, err = openSPIPanel(cfg)
+	} else {
+		panel, err = openFramebuffer(cfg.Device)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newPanelRenderer(panel, cfg.FontSize)
+	if err != nil {
+		panel.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// newPanelRenderer draws on panel in cells of the Go Mono font at
+// fontSize pixels
+func newPanelRenderer(panel lcdPanel, fontSize float64) (*lcdRenderer, error) {
+	face, err := monoFace(fontSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lines are as tall as the font size so a 240px panel holds the
+	// 30 rows the views are laid out for
+	advance, _ := face.GlyphAdvance('0')
+	metrics := face.Metrics()
+	cellH := int(fontSize + 0.5)
+	return &lcdRenderer{
+		panel:    panel,
+		face:     face,
+		img:      image.NewRGBA(panel.Bounds()),
+		cellW:    advance.Ceil(),
+		cellH:    cellH,
+		baseline: (cellH + metrics.Ascent.Ceil() - metrics.Descent.Ceil()) / 2,
+	}, nil
+}
+
+// monoFace returns the built-in Go Mono font at size pixels
+func monoFace(size float64) (font.Face, error) {
+	ttf, err := opentype.Parse(gomono.TTF)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(ttf, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// Render draws the list with its title and, when open, the menu on top
+func (r *lcdRenderer) Render(frame Frame) error {
+	draw.Draw(r.img, r.img.Bounds(), image.NewUniform(currentTheme.rgb("black")), image.Point{}, draw.Src)
+
+	cols := r.img.Bounds().Dx() / r.cellW
+	lines := r.img.Bounds().Dy() / r.cellH
+	if frame.Status != "" {
+		lines--
+		r.drawText(0, lines, frame.Status, cols, currentTheme.term(currentTheme.Text))
+	}
+	r.drawBox(image.Rect(0, 0, cols, lines), frame.Title, frame.Rows, currentTheme.term(currentTheme.Border))
+
+	if menu := frame.Menu; menu != nil {
+		menuRows := menu.rows()
+		width := cols - 4
+		height := len(menuRows) + 2
+		if height > lines {
+			height = lines
+		}
+		x := (cols - width) / 2
+		y := (lines - height) / 2
+		r.drawBox(image.Rect(x, y, x+width, y+height), menu.title, menuRows, currentTheme.term(currentTheme.Menu))
+	}
+	return r.panel.Draw(r.img)
+}
+
+// drawBox draws a bordered list in the character cells of box
+func (r *lcdRenderer) drawBox(box image.Rectangle, title string, rows []string, border ui.Color) {
+	px := image.Rect(box.Min.X*r.cellW, box.Min.Y*r.cellH, box.Max.X*r.cellW, box.Max.Y*r.cellH)
+	draw.Draw(r.img, px, image.NewUniform(currentTheme.rgb("black")), image.Point{}, draw.Src)
+
+	// A one pixel frame through the middle of the outer cells
+	edge := image.NewUniform(lcdPalette[border])
+	left, right := px.Min.X+r.cellW/2, px.Max.X-r.cellW/2
+	top, bottom := px.Min.Y+r.cellH/2, px.Max.Y-r.cellH/2
+	draw.Draw(r.img, image.Rect(left, top, right+1, top+1), edge, image.Point{}, draw.Src)
+	draw.Draw(r.img, image.Rect(left, bottom, right+1, bottom+1), edge, image.Point{}, draw.Src)
+	draw.Draw(r.img, image.Rect(left, top, left+1, bottom+1), edge, image.Point{}, draw.Src)
+	draw.Draw(r.img, image.Rect(right, top, right+1, bottom+1), edge, image.Point{}, draw.Src)
+
+	width := box.Dx() - 2
+	if title != "" {
+		r.drawText(box.Min.X+1, box.Min.Y, title, width, currentTheme.term(currentTheme.Title))
+	}
+	for i, row := range rows {
+		if i >= box.Dy()-2 {
+			break
+		}
+		r.drawText(box.Min.X+1, box.Min.Y+1+i, row, width, currentTheme.term(currentTheme.Text))
+	}
+}
+
+// drawText draws a row with termui style markup starting at cell col,
+// line, clipped to width cells
+func (r *lcdRenderer) drawText(col, line int, text string, width int, fg ui.Color) {
+	d := font.Drawer{Dst: r.img, Face: r.face}
+	for i, cell := range ui.ParseStyles(text, ui.NewStyle(fg)) {
+		if i >= width {
+			break
+		}
+		x := (col + i) * r.cellW
+		y := line * r.cellH
+		if cell.Style.Bg != ui.ColorClear {
+			bg := image.NewUniform(lcdColor(cell.Style.Bg, currentTheme.rgb("black")))
+			draw.Draw(r.img, image.Rect(x, y, x+r.cellW, y+r.cellH), bg, image.Point{}, draw.Src)
+		}
+		d.Src = image.NewUniform(lcdColor(cell.Style.Fg, lcdPalette[fg]))
+		d.Dot = fixed.P(x, y+r.baseline)
+		d.DrawString(string(cell.Rune))
+	}
+}
+
+// Events returns nil; a panel has no keyboard
+func (r *lcdRenderer) Events() <-chan ui.Event {
+	return nil
+}
+
+// Clear blanks the panel, e.g. before exiting
+func (r *lcdRenderer) Clear() error {
+	draw.Draw(r.img, r.img.Bounds(), image.NewUniform(currentTheme.rgb("black")), image.Point{}, draw.Src)
+	return r.panel.Draw(r.img)
+}
+
+// Blank clears the panel and switches its backlight off, or back on
+func (r *lcdRenderer) Blank(blank bool) error {
+	if blank {
+		if err := r.Clear(); err != nil {
+			return err
+		}
+	}
+	if p, ok := r.panel.(backlightPanel); ok {
+		return p.SetBacklight(!blank)
+	}
+	return nil
+}
+
+func (r *lcdRenderer) Close() error {
+	r.Clear()
+	return r.panel.Close()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"log/syslog"
+	"net"
+	"os"
+	"strings"
+)
+
+// Outputs of the monitor's own log, chosen by log_output
+const (
+	logOutputFile     = "file"
+	logOutputSyslog   = "syslog"
+	logOutputJournald = "journald"
+
+	logIdentifier = "raspi-monitor"
+	journalSocket = "/run/systemd/journal/socket"
+)
+
+// logOutputs lists the log outputs for messages and flag help
+var logOutputs = []string{logOutputFile, logOutputSyslog, logOutputJournald}
+
+// setupLogging sends the log to the file at log_file, the local syslog
+// daemon or the systemd journal. The caller closes what it returns.
+func setupLogging(cfg *Config) (io.Closer, error) {
+	var out io.WriteCloser
+	switch cfg.LogOutput {
+	case logOutputSyslog:
+		w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, logIdentifier)
+		if err != nil {
+			return nil, fmt.Errorf("connect to syslog: %w", err)
+		}
+		out = &syslogWriter{w: w}
+	case logOutputJournald:
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+		if err != nil {
+			return nil, fmt.Errorf("connect to journald: %w", err)
+		}
+		out = &journalWriter{conn: conn}
+	default:
+		f, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("open log file: %w", err)
+		}
+		log.SetOutput(f)
+		return f, nil
+	}
+
+	// syslog and the journal stamp the messages themselves
+	log.SetFlags(0)
+	log.SetOutput(out)
+	return out, nil
+}
+
+// logPriority guesses the syslog priority of a message from the way
+// the monitor words them
+func logPriority(msg string) syslog.Priority {
+	switch {
+	case strings.HasPrefix(msg, "Failed"):
+		return syslog.LOG_ERR
+	case strings.HasPrefix(msg, "Warning"):
+		return syslog.LOG_WARNING
+	}
+	return syslog.LOG_INFO
+}
+
+// syslogWriter logs each message with the priority it reads as
+type syslogWriter struct {
+	w *syslog.Writer
+}
+
+func (s *syslogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	var err error
+	switch logPriority(msg) {
+	case syslog.LOG_ERR:
+		err = s.w.Err(msg)
+	case syslog.LOG_WARNING:
+		err = s.w.Warning(msg)
+	default:
+		err = s.w.Info(msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *syslogWriter) Close() error {
+	return s.w.Close()
+}
+
+// journalWriter sends each message to journald over its native
+// protocol, so journalctl shows the priority and -t raspi-monitor
+// finds them
+type journalWriter struct {
+	conn *net.UnixConn
+}
+
+func (j *journalWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "PRIORITY=%d\nSYSLOG_IDENTIFIER=%s\nSYSLOG_PID=%d\n", logPriority(msg), logIdentifier, os.Getpid())
+	if strings.Contains(msg, "\n") {
+		// Values with line breaks are sent with their length instead
+		b.WriteString("MESSAGE\n")
+		binary.Write(&b, binary.LittleEndian, uint64(len(msg)))
+		b.WriteString(msg)
+		b.WriteByte('\n')
+	} else {
+		fmt.Fprintf(&b, "MESSAGE=%s\n", msg)
+	}
+	if _, err := j.conn.Write(b.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (j *journalWriter) Close() error {
+	return j.conn.Close()
+}
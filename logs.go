@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// logEntries is how many recent entries the logs view reads
+	logEntries = 200
+	// journalctlTimeout bounds one journalctl run
+	journalctlTimeout = 5 * time.Second
+	// syslogTail is how much of the end of the syslog file is read
+	syslogTail = 64 * 1024
+	// logUnitChoices is how many units the filter menu offers
+	logUnitChoices = 8
+)
+
+// syslogPath is read when journalctl is not available
+const syslogPath = "/var/log/syslog"
+
+// LogEntry is one line of the system log
+type LogEntry struct {
+	Time     time.Time
+	Unit     string // systemd unit, or the syslog identifier
+	Priority int    // syslog priority, 0 (emerg) to 7 (debug)
+	Message  string
+}
+
+// journalEntry is the part of "journalctl -o json" we use. MESSAGE is
+// an array of bytes instead of a string when it is not valid UTF-8.
+type journalEntry struct {
+	Timestamp  string          `json:"__REALTIME_TIMESTAMP"`
+	Priority   string          `json:"PRIORITY"`
+	Unit       string          `json:"_SYSTEMD_UNIT"`
+	Identifier string          `json:"SYSLOG_IDENTIFIER"`
+	Message    json.RawMessage `json:"MESSAGE"`
+}
+
+// getLogEntries returns the most recent log entries, oldest first. unit
+// limits them to one systemd unit or syslog identifier.
+func getLogEntries(unit string) ([]LogEntry, error) {
+	var filter []string
+	if strings.HasSuffix(unit, ".service") {
+		filter = []string{"-u", unit}
+	} else if unit != "" {
+		filter = []string{"-t", unit}
+	}
+	entries, err := readJournal(filter...)
+	if err == nil {
+		return entries, nil
+	}
+	if _, statErr := os.Stat(syslogPath); statErr != nil {
+		return nil, err
+	}
+	return readSyslog(unit)
+}
+
+// readJournal returns the last logEntries journal entries matching the
+// journalctl filter arguments
+func readJournal(filter ...string) ([]LogEntry, error) {
+	args := append([]string{"-o", "json", "-n", strconv.Itoa(logEntries), "--no-pager"}, filter...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), journalctlTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "journalctl", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("journalctl: %w", err)
+	}
+
+	var entries []LogEntry
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var je journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &je); err != nil {
+			continue
+		}
+		e := LogEntry{Unit: je.Unit, Priority: 6}
+		if e.Unit == "" {
+			e.Unit = je.Identifier
+		}
+		if usec, err := strconv.ParseInt(je.Timestamp, 10, 64); err == nil {
+			e.Time = time.UnixMicro(usec)
+		}
+		if p, err := strconv.Atoi(je.Priority); err == nil {
+			e.Priority = p
+		}
+		if err := json.Unmarshal(je.Message, &e.Message); err != nil {
+			e.Message = "(binary message)"
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// syslogLine matches "<time> <host> <ident>[<pid>]: <message>"
+var syslogLine = regexp.MustCompile(`^(\w{3} [ \d]\d \d\d:\d\d:\d\d|\S+) \S+ ([^:\[ ]+)(?:\[\d+\])?: (.*)$`)
+
+// readSyslog parses the end of the syslog file written by rsyslog. It
+// has no priorities, so they are guessed from the message.
+func readSyslog(unit string) ([]LogEntry, error) {
+	f, err := os.Open(syslogPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err == nil && info.Size() > syslogTail {
+		f.Seek(-syslogTail, io.SeekEnd)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(unit, ".service")
+	var entries []LogEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		m := syslogLine.FindStringSubmatch(line)
+		if m == nil || (name != "" && m[2] != name) {
+			continue
+		}
+		e := LogEntry{Unit: m[2], Message: m[3], Priority: guessPriority(m[3])}
+		if t, err := time.Parse(time.RFC3339Nano, m[1]); err == nil {
+			e.Time = t
+		} else if t, err := time.ParseInLocation(time.Stamp, m[1], time.Local); err == nil {
+			e.Time = t.AddDate(time.Now().Year(), 0, 0)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) > logEntries {
+		entries = entries[len(entries)-logEntries:]
+	}
+	return entries, nil
+}
+
+func guessPriority(msg string) int {
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.Contains(lower, "error"), strings.Contains(lower, "fail"), strings.Contains(lower, "fatal"):
+		return 3
+	case strings.Contains(lower, "warn"):
+		return 4
+	}
+	return 6
+}
+
+// logColor colors errors red and warnings yellow
+func logColor(priority int) string {
+	switch {
+	case priority <= 3:
+		return "red"
+	case priority == 4:
+		return "yellow"
+	case priority == 5:
+		return "green"
+	}
+	return "white"
+}
+
+// logLines formats the entries as wrapped, colored display rows
+func logLines(entries []LogEntry) []string {
+	var lines []string
+	for _, e := range entries {
+		unit := strings.TrimSuffix(e.Unit, ".service")
+		text := fmt.Sprintf("%s %s: %s", e.Time.Format("15:04"), unit, e.Message)
+		text = strings.NewReplacer("[", "(", "]", ")").Replace(text)
+		for _, line := range wrapString(text, 27, 6) {
+			lines = append(lines, fmt.Sprintf("[%s](fg:%s)", line, logColor(e.Priority)))
+		}
+	}
+	return lines
+}
+
+func (d *Dashboard) updateLogsView(stats SystemStats) {
+	// A paused view keeps its entries so they can be read while scrolling
+	if !d.logPaused || d.logEntries == nil {
+		entries, err := getLogEntries(d.logUnit)
+		if err != nil {
+			d.logEntries = nil
+			d.mainList.Title = d.viewTitle("")
+			d.mainList.Rows = []string{"", "No system log available", "", truncateString(err.Error(), 27)}
+			return
+		}
+		d.logEntries = entries
+	}
+
+	mode := "follow"
+	if d.logPaused {
+		mode = "paused"
+	}
+	filter := "all"
+	if d.logUnit != "" {
+		filter = truncateString(strings.TrimSuffix(d.logUnit, ".service"), 10)
+	}
+	d.mainList.Title = d.viewTitle(fmt.Sprintf("%s %s", filter, mode))
+
+	lines := logLines(d.logEntries)
+	if len(lines) == 0 {
+		d.mainList.Rows = []string{"", "No log entries"}
+		return
+	}
+
+	d.mainList.Rows, d.logScroll = tailWindow(lines, d.logScroll, 28)
+}
+
+// tailWindow returns the height lines that end scroll lines above the
+// last one, along with scroll clamped to the available lines
+func tailWindow(lines []string, scroll, height int) ([]string, int) {
+	maxScroll := len(lines) - height
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	if scroll > maxScroll {
+		scroll = maxScroll
+	}
+	end := len(lines) - scroll
+	start := end - height
+	if start < 0 {
+		start = 0
+	}
+	return lines[start:end], scroll
+}
+
+// scrollLogs moves the logs view delta lines down. Scrolling up pauses
+// the view so new entries do not move the text away.
+func (d *Dashboard) scrollLogs(delta int) {
+	d.logScroll -= delta
+	if d.logScroll < 0 {
+		d.logScroll = 0
+	}
+	if d.logScroll > 0 {
+		d.logPaused = true
+	}
+	d.UpdateStats()
+	d.Render()
+}
+
+// toggleLogFollow switches between following new entries and a paused view
+func (d *Dashboard) toggleLogFollow() {
+	d.logPaused = !d.logPaused
+	if !d.logPaused {
+		d.logScroll = 0
+	}
+	d.UpdateStats()
+	d.Render()
+}
+
+// chooseLogUnit opens a menu with the units seen most in the log
+func (d *Dashboard) chooseLogUnit() {
+	counts := make(map[string]int)
+	for _, e := range d.logEntries {
+		if e.Unit != "" {
+			counts[e.Unit]++
+		}
+	}
+	units := make([]string, 0, len(counts))
+	for unit := range counts {
+		units = append(units, unit)
+	}
+	sort.Slice(units, func(i, j int) bool {
+		if counts[units[i]] != counts[units[j]] {
+			return counts[units[i]] > counts[units[j]]
+		}
+		return units[i] < units[j]
+	})
+	if len(units) > logUnitChoices {
+		units = units[:logUnitChoices]
+	}
+
+	options := []menuOption{{label: "All units", action: func(d *Dashboard) { d.setLogUnit("") }}}
+	for _, unit := range units {
+		unit := unit
+		options = append(options, menuOption{
+			label:  truncateString(strings.TrimSuffix(unit, ".service"), 22),
+			action: func(d *Dashboard) { d.setLogUnit(unit) },
+		})
+	}
+	d.openMenu(&Menu{title: "Filter unit", options: options})
+}
+
+func (d *Dashboard) setLogUnit(unit string) {
+	d.logUnit = unit
+	d.logEntries = nil
+	d.logScroll = 0
+	d.UpdateStats()
+	d.Render()
+}
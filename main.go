@@ -1,33 +1,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/exec"
+	"os/signal"
 	"runtime"
-	"sort"
-	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/disk"
-	"github.com/shirou/gopsutil/v3/host"
-	"github.com/shirou/gopsutil/v3/mem"
-	gopsnet "github.com/shirou/gopsutil/v3/net"
-	"github.com/shirou/gopsutil/v3/process"
-	"net"
+	"github.com/warthog618/go-gpiocdev"
+
+	"raspi-monitor/pkg/collector"
 )
 
 const (
 	updateInterval = time.Second
-	historySize    = 20
+	historySize    = 180 // samples kept per metric, 3 minutes at the default interval
+	topProcesses   = 3   // busiest processes listed at the bottom of the System view
+	sparkWidth     = 22  // samples drawn per sparkline row
 	
-	// GPIO Pin definitions for buttons (BCM numbering)
-	// Based on your hardware configuration
+	// Default GPIO pin definitions for buttons (BCM numbering)
+	// Override them with the gpio.pins section of the config file
 	buttonUp     = 3   // KEY1 - Up
 	buttonDown   = 5   // KEY2 - Down
 	buttonLeft   = 6   // KEY3 - Left
@@ -43,158 +43,619 @@ const (
 	buttonCenter = 23  // KEY13 - Center/Menu
 )
 
-type ProcessInfo struct {
-	PID      int32
-	Name     string
-	CPU      float64
-	Memory   float64
-	Status   string
-	Username string
+// View indexes, in the order the views are cycled
+const (
+	viewSystem = iota
+	viewProcess
+	viewGroups
+	viewNetwork
+	viewLAN
+	viewWifi
+	viewMemory
+	viewCPUFreq
+	viewGPU
+	viewSensors
+	viewHardware
+	viewUSB
+	viewGPIOPins
+	viewControl
+	viewConnections
+	viewFirewall
+	viewDisk
+	viewStorage
+	viewHealth
+	viewServices
+	viewContainers
+	viewLogs
+	viewKernel
+	viewCluster
+	// viewCustom is the first view from the views section of the config
+	viewCustom
+)
+
+// view describes one page of the dashboard
+type view struct {
+	name  string // used by default_view and --view
+	title string
 }
 
-type SystemStats struct {
-	CPUPercent   []float64
-	MemPercent   float64
-	MemUsed      uint64
-	MemTotal     uint64
-	DiskPercent  float64
-	Temperature  float64
-	Uptime       uint64
-	NetSent      uint64
-	NetRecv      uint64
-	ProcessCount uint64
-	AllProcesses []ProcessInfo
-	IPAddress    string
-	APMode       string
+var views = []view{
+	viewSystem:      {"system", "System"},
+	viewProcess:     {"process", "Process"},
+	viewGroups:      {"groups", "Groups"},
+	viewNetwork:     {"network", "Network"},
+	viewLAN:         {"lan", "LAN"},
+	viewWifi:        {"wifi", "Wi-Fi"},
+	viewMemory:      {"memory", "Memory"},
+	viewCPUFreq:     {"cpufreq", "CPU Freq"},
+	viewGPU:         {"gpu", "GPU"},
+	viewSensors:     {"sensors", "Sensors"},
+	viewHardware:    {"hardware", "Hardware"},
+	viewUSB:         {"usb", "USB"},
+	viewGPIOPins:    {"gpio", "GPIO"},
+	viewControl:     {"control", "Control"},
+	viewConnections: {"connections", "Conns"},
+	viewFirewall:    {"firewall", "Firewall"},
+	viewDisk:        {"disk", "Disk"},
+	viewStorage:     {"storage", "Storage"},
+	viewHealth:      {"health", "Health"},
+	viewServices:    {"services", "Services"},
+	viewContainers:  {"containers", "Docker"},
+	viewLogs:        {"logs", "Logs"},
+	viewKernel:      {"dmesg", "dmesg"},
+	viewCluster:     {"cluster", "Cluster"},
 }
 
+// SystemStats and ProcessInfo come from the collector package; the
+// aliases keep the views short
+type (
+	SystemStats = collector.SystemStats
+	ProcessInfo = collector.ProcessInfo
+)
+
 type Dashboard struct {
+	config          *Config
+	collector       statsSource      // this Pi's collector, or another host's stats
+	statsUpdates    chan SystemStats // samples taken by runCollector
+	interval        time.Duration    // current update interval, changed with +/-
+	intervalChanges chan time.Duration
+	commandResults  chan commandResult // button commands that finished
+	dockerResults   chan containerResult // container actions that finished
 	mainList        *widgets.List
-	helpParagraph   *widgets.Paragraph
-	currentView     int // 0: System info, 1: Process, 2: Network
+	menu            *Menu // open modal menu, nil when none
+	currentView     int   // index into views
 	selectedProcess int
+	processList     []ProcessInfo // processes as last shown in the process view
+	sortMode        int           // one of the sortBy constants
+	processFilter   string        // name/user filter typed after "/"
+	searching       bool          // keys go to processFilter while set
+	detailPID       int32         // process shown on the detail page, 0 for the list
+	processTop      int           // list row of the first process shown, for clicks
+	processFirst    int           // index into processList of that process
 	prevNetSent     uint64
 	prevNetRecv     uint64
-	
+	netSentRate     float64 // bytes per second
+	netRecvRate     float64 // bytes per second
+	prevIfaces      map[string]InterfaceStats
+	ifaceRates      map[string]ifaceRate
+	ifaceList       []InterfaceStats // interfaces as last shown in the network view
+	selectedIface   int
+	connList        []ConnectionInfo // sockets as last shown in the connections view
+	selectedConn    int
+	fsList          []FilesystemInfo // filesystems as last shown in the disk view
+	selectedFS      int
+	prevDiskIO      map[string]DiskIOStats
+	diskRates       map[string]diskRate
+	diskHistory     map[string]diskIOHistory // throughput per block device
+	healthCache     map[string]DeviceHealth
+	healthAt        time.Time       // when healthCache was read
+	writeWarned     map[string]bool // devices above sd_write_warn_gb
+	serviceList     []ServiceInfo   // units as last shown in the services view
+	selectedService int
+	lastSample      time.Time
+
+	// Firewall view, read once per firewallRefresh
+	firewall       FirewallStatus
+	firewallAt     time.Time
+	firewallSince  time.Duration // between the last two reads
+	firewallRecent uint64        // packets dropped between the last two reads
+
+	// Hardware view, read the first time it is shown except for the
+	// cameras, which are listed again once per cameraRefresh
+	hardware *HardwareInfo
+	camera   CameraStatus
+	cameraAt time.Time
+
+	// USB devices coming and going, checked with every sample
+	usbTracker *USBTracker
+
+	// GPIO view
+	pinList     []HeaderPin // pins as last shown in the view
+	selectedPin int
+
+	// Control view, the pins from the controls list
+	controls        []*controlOutput
+	selectedControl int
+
+	// Command widgets: the commands section, run in the background, and
+	// the commands of custom views by view, row and column
+	commands       []*CommandWidget
+	inlineCommands map[string]*CommandWidget
+
+	// Key -> action from keys.preset and keys.bindings, and the start of
+	// a key sequence typed so far
+	keys      map[string]string
+	keyPrefix string
+
+	// Screensaver: the last key or button press, and whether the screen
+	// is blanked since display.blank_after passed without one
+	lastInput time.Time
+	blanked   bool
+
+	// Logins on the System view, looked up by runCollector
+	logins LoginMonitor
+
+	// Latest stats shared with the exporters
+	snapshot StatsSnapshot
+
+	// Metric history for the sparklines
+	cpuHistory  *History
+	memHistory  *History
+	swapHistory *History
+	tempHistory *History
+	tempTracker *TempTracker // last hour and overheat events
+	netHistory  *History
+	sentHistory *History
+	recvHistory *History
+
+	// Groups view
+	groupList     []ResourceGroup // groups as last shown in the view
+	selectedGroup int
+	groupByCgroup bool // false groups by user
+
+	// LAN view
+	lanScanner  LANScanner
+	lanList     []LANDevice // devices as last shown in the view
+	selectedLAN int
+
+	// Wi-Fi view
+	wifiManager  WifiManager
+	wifiList     []WifiNetwork // networks as last shown in the view
+	selectedWifi int
+	apStatus     APStatus
+	apErr        error // why apStatus could not be read
+
+	// Docker containers view
+	containers        ContainerMonitor // looked up by runCollector
+	containerList     []ContainerInfo  // containers as last shown in the view
+	selectedContainer int
+
+	// Logs view
+	logEntries []LogEntry // entries as last read, kept while paused
+	logUnit    string     // unit filter, empty for all
+	logScroll  int        // lines scrolled up from the newest
+	logPaused  bool       // true when not following new entries
+
+	// Kernel log view
+	kernelScroll    int  // lines scrolled up from the newest
+	kernelImportant bool // only show the highlighted events
+
+	// Lowest and highest readings since start
+	voltRange      MinMax
+	armClockRange  MinMax
+	coreClockRange MinMax
+
 	// Button press tracking
 	lastButtonState map[int]int
+	buttonNames     map[int]string // GPIO offset -> button name
+	buttonMu        sync.Mutex     // guards lastButtonState
+	buttonLines     *gpiocdev.Lines
+	buttonEdges     chan gpiocdev.LineEvent
+	buttonEvents    chan ButtonEvent
+	gpioDone        chan struct{}
 	gpioEnabled     bool // Track if GPIO is available
+
+	fan          *FanController  // nil when fan control is disabled
+	env          *EnvMonitor     // nil without I2C environmental sensors
+	oneWire      *OneWireMonitor // nil when one_wire is disabled
+	ups          *UPSMonitor     // nil without a UPS HAT
+	historyStore *HistoryStore   // nil when the metrics history is disabled
+	alerts       *AlertEngine    // nil when alerts are disabled
+	plugins      []*PluginMonitor
+
+	display DisplayBackend // where frames are drawn, chosen by display.backend
+
+	internet  *InternetMonitor // nil when the connectivity check is disabled
+	publicIP  *PublicIPMonitor // nil when the public IP lookup is disabled
+	dnsNTP    *DNSNTPMonitor   // nil when the DNS and NTP checks are disabled
+	cluster   *ClusterMonitor  // nil without cluster nodes, agents or mDNS discovery
+	speedTest *SpeedTest       // started with t on the Network view
+	vpn       *VPNMonitor      // tunnels shown on the Network view
+
+	// Per-process TCP throughput, toggled with n on the Network view
+	showProcNet  bool
+	procNetPrev  map[string]socketBytes
+	procNetAt    time.Time
+	procNetRates []ProcessNetRate // nil until two samples were taken
+	procNetErr   error
+
+	reuseLists bool // set by redraw while the views redraw cached lists
+
+	// While paused new samples are still recorded and checked for alerts,
+	// but the views keep showing frozen
+	paused bool
+	frozen SystemStats
+
+	opts    *cliOptions // config path and flags, reapplied on SIGHUP
+	restart bool        // set by the power menu or SIGHUP to restart the monitor on exit
 }
 
 func main() {
-	// Setup log file
-	logFile, err := os.OpenFile("raspi-monitor.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(runExport(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "collect" {
+		os.Exit(runCollect(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		os.Exit(runReport(os.Args[2:]))
+	}
+
+	opts := parseFlags()
+	cfg, err := loadConfig(opts.configPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to load config, using defaults: %v\n", err)
+	}
+	if err := opts.apply(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid option: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Restarting from the power menu replaces the process once the
+	// deferred cleanup has released the terminal and the GPIO lines
+	var dashboard *Dashboard
+	defer func() {
+		if dashboard != nil && dashboard.restart {
+			restartSelf()
+		}
+	}()
+
+	// Setup log file, syslog or journald
+	logOutput, err := setupLogging(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up logging: %v\n", err)
 	} else {
-		defer logFile.Close()
-		log.SetOutput(logFile)
+		defer logOutput.Close()
 	}
 	
 	log.Println("=== Raspi Monitor Started ===")
+	cfg.applyRunMode()
 	
-	// Check if gpioget command is available (for Raspberry Pi 5)
-	gpioAvailable := false
-	
-	// Check for gpioget at absolute path
-	if _, err := os.Stat("/usr/bin/gpioget"); err == nil {
-		gpioAvailable = true
-		log.Println("GPIO initialized successfully (using gpiochip0)")
-	} else {
-		log.Println("Warning: gpioget not found at /usr/bin/gpioget")
-		log.Println("Install with: sudo apt-get install gpiod")
-		log.Println("Running without button support.")
-	}
-
-	if err := ui.Init(); err != nil {
-		log.Fatalf("failed to initialize termui: %v", err)
-	}
-	defer ui.Close()
-
-	dashboard := NewDashboard()
+	registerCustomViews(cfg.Views)
+	dashboard = NewDashboard(cfg)
+	dashboard.opts = opts
 	dashboard.InitWidgets()
-	if gpioAvailable {
-		dashboard.InitGPIO()
+	selectGPIOChip(cfg.GPIO)
+	theme, _ := cfg.Theme.theme() // checked by validate
+	applyTheme(theme)
+	display, err := newDisplay(cfg.Display, cfg.Interval)
+	if err != nil {
+		log.Fatalf("failed to initialize the %s display: %v", cfg.Display.Backend, err)
+	}
+	dashboard.display = display
+	defer display.Close()
+	log.Printf("Drawing to the %s display", cfg.Display.Backend)
+	if !cfg.GPIO.Enabled {
+		log.Println("GPIO disabled in config")
+	} else if err := dashboard.InitGPIO(); err != nil {
+		log.Printf("Warning: Failed to initialize GPIO: %v", err)
 	} else {
+		defer dashboard.CloseGPIO()
+	}
+	if !dashboard.gpioEnabled {
 		log.Println("Button controls disabled. Use keyboard: TAB=switch, q=quit, arrows=navigate")
 	}
+	if cfg.Alerts.Enabled {
+		dashboard.alerts = newAlertEngine(cfg.Alerts)
+		for _, out := range dashboard.initAlertOutputs() {
+			defer out.Close()
+		}
+	}
+	for _, c := range dashboard.initControls() {
+		defer c.Close()
+	}
+	if cfg.Fan.Enabled {
+		fan, err := newFanController(cfg.Fan)
+		if err != nil {
+			log.Printf("Warning: fan control disabled: %v", err)
+		} else {
+			dashboard.fan = fan
+			defer fan.Close()
+		}
+	}
+	if cfg.EnvSensors.Enabled {
+		env, err := newEnvMonitor(cfg.EnvSensors)
+		if err != nil {
+			log.Printf("Warning: env sensors disabled: %v", err)
+		} else {
+			dashboard.env = env
+			defer env.Close()
+		}
+	}
+	if cfg.UPS.Enabled {
+		ups, err := newUPSMonitor(cfg.UPS)
+		if err != nil {
+			log.Printf("Warning: UPS disabled: %v", err)
+		} else {
+			dashboard.ups = ups
+			defer ups.Close()
+		}
+	}
+	if c, ok := dashboard.collector.(io.Closer); ok {
+		defer c.Close()
+	}
+	// The first sample is taken up front so the first frame has data;
+	// runCollector takes the rest in the background
+	stats, err := dashboard.collector.Collect(context.Background())
+	if err != nil {
+		log.Printf("Stats collection incomplete: %v", err)
+	}
+	dashboard.applyStats(stats)
 	dashboard.UpdateStats()
 	dashboard.Render()
 
-	ticker := time.NewTicker(updateInterval)
-	defer ticker.Stop()
+	if len(cfg.Cluster.Nodes) > 0 || cfg.Cluster.Accept || cfg.MDNS.Discover {
+		dashboard.cluster = newClusterMonitor(cfg.Cluster)
+		if cfg.Cluster.Accept && cfg.API == "" {
+			log.Println("Warning: cluster.accept needs the api address to receive pushes")
+		}
+	}
+	for _, srv := range dashboard.startHTTPServers() {
+		defer srv.Close()
+	}
+
+	var historyStore *HistoryStore
+	if cfg.History.Enabled {
+		historyStore, err = openHistoryStore(cfg.History.Path)
+		if err != nil {
+			log.Printf("Warning: metrics history disabled: %v", err)
+		} else {
+			dashboard.historyStore = historyStore
+			defer historyStore.Close()
+		}
+	}
+
+	// stop tells the background publishers to shut down
+	stop := make(chan struct{})
+	defer close(stop)
+	if cfg.MQTT.Broker != "" {
+		go dashboard.runMQTT(stop)
+	}
+	if cfg.Influx.URL != "" {
+		go dashboard.runInflux(stop)
+	}
+	if cfg.Graphite.Address != "" {
+		go dashboard.runGraphite(stop)
+	}
+	if cfg.Agent.Server != "" {
+		go dashboard.runAgent(stop)
+	}
+	if historyStore != nil {
+		go dashboard.runHistoryStore(historyStore, stop)
+	}
+	if cfg.Record.Path != "" {
+		go dashboard.runRecorder(stop)
+	}
+	if cfg.Internet.Enabled {
+		dashboard.internet = newInternetMonitor(cfg.Internet)
+		go dashboard.internet.Run(stop)
+	}
+	if cfg.PublicIP.Enabled {
+		dashboard.publicIP = newPublicIPMonitor(cfg.PublicIP)
+		go dashboard.publicIP.Run(stop)
+	}
+	if cfg.DNSNTP.Enabled {
+		dashboard.dnsNTP = newDNSNTPMonitor(cfg.DNSNTP)
+		go dashboard.dnsNTP.Run(stop)
+	}
+	if dashboard.env != nil {
+		go dashboard.env.Run(stop)
+	}
+	if dashboard.ups != nil {
+		go dashboard.ups.Run(stop)
+	}
+	if cfg.OneWire.Enabled {
+		dashboard.oneWire = newOneWireMonitor(cfg.OneWire)
+		go dashboard.oneWire.Run(stop)
+	}
+	if dashboard.cluster != nil {
+		go dashboard.cluster.Run(stop)
+	}
+	if cfg.MDNS.Discover {
+		go dashboard.runMDNSDiscovery(stop)
+	}
+	if cfg.MDNS.Advertise {
+		if cfg.API == "" {
+			log.Println("Warning: mdns.advertise needs the api address")
+		} else {
+			go dashboard.runMDNSAdvertiser(stop)
+		}
+	}
+	for _, c := range cfg.Commands {
+		widget := newCommandWidget(c)
+		dashboard.commands = append(dashboard.commands, widget)
+		go widget.Run(stop)
+	}
+	for _, c := range cfg.Plugins {
+		p, err := newPluginMonitor(c)
+		if err != nil {
+			log.Printf("Warning: plugin %s disabled: %v", c.Name, err)
+			continue
+		}
+		dashboard.plugins = append(dashboard.plugins, p)
+		go p.Run(stop)
+	}
+	go dashboard.runCollector(cfg.Interval, stop)
 
-	dashboard.EventLoop(ticker)
+	if err := sdNotify("READY=1"); err != nil {
+		log.Printf("Warning: systemd notification failed: %v", err)
+	}
+	dashboard.EventLoop()
 }
 
-func NewDashboard() *Dashboard {
+func NewDashboard(cfg *Config) *Dashboard {
 	return &Dashboard{
-		currentView:     0,
+		config:          cfg,
+		collector:       newStatsSource(cfg),
+		statsUpdates:    make(chan SystemStats, 1),
+		interval:        cfg.Interval,
+		intervalChanges: make(chan time.Duration, 1),
+		commandResults:  make(chan commandResult, 1),
+		dockerResults:   make(chan containerResult, 1),
+		speedTest:       newSpeedTest(cfg.SpeedTest),
+		vpn:             &VPNMonitor{},
+		currentView:     cfg.defaultViewIndex(),
+		keys:            cfg.Keys.bindings(),
+		lastInput:       time.Now(),
 		selectedProcess: 0,
 		lastButtonState: make(map[int]int),
 		gpioEnabled:     false,
+		cpuHistory:      NewHistory(historySize),
+		memHistory:      NewHistory(historySize),
+		swapHistory:     NewHistory(historySize),
+		tempHistory:     NewHistory(historySize),
+		tempTracker:     NewTempTracker(cfg.TempWarn),
+		usbTracker:      NewUSBTracker(),
+		netHistory:      NewHistory(historySize),
+		sentHistory:     NewHistory(historySize),
+		recvHistory:     NewHistory(historySize),
+		diskHistory:     make(map[string]diskIOHistory),
+		writeWarned:     make(map[string]bool),
 	}
 }
 
 func (d *Dashboard) InitWidgets() {
-	// Main list - full screen utilization
+	// The views write their title and rows here; the display backend
+	// decides how to draw them
 	d.mainList = widgets.NewList()
 	d.mainList.Title = "System Monitor"
-	d.mainList.SetRect(0, 0, 30, 30) // 240x240 = approx 30x30 chars
-	d.mainList.TextStyle = ui.NewStyle(ui.ColorWhite)
-	d.mainList.BorderStyle = ui.NewStyle(ui.ColorCyan)
-
-	// Bottom help - removed to save space
-	d.helpParagraph = widgets.NewParagraph()
-	d.helpParagraph.Title = ""
-	d.helpParagraph.Text = ""
-	d.helpParagraph.SetRect(0, 30, 30, 30)
-	d.helpParagraph.BorderStyle = ui.NewStyle(ui.ColorYellow)
 }
 
-// InitGPIO initializes GPIO pins for button input using gpioget
-func (d *Dashboard) InitGPIO() {
-	log.Println("Initializing GPIO pins via gpiochip0...")
-	
-	// Initialize last button states (all HIGH/1 initially with pull-up)
-	pins := []int{buttonUp, buttonDown, buttonLeft, buttonRight, 
-		buttonA, buttonB, buttonX, buttonY, 
-		buttonStart, buttonSelect, buttonL, buttonR, buttonCenter}
-	
-	for _, pin := range pins {
-		d.lastButtonState[pin] = 1 // HIGH = not pressed
-	}
-	
-	d.gpioEnabled = true
-	log.Println("GPIO ready - press buttons to test")
-}
-
-// readGPIOValue reads the current value of a GPIO pin using gpioget
-func readGPIOValue(pin int) int {
-	cmd := exec.Command("/usr/bin/gpioget", "gpiochip0", fmt.Sprintf("%d", pin))
-	output, err := cmd.Output()
-	if err != nil {
-		return 1 // Default to HIGH on error
+// applyStats takes in a new sample: it drives the fan, publishes the
+// snapshot and updates the history and alerts
+func (d *Dashboard) applyStats(stats SystemStats) {
+	if d.remoteHost() != "" {
+		// The fan cools this Pi, and the readings below are of this Pi
+		// rather than of the host the views show
+		if d.fan != nil {
+			d.fan.Update(collector.CPUTemperature(context.Background()))
+		}
+	} else {
+		if d.fan != nil {
+			d.fan.Update(stats.Temperature)
+			stats.Fan = d.fan.Status()
+		}
+		if d.env != nil {
+			stats.Environment = d.env.Readings()
+		}
+		if d.ups != nil {
+			stats.Battery = d.ups.Status()
+		}
+		if d.oneWire != nil {
+			stats.Sensors = append(stats.Sensors, d.oneWire.Readings()...)
+		}
+		for _, p := range d.plugins {
+			stats.Plugins = append(stats.Plugins, p.Metrics()...)
+		}
 	}
-	
-	value := strings.TrimSpace(string(output))
-	if value == "0" {
-		return 0 // LOW = pressed
+	d.snapshot.Set(stats)
+	d.recordHistory(stats)
+	d.usbTracker.Observe(time.Now())
+	d.checkWriteRate(stats)
+	if d.alerts != nil {
+		d.alerts.Evaluate(stats, time.Now())
 	}
-	return 1 // HIGH = not pressed
 }
 
+// UpdateStats rebuilds the current view from the latest sample. It does
+// not collect, so it is cheap enough to call on every key press.
 func (d *Dashboard) UpdateStats() {
-	stats := getSystemStats()
+	stats, _ := d.snapshot.Get()
+	if d.paused {
+		stats = d.frozen
+	}
 
 	switch d.currentView {
-	case 0:
+	case viewSystem:
 		d.updateSystemView(stats)
-	case 1:
+	case viewProcess:
 		d.updateProcessView(stats)
-	case 2:
+	case viewGroups:
+		d.updateGroupsView(stats)
+	case viewNetwork:
 		d.updateNetworkView(stats)
+	case viewLAN:
+		d.updateLANView(stats)
+	case viewWifi:
+		d.updateWifiView(stats)
+	case viewMemory:
+		d.updateMemoryView(stats)
+	case viewCPUFreq:
+		d.updateCPUFreqView(stats)
+	case viewGPU:
+		d.updateGPUView(stats)
+	case viewConnections:
+		d.updateConnectionsView(stats)
+	case viewDisk:
+		d.updateDiskView(stats)
+	case viewStorage:
+		d.updateStorageView(stats)
+	case viewSensors:
+		d.updateSensorsView(stats)
+	case viewHardware:
+		d.updateHardwareView(stats)
+	case viewUSB:
+		d.updateUSBView(stats)
+	case viewGPIOPins:
+		d.updateGPIOPinsView(stats)
+	case viewControl:
+		d.updateControlView(stats)
+	case viewFirewall:
+		d.updateFirewallView(stats)
+	case viewHealth:
+		d.updateHealthView(stats)
+	case viewServices:
+		d.updateServicesView(stats)
+	case viewContainers:
+		d.updateContainersView(stats)
+	case viewLogs:
+		d.updateLogsView(stats)
+	case viewKernel:
+		d.updateKernelView(stats)
+	case viewCluster:
+		d.updateClusterView(stats)
+	default:
+		d.updateCustomView(stats)
+	}
+}
+
+// viewTitle formats the list title as "Name (n/total) suffix"
+func (d *Dashboard) viewTitle(suffix string) string {
+	rate := formatInterval(d.interval)
+	if r := d.replay(); r != nil {
+		rate = r.status()
 	}
+	if d.paused {
+		rate = "PAUSED"
+	}
+	title := fmt.Sprintf("%s (%d/%d) %s", views[d.currentView].title, d.currentView+1, len(views), rate)
+	if suffix != "" {
+		title += " " + suffix
+	}
+	if host := d.remoteHost(); host != "" {
+		title = host + ": " + title
+	}
+	return title
 }
 
 func (d *Dashboard) updateSystemView(stats SystemStats) {
@@ -202,35 +663,132 @@ func (d *Dashboard) updateSystemView(stats SystemStats) {
 	days, hours, _ := formatUptime(stats.Uptime)
 	tempStr := formatTemperature(stats.Temperature)
 
-	d.mainList.Title = "System (1/3) [A/B:Switch]"
-	d.mainList.Rows = []string{
-		"",
-		fmt.Sprintf("[CPU:](fg:cyan) %.1f%%", avgCPU),
+	// The first row names the firing alerts
+	alertRow := ""
+	if d.alerts != nil {
+		if firing := d.alerts.Firing(); len(firing) > 0 {
+			alertRow = fmt.Sprintf("[! %s](fg:white,bg:red)", truncateString(strings.Join(firing, ", "), 25))
+		}
+	}
+
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+	rows := []string{
+		alertRow,
+		fmt.Sprintf("[CPU:](fg:%s) %.1f%%", d.alertColor("cpu", "cyan"), avgCPU),
 		getBar(avgCPU, 20),
 		"",
-		fmt.Sprintf("[MEM:](fg:yellow) %.1f%%", stats.MemPercent),
+		fmt.Sprintf("[MEM:](fg:%s) %.1f%%", d.alertColor("mem", "yellow"), stats.MemPercent),
 		getBar(stats.MemPercent, 20),
 		"",
-		fmt.Sprintf("[DSK:](fg:magenta) %.1f%%", stats.DiskPercent),
+		fmt.Sprintf("[DSK:](fg:%s) %.1f%%", d.alertColor("disk", "magenta"), stats.DiskPercent),
 		getBar(stats.DiskPercent, 20),
 		"",
 		"[--System Info--](fg:white)",
-		fmt.Sprintf("Temp: %s", tempStr),
+		fmt.Sprintf("[Temp:](fg:%s) %s", d.alertColor("temp", "white"), tempStr),
+	}
+	rows = append(rows, d.tempRangeRows()...)
+	rows = append(rows, envRows(stats.Environment)...)
+	if stats.Fan != nil {
+		rows = append(rows, fmt.Sprintf("Fan:  %3d%% %s", stats.Fan.Duty, getBar(float64(stats.Fan.Duty), 10)))
+	}
+	rows = append(rows, d.ups.upsRows()...)
+	rows = append(rows, throttleRows(stats.Throttle)...)
+	rows = append(rows, d.clockRows(stats)...)
+	rows = append(rows,
 		fmt.Sprintf("Uptime: %dd %dh", days, hours),
 		fmt.Sprintf("Cores: %d", runtime.NumCPU()),
-		fmt.Sprintf("Procs: %d", stats.ProcessCount),
+		fmt.Sprintf("Procs: %d %s", stats.ProcessCount, stuckProcessCounts(stats)),
+		fmt.Sprintf("[Load:](fg:%s) %.2f %.2f %.2f", d.alertColor("load", "white"),
+			stats.Load.Load1, stats.Load.Load5, stats.Load.Load15),
+	)
+	rows = append(rows, pressureRows(stats.Pressure)...)
+	rows = append(rows,
 		"",
 		"[--Network Info--](fg:green)",
 		fmt.Sprintf("IP: %s", stats.IPAddress),
-		fmt.Sprintf("Mode: %s", stats.APMode),
+	)
+	rows = append(rows, publicIPRows(d.publicIP)...)
+	rows = append(rows, fmt.Sprintf("Mode: %s", stats.APMode))
+	rows = append(rows, internetRows(d.internet)...)
+	rows = append(rows, dnsNTPRows(d.dnsNTP)...)
+	rows = append(rows, d.sessionRows()...)
+	rows = append(rows, d.commandRows()...)
+	rows = append(rows, d.pluginRows(stats)...)
+	rows = append(rows,
 		"",
+		"[--History--](fg:white)",
+		fmt.Sprintf("CPU %s", getSparkline(d.cpuHistory.Last(sparkWidth), 100, "cyan")),
+		fmt.Sprintf("MEM %s", getSparkline(d.memHistory.Last(sparkWidth), 100, "yellow")),
+		fmt.Sprintf("TMP %s", getSparkline(d.tempHistory.Last(sparkWidth), 85, "red")),
+		fmt.Sprintf("NET %s", getSparkline(d.netHistory.Last(sparkWidth), 0, "green")),
+		"",
+		"[--Top CPU--](fg:cyan)",
+	)
+	rows = append(rows, topProcessRows(stats.AllProcesses, sortByCPU, topProcesses)...)
+	rows = append(rows, "", "[--Top MEM--](fg:yellow)")
+	rows = append(rows, topProcessRows(stats.AllProcesses, sortByMemory, topProcesses)...)
+	d.mainList.Rows = rows
+}
+
+// topProcessRows lists the n processes using the most CPU or memory
+// (mode is sortByCPU or sortByMemory) as "name  12.3%"
+func topProcessRows(procs []ProcessInfo, mode, n int) []string {
+	sorted := sortProcesses(procs, mode)
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	rows := make([]string, 0, len(sorted))
+	for _, proc := range sorted {
+		value := proc.CPU
+		if mode == sortByMemory {
+			value = proc.Memory
+		}
+		rows = append(rows, fmt.Sprintf("%-15s %5.1f%%", truncateString(proc.Name, 15), value))
 	}
+	return rows
 }
 
+// pressureRows shows the 10 second stall averages, red above 10%.
+// Kernels without PSI get no rows.
+func pressureRows(p *collector.PressureStats) []string {
+	if p == nil {
+		return nil
+	}
+	color := func(pct float64) string {
+		if pct > 10 {
+			return fmt.Sprintf("[%.1f](fg:red)", pct)
+		}
+		return fmt.Sprintf("%.1f", pct)
+	}
+	return []string{fmt.Sprintf("PSI cpu %s mem %s io %s",
+		color(p.CPU.Some.Avg10), color(p.Memory.Some.Avg10), color(p.IO.Some.Avg10))}
+}
+
+// processPageRows is how many processes fit on the process view
+const processPageRows = 27
+
 func (d *Dashboard) updateProcessView(stats SystemStats) {
-	totalProcesses := len(stats.AllProcesses)
+	if d.detailPID != 0 {
+		d.updateProcessDetailView()
+		return
+	}
+
+	d.processList = sortProcesses(filterProcesses(stats.AllProcesses, d.processFilter), d.sortMode)
+	totalProcesses := len(d.processList)
+
+	var filterRow string
+	if d.searching {
+		filterRow = fmt.Sprintf("[/](fg:yellow)%s[_](fg:yellow)", d.processFilter)
+	} else if d.processFilter != "" {
+		filterRow = fmt.Sprintf("[Filter:](fg:yellow) %s", d.processFilter)
+	}
+
 	if totalProcesses == 0 {
+		d.mainList.Title = d.viewTitle("0/0")
 		d.mainList.Rows = []string{"No processes found"}
+		if filterRow != "" {
+			d.mainList.Rows = append([]string{filterRow}, d.mainList.Rows...)
+		}
 		return
 	}
 
@@ -241,15 +799,24 @@ func (d *Dashboard) updateProcessView(stats SystemStats) {
 		d.selectedProcess = 0
 	}
 
-	d.mainList.Title = fmt.Sprintf("Process (2/3) %d/%d [↑↓:Move]", d.selectedProcess+1, totalProcesses)
+	d.mainList.Title = d.viewTitle(fmt.Sprintf("%d/%d ↓%s", d.selectedProcess+1, totalProcesses, sortModeNames[d.sortMode]))
+
+	// The value column follows the sort key, except for PID/name sorts
+	valueHeader := "CPU%"
+	if d.sortMode == sortByMemory {
+		valueHeader = "MEM%"
+	}
 
 	rows := []string{
-		"[PID   Name         CPU%](fg:cyan)",
+		fmt.Sprintf("[PID   Name         %s](fg:cyan)", valueHeader),
 		"---------------------------",
 	}
 
-	// Visible processes count (about 27 lines)
-	visibleHeight := 27
+	visibleHeight := processPageRows
+	if filterRow != "" {
+		rows = append([]string{filterRow}, rows...)
+		visibleHeight--
+	}
 	startIdx := d.selectedProcess
 	if startIdx > totalProcesses-visibleHeight {
 		startIdx = totalProcesses - visibleHeight
@@ -262,202 +829,218 @@ func (d *Dashboard) updateProcessView(stats SystemStats) {
 		endIdx = totalProcesses
 	}
 
+	d.processTop, d.processFirst = len(rows), startIdx
 	for i := startIdx; i < endIdx; i++ {
-		proc := stats.AllProcesses[i]
+		proc := d.processList[i]
 		name := truncateString(proc.Name, 12)
+		value := proc.CPU
+		if d.sortMode == sortByMemory {
+			value = proc.Memory
+		}
 		
 		if i == d.selectedProcess {
 			rows = append(rows,
 				fmt.Sprintf("[[%-5d] [%-12s] [%4.1f]](bg:white,fg:black)",
-					proc.PID, name, proc.CPU))
+					proc.PID, name, value))
+		} else if color := processStateColor(proc.Status); color != "" {
+			rows = append(rows,
+				fmt.Sprintf("[%-5d](fg:cyan) [%-12s](fg:%s) [%4.1f](fg:red)",
+					proc.PID, name, color, value))
 		} else {
 			rows = append(rows,
 				fmt.Sprintf("[%-5d](fg:cyan) %-12s [%4.1f](fg:red)",
-					proc.PID, name, proc.CPU))
+					proc.PID, name, value))
 		}
 	}
 
 	d.mainList.Rows = rows
 }
 
-func (d *Dashboard) updateNetworkView(stats SystemStats) {
-	sentDiff := stats.NetSent - d.prevNetSent
-	recvDiff := stats.NetRecv - d.prevNetRecv
-	d.prevNetSent = stats.NetSent
-	d.prevNetRecv = stats.NetRecv
-
-	d.mainList.Title = "Network (3/3) [A/B:Switch]"
-	d.mainList.Rows = []string{
-		"",
-		"[--Total Transfer--](fg:cyan)",
-		"",
-		fmt.Sprintf("Total Upload:"),
-		fmt.Sprintf("  %.1f MB", bytesToMB(stats.NetSent)),
-		"",
-		fmt.Sprintf("Total Download:"),
-		fmt.Sprintf("  %.1f MB", bytesToMB(stats.NetRecv)),
-		"",
-		"[--Current Speed--](fg:magenta)",
-		"",
-		fmt.Sprintf("Upload:"),
-		fmt.Sprintf("  %.1f KB/s", bytesToKB(sentDiff)),
-		"",
-		fmt.Sprintf("Download:"),
-		fmt.Sprintf("  %.1f KB/s", bytesToKB(recvDiff)),
-		"",
-	}
-}
-
 func (d *Dashboard) Render() {
-	ui.Render(d.mainList)
+	if d.blanked {
+		return
+	}
+	stats, _ := d.snapshot.Get()
+	frame := Frame{Title: d.mainList.Title, Rows: d.mainList.Rows, Menu: d.menu, Stats: stats}
+	if t, ok := d.display.(*terminalDisplay); ok && t.wide() {
+		frame.Overview = d.overview(stats)
+	}
+	if d.config.Display.StatusBar {
+		frame.Status = d.statusBar(stats)
+	}
+	if err := d.display.Render(frame); err != nil {
+		log.Printf("Display update failed: %v", err)
+	}
 }
 
-func (d *Dashboard) EventLoop(ticker *time.Ticker) {
-	uiEvents := ui.PollEvents()
-	stats := getSystemStats()
+func (d *Dashboard) EventLoop() {
+	// Without a keyboard the buttons drive the monitor and a signal
+	// stops it; SIGHUP reloads the config
+	uiEvents := d.display.Events()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
 	
 	for {
 		select {
 		case e := <-uiEvents:
+			if e.ID != "<Resize>" && e.ID != "<C-c>" && d.wake() {
+				continue
+			}
+			if d.menu != nil && e.ID != "<C-c>" && e.ID != "<Resize>" {
+				d.handleMenuKey(e.ID)
+				continue
+			}
+			if d.searching && e.ID != "<C-c>" && e.ID != "<Resize>" {
+				d.handleSearchKey(e.ID)
+				continue
+			}
 			switch e.ID {
-			case "q", "<C-c>":
+			case "<C-c>":
 				return
-			case "<Tab>":
-				d.currentView = (d.currentView + 1) % 3
-				d.UpdateStats()
-				d.Render()
-			case "<Up>":
-				if d.currentView == 1 && d.selectedProcess > 0 {
-					d.selectedProcess--
-					d.UpdateStats()
-					d.Render()
-				}
-			case "<Down>":
-				if d.currentView == 1 {
-					stats = getSystemStats()
-					if d.selectedProcess < len(stats.AllProcesses)-1 {
-						d.selectedProcess++
-						d.UpdateStats()
-						d.Render()
-					}
-				}
 			case "<Resize>":
 				d.handleResize(e.Payload.(ui.Resize))
+			case "<MouseLeft>", "<MouseRight>", "<MouseWheelUp>", "<MouseWheelDown>":
+				d.handleMouse(e)
+			default:
+				if !d.handleKey(e.ID) {
+					return
+				}
+			}
+		case be := <-d.buttonEvents:
+			if d.wake() {
+				continue
+			}
+			if d.menu != nil {
+				d.handleMenuButton(be)
+				continue
+			}
+			if !d.handleButton(be) {
+				return
+			}
+		case sig := <-signals:
+			if !d.handleSignal(sig) {
+				return
+			}
+		case res := <-d.commandResults:
+			d.showCommandResult(res)
+		case res := <-d.dockerResults:
+			d.showContainerResult(res)
+		case stats := <-d.statsUpdates:
+			d.applyStats(stats)
+			d.checkIdle()
+			if !d.paused {
+				d.UpdateStats()
+				d.Render()
 			}
-		case <-ticker.C:
-			d.UpdateStats()
-			d.Render()
+		}
+		if d.restart {
+			return
 		}
 	}
 }
 
-func (d *Dashboard) handleResize(resize ui.Resize) {
-	width := resize.Width
-	height := resize.Height
-	
-	d.mainList.SetRect(0, 0, width, height)
-	
-	ui.Clear()
+// switchView moves delta views forward (or backward when negative)
+func (d *Dashboard) switchView(delta int) {
+	if p, ok := d.display.(pagedDisplay); ok {
+		p.Turn(delta)
+		d.Render()
+		return
+	}
+	n := len(views)
+	d.currentView = ((d.currentView+delta)%n + n) % n
+	d.UpdateStats()
 	d.Render()
 }
 
-func getSystemStats() SystemStats {
-	stats := SystemStats{}
-
-	if cpuPercents, err := cpu.Percent(0, true); err == nil {
-		stats.CPUPercent = cpuPercents
-	}
-
-	if memInfo, err := mem.VirtualMemory(); err == nil {
-		stats.MemPercent = memInfo.UsedPercent
-		stats.MemUsed = memInfo.Used
-		stats.MemTotal = memInfo.Total
+// moveSelection moves the selection of the list views by delta rows, or
+// scrolls the log views
+func (d *Dashboard) moveSelection(delta int) {
+	if d.currentView == viewLogs {
+		d.scrollLogs(delta)
+		return
 	}
-
-	if diskInfo, err := disk.Usage("/"); err == nil {
-		stats.DiskPercent = diskInfo.UsedPercent
+	if d.currentView == viewKernel {
+		d.scrollKernel(delta)
+		return
 	}
 
-	stats.Temperature = getCPUTemperature()
-
-	if hostInfo, err := host.Info(); err == nil {
-		stats.Uptime = hostInfo.Uptime
-		stats.ProcessCount = hostInfo.Procs
+	var selected *int
+	var count int
+	switch {
+	case d.currentView == viewProcess && d.detailPID == 0:
+		selected, count = &d.selectedProcess, len(d.processList)
+	case d.currentView == viewGroups:
+		selected, count = &d.selectedGroup, len(d.groupList)
+	case d.currentView == viewNetwork:
+		selected, count = &d.selectedIface, len(d.ifaceList)
+	case d.currentView == viewLAN:
+		selected, count = &d.selectedLAN, len(d.lanList)
+	case d.currentView == viewGPIOPins:
+		selected, count = &d.selectedPin, len(d.pinList)
+	case d.currentView == viewControl:
+		selected, count = &d.selectedControl, len(d.controls)
+	case d.currentView == viewWifi:
+		selected, count = &d.selectedWifi, len(d.wifiList)
+	case d.currentView == viewConnections:
+		selected, count = &d.selectedConn, len(d.connList)
+	case d.currentView == viewDisk:
+		selected, count = &d.selectedFS, len(d.fsList)
+	case d.currentView == viewServices:
+		selected, count = &d.selectedService, len(d.serviceList)
+	case d.currentView == viewContainers:
+		selected, count = &d.selectedContainer, len(d.containerList)
+	default:
+		return
 	}
 
-	if netStats, err := gopsnet.IOCounters(false); err == nil && len(netStats) > 0 {
-		stats.NetSent = netStats[0].BytesSent
-		stats.NetRecv = netStats[0].BytesRecv
+	next := *selected + delta
+	if next > count-1 {
+		next = count - 1
 	}
-
-	stats.AllProcesses = getAllProcesses()
-	stats.IPAddress = getIPAddress()
-	stats.APMode = getAPMode()
-
-	return stats
-}
-
-func getCPUTemperature() float64 {
-	data, err := os.ReadFile("/sys/class/thermal/thermal_zone0/temp")
-	if err != nil {
-		return 0
+	if next < 0 {
+		next = 0
 	}
-
-	tempStr := strings.TrimSpace(string(data))
-	temp, err := strconv.ParseFloat(tempStr, 64)
-	if err != nil {
-		return 0
+	if next == *selected {
+		return
 	}
+	*selected = next
+	d.redraw()
+}
 
-	return temp / 1000.0
+// redraw rebuilds the current view from the lists already fetched, so
+// moving the selection does not query systemd, Docker or /proc again
+func (d *Dashboard) redraw() {
+	d.reuseLists = true
+	d.UpdateStats()
+	d.reuseLists = false
+	d.Render()
 }
 
-func getAllProcesses() []ProcessInfo {
-	processes, err := process.Processes()
-	if err != nil {
-		return []ProcessInfo{}
+// handleButton applies a debounced GPIO button event through the action
+// assigned to the button. It reports false when the monitor should exit.
+func (d *Dashboard) handleButton(evt ButtonEvent) bool {
+	log.Printf("Button %s: %s", evt.Button, evt.Kind)
+
+	// Selection moves repeat while held, other actions need a short press.
+	// Holding the select button opens the help.
+	action := d.buttonAction(evt.Button)
+	if evt.Kind == PressLong && action == actionSelect {
+		d.openHelp()
+		return true
 	}
-
-	var processInfos []ProcessInfo
-	totalMem, err := mem.VirtualMemory()
-	if err != nil {
-		return []ProcessInfo{}
+	if evt.Kind != PressShort && action != actionUp && action != actionDown &&
+		action != actionPageUp && action != actionPageDown {
+		return true
 	}
+	return d.runButtonAction(action)
+}
 
-	for _, p := range processes {
-		name, _ := p.Name()
-		cpuPercent, _ := p.CPUPercent()
-		memInfo, _ := p.MemoryInfo()
-		status, _ := p.Status()
-		username, _ := p.Username()
-
-		memPercent := 0.0
-		if memInfo != nil && totalMem.Total > 0 {
-			memPercent = float64(memInfo.RSS) / float64(totalMem.Total) * 100
-		}
-
-		statusStr := "?"
-		if len(status) > 0 {
-			statusStr = string(status[0])
-		}
-
-		procInfo := ProcessInfo{
-			PID:      p.Pid,
-			Name:     name,
-			CPU:      cpuPercent,
-			Memory:   memPercent,
-			Status:   statusStr,
-			Username: username,
-		}
-
-		processInfos = append(processInfos, procInfo)
+func (d *Dashboard) handleResize(resize ui.Resize) {
+	if t, ok := d.display.(*terminalDisplay); ok {
+		t.Resize(resize.Width, resize.Height)
 	}
-
-	sort.Slice(processInfos, func(i, j int) bool {
-		return processInfos[i].CPU > processInfos[j].CPU
-	})
-
-	return processInfos
+	d.Render()
 }
 
 // Utility functions
@@ -480,13 +1063,44 @@ func getBar(percent float64, width int) string {
 	}
 	bar := strings.Repeat("█", filled)
 	empty := strings.Repeat("░", width-filled)
-	return fmt.Sprintf("[%s%s](fg:green)", bar, empty)
+	return fmt.Sprintf("[%s%s](fg:%s)", bar, empty, themeBar)
 }
 
 func bytesToMB(bytes uint64) float64 {
 	return float64(bytes) / 1024 / 1024
 }
 
+// formatBytes renders a byte count with a binary unit, e.g. "1.5G"
+func formatBytes(bytes uint64) string {
+	const units = "KMGTP"
+	if bytes < 1024 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	value := float64(bytes) / 1024
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f%c", value, units[unit])
+}
+
+// formatRate prints a throughput in B/s, KB/s or MB/s, whichever keeps
+// the number short. The result is at most 8 characters wide.
+func formatRate(bytesPerSec float64) string {
+	if bytesPerSec < 1024 {
+		return fmt.Sprintf("%.0fB/s", bytesPerSec)
+	}
+	value, unit := bytesPerSec/1024, "KB/s"
+	if value >= 1024 {
+		value, unit = value/1024, "MB/s"
+	}
+	if value >= 100 {
+		return fmt.Sprintf("%.0f%s", value, unit)
+	}
+	return fmt.Sprintf("%.1f%s", value, unit)
+}
+
 func bytesToKB(bytes uint64) float64 {
 	return float64(bytes) / 1024
 }
@@ -512,68 +1126,11 @@ func truncateString(s string, maxLen int) string {
 	return s[:maxLen-2] + ".."
 }
 
-// getIPAddress returns the current IP address
-func getIPAddress() string {
-	interfaces, err := net.Interfaces()
+// hostname returns the system host name
+func hostname() string {
+	name, err := os.Hostname()
 	if err != nil {
-		return "N/A"
-	}
-
-	for _, iface := range interfaces {
-		// Skip loopback and down interfaces
-		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
-			continue
-		}
-
-		addrs, err := iface.Addrs()
-		if err != nil {
-			continue
-		}
-
-		for _, addr := range addrs {
-			ipNet, ok := addr.(*net.IPNet)
-			if !ok {
-				continue
-			}
-
-			// Return first non-loopback IPv4 address
-			if ipNet.IP.To4() != nil {
-				return ipNet.IP.String()
-			}
-		}
+		return "unknown"
 	}
-
-	return "No IP"
+	return name
 }
-
-// getAPMode checks if the system is in AP mode
-func getAPMode() string {
-	// Check for hostapd process (common AP mode daemon)
-	processes, err := process.Processes()
-	if err != nil {
-		return "Unknown"
-	}
-
-	for _, p := range processes {
-		name, err := p.Name()
-		if err != nil {
-			continue
-		}
-		
-		if strings.Contains(strings.ToLower(name), "hostapd") {
-			return "AP Mode"
-		}
-	}
-
-	// Check for wlan0 in master mode (alternative check)
-	data, err := os.ReadFile("/sys/class/net/wlan0/operstate")
-	if err == nil {
-		state := strings.TrimSpace(string(data))
-		if state == "up" {
-			// Additional check for AP mode via iwconfig or similar
-			return "Client Mode"
-		}
-	}
-
-	return "Client Mode"
-}
\ No newline at end of file
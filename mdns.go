@@ -0,0 +1,374 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mDNS (RFC 6762) and DNS-SD (RFC 6763) just far enough to announce the
+// api and to browse for the other monitors
+const (
+	mdnsService = "_raspimon._tcp.local."
+	mdnsTTL     = 120
+
+	dnsTypeA   = 1
+	dnsTypePTR = 12
+	dnsTypeTXT = 16
+	dnsTypeSRV = 33
+	dnsTypeANY = 255
+	dnsClassIN = 1
+
+	// mdnsCacheFlush is the top bit of the class of unique records
+	mdnsCacheFlush = 0x8000
+
+	// mdnsBrowseWait is how long a browse collects answers
+	mdnsBrowseWait = 2 * time.Second
+)
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// MDNSConfig advertises the api as _raspimon._tcp and finds the other
+// monitors on the LAN, so Pis with DHCP addresses need no cluster.nodes
+type MDNSConfig struct {
+	Advertise bool          `yaml:"advertise"` // needs the api address
+	Discover  bool          `yaml:"discover"`  // add the monitors found to the Cluster view
+	Interval  time.Duration `yaml:"interval"`  // between browses
+}
+
+func (c MDNSConfig) validate() error {
+	if c.Discover && c.Interval < mdnsBrowseWait {
+		return fmt.Errorf("mdns.interval must be at least %s", mdnsBrowseWait)
+	}
+	return nil
+}
+
+// dnsRecord is a resource record; data holds the target name of PTR and
+// SRV records, the address of A records and the strings of TXT records
+type dnsRecord struct {
+	name  string
+	rtype uint16
+	data  string
+	port  uint16 // SRV only
+}
+
+// appendDNSName encodes a dotted name without compression
+func appendDNSName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// readDNSName decodes the name at off, following compression pointers.
+// It returns the name and the offset after it.
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; jumps < 16; {
+		if off >= len(msg) {
+			return "", 0, errors.New("name out of range")
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case n&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, errors.New("pointer out of range")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			jumps++
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errors.New("label out of range")
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+	return "", 0, errors.New("too many compression pointers")
+}
+
+// dnsQuestion is the name and type asked for
+type dnsQuestion struct {
+	name  string
+	qtype uint16
+}
+
+// parseDNS returns the questions and the records of all sections
+func parseDNS(msg []byte) (id uint16, questions []dnsQuestion, records []dnsRecord, err error) {
+	if len(msg) < 12 {
+		return 0, nil, nil, errors.New("short message")
+	}
+	id = binary.BigEndian.Uint16(msg)
+	qd := int(binary.BigEndian.Uint16(msg[4:]))
+	rr := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+	off := 12
+	for i := 0; i < qd; i++ {
+		var name string
+		if name, off, err = readDNSName(msg, off); err != nil {
+			return
+		}
+		if off+4 > len(msg) {
+			return id, nil, nil, errors.New("short question")
+		}
+		questions = append(questions, dnsQuestion{name: strings.ToLower(name), qtype: binary.BigEndian.Uint16(msg[off:])})
+		off += 4
+	}
+	for i := 0; i < rr; i++ {
+		var name string
+		if name, off, err = readDNSName(msg, off); err != nil {
+			return
+		}
+		if off+10 > len(msg) {
+			return id, nil, nil, errors.New("short record")
+		}
+		rtype := binary.BigEndian.Uint16(msg[off:])
+		length := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+length > len(msg) {
+			return id, nil, nil, errors.New("record data out of range")
+		}
+		rec := dnsRecord{name: strings.ToLower(name), rtype: rtype}
+		switch rtype {
+		case dnsTypeA:
+			if length == 4 {
+				rec.data = net.IP(msg[off : off+4]).String()
+			}
+		case dnsTypePTR:
+			rec.data, _, err = readDNSName(msg, off)
+		case dnsTypeSRV:
+			if length >= 7 {
+				rec.port = binary.BigEndian.Uint16(msg[off+4:])
+				rec.data, _, err = readDNSName(msg, off+6)
+			}
+		}
+		if err != nil {
+			return
+		}
+		rec.data = strings.ToLower(rec.data)
+		records = append(records, rec)
+		off += length
+	}
+	return id, questions, records, nil
+}
+
+// appendDNSRecord encodes a record of the answer
+func appendDNSRecord(b []byte, r dnsRecord) []byte {
+	b = appendDNSName(b, r.name)
+	class := uint16(dnsClassIN)
+	if r.rtype != dnsTypePTR {
+		class |= mdnsCacheFlush // shared records keep the bit clear
+	}
+	b = binary.BigEndian.AppendUint16(b, r.rtype)
+	b = binary.BigEndian.AppendUint16(b, class)
+	b = binary.BigEndian.AppendUint32(b, mdnsTTL)
+
+	var data []byte
+	switch r.rtype {
+	case dnsTypeA:
+		data = net.ParseIP(r.data).To4()
+	case dnsTypePTR:
+		data = appendDNSName(nil, r.data)
+	case dnsTypeSRV:
+		data = binary.BigEndian.AppendUint16(data, 0) // priority
+		data = binary.BigEndian.AppendUint16(data, 0) // weight
+		data = binary.BigEndian.AppendUint16(data, r.port)
+		data = appendDNSName(data, r.data)
+	case dnsTypeTXT:
+		for _, s := range strings.Split(r.data, "\n") {
+			data = append(data, byte(len(s)))
+			data = append(data, s...)
+		}
+	}
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+// mdnsMessage builds a message with the given questions and answers
+func mdnsMessage(id uint16, response bool, questions []dnsQuestion, answers []dnsRecord) []byte {
+	var flags uint16
+	if response {
+		flags = 0x8400 // response, authoritative
+	}
+	b := make([]byte, 0, 512)
+	b = binary.BigEndian.AppendUint16(b, id)
+	b = binary.BigEndian.AppendUint16(b, flags)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(questions)))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(answers)))
+	b = binary.BigEndian.AppendUint32(b, 0) // authority and additional
+	for _, q := range questions {
+		b = appendDNSName(b, q.name)
+		b = binary.BigEndian.AppendUint16(b, q.qtype)
+		b = binary.BigEndian.AppendUint16(b, dnsClassIN)
+	}
+	for _, r := range answers {
+		b = appendDNSRecord(b, r)
+	}
+	return b
+}
+
+// mdnsRecords describes this monitor: the service pointer, the port of
+// the api, a TXT record and the IPv4 addresses of the host, or only ip
+// when the api listens on one address
+func mdnsRecords(ip net.IP, port int) []dnsRecord {
+	host := strings.ToLower(hostname())
+	instance := host + "." + mdnsService
+	target := host + ".local."
+	records := []dnsRecord{
+		{name: mdnsService, rtype: dnsTypePTR, data: instance},
+		{name: instance, rtype: dnsTypeSRV, data: target, port: uint16(port)},
+		{name: instance, rtype: dnsTypeTXT, data: "path=/api/stats"},
+	}
+	if ip4 := ip.To4(); ip4 != nil && !ip4.IsUnspecified() {
+		return append(records, dnsRecord{name: target, rtype: dnsTypeA, data: ip4.String()})
+	}
+	addrs, _ := net.InterfaceAddrs()
+	for _, a := range addrs {
+		if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.To4() != nil && !ipNet.IP.IsLoopback() {
+			records = append(records, dnsRecord{name: target, rtype: dnsTypeA, data: ipNet.IP.String()})
+		}
+	}
+	return records
+}
+
+// runMDNSAdvertiser announces the api and answers queries for it until
+// stop is closed. It shares port 5353 with avahi when that runs.
+func (d *Dashboard) runMDNSAdvertiser(stop <-chan struct{}) {
+	host, portStr, err := net.SplitHostPort(d.config.API)
+	port, _ := strconv.Atoi(portStr)
+	ip := net.ParseIP(host)
+	if err != nil || port == 0 {
+		log.Printf("mDNS: cannot advertise api address %q", d.config.API)
+		return
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		log.Printf("mDNS: %v", err)
+		return
+	}
+	go func() {
+		<-stop
+		conn.Close()
+	}()
+
+	// Announce twice a second apart, as RFC 6762 asks
+	for i := 0; i < 2; i++ {
+		conn.WriteToUDP(mdnsMessage(0, true, nil, mdnsRecords(ip, port)), mdnsGroup)
+		time.Sleep(time.Second)
+	}
+	log.Printf("mDNS: advertising %s%s on port %d", strings.ToLower(hostname())+".", mdnsService, port)
+
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return // closed on stop
+		}
+		id, questions, _, err := parseDNS(buf[:n])
+		if err != nil || binary.BigEndian.Uint16(buf[2:])&0x8000 != 0 {
+			continue // not a query
+		}
+		records := mdnsRecords(ip, port)
+		asked := false
+		for _, q := range questions {
+			for _, r := range records {
+				asked = asked || (q.name == r.name && (q.qtype == r.rtype || q.qtype == dnsTypeANY))
+			}
+		}
+		if !asked {
+			continue
+		}
+		// A query not sent from 5353 is a one-shot query answered
+		// directly, echoing its ID and questions
+		if from.Port != mdnsGroup.Port {
+			conn.WriteToUDP(mdnsMessage(id, true, questions, records), from)
+		} else {
+			conn.WriteToUDP(mdnsMessage(0, true, nil, records), mdnsGroup)
+		}
+	}
+}
+
+// browseMDNS asks for _raspimon._tcp and returns the API URLs of the
+// monitors that answer, by instance name, leaving out this one
+func browseMDNS() (map[string]string, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	query := mdnsMessage(0, false, []dnsQuestion{{name: mdnsService, qtype: dnsTypePTR}}, nil)
+	if _, err := conn.WriteToUDP(query, mdnsGroup); err != nil {
+		return nil, err
+	}
+
+	var records []dnsRecord
+	conn.SetReadDeadline(time.Now().Add(mdnsBrowseWait))
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break // deadline
+		}
+		if _, _, rr, err := parseDNS(buf[:n]); err == nil {
+			records = append(records, rr...)
+		}
+	}
+
+	addrs := make(map[string]string)
+	for _, r := range records {
+		if r.rtype == dnsTypeA {
+			addrs[r.name] = r.data
+		}
+	}
+	self := strings.ToLower(hostname()) + "." + mdnsService
+	found := make(map[string]string)
+	for _, ptr := range records {
+		if ptr.rtype != dnsTypePTR || ptr.name != mdnsService || ptr.data == self {
+			continue
+		}
+		for _, srv := range records {
+			if srv.rtype == dnsTypeSRV && srv.name == ptr.data {
+				if ip, ok := addrs[srv.data]; ok {
+					instance := strings.TrimSuffix(ptr.data, "."+mdnsService)
+					found[instance] = "http://" + net.JoinHostPort(ip, strconv.Itoa(int(srv.port)))
+				}
+			}
+		}
+	}
+	return found, nil
+}
+
+// runMDNSDiscovery browses every mdns.interval and hands the monitors
+// found to the Cluster view until stop is closed
+func (d *Dashboard) runMDNSDiscovery(stop <-chan struct{}) {
+	ticker := time.NewTicker(d.config.MDNS.Interval)
+	defer ticker.Stop()
+
+	for {
+		found, err := browseMDNS()
+		if err != nil {
+			log.Printf("mDNS: browse failed: %v", err)
+		} else {
+			d.cluster.discovered(found)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
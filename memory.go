@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// ZramStats is collected by the collector package
+type ZramStats = collector.ZramStats
+
+func (d *Dashboard) updateMemoryView(stats SystemStats) {
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+
+	rows := []string{
+		"",
+		fmt.Sprintf("[RAM:](fg:%s) %.1f%% of %.0f MB", d.alertColor("mem", "yellow"), stats.MemPercent, bytesToMB(stats.MemTotal)),
+		getBar(stats.MemPercent, 20),
+		getSparkline(d.memHistory.Last(sparkWidth), 100, "yellow"),
+		fmt.Sprintf("Used:    %7.1f MB", bytesToMB(stats.MemUsed)),
+		fmt.Sprintf("Free:    %7.1f MB", bytesToMB(stats.MemFree)),
+		fmt.Sprintf("Avail:   %7.1f MB", bytesToMB(stats.MemAvailable)),
+		fmt.Sprintf("Cached:  %7.1f MB", bytesToMB(stats.MemCached)),
+		fmt.Sprintf("Buffers: %7.1f MB", bytesToMB(stats.MemBuffers)),
+		fmt.Sprintf("Shared:  %7.1f MB", bytesToMB(stats.MemShared)),
+		"",
+	}
+
+	if stats.SwapTotal > 0 {
+		rows = append(rows,
+			fmt.Sprintf("[SWAP:](fg:%s) %.1f%% of %.0f MB", d.alertColor("swap", "magenta"), stats.SwapPercent, bytesToMB(stats.SwapTotal)),
+			getBar(stats.SwapPercent, 20),
+			getSparkline(d.swapHistory.Last(sparkWidth), 100, "magenta"),
+			fmt.Sprintf("Used:    %7.1f MB", bytesToMB(stats.SwapUsed)),
+			"",
+		)
+	} else {
+		rows = append(rows, "[SWAP:](fg:magenta) disabled", "")
+	}
+
+	zram := stats.Zram
+	if !zram.Present {
+		rows = append(rows, "[ZRAM:](fg:cyan) not present")
+	} else {
+		rows = append(rows,
+			fmt.Sprintf("[ZRAM:](fg:cyan) %s", zram.Algorithm),
+			fmt.Sprintf("Size:    %7.1f MB", bytesToMB(zram.DiskSize)),
+			fmt.Sprintf("Stored:  %7.1f MB", bytesToMB(zram.OrigData)),
+			fmt.Sprintf("Compr:   %7.1f MB", bytesToMB(zram.ComprData)),
+			fmt.Sprintf("MemUsed: %7.1f MB", bytesToMB(zram.MemUsed)),
+			fmt.Sprintf("Ratio:   %.2fx", zram.Ratio()),
+		)
+	}
+
+	d.mainList.Rows = rows
+}
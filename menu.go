@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+)
+
+// menuOption is one selectable entry of a Menu
+type menuOption struct {
+	label  string
+	action func(d *Dashboard) // nil just closes the menu
+}
+
+// Menu is a modal list of options drawn over the current view. It is
+// used for confirmations and small action pickers so that every action
+// can be reached with the GPIO buttons alone.
+type Menu struct {
+	title    string
+	message  []string
+	options  []menuOption
+	selected int
+	keyboard *keyboard // set for text entry, which replaces the options
+	scroll   bool      // up/down scroll the message, e.g. of the help
+	offset   int       // first message row shown while scrolling
+}
+
+// openMenu shows m on top of the current view until an option is chosen
+func (d *Dashboard) openMenu(m *Menu) {
+	d.menu = m
+	d.Render()
+}
+
+func (d *Dashboard) closeMenu() {
+	d.menu = nil
+	d.Render()
+}
+
+// showMessage opens a menu with a single OK option
+func (d *Dashboard) showMessage(title string, lines ...string) {
+	d.openMenu(&Menu{
+		title:   title,
+		message: lines,
+		options: []menuOption{{label: "OK"}},
+	})
+}
+
+func (d *Dashboard) moveMenuSelection(delta int) {
+	if m := d.menu; m.scroll {
+		m.offset += delta
+		if m.offset > len(m.message)-1 {
+			m.offset = len(m.message) - 1
+		}
+		if m.offset < 0 {
+			m.offset = 0
+		}
+		d.Render()
+		return
+	}
+	n := len(d.menu.options)
+	d.menu.selected = ((d.menu.selected+delta)%n + n) % n
+	d.Render()
+}
+
+// chooseMenuOption closes the menu and runs the selected action
+func (d *Dashboard) chooseMenuOption() {
+	option := d.menu.options[d.menu.selected]
+	d.closeMenu()
+	if option.action != nil {
+		option.action(d)
+	}
+}
+
+// handleMenuKey handles keyboard input while a menu is open
+func (d *Dashboard) handleMenuKey(id string) {
+	if d.menu.keyboard != nil {
+		d.handleKeyboardKey(id)
+		return
+	}
+	switch id {
+	case "<Up>", "<MouseWheelUp>":
+		d.moveMenuSelection(-1)
+	case "<Down>", "<MouseWheelDown>":
+		d.moveMenuSelection(1)
+	case "<Enter>":
+		d.chooseMenuOption()
+	case "<Escape>", "q":
+		d.closeMenu()
+	}
+}
+
+// handleMenuButton handles GPIO buttons while a menu is open. Buttons
+// keep the meaning of their gpio.actions: up/down move, select/action
+// choose and back cancels.
+func (d *Dashboard) handleMenuButton(evt ButtonEvent) {
+	if d.menu.keyboard != nil {
+		d.handleKeyboardButton(evt)
+		return
+	}
+	switch d.buttonAction(evt.Button) {
+	case actionUp:
+		d.moveMenuSelection(-1)
+	case actionDown:
+		d.moveMenuSelection(1)
+	case actionSelect, actionAction:
+		if evt.Kind == PressShort {
+			d.chooseMenuOption()
+		}
+	case actionBack:
+		if evt.Kind == PressShort {
+			d.closeMenu()
+		}
+	}
+}
+
+// rows returns the message and the options with the selection marked
+func (m *Menu) rows() []string {
+	if m.keyboard != nil {
+		return append(append([]string{}, m.message...), m.keyboard.rows()...)
+	}
+	rows := append([]string{}, m.message[m.offset:]...)
+	if len(rows) > 0 {
+		rows = append(rows, "")
+	}
+	for i, option := range m.options {
+		if i == m.selected {
+			rows = append(rows, fmt.Sprintf("[> %s](bg:white,fg:black)", option.label))
+		} else {
+			rows = append(rows, "  "+option.label)
+		}
+	}
+	return rows
+}
+
+// focusRow returns the index in rows of the selected option or key, the
+// row that has to stay visible on small screens
+func (m *Menu) focusRow() int {
+	if m.keyboard != nil {
+		return len(m.message) + 2 + m.keyboard.row
+	}
+	if m.scroll {
+		return 0 // the top of the message
+	}
+	rows := len(m.message) + len(m.options)
+	if len(m.message) > 0 {
+		rows++
+	}
+	return rows - len(m.options) + m.selected
+}
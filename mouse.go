@@ -0,0 +1,48 @@
+package main
+
+import (
+	ui "github.com/gizak/termui/v3"
+)
+
+// handleMouse handles clicks and the scroll wheel in the terminal:
+// clicking the title switches to the next view (right click the
+// previous one), the wheel moves the selection and clicking a process
+// selects it
+func (d *Dashboard) handleMouse(e ui.Event) {
+	switch e.ID {
+	case "<MouseWheelUp>":
+		d.moveSelection(-1)
+		return
+	case "<MouseWheelDown>":
+		d.moveSelection(1)
+		return
+	}
+
+	t, ok := d.display.(*terminalDisplay)
+	if !ok {
+		return
+	}
+	mouse := e.Payload.(ui.Mouse)
+	row, ok := t.listRow(mouse.X, mouse.Y)
+	if !ok {
+		return
+	}
+	switch {
+	case row < 0 && e.ID == "<MouseLeft>":
+		d.switchView(1)
+	case row < 0 && e.ID == "<MouseRight>":
+		d.switchView(-1)
+	case e.ID == "<MouseLeft>" && d.currentView == viewProcess && d.detailPID == 0:
+		d.clickProcess(row)
+	}
+}
+
+// clickProcess selects the process on a row of the process list
+func (d *Dashboard) clickProcess(row int) {
+	i := d.processFirst + row - d.processTop
+	if row < d.processTop || i >= len(d.processList) || i == d.selectedProcess {
+		return
+	}
+	d.selectedProcess = i
+	d.redraw()
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// MQTTConfig configures publishing of the stats to an MQTT broker
+type MQTTConfig struct {
+	Broker          string `yaml:"broker"` // host:port, tcp://host:port or tls://host:port
+	Username        string `yaml:"username"`
+	Password        string `yaml:"password"`
+	TopicPrefix     string `yaml:"topic_prefix"`
+	Discovery       bool   `yaml:"discovery"`
+	DiscoveryPrefix string `yaml:"discovery_prefix"`
+}
+
+// mqttReconnectDelay is how long to wait before reconnecting to the broker
+const mqttReconnectDelay = 10 * time.Second
+
+// mqttSensor describes one published value and its Home Assistant entity
+type mqttSensor struct {
+	key         string // topic suffix and entity id
+	name        string
+	unit        string
+	deviceClass string
+	value       func(stats SystemStats, rates mqttRates) string
+}
+
+// mqttRates holds throughput computed between two publishes
+type mqttRates struct {
+	upKBs   float64
+	downKBs float64
+}
+
+var mqttSensors = []mqttSensor{
+	{"cpu", "CPU usage", "%", "", func(s SystemStats, _ mqttRates) string {
+		return fmt.Sprintf("%.1f", calculateAverage(s.CPUPercent))
+	}},
+	{"mem", "Memory usage", "%", "", func(s SystemStats, _ mqttRates) string {
+		return fmt.Sprintf("%.1f", s.MemPercent)
+	}},
+	{"swap", "Swap usage", "%", "", func(s SystemStats, _ mqttRates) string {
+		return fmt.Sprintf("%.1f", s.SwapPercent)
+	}},
+	{"disk", "Disk usage", "%", "", func(s SystemStats, _ mqttRates) string {
+		return fmt.Sprintf("%.1f", s.DiskPercent)
+	}},
+	{"temp", "CPU temperature", "°C", "temperature", func(s SystemStats, _ mqttRates) string {
+		return fmt.Sprintf("%.1f", s.Temperature)
+	}},
+	{"uptime", "Uptime", "s", "duration", func(s SystemStats, _ mqttRates) string {
+		return fmt.Sprintf("%d", s.Uptime)
+	}},
+	{"processes", "Processes", "", "", func(s SystemStats, _ mqttRates) string {
+		return fmt.Sprintf("%d", s.ProcessCount)
+	}},
+	{"net_up", "Upload", "kB/s", "data_rate", func(_ SystemStats, r mqttRates) string {
+		return fmt.Sprintf("%.1f", r.upKBs)
+	}},
+	{"net_down", "Download", "kB/s", "data_rate", func(_ SystemStats, r mqttRates) string {
+		return fmt.Sprintf("%.1f", r.downKBs)
+	}},
+	{"ip", "IP address", "", "", func(s SystemStats, _ mqttRates) string {
+		return s.IPAddress
+	}},
+}
+
+// mqttClient is a minimal MQTT 3.1.1 client that only publishes at QoS 0
+type mqttClient struct {
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// mqttString encodes s as a length-prefixed UTF-8 string
+func mqttString(s string) []byte {
+	b := make([]byte, 2, 2+len(s))
+	binary.BigEndian.PutUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// writePacket writes a control packet with the remaining length encoding
+func (c *mqttClient) writePacket(header byte, body []byte) error {
+	c.w.WriteByte(header)
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		c.w.WriteByte(b)
+		if n == 0 {
+			break
+		}
+	}
+	c.w.Write(body)
+	return c.w.Flush()
+}
+
+// dialMQTT connects to the broker and sends CONNECT with a last will
+// that marks the device offline
+func dialMQTT(cfg MQTTConfig, clientID, willTopic string, keepAlive time.Duration) (*mqttClient, error) {
+	addr := cfg.Broker
+	useTLS := false
+	switch {
+	case strings.HasPrefix(addr, "tls://"), strings.HasPrefix(addr, "ssl://"):
+		addr, useTLS = addr[6:], true
+	case strings.HasPrefix(addr, "tcp://"):
+		addr = addr[6:]
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		port := "1883"
+		if useTLS {
+			port = "8883"
+		}
+		addr = net.JoinHostPort(addr, port)
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &mqttClient{conn: conn, w: bufio.NewWriter(conn)}
+
+	flags := byte(0x02 | 0x04 | 0x20) // clean session, will flag, will retain
+	if cfg.Username != "" {
+		flags |= 0x80
+	}
+	if cfg.Password != "" {
+		flags |= 0x40
+	}
+
+	body := append(mqttString("MQTT"), 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(keepAlive/time.Second))
+	body = append(body, mqttString(clientID)...)
+	body = append(body, mqttString(willTopic)...)
+	body = append(body, mqttString("offline")...)
+	if cfg.Username != "" {
+		body = append(body, mqttString(cfg.Username)...)
+	}
+	if cfg.Password != "" {
+		body = append(body, mqttString(cfg.Password)...)
+	}
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := c.writePacket(0x10, body); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, ack); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read CONNACK: %w", err)
+	}
+	if ack[0] != 0x20 || ack[3] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("connection refused by broker (code %d)", ack[3])
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// Publish sends payload to topic at QoS 0
+func (c *mqttClient) Publish(topic string, payload []byte, retain bool) error {
+	header := byte(0x30)
+	if retain {
+		header |= 0x01
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return c.writePacket(header, append(mqttString(topic), payload...))
+}
+
+// Close sends DISCONNECT and closes the connection
+func (c *mqttClient) Close() {
+	c.writePacket(0xe0, nil)
+	c.conn.Close()
+}
+
+// mqttNodeID turns the host name into an id usable in topics and entity ids
+func mqttNodeID(host string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return '_'
+	}, host)
+}
+
+// publishDiscovery announces every sensor to Home Assistant
+func (c *mqttClient) publishDiscovery(cfg MQTTConfig, host, base string) error {
+	node := mqttNodeID(host)
+	device := map[string]interface{}{
+		"identifiers":  []string{"raspi-monitor-" + node},
+		"name":         host,
+		"model":        "Raspberry Pi",
+		"manufacturer": "Raspberry Pi Foundation",
+		"sw_version":   "raspi-monitor",
+	}
+
+	for _, sensor := range mqttSensors {
+		entity := map[string]interface{}{
+			"name":               sensor.name,
+			"unique_id":          node + "_" + sensor.key,
+			"state_topic":        base + "/" + sensor.key,
+			"availability_topic": base + "/status",
+			"device":             device,
+		}
+		if sensor.unit != "" {
+			entity["unit_of_measurement"] = sensor.unit
+			entity["state_class"] = "measurement"
+		}
+		if sensor.deviceClass != "" {
+			entity["device_class"] = sensor.deviceClass
+		}
+
+		payload, err := json.Marshal(entity)
+		if err != nil {
+			return err
+		}
+		topic := fmt.Sprintf("%s/sensor/%s/%s/config", cfg.DiscoveryPrefix, node, sensor.key)
+		if err := c.Publish(topic, payload, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runMQTT publishes the latest stats every interval until stop is
+// closed, reconnecting to the broker whenever the connection drops
+func (d *Dashboard) runMQTT(stop <-chan struct{}) {
+	cfg := d.config.MQTT
+	host := hostname()
+	base := fmt.Sprintf("%s/%s", cfg.TopicPrefix, host)
+
+	keepAlive := 3 * d.config.Interval
+	if keepAlive < time.Minute {
+		keepAlive = time.Minute
+	}
+
+	for {
+		client, err := dialMQTT(cfg, "raspi-monitor-"+mqttNodeID(host), base+"/status", keepAlive)
+		if err == nil {
+			log.Printf("MQTT connected to %s", cfg.Broker)
+			err = d.publishMQTT(client, cfg, host, base, stop)
+			if err == nil {
+				return
+			}
+		}
+		log.Printf("MQTT: %v, retrying in %s", err, mqttReconnectDelay)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(mqttReconnectDelay):
+		}
+	}
+}
+
+// publishMQTT runs one broker session. It returns nil when stop is closed.
+func (d *Dashboard) publishMQTT(client *mqttClient, cfg MQTTConfig, host, base string, stop <-chan struct{}) error {
+	defer client.Close()
+
+	if err := client.Publish(base+"/status", []byte("online"), true); err != nil {
+		return err
+	}
+	if cfg.Discovery {
+		if err := client.publishDiscovery(cfg, host, base); err != nil {
+			return fmt.Errorf("discovery: %w", err)
+		}
+	}
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	var prev SystemStats
+	var prevAt time.Time
+	for {
+		select {
+		case <-stop:
+			client.Publish(base+"/status", []byte("offline"), true)
+			return nil
+		case <-ticker.C:
+		}
+
+		stats, at := d.snapshot.Get()
+		if at.IsZero() || !at.After(prevAt) {
+			continue
+		}
+
+		var rates mqttRates
+		if !prevAt.IsZero() {
+			seconds := at.Sub(prevAt).Seconds()
+			rates.upKBs = counterRate(stats.NetSent, prev.NetSent, seconds) / 1024
+			rates.downKBs = counterRate(stats.NetRecv, prev.NetRecv, seconds) / 1024
+		}
+		prev, prevAt = stats, at
+
+		for _, sensor := range mqttSensors {
+			if err := client.Publish(base+"/"+sensor.key, []byte(sensor.value(stats, rates)), false); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// InterfaceStats is collected by the collector package
+type InterfaceStats = collector.InterfaceStats
+
+// ifaceRate is the throughput of one interface in bytes per second
+type ifaceRate struct {
+	sent float64
+	recv float64
+}
+
+// recordInterfaceRates updates the per-interface rates from the counters
+// seen seconds ago. Interfaces that just appeared or whose counters went
+// backwards (driver reload) get no rate for this sample.
+func (d *Dashboard) recordInterfaceRates(ifaces []InterfaceStats, seconds float64) {
+	rates := make(map[string]ifaceRate, len(ifaces))
+	prev := make(map[string]InterfaceStats, len(ifaces))
+	for _, iface := range ifaces {
+		if p, ok := d.prevIfaces[iface.Name]; ok {
+			rates[iface.Name] = ifaceRate{
+				sent: counterRate(iface.BytesSent, p.BytesSent, seconds),
+				recv: counterRate(iface.BytesRecv, p.BytesRecv, seconds),
+			}
+		}
+		prev[iface.Name] = iface
+	}
+	d.ifaceRates = rates
+	d.prevIfaces = prev
+}
+
+func (d *Dashboard) updateNetworkView(stats SystemStats) {
+	d.ifaceList = stats.Interfaces
+	if d.selectedIface >= len(d.ifaceList) {
+		d.selectedIface = len(d.ifaceList) - 1
+	}
+	if d.selectedIface < 0 {
+		d.selectedIface = 0
+	}
+
+	// Both sparklines share one scale so they can be compared
+	sent := d.sentHistory.Last(sparkWidth)
+	recv := d.recvHistory.Last(sparkWidth)
+	peak := 0.0
+	for _, v := range append(append([]float64(nil), sent...), recv...) {
+		if v > peak {
+			peak = v
+		}
+	}
+
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+	rows := []string{
+		"[--Total--](fg:cyan)",
+		fmt.Sprintf("Up:   %8s %9.1fMB", formatRate(d.netSentRate), bytesToMB(stats.NetSent)),
+		fmt.Sprintf("Down: %8s %9.1fMB", formatRate(d.netRecvRate), bytesToMB(stats.NetRecv)),
+		fmt.Sprintf("Up %s", getSparkline(sent, peak, "yellow")),
+		fmt.Sprintf("Dn %s", getSparkline(recv, peak, "green")),
+		fmt.Sprintf("Peak: %s", formatRate(peak)),
+	}
+	rows = append(rows, speedTestRows(d.speedTest)...)
+	if d.showProcNet && !d.reuseLists {
+		d.recordProcessNet(stats.AllProcesses)
+	}
+	rows = append(rows, d.processNetRows()...)
+	rows = append(rows, vpnRows(d.vpn.Tunnels(openVPNProcesses(stats.AllProcesses)), stats.Interfaces)...)
+	rows = append(rows,
+		"",
+		"[--Interfaces--](fg:magenta)",
+		"[Name            Up      Down](fg:cyan)",
+	)
+
+	for i, iface := range d.ifaceList {
+		rate := d.ifaceRates[iface.Name]
+		line := fmt.Sprintf("%-8s %9s %9s", truncateString(iface.Name, 8), formatRate(rate.sent), formatRate(rate.recv))
+		switch {
+		case i == d.selectedIface:
+			rows = append(rows, fmt.Sprintf("[%s](bg:white,fg:black)", line))
+		case !iface.Up:
+			rows = append(rows, fmt.Sprintf("[%s](fg:red)", line))
+		default:
+			rows = append(rows, line)
+		}
+	}
+
+	if len(d.ifaceList) > 0 {
+		rows = append(rows, "", fmt.Sprintf("[--%s--](fg:yellow)", d.ifaceList[d.selectedIface].Name))
+		rows = append(rows, interfaceDetailRows(d.ifaceList[d.selectedIface])...)
+	}
+	d.mainList.Rows = rows
+}
+
+// interfaceDetailRows shows link state, MAC and addresses of iface
+func interfaceDetailRows(iface InterfaceStats) []string {
+	state := "[DOWN](fg:red)"
+	if iface.Up {
+		state = "[UP](fg:green)"
+	}
+	mac := iface.MAC
+	if mac == "" {
+		mac = "-"
+	}
+
+	rows := []string{
+		fmt.Sprintf("State: %s", state),
+		fmt.Sprintf("MAC: %s", mac),
+		fmt.Sprintf("Sent: %.1f MB", bytesToMB(iface.BytesSent)),
+		fmt.Sprintf("Recv: %.1f MB", bytesToMB(iface.BytesRecv)),
+	}
+	if iface.Wifi != nil {
+		rows = append(rows, wifiRows(iface.Wifi)...)
+	}
+	if len(iface.Addrs) == 0 {
+		return append(rows, "IP: -")
+	}
+	for _, addr := range iface.Addrs {
+		rows = append(rows, "IP: "+truncateString(addr, 24))
+	}
+	return rows
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// notifyTimeout bounds one notification request
+const notifyTimeout = 10 * time.Second
+
+var notifyClient = &http.Client{Timeout: notifyTimeout}
+
+// WebhookConfig posts alerts as JSON to a URL
+type WebhookConfig struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"` // e.g. Authorization
+}
+
+// TelegramConfig sends alerts through a Telegram bot
+type TelegramConfig struct {
+	Token  string `yaml:"token"`
+	ChatID string `yaml:"chat_id"`
+}
+
+// NtfyConfig publishes alerts to an ntfy topic, which the ntfy app on a
+// phone subscribes to
+type NtfyConfig struct {
+	Server   string `yaml:"server"`   // ntfy.sh or a self-hosted server
+	Topic    string `yaml:"topic"`    // empty disables ntfy
+	Token    string `yaml:"token"`    // access token for a protected topic
+	Priority int    `yaml:"priority"` // 1 (min) to 5 (max) for firing alerts, 0 uses the server default
+}
+
+func (c NtfyConfig) validate() error {
+	if c.Topic == "" {
+		return nil
+	}
+	if u, err := url.Parse(c.Server); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("alerts.ntfy.server must be an http(s) URL")
+	}
+	if strings.ContainsAny(c.Topic, "/?# ") {
+		return fmt.Errorf("alerts.ntfy.topic must be a plain name, got %q", c.Topic)
+	}
+	if c.Priority < 0 || c.Priority > 5 {
+		return fmt.Errorf("alerts.ntfy.priority must be between 1 and 5")
+	}
+	return nil
+}
+
+// PushoverConfig sends alerts through Pushover
+type PushoverConfig struct {
+	Token    string `yaml:"token"`    // API token of the application
+	User     string `yaml:"user"`     // user or group key
+	Priority int    `yaml:"priority"` // -2 to 1 for firing alerts
+}
+
+func (c PushoverConfig) validate() error {
+	if (c.Token == "") != (c.User == "") {
+		return fmt.Errorf("alerts.pushover needs both token and user")
+	}
+	// Priority 2 repeats until acknowledged and needs retry and expire
+	if c.Priority < -2 || c.Priority > 1 {
+		return fmt.Errorf("alerts.pushover.priority must be between -2 and 1")
+	}
+	return nil
+}
+
+// webhookPayload is the JSON body sent to the webhook
+type webhookPayload struct {
+	Host      string    `json:"host"`
+	Alert     string    `json:"alert"`
+	Metric    string    `json:"metric"`
+	Value     float64   `json:"value"`
+	Op        string    `json:"op"`
+	Threshold float64   `json:"threshold"`
+	Since     time.Time `json:"since"`
+	Resolved  bool      `json:"resolved"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+type webhookAction struct {
+	cfg WebhookConfig
+}
+
+func (a webhookAction) Fire(evt AlertEvent)    { a.send(evt) }
+func (a webhookAction) Resolve(evt AlertEvent) { a.send(evt) }
+
+func (a webhookAction) send(evt AlertEvent) {
+	op := evt.Rule.Op
+	if op == "" {
+		op = ">"
+	}
+	body, err := json.Marshal(webhookPayload{
+		Host:      evt.Host,
+		Alert:     evt.Rule.Name,
+		Metric:    evt.Rule.Metric,
+		Value:     evt.Value,
+		Op:        op,
+		Threshold: evt.Rule.Threshold,
+		Since:     evt.Since,
+		Resolved:  evt.Resolved,
+		Message:   evt.Message(),
+		Time:      time.Now(),
+	})
+	if err != nil {
+		log.Printf("Webhook: %v", err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Webhook: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range a.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if err := doNotify(req); err != nil {
+		log.Printf("Webhook: %v", err)
+	}
+}
+
+type telegramAction struct {
+	cfg TelegramConfig
+}
+
+func (a telegramAction) Fire(evt AlertEvent)    { a.send("🔥 " + evt.Message()) }
+func (a telegramAction) Resolve(evt AlertEvent) { a.send("✅ " + evt.Message()) }
+
+func (a telegramAction) send(text string) {
+	endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", a.cfg.Token)
+	form := url.Values{"chat_id": {a.cfg.ChatID}, "text": {text}}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		log.Printf("Telegram: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := doNotify(req); err != nil {
+		// Keep the bot token in the request URL out of the log
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		log.Printf("Telegram: %v", err)
+	}
+}
+
+type ntfyAction struct {
+	cfg NtfyConfig
+}
+
+func (a ntfyAction) Fire(evt AlertEvent)    { a.send(evt, a.cfg.Priority, "rotating_light") }
+func (a ntfyAction) Resolve(evt AlertEvent) { a.send(evt, 0, "white_check_mark") }
+
+func (a ntfyAction) send(evt AlertEvent, priority int, tag string) {
+	endpoint := strings.TrimSuffix(a.cfg.Server, "/") + "/" + a.cfg.Topic
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBufferString(evt.Message()))
+	if err != nil {
+		log.Printf("ntfy: %v", err)
+		return
+	}
+	req.Header.Set("Title", alertTitle(evt))
+	req.Header.Set("Tags", tag)
+	if priority > 0 {
+		req.Header.Set("Priority", strconv.Itoa(priority))
+	}
+	if a.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.cfg.Token)
+	}
+	if err := doNotify(req); err != nil {
+		// Anyone who knows the topic can read it, so keep it out of the log
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		log.Printf("ntfy: %v", err)
+	}
+}
+
+type pushoverAction struct {
+	cfg PushoverConfig
+}
+
+func (a pushoverAction) Fire(evt AlertEvent)    { a.send(evt, a.cfg.Priority) }
+func (a pushoverAction) Resolve(evt AlertEvent) { a.send(evt, 0) }
+
+func (a pushoverAction) send(evt AlertEvent, priority int) {
+	form := url.Values{
+		"token":    {a.cfg.Token},
+		"user":     {a.cfg.User},
+		"title":    {alertTitle(evt)},
+		"message":  {evt.Message()},
+		"priority": {strconv.Itoa(priority)},
+	}
+	req, err := http.NewRequest(http.MethodPost, "https://api.pushover.net/1/messages.json", bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		log.Printf("Pushover: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := doNotify(req); err != nil {
+		log.Printf("Pushover: %v", err)
+	}
+}
+
+// alertTitle is the short title of a push notification, e.g.
+// "high-temp firing"
+func alertTitle(evt AlertEvent) string {
+	if evt.Resolved {
+		return evt.Rule.Name + " resolved"
+	}
+	return evt.Rule.Name + " firing"
+}
+
+// doNotify sends req and treats any non-2xx answer as an error
+func doNotify(req *http.Request) error {
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"syscall"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	oledWidth  = 128
+	oledHeight = 64
+
+	// i2cSlave is I2C_SLAVE from linux/i2c-dev.h
+	i2cSlave = 0x0703
+
+	// Control bytes that prefix every I2C write
+	oledCommand = 0x00
+	oledData    = 0x40
+)
+
+// oledPage is one screen of the OLED: a label and a large value
+type oledPage struct {
+	label string
+	value func(stats SystemStats) string
+}
+
+// oledPages are the stats that fit a 128x64 screen in readable text
+var oledPages = []oledPage{
+	{"IP", func(s SystemStats) string {
+		if s.IPAddress == "" {
+			return "-"
+		}
+		return s.IPAddress
+	}},
+	{"CPU", func(s SystemStats) string { return fmt.Sprintf("%.0f%%", calculateAverage(s.CPUPercent)) }},
+	{"TEMP", func(s SystemStats) string { return fmt.Sprintf("%.1f°C", s.Temperature) }},
+	{"MEM", func(s SystemStats) string { return fmt.Sprintf("%.0f%%", s.MemPercent) }},
+}
+
+// oledValueSizes are tried largest first until the value fits the width
+var oledValueSizes = []float64{32, 24, 16, 12}
+
+// oledPanel drives an SSD1306 or SH1106 controller through i2c-dev
+type oledPanel struct {
+	f      *os.File
+	sh1106 bool
+	buf    []byte // one byte per column and page of 8 rows
+}
+
+func openOLED(cfg DisplayConfig) (*oledPanel, error) {
+	device := cfg.Device
+	if device == "" {
+		device = "/dev/i2c-1"
+	}
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open %s (dtparam=i2c_arm=on?): %w", device, err)
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), i2cSlave, uintptr(cfg.Address)); errno != 0 {
+		f.Close()
+		return nil, fmt.Errorf("select I2C address 0x%02x: %w", cfg.Address, errno)
+	}
+
+	p := &oledPanel{f: f, sh1106: cfg.Backend == displaySH1106, buf: make([]byte, oledWidth*oledHeight/8)}
+	if err := p.init(cfg.Rotation == 180); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("initialize %s at 0x%02x: %w", cfg.Backend, cfg.Address, err)
+	}
+	return p, nil
+}
+
+// init configures a 128x64 panel in page addressing mode, which both
+// controllers support
+func (p *oledPanel) init(flip bool) error {
+	segRemap, comScan := byte(0xa1), byte(0xc8)
+	if flip {
+		segRemap, comScan = 0xa0, 0xc0
+	}
+	chargePump := []byte{0x8d, 0x14} // SSD1306
+	if p.sh1106 {
+		chargePump = []byte{0xad, 0x8b} // SH1106 DC-DC on
+	}
+
+	cmds := []byte{
+		0xae,       // display off
+		0xd5, 0x80, // clock divide
+		0xa8, 0x3f, // multiplex 64
+		0xd3, 0x00, // display offset
+		0x40, // start line 0
+	}
+	cmds = append(cmds, chargePump...)
+	if !p.sh1106 {
+		cmds = append(cmds, 0x20, 0x02) // page addressing mode
+	}
+	cmds = append(cmds,
+		segRemap, comScan,
+		0xda, 0x12, // COM pins
+		0x81, 0xcf, // contrast
+		0xd9, 0xf1, // precharge
+		0xdb, 0x40, // VCOMH
+		0xa4, // show RAM
+		0xa6, // normal, not inverted
+		0xaf, // display on
+	)
+	return p.command(cmds...)
+}
+
+func (p *oledPanel) command(cmds ...byte) error {
+	_, err := p.f.Write(append([]byte{oledCommand}, cmds...))
+	return err
+}
+
+// Draw packs img into pages of 8 vertical pixels and writes them.
+// Pixels brighter than mid gray are lit.
+func (p *oledPanel) Draw(img *image.Gray) error {
+	for i := range p.buf {
+		p.buf[i] = 0
+	}
+	for y := 0; y < oledHeight; y++ {
+		for x := 0; x < oledWidth; x++ {
+			if img.GrayAt(x, y).Y > 127 {
+				p.buf[y/8*oledWidth+x] |= 1 << (y % 8)
+			}
+		}
+	}
+
+	// The SH1106 has 132 columns of RAM with the panel in the middle
+	column := 0
+	if p.sh1106 {
+		column = 2
+	}
+	for page := 0; page < oledHeight/8; page++ {
+		if err := p.command(0xb0+byte(page), byte(column&0x0f), 0x10|byte(column>>4)); err != nil {
+			return err
+		}
+		row := p.buf[page*oledWidth : (page+1)*oledWidth]
+		if _, err := p.f.Write(append([]byte{oledData}, row...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *oledPanel) Close() error {
+	p.command(0xae)
+	return p.f.Close()
+}
+
+// oledRenderer shows one stat page at a time in large text, turning
+// pages on a timer and with the view buttons
+type oledRenderer struct {
+	panel    *oledPanel
+	small    font.Face
+	values   []font.Face // one per oledValueSizes
+	img      *image.Gray
+	page     int
+	interval time.Duration
+	turned   time.Time
+}
+
+func newOLEDRenderer(cfg DisplayConfig) (*oledRenderer, error) {
+	panel, err := openOLED(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &oledRenderer{
+		panel:    panel,
+		img:      image.NewGray(image.Rect(0, 0, oledWidth, oledHeight)),
+		interval: cfg.PageInterval,
+		turned:   time.Now(),
+	}
+	if r.small, err = monoFace(10); err != nil {
+		panel.Close()
+		return nil, err
+	}
+	for _, size := range oledValueSizes {
+		face, err := monoFace(size)
+		if err != nil {
+			panel.Close()
+			return nil, err
+		}
+		r.values = append(r.values, face)
+	}
+	return r, nil
+}
+
+// Turn moves delta pages forward, or backward when negative
+func (r *oledRenderer) Turn(delta int) {
+	n := len(oledPages)
+	r.page = ((r.page+delta)%n + n) % n
+	r.turned = time.Now()
+}
+
+// Render draws the current page, or the menu while one is open
+func (r *oledRenderer) Render(frame Frame) error {
+	draw.Draw(r.img, r.img.Bounds(), image.Black, image.Point{}, draw.Src)
+
+	if frame.Menu != nil {
+		r.drawMenu(frame.Menu)
+		return r.panel.Draw(r.img)
+	}
+
+	if r.interval > 0 && time.Since(r.turned) >= r.interval {
+		r.Turn(1)
+	}
+	page := oledPages[r.page]
+
+	// Label and page number on top, a rule under them
+	r.drawString(r.small, 0, 10, page.label)
+	counter := fmt.Sprintf("%d/%d", r.page+1, len(oledPages))
+	r.drawString(r.small, oledWidth-font.MeasureString(r.small, counter).Ceil(), 10, counter)
+	draw.Draw(r.img, image.Rect(0, 13, oledWidth, 14), image.White, image.Point{}, draw.Src)
+
+	// The value centered below in the largest size that fits
+	value := page.value(frame.Stats)
+	face := r.values[len(r.values)-1]
+	for _, f := range r.values {
+		if font.MeasureString(f, value).Ceil() <= oledWidth {
+			face = f
+			break
+		}
+	}
+	m := face.Metrics()
+	width := font.MeasureString(face, value).Ceil()
+	baseline := 14 + (oledHeight-14+m.Ascent.Ceil()-m.Descent.Ceil())/2
+	r.drawString(face, (oledWidth-width)/2, baseline, value)
+
+	return r.panel.Draw(r.img)
+}
+
+// drawMenu lists the menu in small text with the selected row inverted
+func (r *oledRenderer) drawMenu(menu *Menu) {
+	r.drawString(r.small, 0, 10, menu.title)
+	draw.Draw(r.img, image.Rect(0, 12, oledWidth, 13), image.White, image.Point{}, draw.Src)
+
+	rows := menu.rows()
+	// Keep the selected option on screen when the menu is longer than
+	// the five rows that fit
+	first := 0
+	if last := menu.focusRow(); last >= 5 {
+		first = last - 4
+	}
+	for i, row := range rows[first:] {
+		if i >= 5 {
+			break
+		}
+		y := 14 + i*10
+		text := ""
+		cells := ui.ParseStyles(row, ui.NewStyle(ui.ColorWhite))
+		highlighted := 0
+		for _, cell := range cells {
+			text += string(cell.Rune)
+			if cell.Style.Bg != ui.ColorClear {
+				highlighted++
+			}
+		}
+		switch {
+		case highlighted == 0:
+			r.drawString(r.small, 0, y+8, text)
+		case highlighted == len(cells):
+			draw.Draw(r.img, image.Rect(0, y, oledWidth, y+10), image.White, image.Point{}, draw.Src)
+			r.drawText(r.small, 0, y+8, text, color.Gray{})
+		default:
+			// Only part of the row is selected, like the key under the
+			// cursor of the on-screen keyboard
+			x := 0
+			for _, cell := range cells {
+				s := string(cell.Rune)
+				w := font.MeasureString(r.small, s).Ceil()
+				if cell.Style.Bg != ui.ColorClear {
+					draw.Draw(r.img, image.Rect(x, y, x+w, y+10), image.White, image.Point{}, draw.Src)
+					r.drawText(r.small, x, y+8, s, color.Gray{})
+				} else {
+					r.drawString(r.small, x, y+8, s)
+				}
+				x += w
+			}
+		}
+	}
+}
+
+func (r *oledRenderer) drawString(face font.Face, x, baseline int, text string) {
+	r.drawText(face, x, baseline, text, color.Gray{Y: 255})
+}
+
+func (r *oledRenderer) drawText(face font.Face, x, baseline int, text string, c color.Gray) {
+	d := font.Drawer{Dst: r.img, Src: image.NewUniform(c), Face: face, Dot: fixed.P(x, baseline)}
+	d.DrawString(text)
+}
+
+// Events returns nil; the OLED is driven by the buttons
+func (r *oledRenderer) Events() <-chan ui.Event {
+	return nil
+}
+
+// Blank switches the panel off, or back on. An OLED has no backlight;
+// switching it off stops the pixels from burning in.
+func (r *oledRenderer) Blank(blank bool) error {
+	if blank {
+		return r.panel.command(0xae) // display off
+	}
+	return r.panel.command(0xaf) // display on
+}
+
+// Close blanks the screen and switches the panel off
+func (r *oledRenderer) Close() error {
+	draw.Draw(r.img, r.img.Bounds(), image.Black, image.Point{}, draw.Src)
+	r.panel.Draw(r.img)
+	return r.panel.Close()
+}
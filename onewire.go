@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// OneWireConfig names the DS18B20 probes on the 1-Wire bus
+type OneWireConfig struct {
+	Enabled  bool              `yaml:"enabled"`
+	Interval time.Duration     `yaml:"interval"`
+	Names    map[string]string `yaml:"names"` // probe ID, e.g. 28-3c01d607d1a2 -> name
+}
+
+func (c OneWireConfig) validate() error {
+	if c.Enabled && c.Interval <= 0 {
+		return fmt.Errorf("one_wire.interval must be positive")
+	}
+	return nil
+}
+
+// OneWireMonitor reads the probes in the background. The conversion of
+// every probe takes up to 750ms, which would hold up the samples.
+type OneWireMonitor struct {
+	cfg OneWireConfig
+
+	mu       sync.Mutex
+	readings []SensorReading
+}
+
+func newOneWireMonitor(cfg OneWireConfig) *OneWireMonitor {
+	return &OneWireMonitor{cfg: cfg}
+}
+
+// Readings returns the latest probe values, labelled with their
+// configured names
+func (m *OneWireMonitor) Readings() []SensorReading {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.readings
+}
+
+// Run reads the probes every interval until stop is closed
+func (m *OneWireMonitor) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	seen := make(map[string]bool)
+	for {
+		readings := collector.OneWireTemps()
+		for i, r := range readings {
+			if !seen[r.Label] {
+				log.Printf("1-Wire probe %s", r.Label)
+				seen[r.Label] = true
+			}
+			readings[i].Chip = "1-Wire"
+			if name := m.cfg.Names[r.Label]; name != "" {
+				readings[i].Label = name
+			}
+		}
+		m.mu.Lock()
+		m.readings = readings
+		m.mu.Unlock()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
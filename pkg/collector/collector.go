@@ -0,0 +1,185 @@
+// Package collector gathers the Raspberry Pi system statistics shown by
+// raspi-monitor: CPU, memory, zram, disk, temperature, firmware clocks
+// and throttling, load and pressure stalls, thermal, hwmon and 1-Wire
+// sensors, network interfaces and processes. It has no UI dependencies so exporters and other programs
+// can reuse it. External collectors implement MetricCollector.
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// SystemStats is one sample of everything the collector measures
+type SystemStats struct {
+	CPUPercent   []float64        `json:"cpu_percent"`
+	MemPercent   float64          `json:"mem_percent"`
+	MemUsed      uint64           `json:"mem_used"`
+	MemTotal     uint64           `json:"mem_total"`
+	MemFree      uint64           `json:"mem_free"`
+	MemAvailable uint64           `json:"mem_available"`
+	MemCached    uint64           `json:"mem_cached"`
+	MemBuffers   uint64           `json:"mem_buffers"`
+	MemShared    uint64           `json:"mem_shared"`
+	SwapPercent  float64          `json:"swap_percent"`
+	SwapUsed     uint64           `json:"swap_used"`
+	SwapTotal    uint64           `json:"swap_total"`
+	Zram         ZramStats        `json:"zram"`
+	DiskPercent  float64          `json:"disk_percent"`
+	Temperature  float64          `json:"temperature_celsius"`
+	Uptime       uint64           `json:"uptime_seconds"`
+	NetSent      uint64           `json:"net_sent_bytes"`
+	NetRecv      uint64           `json:"net_recv_bytes"`
+	ProcessCount uint64           `json:"process_count"`
+	Zombies      int              `json:"zombies"` // processes in state Z
+	Blocked      int              `json:"blocked"` // processes in state D, usually stuck on I/O
+	AllProcesses []ProcessInfo    `json:"processes,omitempty"`
+	IPAddress    string           `json:"ip_address"`
+	APMode       string           `json:"ap_mode"`
+	Throttle     ThrottleStatus   `json:"throttle"`
+	CoreVolts    float64          `json:"core_volts"`
+	ARMClock     uint64           `json:"arm_clock_hz"`
+	CoreClock    uint64           `json:"core_clock_hz"`
+	SDRAMClock   uint64           `json:"sdram_clock_hz"`
+	Fan          *FanStatus       `json:"fan,omitempty"`
+	Battery      *BatteryStatus   `json:"battery,omitempty"`
+	Interfaces   []InterfaceStats `json:"interfaces"`
+	DiskIO       []DiskIOStats    `json:"disk_io"`
+	Load         LoadStats        `json:"load"`
+	Pressure     *PressureStats   `json:"pressure,omitempty"` // nil without kernel PSI
+	Sensors      []SensorReading  `json:"sensors,omitempty"`
+	Environment  []EnvReading     `json:"environment,omitempty"`
+	Plugins      []Metric         `json:"plugins,omitempty"`
+}
+
+// FanStatus is the fan state reported with the stats. The collector
+// does not fill it in; a fan controller sets it on the sample.
+type FanStatus struct {
+	Duty int    `json:"duty_percent"`
+	Mode string `json:"mode"`
+}
+
+// BatteryStatus is the state of a UPS HAT, set on the sample by the
+// program like FanStatus
+type BatteryStatus struct {
+	Percent  float64 `json:"percent"`
+	Voltage  float64 `json:"voltage,omitempty"` // 0 when the HAT does not report it
+	Charging bool    `json:"charging"`
+	Plugged  bool    `json:"power_plugged"` // external power present
+}
+
+// EnvReading is one ambient sensor reported with the stats. Like
+// FanStatus it is set on the sample by the program, not the collector.
+type EnvReading struct {
+	Name        string   `json:"name"`
+	Temperature float64  `json:"temperature_celsius"`
+	Humidity    *float64 `json:"humidity_percent,omitempty"` // nil without a humidity sensor
+	Pressure    *float64 `json:"pressure_hpa,omitempty"`     // nil without a pressure sensor
+}
+
+// Options configures a Collector
+type Options struct {
+	DiskMount string // mount point for DiskPercent, "/" when empty
+}
+
+// Collector takes SystemStats samples. It remembers the CPU time of
+// every process between samples, so keep one Collector per sampling loop.
+type Collector struct {
+	opts Options
+
+	mu         sync.Mutex
+	prevProc   map[int32]procCPU
+	prevProcAt time.Time
+}
+
+// New returns a collector with opts
+func New(opts Options) *Collector {
+	if opts.DiskMount == "" {
+		opts.DiskMount = "/"
+	}
+	return &Collector{opts: opts}
+}
+
+// Collect takes one sample. Figures that cannot be read, e.g. the
+// firmware values on other hardware, are left at zero. The error is
+// only set when ctx ends before the sample is complete.
+func (c *Collector) Collect(ctx context.Context) (SystemStats, error) {
+	stats := SystemStats{}
+
+	if cpuPercents, err := cpu.PercentWithContext(ctx, 0, true); err == nil {
+		stats.CPUPercent = cpuPercents
+	}
+
+	if memInfo, err := mem.VirtualMemoryWithContext(ctx); err == nil {
+		stats.MemPercent = memInfo.UsedPercent
+		stats.MemUsed = memInfo.Used
+		stats.MemTotal = memInfo.Total
+		stats.MemFree = memInfo.Free
+		stats.MemAvailable = memInfo.Available
+		stats.MemCached = memInfo.Cached
+		stats.MemBuffers = memInfo.Buffers
+		stats.MemShared = memInfo.Shared
+	}
+
+	if swapInfo, err := mem.SwapMemoryWithContext(ctx); err == nil {
+		stats.SwapPercent = swapInfo.UsedPercent
+		stats.SwapUsed = swapInfo.Used
+		stats.SwapTotal = swapInfo.Total
+	}
+
+	stats.Zram = Zram()
+
+	if diskInfo, err := disk.UsageWithContext(ctx, c.opts.DiskMount); err == nil {
+		stats.DiskPercent = diskInfo.UsedPercent
+	}
+	stats.DiskIO = DiskIO(ctx)
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+
+	stats.Temperature = CPUTemperature(ctx)
+	stats.Sensors = Sensors()
+	stats.Throttle = Throttle(ctx)
+	stats.CoreVolts = CoreVolts(ctx)
+	stats.ARMClock = MeasureClock(ctx, "arm")
+	stats.CoreClock = MeasureClock(ctx, "core")
+	stats.SDRAMClock = SDRAMClock(ctx)
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+
+	stats.Load = Load(ctx)
+	stats.Pressure = PressureStall()
+
+	if hostInfo, err := host.InfoWithContext(ctx); err == nil {
+		stats.Uptime = hostInfo.Uptime
+		stats.ProcessCount = hostInfo.Procs
+	}
+
+	stats.Interfaces = Interfaces(ctx)
+	for _, iface := range stats.Interfaces {
+		stats.NetSent += iface.BytesSent
+		stats.NetRecv += iface.BytesRecv
+	}
+
+	stats.AllProcesses = c.Processes(ctx)
+	for _, p := range stats.AllProcesses {
+		switch p.Status {
+		case process.Zombie:
+			stats.Zombies++
+		case process.Blocked:
+			stats.Blocked++
+		}
+	}
+	stats.IPAddress = IPAddress()
+	stats.APMode = APMode(ctx)
+
+	return stats, ctx.Err()
+}
@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// DiskIOStats holds the cumulative I/O counters of one block device
+type DiskIOStats struct {
+	Name       string `json:"name"`
+	ReadBytes  uint64 `json:"read_bytes"`
+	WriteBytes uint64 `json:"write_bytes"`
+	ReadCount  uint64 `json:"read_count"`
+	WriteCount uint64 `json:"write_count"`
+}
+
+// DiskIO returns the counters of whole block devices, sorted by name.
+// Partitions, loop and ram devices are skipped.
+func DiskIO(ctx context.Context) []DiskIOStats {
+	counters, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		return nil
+	}
+
+	var list []DiskIOStats
+	for name, c := range counters {
+		if !isWholeDisk(name) {
+			continue
+		}
+		list = append(list, DiskIOStats{
+			Name:       name,
+			ReadBytes:  c.ReadBytes,
+			WriteBytes: c.WriteBytes,
+			ReadCount:  c.ReadCount,
+			WriteCount: c.WriteCount,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// isWholeDisk reports whether name is a disk rather than a partition,
+// using the /sys/block directory that only lists whole devices
+func isWholeDisk(name string) bool {
+	for _, prefix := range []string{"loop", "ram", "zram"} {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	_, err := os.Stat("/sys/block/" + name)
+	return err == nil
+}
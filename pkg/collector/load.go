@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/load"
+)
+
+// pressureDir holds the pressure stall files of kernels built with PSI
+const pressureDir = "/proc/pressure/"
+
+// LoadStats holds the 1, 5 and 15 minute load averages
+type LoadStats struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
+// PressureAvg is the share of time in percent that tasks were stalled,
+// averaged over 10 seconds, 1 minute and 5 minutes
+type PressureAvg struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+}
+
+// Pressure is one /proc/pressure file. Some counts time in which at
+// least one task was stalled, Full time in which all of them were.
+type Pressure struct {
+	Some PressureAvg `json:"some"`
+	Full PressureAvg `json:"full"`
+}
+
+// PressureStats holds the pressure stall information of the kernel
+type PressureStats struct {
+	CPU    Pressure `json:"cpu"`
+	Memory Pressure `json:"memory"`
+	IO     Pressure `json:"io"`
+}
+
+// Load returns the load averages, zero when they cannot be read
+func Load(ctx context.Context) LoadStats {
+	avg, err := load.AvgWithContext(ctx)
+	if err != nil {
+		return LoadStats{}
+	}
+	return LoadStats{Load1: avg.Load1, Load5: avg.Load5, Load15: avg.Load15}
+}
+
+// PressureStall reads /proc/pressure. It returns nil on kernels without
+// PSI, including Raspberry Pi kernels booted without psi=1.
+func PressureStall() *PressureStats {
+	cpu, err := readPressure(pressureDir, "cpu")
+	if err != nil {
+		return nil
+	}
+	memory, err := readPressure(pressureDir, "memory")
+	if err != nil {
+		return nil
+	}
+	io, err := readPressure(pressureDir, "io")
+	if err != nil {
+		return nil
+	}
+	return &PressureStats{CPU: cpu, Memory: memory, IO: io}
+}
+
+// readPressure parses the file of resource in dir, with lines like
+// "some avg10=0.00 avg60=0.00 avg300=0.00 total=0"
+func readPressure(dir, resource string) (Pressure, error) {
+	var p Pressure
+	data, err := os.ReadFile(filepath.Join(dir, resource))
+	if err != nil {
+		return p, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var avg *PressureAvg
+		switch fields[0] {
+		case "some":
+			avg = &p.Some
+		case "full":
+			avg = &p.Full
+		default:
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			v, _ := strconv.ParseFloat(value, 64)
+			switch key {
+			case "avg10":
+				avg.Avg10 = v
+			case "avg60":
+				avg.Avg60 = v
+			case "avg300":
+				avg.Avg300 = v
+			}
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPressure(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string // file contents, "" for no file
+		want    Pressure
+		wantErr bool
+	}{
+		{
+			name: "cpu without full line",
+			data: "some avg10=1.50 avg60=0.75 avg300=0.25 total=123456\n",
+			want: Pressure{Some: PressureAvg{Avg10: 1.5, Avg60: 0.75, Avg300: 0.25}},
+		},
+		{
+			name: "memory with both lines",
+			data: "some avg10=12.00 avg60=8.40 avg300=3.10 total=9999\n" +
+				"full avg10=4.00 avg60=2.20 avg300=0.90 total=4444\n",
+			want: Pressure{
+				Some: PressureAvg{Avg10: 12, Avg60: 8.4, Avg300: 3.1},
+				Full: PressureAvg{Avg10: 4, Avg60: 2.2, Avg300: 0.9},
+			},
+		},
+		{
+			name: "unknown lines and fields are skipped",
+			data: "\nbogus avg10=99.00\nsome avg10=2.00 weird avg60=x avg300=1.00\n",
+			want: Pressure{Some: PressureAvg{Avg10: 2, Avg300: 1}},
+		},
+		{
+			name: "empty file",
+			data: "\n",
+			want: Pressure{},
+		},
+		{
+			name:    "missing file",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.data != "" {
+				if err := os.WriteFile(filepath.Join(dir, "cpu"), []byte(tt.data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := readPressure(dir, "cpu")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readPressure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readPressure() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
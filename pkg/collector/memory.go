@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// zramDevice is the sysfs directory of the compressed swap device
+const zramDevice = "/sys/block/zram0"
+
+// ZramStats holds the compression figures of the zram swap device
+type ZramStats struct {
+	Present   bool   `json:"present"`
+	DiskSize  uint64 `json:"disk_size"`  // configured uncompressed capacity
+	OrigData  uint64 `json:"orig_data"`  // uncompressed size of the stored data
+	ComprData uint64 `json:"compr_data"` // compressed size of the stored data
+	MemUsed   uint64 `json:"mem_used"`   // memory used including allocator overhead
+	Algorithm string `json:"algorithm"`
+}
+
+// Ratio returns the compression ratio of the stored data
+func (z ZramStats) Ratio() float64 {
+	if z.ComprData == 0 {
+		return 0
+	}
+	return float64(z.OrigData) / float64(z.ComprData)
+}
+
+// Zram reads mm_stat of zram0. See the kernel zram documentation for
+// the field order.
+func Zram() ZramStats {
+	return readZram(zramDevice)
+}
+
+// readZram reads the zram figures from the sysfs directory dev
+func readZram(dev string) ZramStats {
+	zram := ZramStats{}
+
+	data, err := os.ReadFile(filepath.Join(dev, "mm_stat"))
+	if err != nil {
+		return zram
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return zram
+	}
+
+	zram.Present = true
+	zram.OrigData, _ = strconv.ParseUint(fields[0], 10, 64)
+	zram.ComprData, _ = strconv.ParseUint(fields[1], 10, 64)
+	zram.MemUsed, _ = strconv.ParseUint(fields[2], 10, 64)
+
+	if data, err := os.ReadFile(filepath.Join(dev, "disksize")); err == nil {
+		zram.DiskSize, _ = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	}
+
+	// comp_algorithm lists all algorithms with the active one in brackets
+	if data, err := os.ReadFile(filepath.Join(dev, "comp_algorithm")); err == nil {
+		for _, alg := range strings.Fields(string(data)) {
+			if strings.HasPrefix(alg, "[") {
+				zram.Algorithm = strings.Trim(alg, "[]")
+			}
+		}
+	}
+
+	return zram
+}
@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadZram(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string // sysfs attribute -> contents
+		want  ZramStats
+		ratio float64
+	}{
+		{
+			name: "all attributes",
+			files: map[string]string{
+				"mm_stat":        "  8388608  2097152  2359296        0  2359296      120        0        0        0\n",
+				"disksize":       "536870912\n",
+				"comp_algorithm": "lzo lzo-rle lz4 [zstd]\n",
+			},
+			want: ZramStats{
+				Present:   true,
+				DiskSize:  536870912,
+				OrigData:  8388608,
+				ComprData: 2097152,
+				MemUsed:   2359296,
+				Algorithm: "zstd",
+			},
+			ratio: 4,
+		},
+		{
+			name: "only mm_stat",
+			files: map[string]string{
+				"mm_stat": "4096 1024 2048\n",
+			},
+			want:  ZramStats{Present: true, OrigData: 4096, ComprData: 1024, MemUsed: 2048},
+			ratio: 4,
+		},
+		{
+			name: "nothing stored yet",
+			files: map[string]string{
+				"mm_stat":  "0 0 0 0 0 0 0\n",
+				"disksize": "104857600\n",
+			},
+			want: ZramStats{Present: true, DiskSize: 104857600},
+		},
+		{
+			name: "truncated mm_stat",
+			files: map[string]string{
+				"mm_stat":  "4096 1024\n",
+				"disksize": "104857600\n",
+			},
+			want: ZramStats{},
+		},
+		{
+			name: "malformed numbers",
+			files: map[string]string{
+				"mm_stat":  "abc 1024 -5\n",
+				"disksize": "lots\n",
+			},
+			want: ZramStats{Present: true, ComprData: 1024},
+		},
+		{
+			name: "no zram device",
+			want: ZramStats{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, data := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := readZram(dir)
+			if got != tt.want {
+				t.Errorf("readZram() = %+v, want %+v", got, tt.want)
+			}
+			if r := got.Ratio(); r != tt.ratio {
+				t.Errorf("Ratio() = %v, want %v", r, tt.ratio)
+			}
+		})
+	}
+}
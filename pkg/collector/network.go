@@ -0,0 +1,174 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	gopsnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// iwTimeout bounds a single iw call
+const iwTimeout = 2 * time.Second
+
+// InterfaceStats describes one network interface
+type InterfaceStats struct {
+	Name      string     `json:"name"`
+	Up        bool       `json:"up"`
+	MAC       string     `json:"mac,omitempty"`
+	Addrs     []string   `json:"addresses,omitempty"`
+	BytesSent uint64     `json:"bytes_sent"`
+	BytesRecv uint64     `json:"bytes_recv"`
+	Wifi      *WifiStats `json:"wifi,omitempty"` // wireless interfaces only
+}
+
+// WifiStats describes the link of a wireless interface
+type WifiStats struct {
+	Connected bool    `json:"connected"`
+	SSID      string  `json:"ssid,omitempty"`
+	Freq      int     `json:"freq_mhz,omitempty"`
+	Channel   int     `json:"channel,omitempty"`
+	Signal    float64 `json:"signal_dbm"`
+	Quality   float64 `json:"quality_percent"`
+	TxBitrate float64 `json:"tx_bitrate_mbps,omitempty"`
+	RxBitrate float64 `json:"rx_bitrate_mbps,omitempty"`
+}
+
+// Interfaces lists the interfaces in kernel order with their link
+// state, addresses and byte counters
+func Interfaces(ctx context.Context) []InterfaceStats {
+	counters := make(map[string]gopsnet.IOCountersStat)
+	if perNIC, err := gopsnet.IOCountersWithContext(ctx, true); err == nil {
+		for _, c := range perNIC {
+			counters[c.Name] = c
+		}
+	}
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	wifi := Wifi(ctx)
+	list := make([]InterfaceStats, 0, len(interfaces))
+	for _, iface := range interfaces {
+		s := InterfaceStats{
+			Name: iface.Name,
+			Up:   iface.Flags&net.FlagUp != 0,
+			MAC:  iface.HardwareAddr.String(),
+			Wifi: wifi[iface.Name],
+		}
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				if ipNet, ok := addr.(*net.IPNet); ok {
+					s.Addrs = append(s.Addrs, ipNet.IP.String())
+				}
+			}
+		}
+		if c, ok := counters[iface.Name]; ok {
+			s.BytesSent = c.BytesSent
+			s.BytesRecv = c.BytesRecv
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+// Wifi reads the link quality of every wireless interface from
+// /proc/net/wireless and adds SSID, channel and bitrates from iw
+func Wifi(ctx context.Context) map[string]*WifiStats {
+	f, err := os.Open("/proc/net/wireless")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	wifi := make(map[string]*WifiStats)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// " wlan0: 0000   70.  -40.  -256 ..." after two header lines
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 3 {
+			continue
+		}
+		link, _ := strconv.ParseFloat(strings.TrimSuffix(fields[1], "."), 64)
+		level, _ := strconv.ParseFloat(strings.TrimSuffix(fields[2], "."), 64)
+
+		name = strings.TrimSpace(name)
+		stats := &WifiStats{Signal: level, Quality: link / 70 * 100}
+		readIwLink(ctx, name, stats)
+		wifi[name] = stats
+	}
+	return wifi
+}
+
+// readIwLink fills in the fields reported by "iw dev <iface> link".
+// Without iw only the /proc/net/wireless values are set.
+func readIwLink(ctx context.Context, iface string, stats *WifiStats) {
+	ctx, cancel := context.WithTimeout(ctx, iwTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "iw", "dev", iface, "link").Output()
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "Connected to") {
+			stats.Connected = true
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "SSID":
+			stats.SSID = value
+		case "freq":
+			stats.Freq = int(firstFloat(value))
+			stats.Channel = WifiChannel(stats.Freq)
+		case "signal":
+			stats.Signal = firstFloat(value)
+		case "tx bitrate":
+			stats.TxBitrate = firstFloat(value)
+		case "rx bitrate":
+			stats.RxBitrate = firstFloat(value)
+		}
+	}
+}
+
+// firstFloat parses the leading number of values like "-40 dBm"
+func firstFloat(s string) float64 {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+	v, _ := strconv.ParseFloat(fields[0], 64)
+	return v
+}
+
+// WifiChannel converts a frequency in MHz to the 802.11 channel number
+func WifiChannel(freq int) int {
+	switch {
+	case freq == 2484:
+		return 14
+	case freq >= 2412 && freq < 2484:
+		return (freq - 2407) / 5
+	case freq >= 5000 && freq < 5925:
+		return (freq - 5000) / 5
+	case freq >= 5925:
+		return (freq - 5950) / 5
+	}
+	return 0
+}
@@ -0,0 +1,48 @@
+package collector
+
+import "testing"
+
+func TestFirstFloat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"-40 dBm", -40},
+		{"2437.0", 2437},
+		{"72.2 MBit/s MCS 7 short GI", 72.2},
+		{"  866.7 MBit/s", 866.7},
+		{"", 0},
+		{"   ", 0},
+		{"unknown", 0},
+	}
+	for _, tt := range tests {
+		if got := firstFloat(tt.in); got != tt.want {
+			t.Errorf("firstFloat(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWifiChannel(t *testing.T) {
+	tests := []struct {
+		freq int
+		want int
+	}{
+		{2412, 1},
+		{2437, 6},
+		{2472, 13},
+		{2484, 14},
+		{5180, 36},
+		{5500, 100},
+		{5825, 165},
+		{5955, 1},  // 6 GHz
+		{6115, 33}, // 6 GHz
+		{0, 0},     // not connected
+		{2400, 0},  // below the 2.4 GHz band
+		{4900, 0},  // between the bands
+	}
+	for _, tt := range tests {
+		if got := WifiChannel(tt.freq); got != tt.want {
+			t.Errorf("WifiChannel(%d) = %d, want %d", tt.freq, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package collector
+
+import "context"
+
+// Metric is one value of an external collector, e.g. the query rate of
+// Pi-hole. Like FanStatus it is set on the sample by the program.
+type Metric struct {
+	Plugin string  `json:"plugin"` // name of the collector, set by the program
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+}
+
+// MetricCollector is the interface of collectors added to raspi-monitor
+// without changing it. A Go plugin built with -buildmode=plugin exports
+// an implementation as the variable Collector:
+//
+//	var Collector collector.MetricCollector = &pihole{}
+//
+// Collect is called from a goroutine of its own every interval and
+// should return when ctx is done.
+type MetricCollector interface {
+	Collect(ctx context.Context) ([]Metric, error)
+}
@@ -0,0 +1,169 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sensor kinds
+const (
+	SensorTemp    = "temp"
+	SensorFan     = "fan"
+	SensorVoltage = "voltage"
+	SensorCurrent = "current"
+	SensorPower   = "power"
+)
+
+// hwmonKinds maps the hwmon attribute prefixes to a kind and the factor
+// that turns the raw integer into the unit of the kind
+var hwmonKinds = map[string]struct {
+	kind  string
+	scale float64
+}{
+	"temp":  {SensorTemp, 1e-3},    // millidegree Celsius
+	"fan":   {SensorFan, 1},        // RPM
+	"in":    {SensorVoltage, 1e-3}, // millivolt
+	"curr":  {SensorCurrent, 1e-3}, // milliampere
+	"power": {SensorPower, 1e-6},   // microwatt
+}
+
+// SensorReading is one value of a thermal zone or hwmon chip
+type SensorReading struct {
+	Chip  string  `json:"chip"`           // thermal zone type or hwmon name, e.g. "nvme"
+	Label string  `json:"label"`          // e.g. "Composite", the attribute name without a label
+	Kind  string  `json:"kind"`           // one of the Sensor constants
+	Value float64 `json:"value"`          // °C, RPM, V, A or W
+	Crit  float64 `json:"crit,omitempty"` // critical or max limit, 0 when unknown
+}
+
+// Sensors reads every thermal zone and hwmon chip. Chips that only
+// mirror a thermal zone, like cpu_thermal, are left out.
+func Sensors() []SensorReading {
+	var readings []SensorReading
+	zones := make(map[string]bool)
+
+	paths, _ := filepath.Glob("/sys/class/thermal/thermal_zone*")
+	sort.Strings(paths)
+	for _, dir := range paths {
+		zone := readTrimmed(filepath.Join(dir, "type"))
+		millis, err := strconv.ParseFloat(readTrimmed(filepath.Join(dir, "temp")), 64)
+		if zone == "" || err != nil {
+			continue
+		}
+		zones[zone] = true
+		r := SensorReading{Chip: zone, Label: filepath.Base(dir), Kind: SensorTemp, Value: millis / 1000}
+		r.Crit = zoneCritical(dir)
+		readings = append(readings, r)
+	}
+
+	paths, _ = filepath.Glob("/sys/class/hwmon/hwmon*")
+	sort.Strings(paths)
+	for _, dir := range paths {
+		chip := readTrimmed(filepath.Join(dir, "name"))
+		if chip == "" || zones[chip] || chip == "w1_slave_temp" {
+			continue // 1-Wire probes are slow to read, see OneWireTemps
+		}
+		readings = append(readings, hwmonReadings(dir, chip)...)
+	}
+	return readings
+}
+
+// OneWireTemps reads the DS18B20 probes on the 1-Wire bus
+// (dtoverlay=w1-gpio). Each probe takes up to 750ms to convert, so call
+// it from a background goroutine rather than with every sample. The
+// label is the probe ID, e.g. "28-3c01d607d1a2".
+func OneWireTemps() []SensorReading {
+	paths, _ := filepath.Glob("/sys/bus/w1/devices/28-*")
+	sort.Strings(paths)
+
+	var readings []SensorReading
+	for _, dir := range paths {
+		millis, ok := oneWireMillis(dir)
+		// 85°C is the power-on value of the scratchpad, seen when the
+		// probe lost power during the conversion
+		if !ok || millis == 85000 {
+			continue
+		}
+		readings = append(readings, SensorReading{Chip: "w1", Label: filepath.Base(dir), Kind: SensorTemp, Value: millis / 1000})
+	}
+	return readings
+}
+
+// oneWireMillis reads the temperature of one probe in millidegrees. Older
+// kernels only have w1_slave, whose first line ends in YES when the CRC
+// matched and whose second line ends in t=<millidegrees>.
+func oneWireMillis(dir string) (float64, bool) {
+	if v, err := strconv.ParseFloat(readTrimmed(filepath.Join(dir, "temperature")), 64); err == nil {
+		return v, true
+	}
+	lines := strings.Split(readTrimmed(filepath.Join(dir, "w1_slave")), "\n")
+	if len(lines) < 2 || !strings.HasSuffix(lines[0], "YES") {
+		return 0, false
+	}
+	i := strings.LastIndex(lines[1], "t=")
+	if i < 0 {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(lines[1][i+2:], 64)
+	return v, err == nil
+}
+
+// zoneCritical returns the temperature of the critical trip point of a
+// thermal zone, or 0
+func zoneCritical(dir string) float64 {
+	types, _ := filepath.Glob(filepath.Join(dir, "trip_point_*_type"))
+	for _, t := range types {
+		if readTrimmed(t) != "critical" {
+			continue
+		}
+		temp := strings.TrimSuffix(t, "_type") + "_temp"
+		if millis, err := strconv.ParseFloat(readTrimmed(temp), 64); err == nil {
+			return millis / 1000
+		}
+	}
+	return 0
+}
+
+// hwmonReadings reads the *_input attributes of one hwmon chip
+func hwmonReadings(dir, chip string) []SensorReading {
+	inputs, _ := filepath.Glob(filepath.Join(dir, "*_input"))
+	sort.Strings(inputs)
+
+	var readings []SensorReading
+	for _, input := range inputs {
+		attr := strings.TrimSuffix(filepath.Base(input), "_input") // e.g. temp1
+		prefix := strings.TrimRight(attr, "0123456789")
+		kind, ok := hwmonKinds[prefix]
+		if !ok {
+			continue
+		}
+		raw, err := strconv.ParseFloat(readTrimmed(input), 64)
+		if err != nil {
+			continue // e.g. a sensor that is asleep
+		}
+		r := SensorReading{Chip: chip, Label: attr, Kind: kind.kind, Value: raw * kind.scale}
+		if label := readTrimmed(filepath.Join(dir, attr+"_label")); label != "" {
+			r.Label = label
+		}
+		for _, limit := range []string{"_crit", "_max"} {
+			if v, err := strconv.ParseFloat(readTrimmed(filepath.Join(dir, attr+limit)), 64); err == nil && v > 0 {
+				r.Crit = v * kind.scale
+				break
+			}
+		}
+		readings = append(readings, r)
+	}
+	return readings
+}
+
+// readTrimmed returns the trimmed contents of a file, empty on errors
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
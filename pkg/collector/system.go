@@ -0,0 +1,190 @@
+package collector
+
+import (
+	"context"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessInfo is one row of the process list
+type ProcessInfo struct {
+	PID      int32   `json:"pid"`
+	Name     string  `json:"name"`
+	CPU      float64 `json:"cpu_percent"`
+	Memory   float64 `json:"memory_percent"`
+	Status   string  `json:"status"` // a gopsutil process state such as "zombie"
+	Username string  `json:"username"`
+}
+
+// CPUTemperature reads thermal_zone0 and falls back to vcgencmd when
+// the sysfs node is missing (e.g. inside some containers)
+func CPUTemperature(ctx context.Context) float64 {
+	data, err := os.ReadFile("/sys/class/thermal/thermal_zone0/temp")
+	if err != nil {
+		return VcgencmdTemperature(ctx)
+	}
+
+	tempStr := strings.TrimSpace(string(data))
+	temp, err := strconv.ParseFloat(tempStr, 64)
+	if err != nil {
+		return VcgencmdTemperature(ctx)
+	}
+
+	return temp / 1000.0
+}
+
+// procCPU is the CPU time of a process at the previous sample
+type procCPU struct {
+	created int64   // ms since the epoch, tells a reused PID apart
+	seconds float64 // user + system
+}
+
+// procCPUPercent is the share of one core a process used in the elapsed
+// seconds since prev was taken. Without a previous sample of the same
+// process (seen is false, or the PID was reused) it is the average over
+// the lifetime of the process.
+func procCPUPercent(cur, prev procCPU, seen bool, elapsed float64, now time.Time) float64 {
+	if seen && prev.created == cur.created && elapsed > 0 {
+		return math.Max(0, (cur.seconds-prev.seconds)/elapsed*100)
+	}
+	if lifetime := now.Sub(time.UnixMilli(cur.created)).Seconds(); cur.created > 0 && lifetime > 0 {
+		return cur.seconds / lifetime * 100
+	}
+	return 0
+}
+
+// Processes lists all processes, busiest first. CPU is the share of one
+// core used since the previous call, like top shows it; a process seen
+// for the first time gets its average over its lifetime.
+func (c *Collector) Processes(ctx context.Context) []ProcessInfo {
+	processes, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return []ProcessInfo{}
+	}
+
+	var processInfos []ProcessInfo
+	totalMem, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return []ProcessInfo{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	elapsed := now.Sub(c.prevProcAt).Seconds()
+	seen := make(map[int32]procCPU, len(processes))
+
+	for _, p := range processes {
+		if ctx.Err() != nil {
+			break
+		}
+		name, _ := p.NameWithContext(ctx)
+		cpuPercent := 0.0
+		if times, err := p.TimesWithContext(ctx); err == nil {
+			created, _ := p.CreateTimeWithContext(ctx)
+			cur := procCPU{created: created, seconds: times.User + times.System}
+			seen[p.Pid] = cur
+			prev, ok := c.prevProc[p.Pid]
+			cpuPercent = procCPUPercent(cur, prev, ok, elapsed, now)
+		}
+		memInfo, _ := p.MemoryInfoWithContext(ctx)
+		status, _ := p.StatusWithContext(ctx)
+		username, _ := p.UsernameWithContext(ctx)
+
+		memPercent := 0.0
+		if memInfo != nil && totalMem.Total > 0 {
+			memPercent = float64(memInfo.RSS) / float64(totalMem.Total) * 100
+		}
+
+		statusStr := "?"
+		if len(status) > 0 {
+			statusStr = string(status[0])
+		}
+
+		procInfo := ProcessInfo{
+			PID:      p.Pid,
+			Name:     name,
+			CPU:      cpuPercent,
+			Memory:   memPercent,
+			Status:   statusStr,
+			Username: username,
+		}
+
+		processInfos = append(processInfos, procInfo)
+	}
+
+	c.prevProc = seen
+	c.prevProcAt = now
+
+	sort.Slice(processInfos, func(i, j int) bool {
+		return processInfos[i].CPU > processInfos[j].CPU
+	})
+
+	return processInfos
+}
+
+// IPAddress returns the first IPv4 address of an interface that is up,
+// skipping loopback
+func IPAddress() string {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "N/A"
+	}
+
+	for _, iface := range interfaces {
+		// Skip loopback and down interfaces
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+
+			// Return first non-loopback IPv4 address
+			if ipNet.IP.To4() != nil {
+				return ipNet.IP.String()
+			}
+		}
+	}
+
+	return "No IP"
+}
+
+// APMode reports "AP Mode" while hostapd runs and "Client Mode"
+// otherwise
+func APMode(ctx context.Context) string {
+	// Check for hostapd process (common AP mode daemon)
+	processes, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return "Unknown"
+	}
+
+	for _, p := range processes {
+		name, err := p.NameWithContext(ctx)
+		if err != nil {
+			continue
+		}
+
+		if strings.Contains(strings.ToLower(name), "hostapd") {
+			return "AP Mode"
+		}
+	}
+
+	return "Client Mode"
+}
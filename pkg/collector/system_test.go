@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProcCPUPercent(t *testing.T) {
+	now := time.UnixMilli(1_700_000_100_000)
+	created := now.Add(-100 * time.Second).UnixMilli()
+
+	tests := []struct {
+		name    string
+		cur     procCPU
+		prev    procCPU
+		seen    bool
+		elapsed float64
+		want    float64
+	}{
+		{
+			name:    "half a core since the previous sample",
+			cur:     procCPU{created: created, seconds: 11},
+			prev:    procCPU{created: created, seconds: 10},
+			seen:    true,
+			elapsed: 2,
+			want:    50,
+		},
+		{
+			name:    "two busy cores",
+			cur:     procCPU{created: created, seconds: 14},
+			prev:    procCPU{created: created, seconds: 10},
+			seen:    true,
+			elapsed: 2,
+			want:    200,
+		},
+		{
+			name:    "idle",
+			cur:     procCPU{created: created, seconds: 10},
+			prev:    procCPU{created: created, seconds: 10},
+			seen:    true,
+			elapsed: 2,
+			want:    0,
+		},
+		{
+			name:    "counter went backwards",
+			cur:     procCPU{created: created, seconds: 9},
+			prev:    procCPU{created: created, seconds: 10},
+			seen:    true,
+			elapsed: 2,
+			want:    0,
+		},
+		{
+			name:    "first sample of the process uses its lifetime",
+			cur:     procCPU{created: created, seconds: 25},
+			elapsed: 2,
+			want:    25,
+		},
+		{
+			name:    "reused PID uses the lifetime of the new process",
+			cur:     procCPU{created: created, seconds: 50},
+			prev:    procCPU{created: created - 5000, seconds: 1000},
+			seen:    true,
+			elapsed: 2,
+			want:    50,
+		},
+		{
+			name: "first call of the collector",
+			cur:  procCPU{created: created, seconds: 10},
+			prev: procCPU{created: created, seconds: 10},
+			seen: true,
+			want: 10,
+		},
+		{
+			name:    "unknown start time",
+			cur:     procCPU{seconds: 10},
+			elapsed: 2,
+			want:    0,
+		},
+		{
+			name:    "start time in the future",
+			cur:     procCPU{created: now.Add(time.Second).UnixMilli(), seconds: 1},
+			elapsed: 2,
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := procCPUPercent(tt.cur, tt.prev, tt.seen, tt.elapsed, now)
+			if got != tt.want {
+				t.Errorf("procCPUPercent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
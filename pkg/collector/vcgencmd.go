@@ -0,0 +1,143 @@
+package collector
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vcgencmdTimeout bounds a single vcgencmd call; the firmware mailbox
+// normally answers within a few milliseconds
+const vcgencmdTimeout = 2 * time.Second
+
+// Vcgencmd runs the VideoCore firmware tool and returns its trimmed
+// output. It fails on systems without vcgencmd (non-Pi hardware).
+func Vcgencmd(ctx context.Context, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, vcgencmdTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "vcgencmd", args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// VcgencmdValue returns the part after "=" of a key=value reply
+func VcgencmdValue(out string) string {
+	if i := strings.IndexByte(out, '='); i >= 0 {
+		return out[i+1:]
+	}
+	return out
+}
+
+// VcgencmdTemperature parses "temp=48.3'C"
+func VcgencmdTemperature(ctx context.Context) float64 {
+	out, err := Vcgencmd(ctx, "measure_temp")
+	if err != nil {
+		return 0
+	}
+	value := strings.TrimSuffix(VcgencmdValue(out), "'C")
+	temp, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return temp
+}
+
+// Bits of the vcgencmd get_throttled value
+const (
+	ThrottleUnderVoltage         = 1 << 0
+	ThrottleFreqCapped           = 1 << 1
+	ThrottleThrottled            = 1 << 2
+	ThrottleSoftTempLimit        = 1 << 3
+	ThrottleUnderVoltageOccurred = 1 << 16
+	ThrottleFreqCappedOccurred   = 1 << 17
+	ThrottleThrottledOccurred    = 1 << 18
+	ThrottleSoftTempOccurred     = 1 << 19
+)
+
+// ThrottleStatus is the decoded result of vcgencmd get_throttled
+type ThrottleStatus struct {
+	Known bool   `json:"known"`
+	Flags uint64 `json:"flags"`
+}
+
+// Throttle queries the under-voltage and throttling flags
+func Throttle(ctx context.Context) ThrottleStatus {
+	out, err := Vcgencmd(ctx, "get_throttled")
+	if err != nil {
+		return ThrottleStatus{}
+	}
+	flags, err := strconv.ParseUint(VcgencmdValue(out), 0, 64)
+	if err != nil {
+		return ThrottleStatus{}
+	}
+	return ThrottleStatus{Known: true, Flags: flags}
+}
+
+// Current returns the conditions active right now
+func (t ThrottleStatus) Current() []string {
+	return t.names(ThrottleUnderVoltage, ThrottleFreqCapped, ThrottleThrottled, ThrottleSoftTempLimit)
+}
+
+// Past returns the conditions that occurred since boot
+func (t ThrottleStatus) Past() []string {
+	return t.names(ThrottleUnderVoltageOccurred, ThrottleFreqCappedOccurred, ThrottleThrottledOccurred, ThrottleSoftTempOccurred)
+}
+
+// names maps the under-voltage, capped, throttled and soft limit bits
+// (in that order) to short labels
+func (t ThrottleStatus) names(bits ...uint64) []string {
+	labels := []string{"Undervolt", "Capped", "Throttled", "SoftTemp"}
+	var names []string
+	for i, bit := range bits {
+		if t.Flags&bit != 0 {
+			names = append(names, labels[i])
+		}
+	}
+	return names
+}
+
+// CoreVolts parses "volt=0.8600V"
+func CoreVolts(ctx context.Context) float64 {
+	out, err := Vcgencmd(ctx, "measure_volts", "core")
+	if err != nil {
+		return 0
+	}
+	volts, _ := strconv.ParseFloat(strings.TrimSuffix(VcgencmdValue(out), "V"), 64)
+	return volts
+}
+
+// MeasureClock parses "frequency(28)=500000000"
+func MeasureClock(ctx context.Context, name string) uint64 {
+	out, err := Vcgencmd(ctx, "measure_clock", name)
+	if err != nil {
+		return 0
+	}
+	hz, _ := strconv.ParseUint(VcgencmdValue(out), 10, 64)
+	return hz
+}
+
+var (
+	sdramClockOnce sync.Once
+	sdramClock     uint64
+)
+
+// SDRAMClock returns the configured SDRAM frequency in Hz. The firmware
+// cannot measure it, and it does not change at runtime, so it is only
+// queried once.
+func SDRAMClock(ctx context.Context) uint64 {
+	sdramClockOnce.Do(func() {
+		out, err := Vcgencmd(ctx, "get_config", "sdram_freq")
+		if err != nil {
+			return
+		}
+		mhz, _ := strconv.ParseUint(VcgencmdValue(out), 10, 64)
+		sdramClock = mhz * 1000000
+	})
+	return sdramClock
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"plugin"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// PluginConfig is an external collector: a Go plugin (.so) exporting a
+// collector.MetricCollector as Collector, or a command printing its
+// metrics as JSON
+type PluginConfig struct {
+	Name     string        `yaml:"name"`
+	Path     string        `yaml:"path"`    // Go plugin
+	Command  string        `yaml:"command"` // exec plugin, run with sh -c
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+func (c PluginConfig) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("plugins: name is required")
+	}
+	if (c.Path == "") == (strings.TrimSpace(c.Command) == "") {
+		return fmt.Errorf("plugin %q: set either path or command", c.Name)
+	}
+	if c.Interval < 0 || c.Timeout < 0 {
+		return fmt.Errorf("plugin %q: interval and timeout must not be negative", c.Name)
+	}
+	return nil
+}
+
+// validatePlugins checks the plugins and that their names are unique
+func validatePlugins(plugins []PluginConfig) error {
+	names := make(map[string]bool)
+	for _, p := range plugins {
+		if err := p.validate(); err != nil {
+			return err
+		}
+		if names[p.Name] {
+			return fmt.Errorf("plugin %q is defined twice", p.Name)
+		}
+		names[p.Name] = true
+	}
+	return nil
+}
+
+// loadGoPlugin opens a Go plugin and looks up its Collector. The plugin
+// must be built with the same Go version and module versions as the
+// program.
+func loadGoPlugin(path string) (collector.MetricCollector, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup("Collector")
+	if err != nil {
+		return nil, err
+	}
+	// Lookup returns a pointer to the variable, which is either declared
+	// as the interface or as a type implementing it
+	switch c := sym.(type) {
+	case *collector.MetricCollector:
+		if *c == nil {
+			return nil, fmt.Errorf("%s: Collector is nil", path)
+		}
+		return *c, nil
+	case collector.MetricCollector:
+		return c, nil
+	}
+	return nil, fmt.Errorf("%s: Collector does not implement collector.MetricCollector", path)
+}
+
+// execCollector runs a command printing either a list of metrics,
+// [{"name": "queries", "value": 12.5, "unit": "q/min"}], or an object
+// of names and values, {"queries": 12.5}
+type execCollector struct {
+	command string
+}
+
+func (e execCollector) Collect(ctx context.Context) ([]collector.Metric, error) {
+	out, err := exec.CommandContext(ctx, "sh", "-c", e.command).Output()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, errors.New(commandError(exitErr.Stderr, err))
+		}
+		return nil, err
+	}
+	return parsePluginOutput(out)
+}
+
+func parsePluginOutput(out []byte) ([]collector.Metric, error) {
+	var metrics []collector.Metric
+	if err := json.Unmarshal(out, &metrics); err == nil {
+		for _, m := range metrics {
+			if m.Name == "" {
+				return nil, fmt.Errorf("metric without a name")
+			}
+		}
+		return metrics, nil
+	}
+
+	var values map[string]float64
+	if err := json.Unmarshal(out, &values); err != nil {
+		return nil, fmt.Errorf("output is neither a list of metrics nor an object of numbers")
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		metrics = append(metrics, collector.Metric{Name: name, Value: values[name]})
+	}
+	return metrics, nil
+}
+
+// PluginMonitor runs an external collector in the background, so a slow
+// one does not hold up the samples
+type PluginMonitor struct {
+	cfg    PluginConfig
+	source collector.MetricCollector
+
+	mu      sync.Mutex
+	metrics []collector.Metric
+	err     string // why the last collection failed
+}
+
+// newPluginMonitor loads the plugin of cfg, filling in the default
+// interval and timeout
+func newPluginMonitor(cfg PluginConfig) (*PluginMonitor, error) {
+	if cfg.Interval == 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	m := &PluginMonitor{cfg: cfg, source: execCollector{cfg.Command}}
+	if cfg.Path != "" {
+		source, err := loadGoPlugin(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		m.source = source
+	}
+	return m, nil
+}
+
+// Metrics returns the metrics of the last successful collection
+func (m *PluginMonitor) Metrics() []collector.Metric {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.metrics
+}
+
+// Err returns why the last collection failed, or ""
+func (m *PluginMonitor) Err() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Run collects every interval until stop is closed
+func (m *PluginMonitor) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Timeout)
+		metrics, err := m.source.Collect(ctx)
+		cancel()
+
+		m.mu.Lock()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = fmt.Errorf("timed out after %s", m.cfg.Timeout)
+			}
+			if m.err != err.Error() {
+				log.Printf("Plugin %s failed: %v", m.cfg.Name, err)
+			}
+			m.metrics, m.err = nil, err.Error()
+		} else {
+			for i := range metrics {
+				metrics[i].Plugin = m.cfg.Name
+			}
+			m.metrics, m.err = metrics, ""
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pluginRows shows the plugin metrics on the System view, grouped by
+// plugin
+func (d *Dashboard) pluginRows(stats SystemStats) []string {
+	if len(d.plugins) == 0 {
+		return nil
+	}
+	rows := []string{"", "[--Plugins--](fg:cyan)"}
+	for _, p := range d.plugins {
+		rows = append(rows, fmt.Sprintf("[%s](fg:cyan)", truncateString(p.cfg.Name, viewContentWidth)))
+		if err := p.Err(); err != "" {
+			rows = append(rows, fmt.Sprintf(" [%s](fg:red)", truncateString(err, viewContentWidth-1)))
+			continue
+		}
+		for _, m := range stats.Plugins {
+			if m.Plugin != p.cfg.Name {
+				continue
+			}
+			value := truncateString(strings.TrimSpace(fmt.Sprintf("%.4g %s", m.Value, m.Unit)), 14)
+			nameWidth := viewContentWidth - len(value) - 2
+			rows = append(rows, fmt.Sprintf(" %-*s %s", nameWidth, truncateString(m.Name, nameWidth), value))
+		}
+	}
+	return rows
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"syscall"
+)
+
+// openPowerMenu offers to reboot or shut down the Pi, or to restart the
+// monitor. Cancel comes first so a stray press does nothing.
+func (d *Dashboard) openPowerMenu() {
+	d.openMenu(&Menu{
+		title: "Power",
+		options: []menuOption{
+			{label: "Cancel"},
+			{label: "Reboot", action: func(d *Dashboard) {
+				d.confirmPower("Reboot the Pi now?", "reboot")
+			}},
+			{label: "Shut down", action: func(d *Dashboard) {
+				d.confirmPower("Shut down the Pi now?", "poweroff")
+			}},
+			{label: "Restart monitor", action: func(d *Dashboard) {
+				log.Println("Restarting from the power menu")
+				d.restart = true
+			}},
+		},
+	})
+}
+
+// confirmPower asks once more before running systemctl verb
+func (d *Dashboard) confirmPower(question, verb string) {
+	d.openMenu(&Menu{
+		title:   "Confirm",
+		message: []string{question},
+		options: []menuOption{
+			{label: "Cancel"},
+			{label: "Yes, " + verb, action: func(d *Dashboard) { d.runPowerAction(verb) }},
+		},
+	})
+}
+
+func (d *Dashboard) runPowerAction(verb string) {
+	log.Printf("Power menu: %s", verb)
+	out, err := systemctl(verb)
+	if err != nil {
+		msg := commandError(out, err)
+		log.Printf("Failed to %s: %s", verb, msg)
+		d.showMessage("Error", fmt.Sprintf("%s failed", verb), truncateString(msg, 24))
+		return
+	}
+	d.showMessage("Power", fmt.Sprintf("%s in progress...", verb))
+}
+
+// restartSelf replaces the process with a fresh copy of the monitor
+func restartSelf() {
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Restart failed: %v\n", err)
+		os.Exit(1)
+	}
+	env := os.Environ()
+	if notifySocket != "" {
+		env = append(env, "NOTIFY_SOCKET="+notifySocket) // for READY=1 of the new copy
+	}
+	if err := syscall.Exec(exe, os.Args, env); err != nil {
+		fmt.Fprintf(os.Stderr, "Restart failed: %v\n", err)
+		os.Exit(1)
+	}
+}
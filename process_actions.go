@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"syscall"
+	"unicode/utf8"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Process sort modes, cycled with the s key or the X button
+const (
+	sortByCPU = iota
+	sortByMemory
+	sortByPID
+	sortByName
+)
+
+var sortModeNames = []string{
+	sortByCPU:    "CPU",
+	sortByMemory: "MEM",
+	sortByPID:    "PID",
+	sortByName:   "Name",
+}
+
+// sortProcesses returns a copy of procs ordered by mode. CPU and memory
+// sort descending, PID and name ascending.
+func sortProcesses(procs []ProcessInfo, mode int) []ProcessInfo {
+	sorted := append([]ProcessInfo(nil), procs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch mode {
+		case sortByMemory:
+			return a.Memory > b.Memory
+		case sortByPID:
+			return a.PID < b.PID
+		case sortByName:
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+		return a.CPU > b.CPU
+	})
+	return sorted
+}
+
+// processStateColor highlights zombies and processes stuck in
+// uninterruptible sleep, which on a Pi usually means a hung SD card or
+// USB drive. Other states get no color.
+func processStateColor(status string) string {
+	switch status {
+	case process.Zombie:
+		return "magenta"
+	case process.Blocked:
+		return "yellow"
+	}
+	return ""
+}
+
+// stuckProcessCounts formats the zombie and D-state counts for the
+// System view, red when there are any
+func stuckProcessCounts(stats SystemStats) string {
+	counts := fmt.Sprintf("Z:%d D:%d", stats.Zombies, stats.Blocked)
+	if stats.Zombies > 0 || stats.Blocked > 0 {
+		return fmt.Sprintf("[%s](fg:red)", counts)
+	}
+	return counts
+}
+
+// cycleSortMode switches the process view to the next sort mode
+func (d *Dashboard) cycleSortMode() {
+	d.sortMode = (d.sortMode + 1) % len(sortModeNames)
+	d.selectedProcess = 0
+	d.UpdateStats()
+	d.Render()
+}
+
+// filterProcesses keeps the processes whose name or user contains filter,
+// ignoring case
+func filterProcesses(procs []ProcessInfo, filter string) []ProcessInfo {
+	if filter == "" {
+		return procs
+	}
+	filter = strings.ToLower(filter)
+	var matched []ProcessInfo
+	for _, p := range procs {
+		if strings.Contains(strings.ToLower(p.Name), filter) ||
+			strings.Contains(strings.ToLower(p.Username), filter) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// startSearch enters incremental search mode in the process view
+func (d *Dashboard) startSearch() {
+	d.searching = true
+	d.UpdateStats()
+	d.Render()
+}
+
+// handleSearchKey edits the process filter while search mode is active.
+// Enter keeps the filter, Escape clears it.
+func (d *Dashboard) handleSearchKey(id string) {
+	switch id {
+	case "<Enter>":
+		d.searching = false
+	case "<Escape>":
+		d.searching = false
+		d.processFilter = ""
+	case "<Backspace>", "<C-<Backspace>>":
+		if d.processFilter != "" {
+			_, size := utf8.DecodeLastRuneInString(d.processFilter)
+			d.processFilter = d.processFilter[:len(d.processFilter)-size]
+		}
+	case "<Space>":
+		d.processFilter += " "
+	default:
+		if utf8.RuneCountInString(id) != 1 {
+			return
+		}
+		d.processFilter += id
+	}
+
+	d.selectedProcess = 0
+	d.UpdateStats()
+	d.Render()
+}
+
+// selectedProcessInfo returns the process highlighted in the process view,
+// or the one on the detail page when it is open
+func (d *Dashboard) selectedProcessInfo() (ProcessInfo, bool) {
+	if d.detailPID != 0 {
+		for _, p := range d.processList {
+			if p.PID == d.detailPID {
+				return p, true
+			}
+		}
+		return ProcessInfo{}, false
+	}
+	if d.selectedProcess < 0 || d.selectedProcess >= len(d.processList) {
+		return ProcessInfo{}, false
+	}
+	return d.processList[d.selectedProcess], true
+}
+
+// menuSignals are the signals offered by the signal menu
+var menuSignals = []struct {
+	sig   syscall.Signal
+	label string
+}{
+	{syscall.SIGTERM, "SIGTERM (terminate)"},
+	{syscall.SIGKILL, "SIGKILL (force)"},
+	{syscall.SIGHUP, "SIGHUP (reload)"},
+	{syscall.SIGSTOP, "SIGSTOP (pause)"},
+	{syscall.SIGCONT, "SIGCONT (resume)"},
+}
+
+// openSignalMenu asks which signal to send to the selected process, or
+// how to change its nice value
+func (d *Dashboard) openSignalMenu() {
+	if d.localOnly("Signal") {
+		return
+	}
+	proc, ok := d.selectedProcessInfo()
+	if !ok {
+		return
+	}
+
+	options := []menuOption{{label: "Cancel"}}
+	for _, s := range menuSignals {
+		sig := s.sig
+		options = append(options, menuOption{label: s.label, action: func(d *Dashboard) {
+			d.signalProcess(proc, sig)
+		}})
+	}
+	options = append(options,
+		menuOption{label: "Renice +1 (slower)", action: func(d *Dashboard) {
+			d.reniceProcess(proc, 1)
+		}},
+		menuOption{label: "Renice -1 (faster)", action: func(d *Dashboard) {
+			d.reniceProcess(proc, -1)
+		}},
+	)
+
+	nice := "?"
+	if n, err := getNice(proc.PID); err == nil {
+		nice = fmt.Sprint(n)
+	}
+	d.openMenu(&Menu{
+		title: "Signal process",
+		message: []string{
+			fmt.Sprintf("PID:  %d", proc.PID),
+			fmt.Sprintf("Name: %s", truncateString(proc.Name, 20)),
+			fmt.Sprintf("User: %s", proc.Username),
+			fmt.Sprintf("Nice: %s", nice),
+		},
+		options: options,
+	})
+}
+
+// signalProcess sends sig to proc and reports the result in a message box
+func (d *Dashboard) signalProcess(proc ProcessInfo, sig syscall.Signal) {
+	err := syscall.Kill(int(proc.PID), sig)
+	if err != nil {
+		log.Printf("Failed to send %s to %d (%s): %v", sig, proc.PID, proc.Name, err)
+		d.showMessage("Error", describeSignalError(err))
+		return
+	}
+
+	log.Printf("Sent %s to %d (%s)", sig, proc.PID, proc.Name)
+	d.UpdateStats()
+	d.showMessage("Done", fmt.Sprintf("Sent %s", sig), fmt.Sprintf("to %d %s", proc.PID, truncateString(proc.Name, 12)))
+}
+
+// getNice returns the nice value of pid. The raw getpriority(2) syscall
+// returns 20 - nice so that it never has to return a negative number.
+func getNice(pid int32) (int, error) {
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, int(pid))
+	if err != nil {
+		return 0, err
+	}
+	return 20 - prio, nil
+}
+
+// reniceProcess changes the nice value of proc by delta, within -20..19
+func (d *Dashboard) reniceProcess(proc ProcessInfo, delta int) {
+	nice, err := getNice(proc.PID)
+	if err == nil {
+		nice += delta
+		if nice < -20 {
+			nice = -20
+		} else if nice > 19 {
+			nice = 19
+		}
+		err = syscall.Setpriority(syscall.PRIO_PROCESS, int(proc.PID), nice)
+	}
+	if err != nil {
+		log.Printf("Failed to renice %d (%s): %v", proc.PID, proc.Name, err)
+		d.showMessage("Error", describeSignalError(err))
+		return
+	}
+
+	log.Printf("Reniced %d (%s) to %d", proc.PID, proc.Name, nice)
+	d.showMessage("Done", fmt.Sprintf("Nice is now %d", nice), fmt.Sprintf("for %d %s", proc.PID, truncateString(proc.Name, 12)))
+}
+
+// describeSignalError turns a kill(2) or setpriority(2) error into a
+// short hint
+func describeSignalError(err error) string {
+	switch {
+	case errors.Is(err, syscall.EPERM):
+		return "Permission denied (root?)"
+	case errors.Is(err, syscall.EACCES):
+		return "Only root can raise priority"
+	case errors.Is(err, syscall.ESRCH):
+		return "Process already exited"
+	}
+	return err.Error()
+}
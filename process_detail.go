@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessDetail holds the extra information shown on the detail page
+type ProcessDetail struct {
+	PID        int32
+	PPID       int32
+	Name       string
+	Username   string
+	Status     string
+	Cmdline    string
+	Threads    int32
+	OpenFiles  int32
+	RSS        uint64
+	VMS        uint64
+	Shared     uint64
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadCount  uint64
+	WriteCount uint64
+	StartTime  time.Time
+	Nice       int32
+	Cgroup     string
+}
+
+// getProcessDetail collects the detail page data for pid. Fields that
+// cannot be read (usually for lack of privileges) are left empty.
+func getProcessDetail(pid int32) (ProcessDetail, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return ProcessDetail{}, err
+	}
+
+	detail := ProcessDetail{PID: pid}
+	detail.Name, _ = p.Name()
+	detail.Username, _ = p.Username()
+	detail.PPID, _ = p.Ppid()
+	detail.Cmdline, _ = p.Cmdline()
+	detail.Threads, _ = p.NumThreads()
+	detail.OpenFiles, _ = p.NumFDs()
+	detail.Nice, _ = p.Nice()
+
+	if status, err := p.Status(); err == nil && len(status) > 0 {
+		detail.Status = status[0]
+	}
+	if memInfo, err := p.MemoryInfoEx(); err == nil {
+		detail.RSS = memInfo.RSS
+		detail.VMS = memInfo.VMS
+		detail.Shared = memInfo.Shared
+	}
+	if io, err := p.IOCounters(); err == nil {
+		detail.ReadBytes = io.ReadBytes
+		detail.WriteBytes = io.WriteBytes
+		detail.ReadCount = io.ReadCount
+		detail.WriteCount = io.WriteCount
+	}
+	if created, err := p.CreateTime(); err == nil {
+		detail.StartTime = time.UnixMilli(created)
+	}
+	detail.Cgroup = getProcessCgroup(pid)
+
+	return detail, nil
+}
+
+// getProcessCgroup returns the cgroup path of pid. With cgroup v2 there
+// is a single "0::/path" line; with v1 the first named hierarchy is used.
+func getProcessCgroup(pid int32) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) == 3 && parts[2] != "" {
+			return parts[2]
+		}
+	}
+	return ""
+}
+
+// wrapString splits s into lines of at most width characters
+func wrapString(s string, width, maxLines int) []string {
+	var lines []string
+	runes := []rune(s)
+	for len(runes) > 0 && len(lines) < maxLines {
+		n := width
+		if n > len(runes) {
+			n = len(runes)
+		}
+		lines = append(lines, string(runes[:n]))
+		runes = runes[n:]
+	}
+	if len(runes) > 0 && len(lines) > 0 {
+		last := []rune(lines[len(lines)-1])
+		lines[len(lines)-1] = string(last[:len(last)-2]) + ".."
+	}
+	return lines
+}
+
+// openProcessDetail shows the detail page for the selected process
+func (d *Dashboard) openProcessDetail() {
+	if d.localOnly("Detail") {
+		return
+	}
+	proc, ok := d.selectedProcessInfo()
+	if !ok {
+		return
+	}
+	d.detailPID = proc.PID
+	d.UpdateStats()
+	d.Render()
+}
+
+func (d *Dashboard) closeProcessDetail() {
+	d.detailPID = 0
+	d.UpdateStats()
+	d.Render()
+}
+
+func (d *Dashboard) updateProcessDetailView() {
+	d.mainList.Title = d.viewTitle(fmt.Sprintf("PID %d [B:Back]", d.detailPID))
+
+	detail, err := getProcessDetail(d.detailPID)
+	if err != nil {
+		d.mainList.Rows = []string{"", "Process has exited"}
+		return
+	}
+
+	cmdline := detail.Cmdline
+	if cmdline == "" {
+		cmdline = "[" + detail.Name + "]" // kernel threads have no cmdline
+	}
+
+	rows := []string{
+		fmt.Sprintf("[Name:](fg:cyan) %s", truncateString(detail.Name, 20)),
+		fmt.Sprintf("[User:](fg:cyan) %s  [St:](fg:cyan) %s", truncateString(detail.Username, 10), detail.Status),
+		fmt.Sprintf("[PPID:](fg:cyan) %d  [Nice:](fg:cyan) %d", detail.PPID, detail.Nice),
+		"",
+		"[--Command--](fg:white)",
+	}
+	for _, line := range wrapString(cmdline, 26, 4) {
+		rows = append(rows, strings.ReplaceAll(line, "[", "("))
+	}
+
+	rows = append(rows,
+		"",
+		"[--Memory--](fg:yellow)",
+		fmt.Sprintf("RSS:    %8.1f MB", bytesToMB(detail.RSS)),
+		fmt.Sprintf("VMS:    %8.1f MB", bytesToMB(detail.VMS)),
+		fmt.Sprintf("Shared: %8.1f MB", bytesToMB(detail.Shared)),
+		"",
+		"[--IO--](fg:magenta)",
+		fmt.Sprintf("Read:  %7.1f MB %6d", bytesToMB(detail.ReadBytes), detail.ReadCount),
+		fmt.Sprintf("Write: %7.1f MB %6d", bytesToMB(detail.WriteBytes), detail.WriteCount),
+		"",
+		fmt.Sprintf("Threads: %d  FDs: %d", detail.Threads, detail.OpenFiles),
+		fmt.Sprintf("Started: %s", detail.StartTime.Format("01-02 15:04:05")),
+		fmt.Sprintf("Cgroup: %s", truncateString(detail.Cgroup, 20)),
+	)
+
+	d.mainList.Rows = rows
+}
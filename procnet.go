@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gopsnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// procNetRows is how many processes the Network view lists
+const procNetRows = 5
+
+// socketBytes are the byte counters of one TCP socket
+type socketBytes struct {
+	sent uint64
+	recv uint64
+}
+
+// ProcessNetRate is the TCP throughput of one process in bytes per second
+type ProcessNetRate struct {
+	PID  int32 // 0 for sockets whose owner is not visible
+	Name string
+	Sent float64
+	Recv float64
+}
+
+// tcpSocketBytes reads the byte counters of the established TCP sockets
+// from "ss -tinH", keyed by local and remote address. The kernel keeps
+// them in tcp_info; /proc/net/tcp has no counters.
+func tcpSocketBytes() (map[string]socketBytes, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "ss", "-tinH").Output()
+	if err != nil {
+		return nil, toolError("ss", err)
+	}
+
+	// Each socket is a line "ESTAB 0 0 local peer" followed by an
+	// indented line of tcp_info fields
+	sockets := make(map[string]socketBytes)
+	key := ""
+	for _, line := range strings.Split(string(out), "\n") {
+		if line == "" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			key = ""
+			if f := strings.Fields(line); len(f) >= 5 {
+				key = socketKey(f[3], f[4])
+			}
+			continue
+		}
+		if key == "" {
+			continue
+		}
+		var b socketBytes
+		acked := uint64(0)
+		for _, field := range strings.Fields(line) {
+			name, value, ok := strings.Cut(field, ":")
+			if !ok {
+				continue
+			}
+			switch name {
+			case "bytes_sent":
+				b.sent, _ = strconv.ParseUint(value, 10, 64)
+			case "bytes_acked":
+				acked, _ = strconv.ParseUint(value, 10, 64)
+			case "bytes_received":
+				b.recv, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+		// bytes_sent is only reported since Linux 4.19
+		if b.sent == 0 {
+			b.sent = acked
+		}
+		sockets[key] = b
+		key = ""
+	}
+	return sockets, nil
+}
+
+// socketKey joins two addresses as printed by ss ("[::ffff:1.2.3.4]:22",
+// "10.0.0.2%wlan0:22") or gopsutil into one canonical key
+func socketKey(local, remote string) string {
+	return canonicalAddr(local) + " " + canonicalAddr(remote)
+}
+
+func canonicalAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	host = strings.SplitN(host, "%", 2)[0]
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String() // prints IPv4-mapped addresses as IPv4
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// socketOwners maps the TCP sockets to their processes through the
+// socket inodes in /proc/<pid>/fd. Sockets of other users are only
+// found when running as root.
+func socketOwners() map[string]int32 {
+	conns, err := gopsnet.Connections("tcp")
+	if err != nil {
+		return nil
+	}
+	owners := make(map[string]int32, len(conns))
+	for _, c := range conns {
+		if c.Pid == 0 || c.Raddr.Port == 0 {
+			continue
+		}
+		local := net.JoinHostPort(c.Laddr.IP, strconv.Itoa(int(c.Laddr.Port)))
+		remote := net.JoinHostPort(c.Raddr.IP, strconv.Itoa(int(c.Raddr.Port)))
+		owners[socketKey(local, remote)] = c.Pid
+	}
+	return owners
+}
+
+// recordProcessNet updates the per-process TCP rates from the socket
+// counters. A socket that was not there at the previous sample counts
+// with all its bytes, except on the first sample where that would
+// charge long-lived connections with their whole history.
+func (d *Dashboard) recordProcessNet(procs []ProcessInfo) {
+	sockets, err := tcpSocketBytes()
+	now := time.Now()
+	d.procNetErr = err
+	if err != nil {
+		d.procNetPrev, d.procNetRates = nil, nil
+		return
+	}
+
+	first := d.procNetPrev == nil
+	seconds := now.Sub(d.procNetAt).Seconds()
+	prev := d.procNetPrev
+	d.procNetPrev, d.procNetAt = sockets, now
+	if first || seconds <= 0 {
+		d.procNetRates = nil
+		return
+	}
+
+	names := make(map[int32]string, len(procs))
+	for _, p := range procs {
+		names[p.PID] = p.Name
+	}
+	owners := socketOwners()
+	byPID := make(map[int32]*ProcessNetRate)
+	for key, cur := range sockets {
+		p := prev[key] // zero for new sockets
+		sent := counterRate(cur.sent, p.sent, seconds)
+		recv := counterRate(cur.recv, p.recv, seconds)
+		if sent == 0 && recv == 0 {
+			continue
+		}
+		pid := owners[key]
+		r, ok := byPID[pid]
+		if !ok {
+			r = &ProcessNetRate{PID: pid, Name: names[pid]}
+			if pid == 0 {
+				r.Name = "(other)"
+			}
+			byPID[pid] = r
+		}
+		r.Sent += sent
+		r.Recv += recv
+	}
+
+	rates := make([]ProcessNetRate, 0, len(byPID))
+	for _, r := range byPID {
+		rates = append(rates, *r)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Sent+rates[i].Recv > rates[j].Sent+rates[j].Recv
+	})
+	d.procNetRates = rates
+}
+
+// toggleProcessNet shows or hides the per-process breakdown
+func (d *Dashboard) toggleProcessNet() {
+	d.showProcNet = !d.showProcNet
+	d.procNetPrev, d.procNetRates, d.procNetErr = nil, nil, nil
+	d.UpdateStats()
+	d.Render()
+}
+
+// processNetRows lists the processes moving the most TCP data
+func (d *Dashboard) processNetRows() []string {
+	if !d.showProcNet {
+		return nil
+	}
+	rows := []string{"", "[--Processes (TCP)--](fg:magenta)"}
+	switch {
+	case d.procNetErr != nil:
+		return append(rows, "["+truncateString(d.procNetErr.Error(), 27)+"](fg:red)")
+	case d.procNetRates == nil:
+		return append(rows, "Measuring...")
+	case len(d.procNetRates) == 0:
+		return append(rows, "No TCP traffic")
+	}
+
+	rows = append(rows, "[Process         Up      Down](fg:cyan)")
+	for i, r := range d.procNetRates {
+		if i == procNetRows {
+			break
+		}
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("pid %d", r.PID)
+		}
+		rows = append(rows, fmt.Sprintf("%-8s %9s %9s", truncateString(name, 8), formatRate(r.Sent), formatRate(r.Recv)))
+	}
+	return rows
+}
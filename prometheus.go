@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// prometheusTopProcesses bounds the per-process series so that
+// short-lived processes do not blow up the metric cardinality
+const prometheusTopProcesses = 20
+
+// promSample is one line of a metric family
+type promSample struct {
+	labels [][2]string
+	value  float64
+}
+
+// writePromMetric writes a metric family in the Prometheus text
+// exposition format
+func writePromMetric(w io.Writer, name, help, kind string, samples ...promSample) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+	for _, s := range samples {
+		fmt.Fprint(w, name)
+		if len(s.labels) > 0 {
+			pairs := make([]string, len(s.labels))
+			for i, l := range s.labels {
+				pairs[i] = fmt.Sprintf(`%s="%s"`, l[0], escapePromLabel(l[1]))
+			}
+			fmt.Fprintf(w, "{%s}", strings.Join(pairs, ","))
+		}
+		fmt.Fprintf(w, " %s\n", strconv.FormatFloat(s.value, 'g', -1, 64))
+	}
+}
+
+// promLabelEscaper escapes the only characters the exposition format
+// escapes in a label value: \\, \" and \n
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapePromLabel returns v for a quoted label value. Everything else,
+// such as non-ASCII host or process names, stays raw UTF-8; invalid bytes
+// become U+FFFD since the format must be UTF-8.
+func escapePromLabel(v string) string {
+	return promLabelEscaper.Replace(strings.ToValidUTF8(v, "\uFFFD"))
+}
+
+// promValue is a sample without labels
+func promValue(v float64) promSample {
+	return promSample{value: v}
+}
+
+// prometheusHandler serves the latest stats snapshot as metrics
+func (d *Dashboard) prometheusHandler(w http.ResponseWriter, r *http.Request) {
+	stats, at := d.snapshot.Get()
+	if at.IsZero() {
+		http.Error(w, "no stats collected yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	cpuSamples := make([]promSample, len(stats.CPUPercent))
+	for i, pct := range stats.CPUPercent {
+		cpuSamples[i] = promSample{labels: [][2]string{{"cpu", strconv.Itoa(i)}}, value: pct}
+	}
+	writePromMetric(w, "raspi_cpu_usage_percent", "CPU usage per core.", "gauge", cpuSamples...)
+	writePromMetric(w, "raspi_cpu_temperature_celsius", "CPU temperature.", "gauge", promValue(stats.Temperature))
+
+	if stats.Throttle.Known {
+		writePromMetric(w, "raspi_throttled_flags", "Raw bits of vcgencmd get_throttled.", "gauge", promValue(float64(stats.Throttle.Flags)))
+	}
+
+	if stats.CoreVolts > 0 {
+		writePromMetric(w, "raspi_core_volts", "Core voltage.", "gauge", promValue(stats.CoreVolts))
+		writePromMetric(w, "raspi_clock_hz", "Measured clock frequencies.", "gauge",
+			promSample{labels: [][2]string{{"clock", "arm"}}, value: float64(stats.ARMClock)},
+			promSample{labels: [][2]string{{"clock", "core"}}, value: float64(stats.CoreClock)})
+	}
+
+	if stats.Fan != nil {
+		writePromMetric(w, "raspi_fan_duty_percent", "Fan duty cycle set by the fan controller.", "gauge", promValue(float64(stats.Fan.Duty)))
+	}
+
+	if b := stats.Battery; b != nil {
+		writePromMetric(w, "raspi_battery_percent", "Charge of the UPS HAT battery.", "gauge", promValue(b.Percent))
+		if b.Voltage > 0 {
+			writePromMetric(w, "raspi_battery_volts", "Voltage of the UPS HAT battery.", "gauge", promValue(b.Voltage))
+		}
+		charging, plugged := promValue(0), promValue(0)
+		if b.Charging {
+			charging.value = 1
+		}
+		if b.Plugged {
+			plugged.value = 1
+		}
+		writePromMetric(w, "raspi_battery_charging", "Whether the UPS HAT battery is charging.", "gauge", charging)
+		writePromMetric(w, "raspi_battery_power_plugged", "Whether the UPS HAT has external power.", "gauge", plugged)
+	}
+
+	writePromMetric(w, "raspi_load_average", "Load average.", "gauge",
+		promSample{labels: [][2]string{{"period", "1m"}}, value: stats.Load.Load1},
+		promSample{labels: [][2]string{{"period", "5m"}}, value: stats.Load.Load5},
+		promSample{labels: [][2]string{{"period", "15m"}}, value: stats.Load.Load15})
+
+	if p := stats.Pressure; p != nil {
+		var pressure []promSample
+		for _, r := range []struct {
+			name string
+			p    collector.Pressure
+		}{{"cpu", p.CPU}, {"memory", p.Memory}, {"io", p.IO}} {
+			pressure = append(pressure,
+				promSample{labels: [][2]string{{"resource", r.name}, {"kind", "some"}}, value: r.p.Some.Avg10},
+				promSample{labels: [][2]string{{"resource", r.name}, {"kind", "full"}}, value: r.p.Full.Avg10})
+		}
+		writePromMetric(w, "raspi_pressure_stall_percent", "Share of the last 10s tasks were stalled on a resource.", "gauge", pressure...)
+	}
+
+	if len(stats.Sensors) > 0 {
+		sensors := make([]promSample, len(stats.Sensors))
+		for i, s := range stats.Sensors {
+			sensors[i] = promSample{labels: [][2]string{{"chip", s.Chip}, {"label", s.Label}, {"kind", s.Kind}}, value: s.Value}
+		}
+		writePromMetric(w, "raspi_sensor_value", "Thermal zone and hwmon readings in °C, RPM, V, A or W by kind.", "gauge", sensors...)
+	}
+
+	if len(stats.Environment) > 0 {
+		var temps, humidity, pressure []promSample
+		for _, r := range stats.Environment {
+			labels := [][2]string{{"sensor", r.Name}}
+			temps = append(temps, promSample{labels: labels, value: r.Temperature})
+			if r.Humidity != nil {
+				humidity = append(humidity, promSample{labels: labels, value: *r.Humidity})
+			}
+			if r.Pressure != nil {
+				pressure = append(pressure, promSample{labels: labels, value: *r.Pressure})
+			}
+		}
+		writePromMetric(w, "raspi_ambient_temperature_celsius", "Temperature of an I2C environmental sensor.", "gauge", temps...)
+		if len(humidity) > 0 {
+			writePromMetric(w, "raspi_ambient_humidity_percent", "Relative humidity of an I2C environmental sensor.", "gauge", humidity...)
+		}
+		if len(pressure) > 0 {
+			writePromMetric(w, "raspi_ambient_pressure_hpa", "Air pressure of an I2C environmental sensor.", "gauge", pressure...)
+		}
+	}
+
+	if len(stats.Plugins) > 0 {
+		plugins := make([]promSample, len(stats.Plugins))
+		for i, m := range stats.Plugins {
+			plugins[i] = promSample{labels: [][2]string{{"plugin", m.Plugin}, {"name", m.Name}, {"unit", m.Unit}}, value: m.Value}
+		}
+		writePromMetric(w, "raspi_plugin_value", "Metrics of external collector plugins.", "gauge", plugins...)
+	}
+
+	writePromMetric(w, "raspi_memory_total_bytes", "Total memory.", "gauge", promValue(float64(stats.MemTotal)))
+	writePromMetric(w, "raspi_memory_used_bytes", "Used memory.", "gauge", promValue(float64(stats.MemUsed)))
+	writePromMetric(w, "raspi_memory_available_bytes", "Available memory.", "gauge", promValue(float64(stats.MemAvailable)))
+	writePromMetric(w, "raspi_memory_cached_bytes", "Page cache memory.", "gauge", promValue(float64(stats.MemCached)))
+	writePromMetric(w, "raspi_swap_total_bytes", "Total swap.", "gauge", promValue(float64(stats.SwapTotal)))
+	writePromMetric(w, "raspi_swap_used_bytes", "Used swap.", "gauge", promValue(float64(stats.SwapUsed)))
+
+	writePromMetric(w, "raspi_disk_usage_percent", "Disk usage of the monitored mount point.", "gauge",
+		promSample{labels: [][2]string{{"mountpoint", d.config.DiskMount}}, value: stats.DiskPercent})
+
+	diskRead := make([]promSample, len(stats.DiskIO))
+	diskWritten := make([]promSample, len(stats.DiskIO))
+	diskReads := make([]promSample, len(stats.DiskIO))
+	diskWrites := make([]promSample, len(stats.DiskIO))
+	for i, dev := range stats.DiskIO {
+		labels := [][2]string{{"device", dev.Name}}
+		diskRead[i] = promSample{labels: labels, value: float64(dev.ReadBytes)}
+		diskWritten[i] = promSample{labels: labels, value: float64(dev.WriteBytes)}
+		diskReads[i] = promSample{labels: labels, value: float64(dev.ReadCount)}
+		diskWrites[i] = promSample{labels: labels, value: float64(dev.WriteCount)}
+	}
+	writePromMetric(w, "raspi_disk_read_bytes_total", "Bytes read per block device.", "counter", diskRead...)
+	writePromMetric(w, "raspi_disk_written_bytes_total", "Bytes written per block device.", "counter", diskWritten...)
+	writePromMetric(w, "raspi_disk_reads_completed_total", "Reads completed per block device.", "counter", diskReads...)
+	writePromMetric(w, "raspi_disk_writes_completed_total", "Writes completed per block device.", "counter", diskWrites...)
+
+	writePromMetric(w, "raspi_network_transmit_bytes_total", "Bytes sent on all interfaces.", "counter", promValue(float64(stats.NetSent)))
+	writePromMetric(w, "raspi_network_receive_bytes_total", "Bytes received on all interfaces.", "counter", promValue(float64(stats.NetRecv)))
+
+	ifaceSent := make([]promSample, len(stats.Interfaces))
+	ifaceRecv := make([]promSample, len(stats.Interfaces))
+	ifaceUp := make([]promSample, len(stats.Interfaces))
+	for i, iface := range stats.Interfaces {
+		labels := [][2]string{{"interface", iface.Name}}
+		ifaceSent[i] = promSample{labels: labels, value: float64(iface.BytesSent)}
+		ifaceRecv[i] = promSample{labels: labels, value: float64(iface.BytesRecv)}
+		ifaceUp[i] = promSample{labels: labels}
+		if iface.Up {
+			ifaceUp[i].value = 1
+		}
+	}
+	writePromMetric(w, "raspi_network_interface_transmit_bytes_total", "Bytes sent per interface.", "counter", ifaceSent...)
+	writePromMetric(w, "raspi_network_interface_receive_bytes_total", "Bytes received per interface.", "counter", ifaceRecv...)
+	writePromMetric(w, "raspi_network_interface_up", "Whether the interface is administratively up.", "gauge", ifaceUp...)
+
+	var wifiSignal, wifiQuality []promSample
+	for _, iface := range stats.Interfaces {
+		if iface.Wifi == nil {
+			continue
+		}
+		labels := [][2]string{{"interface", iface.Name}, {"ssid", iface.Wifi.SSID}}
+		wifiSignal = append(wifiSignal, promSample{labels: labels, value: iface.Wifi.Signal})
+		wifiQuality = append(wifiQuality, promSample{labels: labels, value: iface.Wifi.Quality})
+	}
+	if len(wifiSignal) > 0 {
+		writePromMetric(w, "raspi_wifi_signal_dbm", "Wi-Fi signal level.", "gauge", wifiSignal...)
+		writePromMetric(w, "raspi_wifi_link_quality_percent", "Wi-Fi link quality.", "gauge", wifiQuality...)
+	}
+
+	writePromMetric(w, "raspi_uptime_seconds", "System uptime.", "gauge", promValue(float64(stats.Uptime)))
+	writePromMetric(w, "raspi_processes", "Number of processes.", "gauge", promValue(float64(stats.ProcessCount)))
+	writePromMetric(w, "raspi_processes_state", "Processes in zombie or uninterruptible (D) state.", "gauge",
+		promSample{labels: [][2]string{{"state", "zombie"}}, value: float64(stats.Zombies)},
+		promSample{labels: [][2]string{{"state", "blocked"}}, value: float64(stats.Blocked)})
+
+	// Per-process series for the busiest processes only
+	procs := append([]ProcessInfo(nil), stats.AllProcesses...)
+	sort.Slice(procs, func(i, j int) bool { return procs[i].CPU > procs[j].CPU })
+	if len(procs) > prometheusTopProcesses {
+		procs = procs[:prometheusTopProcesses]
+	}
+	procCPU := make([]promSample, len(procs))
+	procMem := make([]promSample, len(procs))
+	for i, p := range procs {
+		labels := [][2]string{{"pid", strconv.Itoa(int(p.PID))}, {"name", p.Name}, {"user", p.Username}}
+		procCPU[i] = promSample{labels: labels, value: p.CPU}
+		procMem[i] = promSample{labels: labels, value: p.Memory}
+	}
+	writePromMetric(w, "raspi_process_cpu_percent", "CPU usage of the top processes.", "gauge", procCPU...)
+	writePromMetric(w, "raspi_process_memory_percent", "Memory usage of the top processes.", "gauge", procMem...)
+}
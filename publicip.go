@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ddnsTimeout bounds a run of the dynamic DNS hook
+const ddnsTimeout = 30 * time.Second
+
+// PublicIPConfig sets up the public address lookup shown next to the
+// LAN IP on the System view
+type PublicIPConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	URL      string        `yaml:"url"` // answers with the caller's address as plain text
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+	// Hook runs in sh when the address changes, e.g. to update a dynamic
+	// DNS name, with PUBLIC_IP and PREVIOUS_IP in its environment
+	Hook string `yaml:"hook"`
+}
+
+func (c PublicIPConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("public_ip.url must be an http(s) URL, got %q", c.URL)
+	}
+	if c.Interval <= 0 || c.Timeout <= 0 {
+		return fmt.Errorf("public_ip.interval and public_ip.timeout must be positive")
+	}
+	return nil
+}
+
+// PublicIPStatus is the outcome of the latest lookup
+type PublicIPStatus struct {
+	IP      string    // empty before the first successful lookup
+	Err     string    // why the latest lookup failed
+	Changed time.Time // when IP last changed, zero if it has not
+	Hook    string    // result of the latest hook run, empty if none ran
+}
+
+// PublicIPMonitor looks up the public address in the background and
+// runs the hook when it changes
+type PublicIPMonitor struct {
+	cfg    PublicIPConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	status PublicIPStatus
+}
+
+func newPublicIPMonitor(cfg PublicIPConfig) *PublicIPMonitor {
+	return &PublicIPMonitor{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Status returns the result of the latest lookup
+func (m *PublicIPMonitor) Status() PublicIPStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Run looks the address up every interval until stop is closed
+func (m *PublicIPMonitor) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.check()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check fetches the address and, when it differs from the previous one,
+// runs the hook. The first address found counts as a change too, since
+// it may have moved while the monitor was not running.
+func (m *PublicIPMonitor) check() {
+	ip, err := m.lookup()
+
+	m.mu.Lock()
+	if err != nil {
+		if m.status.Err == "" {
+			log.Printf("Public IP lookup failed: %v", err)
+		}
+		m.status.Err = err.Error()
+		m.mu.Unlock()
+		return
+	}
+	prev := m.status.IP
+	m.status.IP, m.status.Err = ip, ""
+	if ip == prev {
+		m.mu.Unlock()
+		return
+	}
+	if prev != "" {
+		m.status.Changed = time.Now()
+	}
+	m.mu.Unlock()
+
+	log.Printf("Public IP is %s (was %q)", ip, prev)
+	if m.cfg.Hook == "" {
+		return
+	}
+	result := m.runHook(ip, prev)
+	m.mu.Lock()
+	m.status.Hook = result
+	m.mu.Unlock()
+}
+
+// lookup asks the provider for the public address
+func (m *PublicIPMonitor) lookup() (string, error) {
+	resp, err := m.client.Get(m.cfg.URL)
+	if err != nil {
+		return "", fmt.Errorf("lookup failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", fmt.Errorf("lookup failed")
+	}
+	ip := strings.TrimSpace(string(body))
+	if net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("not an address: %q", truncateString(ip, 16))
+	}
+	return ip, nil
+}
+
+// runHook runs the configured command for a new address and returns a
+// short result for the System view
+func (m *PublicIPMonitor) runHook(ip, prev string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), ddnsTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", m.cfg.Hook)
+	cmd.Env = append(os.Environ(), "PUBLIC_IP="+ip, "PREVIOUS_IP="+prev)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		msg := commandError(out, err)
+		log.Printf("DDNS hook failed: %s", msg)
+		return "failed: " + msg
+	}
+	log.Printf("DDNS hook updated %s", ip)
+	return "updated " + time.Now().Format("15:04")
+}
+
+// publicIPRows shows the public address under the LAN IP on the System
+// view
+func publicIPRows(m *PublicIPMonitor) []string {
+	if m == nil {
+		return nil
+	}
+	status := m.Status()
+	var rows []string
+	switch {
+	case status.IP == "" && status.Err == "":
+		rows = append(rows, "Public: checking...")
+	case status.IP == "":
+		rows = append(rows, fmt.Sprintf("Public: [%s](fg:red)", truncateString(status.Err, 19)))
+	case status.Err != "":
+		rows = append(rows, fmt.Sprintf("Public: [%s](fg:yellow)", status.IP))
+	default:
+		rows = append(rows, "Public: "+status.IP)
+	}
+	if !status.Changed.IsZero() {
+		color := "white"
+		if time.Since(status.Changed) < time.Hour {
+			color = "yellow"
+		}
+		rows = append(rows, fmt.Sprintf("[Changed %s ago](fg:%s)", formatAge(time.Since(status.Changed)), color))
+	}
+	if status.Hook != "" {
+		color := "green"
+		if strings.HasPrefix(status.Hook, "failed") {
+			color = "red"
+		}
+		rows = append(rows, fmt.Sprintf("DDNS: [%s](fg:%s)", truncateString(status.Hook, 21), color))
+	}
+	return rows
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replaySpeeds are the steps the faster and slower keys move the replay
+// speed along
+var replaySpeeds = []float64{1, 2, 5, 10, 30, 60, 120, 300, 600, 1800, 3600}
+
+// RecordConfig appends every stats sample to a file, for replaying a
+// night of them later with --replay. A path ending in .gz is compressed.
+type RecordConfig struct {
+	Path     string        `yaml:"path"`     // empty disables recording
+	Interval time.Duration `yaml:"interval"` // time between recorded samples, 0 records every one
+}
+
+func (c RecordConfig) validate() error {
+	if c.Interval < 0 {
+		return fmt.Errorf("record.interval must not be negative")
+	}
+	return nil
+}
+
+// ReplayConfig plays a recording back on the views instead of showing
+// this Pi
+type ReplayConfig struct {
+	Path  string  `yaml:"path"`  // empty shows this Pi
+	Speed float64 `yaml:"speed"` // recorded seconds per second
+}
+
+func (c ReplayConfig) validate() error {
+	if c.Path == "" {
+		return nil
+	}
+	if _, err := os.Stat(c.Path); err != nil {
+		return fmt.Errorf("replay.path: %w", err)
+	}
+	if c.Speed <= 0 {
+		return fmt.Errorf("replay.speed must be positive")
+	}
+	return nil
+}
+
+// runRecorder appends the latest sample to record.path as a JSON line,
+// like the ones of /api/stats with the process list, until stop is
+// closed
+func (d *Dashboard) runRecorder(stop <-chan struct{}) {
+	cfg := d.config.Record
+	f, err := os.OpenFile(cfg.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		log.Printf("Recording disabled: %v", err)
+		return
+	}
+	defer f.Close()
+
+	// Each sample is a gzip stream of its own, and gzip streams in a row
+	// read back as one, so a recording stopped or appended to at any
+	// point stays whole
+	var gz *gzip.Writer
+	if strings.HasSuffix(cfg.Path, ".gz") {
+		gz = gzip.NewWriter(f)
+	}
+
+	every := cfg.Interval
+	if every <= 0 {
+		every = d.config.Interval
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	log.Printf("Recording to %s", cfg.Path)
+
+	var lastAt time.Time
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		stats, at := d.snapshot.Get()
+		if at.IsZero() || !at.After(lastAt) {
+			continue
+		}
+		lastAt = at
+		line, err := json.Marshal(apiStats{Timestamp: at, Hostname: d.statsHost(), SystemStats: stats})
+		if err == nil {
+			line = append(line, '\n')
+			if gz != nil {
+				gz.Reset(f)
+				if _, err = gz.Write(line); err == nil {
+					err = gz.Close()
+				}
+			} else {
+				_, err = f.Write(line)
+			}
+		}
+		if err != nil {
+			log.Printf("Recording stopped: %v", err)
+			return
+		}
+	}
+}
+
+// replaySource plays a recording back as the stats of the views. Its
+// clock starts at the first sample and runs speed times faster than the
+// wall clock; Collect returns the last sample the clock has passed.
+type replaySource struct {
+	path string
+
+	mu      sync.Mutex
+	file    io.Closer
+	dec     *json.Decoder
+	cur     apiStats
+	next    *apiStats // first sample after the clock, nil at the end
+	clock   time.Time
+	wall    time.Time // when clock was last advanced
+	speed   float64
+	paused  bool
+	openErr error
+}
+
+func newReplaySource(cfg ReplayConfig) *replaySource {
+	return &replaySource{path: cfg.Path, speed: cfg.Speed}
+}
+
+// open reads the first sample and starts the clock there. The caller
+// holds mu.
+func (s *replaySource) open() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	var in io.Reader = r
+	if magic, _ := r.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		in = gz
+	}
+	s.file = f
+	s.dec = json.NewDecoder(in)
+	if err := s.dec.Decode(&s.cur); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: no samples: %w", s.path, err)
+	}
+	s.clock, s.wall = s.cur.Timestamp, time.Now()
+	s.readNext()
+	return nil
+}
+
+// readNext reads the sample after cur, leaving next nil at the end of
+// the recording. A recording cut short by a crash ends at its last
+// whole sample. The caller holds mu.
+func (s *replaySource) readNext() {
+	var body apiStats
+	if err := s.dec.Decode(&body); err != nil {
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			log.Printf("Replay: %s ends early: %v", s.path, err)
+		}
+		s.next = nil
+		return
+	}
+	s.next = &body
+}
+
+func (s *replaySource) Collect(ctx context.Context) (SystemStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dec == nil {
+		if s.openErr == nil {
+			s.openErr = s.open()
+		}
+		if s.openErr != nil {
+			return SystemStats{}, fmt.Errorf("replay: %w", s.openErr)
+		}
+	}
+
+	now := time.Now()
+	if !s.paused {
+		s.clock = s.clock.Add(time.Duration(float64(now.Sub(s.wall)) * s.speed))
+	}
+	s.wall = now
+	for s.next != nil && !s.next.Timestamp.After(s.clock) {
+		s.cur = *s.next
+		s.readNext()
+	}
+	if s.next == nil {
+		s.clock = s.cur.Timestamp // the clock stops at the end
+	}
+	return s.cur.SystemStats, nil
+}
+
+func (s *replaySource) Host() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cur.Hostname == "" {
+		return "replay"
+	}
+	return s.cur.Hostname
+}
+
+// Close closes the recording
+func (s *replaySource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file != nil {
+		return s.file.Close()
+	}
+	return nil
+}
+
+// SetPaused stops or restarts the clock
+func (s *replaySource) SetPaused(paused bool) {
+	s.mu.Lock()
+	s.paused = paused
+	s.mu.Unlock()
+}
+
+// changeSpeed moves the speed delta steps along replaySpeeds, a positive
+// delta playing faster. A speed between two steps snaps to the
+// neighbouring one, as the update interval does.
+func (s *replaySource) changeSpeed(delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := 0
+	for i < len(replaySpeeds)-1 && replaySpeeds[i] < s.speed {
+		i++
+	}
+	if delta > 0 && replaySpeeds[i] > s.speed {
+		i-- // replaySpeeds[i] is already one step up
+	}
+	i += delta
+	if i < 0 {
+		i = 0
+	} else if i >= len(replaySpeeds) {
+		i = len(replaySpeeds) - 1
+	}
+	s.speed = replaySpeeds[i]
+}
+
+// status is shown in the title in place of the update interval, e.g.
+// "03:12:45 x60", or "03:59:58 END" once the recording has played
+func (s *replaySource) status() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at := s.clock.Local().Format("15:04:05")
+	if s.dec != nil && s.next == nil {
+		return at + " END"
+	}
+	return fmt.Sprintf("%s x%g", at, s.speed)
+}
+
+// taken returns when the sample Collect last returned was recorded
+func (s *replaySource) taken() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cur.Timestamp
+}
+
+// sampleTime is when the latest sample was taken: now for live stats,
+// the recorded time when replaying, so rates are per recorded second
+func (d *Dashboard) sampleTime() time.Time {
+	if r := d.replay(); r != nil {
+		return r.taken()
+	}
+	return time.Now()
+}
+
+// replay returns the recording on the views, or nil when they show live
+// stats
+func (d *Dashboard) replay() *replaySource {
+	r, _ := d.collector.(*replaySource)
+	return r
+}
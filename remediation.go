@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Remediation types
+const (
+	remedyRestart = "restart"  // restart a systemd service
+	remedyKillTop = "kill_top" // signal the busiest process
+	remedyScript  = "script"   // run a shell command
+	remedyReboot  = "reboot"   // reboot the Pi
+)
+
+const (
+	// remediationTimeout bounds a remediation script
+	remediationTimeout = time.Minute
+	// remediationCooldown is how long a remedy waits after running
+	// before it may run again, unless configured
+	remediationCooldown = 10 * time.Minute
+)
+
+// RemediationConfig is an action that tries to fix what an alert found,
+// so an unattended Pi can heal itself. Unlike the notifications it only
+// runs for the rules that list it in their actions.
+type RemediationConfig struct {
+	Name     string        `yaml:"name"`     // action name used in the rules
+	Type     string        `yaml:"type"`     // restart, kill_top, script or reboot
+	Service  string        `yaml:"service"`  // restart: the systemd unit
+	Command  string        `yaml:"command"`  // script: run with sh -c
+	Signal   string        `yaml:"signal"`   // kill_top: TERM (default) or KILL
+	After    int           `yaml:"after"`    // run once the alert breaches this many samples in a row, 0 when it fires
+	Cooldown time.Duration `yaml:"cooldown"` // wait after running before running again, default 10m
+}
+
+func (c RemediationConfig) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("alerts.remediations: name is required")
+	}
+	switch c.Type {
+	case remedyRestart:
+		if c.Service == "" {
+			return fmt.Errorf("remediation %q: service is required", c.Name)
+		}
+	case remedyKillTop:
+		if c.Signal != "" && c.Signal != "TERM" && c.Signal != "KILL" {
+			return fmt.Errorf("remediation %q: signal must be TERM or KILL", c.Name)
+		}
+	case remedyScript:
+		if c.Command == "" {
+			return fmt.Errorf("remediation %q: command is required", c.Name)
+		}
+	case remedyReboot:
+	default:
+		return fmt.Errorf("remediation %q: unknown type %q (restart, kill_top, script, reboot)", c.Name, c.Type)
+	}
+	if c.After < 0 {
+		return fmt.Errorf("remediation %q: after must not be negative", c.Name)
+	}
+	if c.Cooldown < 0 {
+		return fmt.Errorf("remediation %q: cooldown must not be negative", c.Name)
+	}
+	return nil
+}
+
+// remedyStreak follows one rule: the breaching samples in a row since
+// the alert fired and when the remedy last ran for it
+type remedyStreak struct {
+	count   int
+	lastRun time.Time
+	running bool
+}
+
+// remediation is an AlertAction that runs its remedy once the alert has
+// breached its threshold After samples in a row, then waits Cooldown
+// before counting again
+type remediation struct {
+	cfg RemediationConfig
+
+	mu      sync.Mutex
+	streaks map[string]*remedyStreak // by rule name
+}
+
+func newRemediation(cfg RemediationConfig) *remediation {
+	if cfg.Cooldown == 0 {
+		cfg.Cooldown = remediationCooldown
+	}
+	return &remediation{cfg: cfg, streaks: make(map[string]*remedyStreak)}
+}
+
+// Fire counts the sample that fired the alert as its first breach
+func (r *remediation) Fire(evt AlertEvent) {
+	r.Breach(evt)
+}
+
+func (r *remediation) Breach(evt AlertEvent) {
+	r.mu.Lock()
+	streak := r.streaks[evt.Rule.Name]
+	if streak == nil {
+		streak = &remedyStreak{}
+		r.streaks[evt.Rule.Name] = streak
+	}
+	if streak.running || (!streak.lastRun.IsZero() && time.Since(streak.lastRun) < r.cfg.Cooldown) {
+		r.mu.Unlock()
+		return
+	}
+	streak.count++
+	if streak.count < r.cfg.After {
+		r.mu.Unlock()
+		return
+	}
+	streak.count = 0
+	streak.lastRun = time.Now()
+	streak.running = true
+	r.mu.Unlock()
+
+	if err := r.run(evt); err != nil {
+		log.Printf("Remediation %s failed: %v", r.cfg.Name, err)
+	}
+
+	r.mu.Lock()
+	streak.running = false
+	r.mu.Unlock()
+}
+
+// Resolve ends the streak of the rule; the cooldown still applies
+func (r *remediation) Resolve(evt AlertEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if streak := r.streaks[evt.Rule.Name]; streak != nil {
+		streak.count = 0
+	}
+}
+
+func (r *remediation) run(evt AlertEvent) error {
+	switch r.cfg.Type {
+	case remedyRestart:
+		log.Printf("Remediation %s: restarting %s for %s", r.cfg.Name, r.cfg.Service, evt.Rule.Name)
+		if out, err := systemctl("restart", r.cfg.Service); err != nil {
+			return errors.New(commandError(out, err))
+		}
+	case remedyKillTop:
+		return r.killTop(evt)
+	case remedyScript:
+		log.Printf("Remediation %s: running %q for %s", r.cfg.Name, r.cfg.Command, evt.Rule.Name)
+		ctx, cancel := context.WithTimeout(context.Background(), remediationTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "sh", "-c", r.cfg.Command)
+		cmd.Env = append(os.Environ(),
+			"ALERT_NAME="+evt.Rule.Name,
+			"ALERT_METRIC="+evt.Rule.Metric,
+			fmt.Sprintf("ALERT_VALUE=%.1f", evt.Value),
+			"ALERT_HOST="+evt.Host)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("timed out after %s", remediationTimeout)
+			}
+			return errors.New(commandError(out, err))
+		}
+	case remedyReboot:
+		log.Printf("Remediation %s: rebooting for %s", r.cfg.Name, evt.Message())
+		if out, err := systemctl("reboot"); err != nil {
+			return errors.New(commandError(out, err))
+		}
+	}
+	return nil
+}
+
+// killTop signals the busiest process of the sample that fired the
+// alert: by memory for a memory or swap alert, by CPU otherwise. The
+// monitor itself, init and kernel threads are passed over.
+func (r *remediation) killTop(evt AlertEvent) error {
+	sig, sigName := syscall.SIGTERM, "SIGTERM"
+	if r.cfg.Signal == "KILL" {
+		sig, sigName = syscall.SIGKILL, "SIGKILL"
+	}
+	byMemory := evt.Rule.Metric == "mem" || evt.Rule.Metric == "swap"
+
+	var top *ProcessInfo
+	for i, p := range evt.Processes {
+		if p.PID <= 2 || int(p.PID) == os.Getpid() || isKernelThread(p.PID) {
+			continue
+		}
+		if top == nil || (byMemory && p.Memory > top.Memory) || (!byMemory && p.CPU > top.CPU) {
+			top = &evt.Processes[i]
+		}
+	}
+	if top == nil {
+		return fmt.Errorf("no process to signal")
+	}
+	log.Printf("Remediation %s: sending %s to %d (%s, %.1f%% CPU, %.1f%% memory) for %s",
+		r.cfg.Name, sigName, top.PID, top.Name, top.CPU, top.Memory, evt.Rule.Name)
+	if err := syscall.Kill(int(top.PID), sig); err != nil {
+		return fmt.Errorf("%d (%s): %w", top.PID, top.Name, err)
+	}
+	return nil
+}
+
+// isKernelThread reports whether pid is a kernel thread, which has no
+// command line
+func isKernelThread(pid int32) bool {
+	cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	return err == nil && len(strings.TrimSpace(string(cmdline))) == 0
+}
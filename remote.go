@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"raspi-monitor/pkg/collector"
+)
+
+const (
+	defaultRemoteCommand = "raspi-monitor collect"
+	// remoteTimeout bounds an API request and the wait for the first
+	// sample of an ssh session
+	remoteTimeout = 10 * time.Second
+	// remoteRetryDelay is how long to wait before starting a new ssh
+	// session after one ended
+	remoteRetryDelay = 10 * time.Second
+	// remoteLineLimit bounds one sample; the process list makes it long
+	remoteLineLimit = 8 << 20
+)
+
+// statsSource takes a sample of the stats the views show: this Pi's
+// collector, or another host's stats
+type statsSource interface {
+	Collect(ctx context.Context) (SystemStats, error)
+}
+
+// remoteSource is a statsSource for another host
+type remoteSource interface {
+	statsSource
+	Host() string // host name the stats came from
+}
+
+// RemoteConfig shows another host's stats on the views instead of this
+// Pi's, so one Pi with a screen can page through headless ones. Views
+// that read the local system, like Services or GPIO, still show this Pi.
+type RemoteConfig struct {
+	Host    string `yaml:"host"`    // user@host for ssh, or the URL of the host's api; empty shows this Pi
+	Command string `yaml:"command"` // run over ssh, printing a sample per line
+}
+
+func (c RemoteConfig) validate() error {
+	if c.Host == "" {
+		return nil
+	}
+	if c.isURL() {
+		if _, err := url.Parse(c.Host); err != nil {
+			return fmt.Errorf("remote.host: %w", err)
+		}
+		return nil
+	}
+	if strings.HasPrefix(c.Host, "-") || strings.ContainsAny(c.Host, " \t") {
+		return fmt.Errorf("remote.host must be a URL or an ssh destination like pi@host, got %q", c.Host)
+	}
+	if c.Command == "" {
+		return fmt.Errorf("remote.command must not be empty")
+	}
+	return nil
+}
+
+// isURL reports whether the stats are read from the host's api
+func (c RemoteConfig) isURL() bool {
+	return strings.HasPrefix(c.Host, "http://") || strings.HasPrefix(c.Host, "https://")
+}
+
+// newStatsSource returns the collector of this Pi, or of the host set
+// in remote.host
+func newStatsSource(cfg *Config) statsSource {
+	switch {
+	case cfg.Replay.Path != "":
+		return newReplaySource(cfg.Replay)
+	case cfg.Remote.Host == "":
+		return collector.New(collector.Options{DiskMount: cfg.DiskMount})
+	case cfg.Remote.isURL():
+		return &apiSource{base: strings.TrimSuffix(cfg.Remote.Host, "/"), client: &http.Client{Timeout: remoteTimeout}}
+	}
+	return &sshSource{target: cfg.Remote.Host, command: cfg.Remote.Command, interval: cfg.Interval, ready: make(chan struct{})}
+}
+
+// apiSource reads the stats from the JSON API of another monitor
+type apiSource struct {
+	base   string
+	client *http.Client
+
+	mu   sync.Mutex
+	host string
+}
+
+func (s *apiSource) Collect(ctx context.Context) (SystemStats, error) {
+	var body apiStats
+	if err := s.get(ctx, "/api/stats", &body); err != nil {
+		return SystemStats{}, err
+	}
+	// The process list is served on its own; without it the Process
+	// view stays empty
+	var procs []ProcessInfo
+	if err := s.get(ctx, "/api/processes", &procs); err == nil {
+		body.AllProcesses = procs
+	}
+	s.mu.Lock()
+	s.host = body.Hostname
+	s.mu.Unlock()
+	return body.SystemStats, nil
+}
+
+func (s *apiSource) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.base+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s%s: %s", s.base, path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (s *apiSource) Host() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.host == "" {
+		return strings.TrimPrefix(strings.TrimPrefix(s.base, "http://"), "https://")
+	}
+	return s.host
+}
+
+// sshSource runs "raspi-monitor collect" on another host over ssh and
+// keeps the latest sample it printed. The system ssh client is used, so
+// keys, ~/.ssh/config and known_hosts apply; it must not ask for a
+// password.
+type sshSource struct {
+	target   string
+	command  string
+	interval time.Duration
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd // running session, nil between sessions
+	started time.Time
+	latest  apiStats
+	err     error         // why the last session ended
+	ended   chan struct{} // closed when the session ends
+	ready   chan struct{} // closed by the first sample
+}
+
+func (s *sshSource) Collect(ctx context.Context) (SystemStats, error) {
+	s.mu.Lock()
+	if s.cmd == nil && time.Since(s.started) >= remoteRetryDelay {
+		s.start()
+	}
+	ended := s.ended
+	s.mu.Unlock()
+
+	select {
+	case <-s.ready:
+	case <-ended:
+	case <-ctx.Done():
+		return SystemStats{}, ctx.Err()
+	case <-time.After(remoteTimeout):
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cmd == nil && s.err != nil {
+		return s.latest.SystemStats, fmt.Errorf("ssh %s: %w", s.target, s.err)
+	}
+	if s.latest.Timestamp.IsZero() {
+		return SystemStats{}, fmt.Errorf("ssh %s: no sample yet", s.target)
+	}
+	return s.latest.SystemStats, nil
+}
+
+// start runs a new session. The caller holds mu.
+func (s *sshSource) start() {
+	remote := fmt.Sprintf("%s --interval %s", s.command, s.interval)
+	cmd := exec.Command("ssh", "-o", "BatchMode=yes", "-o", "ServerAliveInterval=15", s.target, remote)
+	stdout, err := cmd.StdoutPipe()
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err == nil {
+		err = cmd.Start()
+	}
+	s.started = time.Now()
+	s.ended = make(chan struct{})
+	if err != nil {
+		s.err = err
+		close(s.ended)
+		return
+	}
+	s.cmd = cmd
+	ended := s.ended
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), remoteLineLimit)
+		for scanner.Scan() {
+			var body apiStats
+			// a line without a timestamp is not a sample, and would
+			// close ready a second time
+			if json.Unmarshal(scanner.Bytes(), &body) != nil || body.Timestamp.IsZero() {
+				continue
+			}
+			s.mu.Lock()
+			if s.latest.Timestamp.IsZero() {
+				close(s.ready)
+			}
+			s.latest = body
+			s.mu.Unlock()
+		}
+		err := cmd.Wait()
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.New(msg)
+		} else if err == nil {
+			err = errors.New("session ended")
+		}
+		s.mu.Lock()
+		s.cmd, s.err = nil, err
+		s.mu.Unlock()
+		close(ended)
+	}()
+}
+
+func (s *sshSource) Host() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.latest.Hostname == "" {
+		return s.target
+	}
+	return s.latest.Hostname
+}
+
+// Close ends the ssh session
+func (s *sshSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cmd != nil {
+		return s.cmd.Process.Kill()
+	}
+	return nil
+}
+
+// disablePublishers turns off the alerts, history and exporters, which
+// would report another host's stats as this Pi's. It returns the names
+// of the ones that were on.
+func (c *Config) disablePublishers() []string {
+	var off []string
+	if c.Alerts.Enabled {
+		c.Alerts.Enabled = false
+		off = append(off, "alerts")
+	}
+	if c.History.Enabled {
+		c.History.Enabled = false
+		off = append(off, "history")
+	}
+	if c.MQTT.Broker != "" {
+		c.MQTT.Broker = ""
+		off = append(off, "mqtt")
+	}
+	if c.Influx.URL != "" {
+		c.Influx.URL = ""
+		off = append(off, "influxdb")
+	}
+	if c.Graphite.Address != "" {
+		c.Graphite.Address = ""
+		off = append(off, "graphite")
+	}
+	if c.Agent.Server != "" {
+		c.Agent.Server = ""
+		off = append(off, "agent")
+	}
+	return off
+}
+
+// applyRunMode turns off what must not run while the views show another
+// host or a recording, logging what it turned off
+func (c *Config) applyRunMode() {
+	if c.Remote.Host != "" {
+		if off := c.disablePublishers(); len(off) > 0 {
+			log.Printf("Showing %s, so %s stay off", c.Remote.Host, strings.Join(off, ", "))
+		}
+	}
+	if c.Replay.Path != "" {
+		// Recorded samples must not be recorded, alerted on or exported
+		// again as if they were live
+		c.Record.Path = ""
+		off := c.disablePublishers()
+		log.Printf("Replaying %s at x%g", c.Replay.Path, c.Replay.Speed)
+		if len(off) > 0 {
+			log.Printf("Replaying, so %s stay off", strings.Join(off, ", "))
+		}
+	}
+}
+
+// remoteHost is the host whose stats the views show, empty when they
+// are this Pi's
+func (d *Dashboard) remoteHost() string {
+	if r, ok := d.collector.(remoteSource); ok {
+		return r.Host()
+	}
+	return ""
+}
+
+// statsHost is the host name of the stats the views show
+func (d *Dashboard) statsHost() string {
+	if host := d.remoteHost(); host != "" {
+		return host
+	}
+	return hostname()
+}
+
+// localOnly tells the user that action acts on this Pi's processes and
+// reports whether the views show another host, so it must not run
+func (d *Dashboard) localOnly(action string) bool {
+	host := d.remoteHost()
+	if host == "" {
+		return false
+	}
+	d.showMessage(action, "Not available for", fitString(host, viewColumnWidth-2))
+	return true
+}
+
+// runCollect implements "raspi-monitor collect", printing a sample of
+// the stats as a JSON line every interval for a monitor reading them
+// over ssh. It returns the process exit code.
+func runCollect(args []string) int {
+	fs := flag.NewFlagSet("collect", flag.ContinueOnError)
+	interval := fs.Duration("interval", updateInterval, "time between samples")
+	mount := fs.String("mount", "/", "mount point shown in the disk usage")
+	once := fs.Bool("once", false, "print one sample and exit")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: raspi-monitor collect [--interval 1s] [--mount /] [--once]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *interval < minInterval {
+		*interval = minInterval
+	}
+
+	c := collector.New(collector.Options{DiskMount: *mount})
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		stats, err := c.Collect(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "collect: %v\n", err)
+		}
+		if err := enc.Encode(apiStats{Timestamp: time.Now(), Hostname: hostname(), SystemStats: stats}); err != nil {
+			return 0 // the reader went away
+		}
+		if *once {
+			return 0
+		}
+		time.Sleep(*interval)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// Formats of the diagnostic report
+const (
+	reportText = "text"
+	reportJSON = "json"
+)
+
+// reportKernelLines is how much of the end of the kernel log a report
+// keeps
+const reportKernelLines = 200
+
+// ReportConfig sets where the report key writes diagnostic reports
+type ReportConfig struct {
+	Dir    string `yaml:"dir"`    // empty uses $XDG_DATA_HOME/raspi-monitor/reports
+	Format string `yaml:"format"` // text or json
+}
+
+func (c ReportConfig) validate() error {
+	if c.Format != reportText && c.Format != reportJSON {
+		return fmt.Errorf("report.format must be %s or %s, got %q", reportText, reportJSON, c.Format)
+	}
+	return nil
+}
+
+// defaultReportDir returns $XDG_DATA_HOME/raspi-monitor/reports, falling
+// back to ~/.local/share when XDG_DATA_HOME is unset
+func defaultReportDir() string {
+	return filepath.Join(filepath.Dir(defaultHistoryPath()), "reports")
+}
+
+// diagReport is a snapshot of the state of the Pi, for attaching to a
+// support request after an incident
+type diagReport struct {
+	Time        time.Time        `json:"time"`
+	Hostname    string           `json:"hostname"`
+	Throttle    reportThrottle   `json:"throttle"`
+	Filesystems []FilesystemInfo `json:"filesystems"`
+	Kernel      []reportLogLine  `json:"kernel_log"`
+	KernelError string           `json:"kernel_log_error,omitempty"` // why the kernel log is missing
+	Stats       SystemStats      `json:"stats"`                      // with the full process table
+}
+
+// reportThrottle spells out the get_throttled flags
+type reportThrottle struct {
+	Known   bool     `json:"known"`
+	Flags   string   `json:"flags"`
+	Current []string `json:"current"`
+	Past    []string `json:"since_boot"`
+}
+
+type reportLogLine struct {
+	Time     time.Time `json:"time"`
+	Priority int       `json:"priority"`
+	Message  string    `json:"message"`
+}
+
+// newDiagReport gathers the parts of a report that are not in stats
+func newDiagReport(stats SystemStats, at time.Time) diagReport {
+	r := diagReport{
+		Time:     at,
+		Hostname: hostname(),
+		Throttle: reportThrottle{
+			Known:   stats.Throttle.Known,
+			Flags:   fmt.Sprintf("0x%x", stats.Throttle.Flags),
+			Current: stats.Throttle.Current(),
+			Past:    stats.Throttle.Past(),
+		},
+		Filesystems: getFilesystems(),
+		Stats:       stats,
+	}
+	r.Stats.AllProcesses = sortProcesses(stats.AllProcesses, sortByCPU)
+
+	entries, err := getKernelLog()
+	if err != nil {
+		r.KernelError = err.Error()
+	}
+	if len(entries) > reportKernelLines {
+		entries = entries[len(entries)-reportKernelLines:]
+	}
+	for _, e := range entries {
+		r.Kernel = append(r.Kernel, reportLogLine{Time: e.Time, Priority: e.Priority, Message: e.Message})
+	}
+	return r
+}
+
+// writeText writes the report for reading, most telling sections first
+func (r diagReport) writeText(w io.Writer) error {
+	s := r.Stats
+	days, hours, minutes := formatUptime(s.Uptime)
+	fmt.Fprintf(w, "raspi-monitor diagnostic report\n")
+	fmt.Fprintf(w, "Host:    %s\n", r.Hostname)
+	fmt.Fprintf(w, "Time:    %s\n", r.Time.Format(time.RFC3339))
+	fmt.Fprintf(w, "Uptime:  %dd %dh %dm\n", days, hours, minutes)
+	fmt.Fprintf(w, "IP:      %s\n", s.IPAddress)
+
+	fmt.Fprintf(w, "\n== Throttling ==\n")
+	if !r.Throttle.Known {
+		fmt.Fprintf(w, "unknown (vcgencmd not available)\n")
+	} else {
+		fmt.Fprintf(w, "Flags:      %s\n", r.Throttle.Flags)
+		fmt.Fprintf(w, "Now:        %s\n", reportList(r.Throttle.Current))
+		fmt.Fprintf(w, "Since boot: %s\n", reportList(r.Throttle.Past))
+	}
+
+	fmt.Fprintf(w, "\n== System ==\n")
+	fmt.Fprintf(w, "CPU:         %.1f%% avg, per core %s\n", calculateAverage(s.CPUPercent), reportPercents(s.CPUPercent))
+	fmt.Fprintf(w, "Load:        %.2f %.2f %.2f\n", s.Load.Load1, s.Load.Load5, s.Load.Load15)
+	fmt.Fprintf(w, "Temperature: %s\n", formatTemperature(s.Temperature))
+	if s.ARMClock > 0 {
+		fmt.Fprintf(w, "Clocks:      ARM %d MHz, core %d MHz, %.2f V\n", s.ARMClock/1e6, s.CoreClock/1e6, s.CoreVolts)
+	}
+	fmt.Fprintf(w, "Memory:      %s / %s (%.1f%%), available %s\n", formatBytes(s.MemUsed), formatBytes(s.MemTotal), s.MemPercent, formatBytes(s.MemAvailable))
+	fmt.Fprintf(w, "Swap:        %s / %s (%.1f%%)\n", formatBytes(s.SwapUsed), formatBytes(s.SwapTotal), s.SwapPercent)
+	fmt.Fprintf(w, "Processes:   %d, %d zombie, %d blocked\n", s.ProcessCount, s.Zombies, s.Blocked)
+	if p := s.Pressure; p != nil {
+		fmt.Fprintf(w, "Pressure:    cpu %.1f%%, memory %.1f%%, io %.1f%% (some, avg10)\n", p.CPU.Some.Avg10, p.Memory.Some.Avg10, p.IO.Some.Avg10)
+	}
+	for _, sensor := range s.Sensors {
+		fmt.Fprintf(w, "Sensor:      %s/%s %s\n", sensor.Chip, sensor.Label, formatSensor(sensor))
+	}
+
+	fmt.Fprintf(w, "\n== Filesystems ==\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MOUNT\tDEVICE\tTYPE\tSIZE\tUSED\tUSE%\tINODE%")
+	for _, fs := range r.Filesystems {
+		if fs.Stale {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t-\t-\tstale\t-\n", fs.Mount, fs.Device, fs.FSType)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.1f\t%.1f\n", fs.Mount, fs.Device, fs.FSType,
+			formatBytes(fs.Total), formatBytes(fs.Used), fs.Percent, fs.InodesPercent)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n== Network ==\n")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INTERFACE\tSTATE\tADDRESSES\tRECEIVED\tSENT")
+	for _, iface := range s.Interfaces {
+		state := "down"
+		if iface.Up {
+			state = "up"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", iface.Name, state, strings.Join(iface.Addrs, " "),
+			formatBytes(iface.BytesRecv), formatBytes(iface.BytesSent))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n== Processes (by CPU) ==\n")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PID\tUSER\tCPU%\tMEM%\tSTATE\tNAME")
+	for _, p := range s.AllProcesses {
+		fmt.Fprintf(tw, "%d\t%s\t%.1f\t%.1f\t%s\t%s\n", p.PID, p.Username, p.CPU, p.Memory, p.Status, p.Name)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n== Kernel log (last %d lines) ==\n", reportKernelLines)
+	if r.KernelError != "" {
+		fmt.Fprintf(w, "unavailable: %s\n", r.KernelError)
+	}
+	for _, l := range r.Kernel {
+		fmt.Fprintf(w, "%s %s\n", l.Time.Format("2006-01-02 15:04:05"), l.Message)
+	}
+	return nil
+}
+
+// reportList joins names, or says there are none
+func reportList(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+func reportPercents(values []float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%.0f%%", v)
+	}
+	return strings.Join(parts, " ")
+}
+
+// write writes the report in format to w
+func (r diagReport) write(w io.Writer, format string) error {
+	if format == reportJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return r.writeText(w)
+}
+
+// save writes the report to a file named after the host and time
+// in dir and returns its path. Reports list command names and users, so
+// only the owner may read them.
+func (r diagReport) save(dir, format string) (string, error) {
+	if dir == "" {
+		dir = defaultReportDir()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	ext := ".txt"
+	if format == reportJSON {
+		ext = ".json"
+	}
+	name := fmt.Sprintf("raspi-monitor-%s-%s%s", graphiteName(r.Hostname), r.Time.Format("20060102-150405"), ext)
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	if err := r.write(f, format); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// saveReport writes a report of the latest sample, for the report key
+func (d *Dashboard) saveReport() {
+	if d.localOnly("Report") {
+		return
+	}
+	stats, at := d.snapshot.Get()
+	if at.IsZero() {
+		return
+	}
+	path, err := newDiagReport(stats, at).save(d.config.Report.Dir, d.config.Report.Format)
+	if err != nil {
+		log.Printf("Failed to write report: %v", err)
+		d.showMessage("Report", "Failed:", fitString(err.Error(), viewColumnWidth-2))
+		return
+	}
+	log.Printf("Wrote diagnostic report %s", path)
+	d.showMessage("Report", "Saved to", fitString(filepath.Base(path), viewColumnWidth-2))
+}
+
+// runReport implements "raspi-monitor report", writing a report without
+// starting the monitor. It returns the process exit code.
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath(), "path to the config file")
+	format := fs.String("format", "", "report format: text or json (default: report.format from the config)")
+	output := fs.String("output", "", "output file, - for stdout (default: a new file in report.dir)")
+	mount := fs.String("mount", "", "mount point shown in the disk usage (default: disk_mount from the config)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: raspi-monitor report [--format text|json] [--output FILE]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "report: %v\n", err)
+		return 1
+	}
+	if *format != "" {
+		cfg.Report.Format = *format
+	}
+	if *mount != "" {
+		cfg.DiskMount = *mount
+	}
+	if err := cfg.Report.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "report: %v\n", err)
+		return 2
+	}
+
+	// CPU usage is measured between two samples, so the first one only
+	// sets the baseline
+	c := collector.New(collector.Options{DiskMount: cfg.DiskMount})
+	c.Collect(context.Background())
+	time.Sleep(time.Second)
+	stats, err := c.Collect(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "report: %v\n", err)
+	}
+	r := newDiagReport(stats, time.Now())
+
+	switch *output {
+	case "-":
+		err = r.write(os.Stdout, cfg.Report.Format)
+	case "":
+		var path string
+		if path, err = r.save(cfg.Report.Dir, cfg.Report.Format); err == nil {
+			fmt.Println(path)
+		}
+	default:
+		var f *os.File
+		if f, err = os.Create(*output); err == nil {
+			err = r.write(f, cfg.Report.Format)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "report: %v\n", err)
+		return 1
+	}
+	return 0
+}
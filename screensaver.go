@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// blankingDisplay is implemented by backends that can switch their
+// screen off for the screensaver
+type blankingDisplay interface {
+	// Blank switches the screen off, or back on when blank is false.
+	// The next Render draws the current frame again.
+	Blank(blank bool) error
+}
+
+// backlightPanel is implemented by LCD panels that can switch their
+// backlight
+type backlightPanel interface {
+	SetBacklight(on bool) error
+}
+
+// wake records a key or button press. It reports true when the press
+// only woke the blanked screen and should not act on the view.
+func (d *Dashboard) wake() bool {
+	d.lastInput = time.Now()
+	if !d.blanked {
+		return false
+	}
+	d.setBlank(false)
+	d.UpdateStats()
+	d.Render()
+	return true
+}
+
+// checkIdle blanks the screen once display.blank_after has passed
+// without a key or button press
+func (d *Dashboard) checkIdle() {
+	after := d.config.Display.BlankAfter
+	if after > 0 && !d.blanked && time.Since(d.lastInput) >= after {
+		d.setBlank(true)
+	}
+}
+
+// setBlank switches the screen, and the backlight at
+// display.backlight_path, off or back on
+func (d *Dashboard) setBlank(blank bool) {
+	d.blanked = blank
+	if b, ok := d.display.(blankingDisplay); ok {
+		if err := b.Blank(blank); err != nil {
+			log.Printf("Display blanking failed: %v", err)
+		}
+	}
+	if path := d.config.Display.BacklightPath; path != "" {
+		if err := setSysfsBacklight(path, !blank); err != nil {
+			log.Printf("Backlight %s: %v", path, err)
+		}
+	}
+}
+
+// setSysfsBacklight switches a backlight class device such as
+// /sys/class/backlight/rpi_backlight through its bl_power file
+func setSysfsBacklight(dir string, on bool) error {
+	value := "4" // FB_BLANK_POWERDOWN
+	if on {
+		value = "0" // FB_BLANK_UNBLANK
+	}
+	return os.WriteFile(filepath.Join(dir, "bl_power"), []byte(value), 0644)
+}
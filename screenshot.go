@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// ScreenshotConfig sets where the screenshot key saves the current view
+type ScreenshotConfig struct {
+	Dir      string  `yaml:"dir"`       // empty uses $XDG_DATA_HOME/raspi-monitor/screenshots
+	PNG      bool    `yaml:"png"`       // also draw the view, in its colors, to a .png
+	FontSize float64 `yaml:"font_size"` // pixels per line of the .png
+}
+
+func (c ScreenshotConfig) validate() error {
+	if c.PNG && c.FontSize <= 0 {
+		return fmt.Errorf("screenshot.font_size must be positive")
+	}
+	return nil
+}
+
+// defaultScreenshotDir returns $XDG_DATA_HOME/raspi-monitor/screenshots,
+// falling back to ~/.local/share when XDG_DATA_HOME is unset
+func defaultScreenshotDir() string {
+	return filepath.Join(filepath.Dir(defaultHistoryPath()), "screenshots")
+}
+
+// plainRow returns a row without its termui style markup
+func plainRow(row string) string {
+	cells := ui.ParseStyles(row, ui.Style{})
+	runes := make([]rune, len(cells))
+	for i, cell := range cells {
+		runes[i] = cell.Rune
+	}
+	return strings.TrimRight(string(runes), " ")
+}
+
+// screenshotText is the frame as plain text: the title, the rows and
+// the status bar
+func screenshotText(frame Frame) string {
+	var b strings.Builder
+	b.WriteString(plainRow(frame.Title) + "\n")
+	b.WriteString(strings.Repeat("-", viewColumnWidth) + "\n")
+	for _, row := range frame.Rows {
+		b.WriteString(plainRow(row) + "\n")
+	}
+	if frame.Status != "" {
+		b.WriteString(strings.Repeat("-", viewColumnWidth) + "\n")
+		b.WriteString(plainRow(frame.Status) + "\n")
+	}
+	return b.String()
+}
+
+// imagePanel is an lcdPanel that only keeps the drawn image
+type imagePanel struct {
+	bounds image.Rectangle
+	img    *image.RGBA
+}
+
+func (p *imagePanel) Bounds() image.Rectangle    { return p.bounds }
+func (p *imagePanel) Draw(img *image.RGBA) error { p.img = img; return nil }
+func (p *imagePanel) Close() error               { return nil }
+
+// screenshotImage draws the frame the way an LCD panel shows it, as tall
+// as its rows need
+func screenshotImage(frame Frame, fontSize float64) (image.Image, error) {
+	panel := &imagePanel{}
+	r, err := newPanelRenderer(panel, fontSize)
+	if err != nil {
+		return nil, err
+	}
+	lines := len(frame.Rows) + 2
+	if frame.Status != "" {
+		lines++
+	}
+	panel.bounds = image.Rect(0, 0, (viewColumnWidth+2)*r.cellW, lines*r.cellH)
+	r.img = image.NewRGBA(panel.bounds)
+	frame.Menu = nil
+	if err := r.Render(frame); err != nil {
+		return nil, err
+	}
+	return panel.img, nil
+}
+
+// saveScreenshot writes the current view to a .txt file, and a .png when
+// screenshot.png is set, named after the view and the time
+func (d *Dashboard) saveScreenshot() {
+	stats, _ := d.snapshot.Get()
+	frame := Frame{Title: d.mainList.Title, Rows: d.mainList.Rows}
+	if d.config.Display.StatusBar {
+		frame.Status = d.statusBar(stats)
+	}
+
+	cfg := d.config.Screenshot
+	dir := cfg.Dir
+	if dir == "" {
+		dir = defaultScreenshotDir()
+	}
+	base := filepath.Join(dir, fmt.Sprintf("raspi-monitor-%s-%s", views[d.currentView].name, time.Now().Format("20060102-150405")))
+
+	err := os.MkdirAll(dir, 0755)
+	if err == nil {
+		err = os.WriteFile(base+".txt", []byte(screenshotText(frame)), 0644)
+	}
+	if err == nil && cfg.PNG {
+		err = writePNG(base+".png", frame, cfg.FontSize)
+	}
+	if err != nil {
+		log.Printf("Failed to save screenshot: %v", err)
+		d.showMessage("Screenshot", "Failed:", fitString(err.Error(), viewColumnWidth-2))
+		return
+	}
+	log.Printf("Saved screenshot %s", base+".txt")
+	d.showMessage("Screenshot", "Saved to", fitString(filepath.Base(base)+".txt", viewColumnWidth-2))
+}
+
+func writePNG(path string, frame Frame, fontSize float64) error {
+	img, err := screenshotImage(frame, fontSize)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// notifySocket is NOTIFY_SOCKET, taken out of the environment so the
+// commands the monitor runs do not notify systemd as the service; a
+// failing systemctl or timedatectl would report its ERRNO= for it
+var notifySocket = takeNotifySocket()
+
+func takeNotifySocket() string {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	os.Unsetenv("NOTIFY_SOCKET")
+	return socket
+}
+
+// sdNotify sends a state like READY=1 to systemd when it started the
+// monitor as a Type=notify service. Without NOTIFY_SOCKET it does
+// nothing.
+func sdNotify(state string) error {
+	socket := notifySocket
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:] // abstract socket
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// sdReloading tells systemd a reload started. MONOTONIC_USEC is the
+// CLOCK_MONOTONIC time that Type=notify-reload units must send with it;
+// the READY=1 that ends the reload must come after that time.
+func sdReloading() error {
+	const clockMonotonic = 1 // CLOCK_MONOTONIC, missing from syscall
+	var ts syscall.Timespec
+	if _, _, errno := syscall.Syscall(syscall.SYS_CLOCK_GETTIME, clockMonotonic, uintptr(unsafe.Pointer(&ts)), 0); errno != 0 {
+		return sdNotify("RELOADING=1")
+	}
+	usec := ts.Nano() / 1000
+	return sdNotify(fmt.Sprintf("RELOADING=1\nMONOTONIC_USEC=%d", usec))
+}
+
+// watchdogInterval returns how often to ping the systemd watchdog, half
+// of WatchdogSec, or 0 when the unit has no watchdog
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0 // meant for another process
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
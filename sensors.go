@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// SensorReading is collected by the collector package
+type SensorReading = collector.SensorReading
+
+// formatSensor prints a reading in the unit of its kind
+func formatSensor(r SensorReading) string {
+	switch r.Kind {
+	case collector.SensorTemp:
+		return formatTemperature(r.Value)
+	case collector.SensorFan:
+		return fmt.Sprintf("%.0f RPM", r.Value)
+	case collector.SensorVoltage:
+		return fmt.Sprintf("%.3f V", r.Value)
+	case collector.SensorCurrent:
+		return fmt.Sprintf("%.2f A", r.Value)
+	case collector.SensorPower:
+		return fmt.Sprintf("%.2f W", r.Value)
+	}
+	return fmt.Sprintf("%g", r.Value)
+}
+
+// sensorColor warns about temperatures close to the limit of the chip,
+// or to the usual Pi limits when it does not report one
+func sensorColor(r SensorReading) string {
+	if r.Kind != collector.SensorTemp {
+		return "white"
+	}
+	crit := r.Crit
+	if crit <= 0 {
+		crit = 85
+	}
+	switch {
+	case r.Value >= crit:
+		return "red"
+	case r.Value >= crit-10:
+		return "yellow"
+	}
+	return "green"
+}
+
+func (d *Dashboard) updateSensorsView(stats SystemStats) {
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+	if len(stats.Sensors) == 0 && len(stats.Environment) == 0 {
+		d.mainList.Rows = []string{"", "No sensors found", "", "Neither /sys/class/thermal", "nor /sys/class/hwmon has", "readable sensors."}
+		return
+	}
+
+	var rows []string
+	chip := ""
+	for _, r := range stats.Sensors {
+		if r.Chip != chip {
+			if chip != "" {
+				rows = append(rows, "")
+			}
+			chip = r.Chip
+			rows = append(rows, fmt.Sprintf("[--%s--](fg:cyan)", truncateString(chip, 22)))
+		}
+		rows = append(rows, fmt.Sprintf("%-16s [%11s](fg:%s)", truncateString(r.Label, 16), formatSensor(r), sensorColor(r)))
+	}
+	if len(stats.Environment) > 0 {
+		if len(rows) > 0 {
+			rows = append(rows, "")
+		}
+		rows = append(rows, "[--Environment--](fg:green)")
+		for _, r := range stats.Environment {
+			rows = append(rows, fmt.Sprintf("[%s](fg:cyan)", truncateString(r.Name, 27)), " "+formatEnv(r))
+		}
+	}
+	d.mainList.Rows = rows
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// systemctlTimeout bounds one systemctl run
+const systemctlTimeout = 10 * time.Second
+
+// ServiceInfo is one systemd service unit
+type ServiceInfo struct {
+	Unit        string `json:"unit"`
+	Load        string `json:"load"`   // loaded, masked, ...
+	Active      string `json:"active"` // active, inactive, failed, activating, ...
+	Sub         string `json:"sub"`    // running, exited, dead, ...
+	Description string `json:"description"`
+}
+
+// Name returns the unit name without the .service suffix
+func (s ServiceInfo) Name() string {
+	return strings.TrimSuffix(s.Unit, ".service")
+}
+
+// serviceRank orders failed services first, then active ones
+func serviceRank(s ServiceInfo) int {
+	switch s.Active {
+	case "failed":
+		return 0
+	case "active", "activating", "reloading", "deactivating":
+		return 1
+	}
+	return 2
+}
+
+func systemctl(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), systemctlTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, "systemctl", args...).CombinedOutput()
+}
+
+// commandError returns the first line a failed command printed, or err
+// when it printed nothing
+func commandError(out []byte, err error) string {
+	msg := strings.TrimSpace(string(bytes.SplitN(out, []byte("\n"), 2)[0]))
+	if msg == "" {
+		msg = err.Error()
+	}
+	return msg
+}
+
+// getServices lists the loaded service units through systemctl. Units
+// that are only referenced but not installed are left out.
+func getServices() ([]ServiceInfo, error) {
+	out, err := systemctl("list-units", "--type=service", "--all", "--no-legend", "--plain", "--no-pager")
+	if err != nil {
+		return nil, fmt.Errorf("systemctl: %w", err)
+	}
+
+	var services []ServiceInfo
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[1] == "not-found" {
+			continue
+		}
+		services = append(services, ServiceInfo{
+			Unit:        fields[0],
+			Load:        fields[1],
+			Active:      fields[2],
+			Sub:         fields[3],
+			Description: strings.Join(fields[4:], " "),
+		})
+	}
+
+	sort.SliceStable(services, func(i, j int) bool {
+		a, b := services[i], services[j]
+		if serviceRank(a) != serviceRank(b) {
+			return serviceRank(a) < serviceRank(b)
+		}
+		return a.Unit < b.Unit
+	})
+	return services, nil
+}
+
+func serviceColor(s ServiceInfo) string {
+	switch serviceRank(s) {
+	case 0:
+		return "red"
+	case 1:
+		return "green"
+	}
+	return "white"
+}
+
+func (d *Dashboard) updateServicesView(stats SystemStats) {
+	if !d.reuseLists {
+		services, err := getServices()
+		if err != nil {
+			d.serviceList = nil
+			d.mainList.Title = d.viewTitle("")
+			d.mainList.Rows = []string{"", "systemd not available", "", truncateString(err.Error(), 27)}
+			return
+		}
+		d.serviceList = services
+	}
+	services := d.serviceList
+	total := len(services)
+	if total == 0 {
+		d.mainList.Title = d.viewTitle("0/0")
+		d.mainList.Rows = []string{"", "No services"}
+		return
+	}
+	if d.selectedService >= total {
+		d.selectedService = total - 1
+	}
+
+	failed := 0
+	for _, s := range services {
+		if s.Active == "failed" {
+			failed++
+		}
+	}
+	d.mainList.Title = d.viewTitle(fmt.Sprintf("%d/%d F:%d", d.selectedService+1, total, failed))
+
+	rows := []string{"[Service            State](fg:cyan)"}
+
+	// Leave room for the detail rows of the selected unit
+	visibleHeight := 21
+	startIdx := d.selectedService - visibleHeight/2
+	if startIdx > total-visibleHeight {
+		startIdx = total - visibleHeight
+	}
+	if startIdx < 0 {
+		startIdx = 0
+	}
+	endIdx := startIdx + visibleHeight
+	if endIdx > total {
+		endIdx = total
+	}
+
+	for i := startIdx; i < endIdx; i++ {
+		s := services[i]
+		line := fmt.Sprintf("%-18s %-8s", truncateString(s.Name(), 18), truncateString(s.Sub, 8))
+		if i == d.selectedService {
+			rows = append(rows, fmt.Sprintf("[%s](bg:white,fg:black)", line))
+		} else {
+			rows = append(rows, fmt.Sprintf("[%s](fg:%s)", line, serviceColor(s)))
+		}
+	}
+
+	s := services[d.selectedService]
+	rows = append(rows,
+		"",
+		truncateString(s.Description, 27),
+		fmt.Sprintf("[%s](fg:%s) %s, %s", s.Active, serviceColor(s), s.Sub, s.Load),
+		"[Enter/A: actions](fg:yellow)",
+	)
+	d.mainList.Rows = rows
+}
+
+// confirmServiceAction asks what to do with the selected service
+func (d *Dashboard) confirmServiceAction() {
+	if d.selectedService < 0 || d.selectedService >= len(d.serviceList) {
+		return
+	}
+	s := d.serviceList[d.selectedService]
+
+	options := []menuOption{{label: "Cancel"}}
+	for _, action := range []string{"restart", "stop", "start"} {
+		action := action
+		if action == "start" && serviceRank(s) == 1 {
+			continue // already running
+		}
+		if action == "stop" && serviceRank(s) != 1 {
+			continue
+		}
+		options = append(options, menuOption{
+			label:  strings.ToUpper(action[:1]) + action[1:],
+			action: func(d *Dashboard) { d.runServiceAction(s, action) },
+		})
+	}
+
+	d.openMenu(&Menu{
+		title: "Service",
+		message: []string{
+			truncateString(s.Name(), 24),
+			fmt.Sprintf("State: %s (%s)", s.Active, s.Sub),
+		},
+		options: options,
+	})
+}
+
+// runServiceAction queues a start, stop or restart job for s. The job
+// runs in the background so a slow unit does not freeze the display.
+func (d *Dashboard) runServiceAction(s ServiceInfo, action string) {
+	out, err := systemctl("--no-block", action, s.Unit)
+	if err != nil {
+		msg := commandError(out, err)
+		log.Printf("Failed to %s %s: %s", action, s.Unit, msg)
+		// Keep the reason, e.g. "Access denied", on the small screen
+		if i := strings.LastIndex(msg, ": "); i >= 0 {
+			msg = msg[i+2:]
+		}
+		d.showMessage("Error", truncateString(msg, 24))
+		return
+	}
+
+	log.Printf("Service %s: %s", s.Unit, action)
+	d.UpdateStats()
+	d.showMessage("Done", fmt.Sprintf("%s queued", action), truncateString(s.Name(), 24))
+}
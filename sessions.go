@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+const (
+	// maxSessions is how many sessions the System view lists
+	maxSessions = 4
+	// failedLoginWindow is how far back failed logins are counted
+	failedLoginWindow = time.Hour
+	// failedLoginRefresh is how often the journal is searched again
+	failedLoginRefresh = time.Minute
+)
+
+// failedLoginMarkers are the sshd and login messages of a failed attempt
+var failedLoginMarkers = []string{
+	"Failed password",
+	"Invalid user",
+	"authentication failure",
+	"FAILED LOGIN",
+}
+
+// UserSession is a login from utmp
+type UserSession struct {
+	User     string
+	Terminal string
+	Host     string // remote address of SSH sessions, empty on the console
+	Idle     time.Duration
+}
+
+// getSessions lists the logins recorded in utmp. Idle time is the age
+// of the last access to the terminal, as w computes it.
+func getSessions() []UserSession {
+	users, err := host.Users()
+	if err != nil {
+		return nil
+	}
+	sessions := make([]UserSession, 0, len(users))
+	for _, u := range users {
+		s := UserSession{User: u.User, Terminal: u.Terminal, Host: u.Host}
+		var st syscall.Stat_t
+		if syscall.Stat("/dev/"+u.Terminal, &st) == nil {
+			s.Idle = time.Since(time.Unix(st.Atim.Unix()))
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// countFailedLogins counts failed sshd and console logins in the journal
+// within failedLoginWindow. It reports false when the journal cannot be
+// read, which needs root or the systemd-journal group.
+func countFailedLogins() (int, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), journalctlTimeout)
+	defer cancel()
+
+	since := fmt.Sprintf("-%ds", int(failedLoginWindow.Seconds()))
+	out, err := exec.CommandContext(ctx, "journalctl", "--since", since, "-q", "-o", "cat",
+		"-t", "sshd", "-t", "sshd-session", "-t", "login").Output()
+	if err != nil {
+		return 0, false
+	}
+	count := 0
+	for _, line := range strings.Split(string(out), "\n") {
+		for _, marker := range failedLoginMarkers {
+			if strings.Contains(line, marker) {
+				count++
+				break
+			}
+		}
+	}
+	// journalctl prints nothing for journals it may not read
+	if count == 0 && len(out) == 0 && os.Geteuid() != 0 {
+		return 0, false
+	}
+	return count, true
+}
+
+// LoginStatus is who is logged in and how many logins failed lately
+type LoginStatus struct {
+	Sessions          []UserSession
+	FailedLogins      int  // failed logins within failedLoginWindow
+	FailedLoginsKnown bool // false when the journal cannot be read
+	FailedLoginsAt    time.Time
+}
+
+// LoginMonitor keeps the logins for the System view. runCollector
+// refreshes it with every sample, so reading utmp and the journal does
+// not hold up the UI goroutine.
+type LoginMonitor struct {
+	mu     sync.Mutex
+	status LoginStatus
+}
+
+// Status returns the result of the latest refresh
+func (m *LoginMonitor) Status() LoginStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Refresh reads the sessions, and searches the journal for failed logins
+// once per failedLoginRefresh
+func (m *LoginMonitor) Refresh() {
+	sessions := getSessions()
+	m.mu.Lock()
+	status := m.status
+	m.mu.Unlock()
+
+	status.Sessions = sessions
+	if status.FailedLoginsAt.IsZero() || time.Since(status.FailedLoginsAt) >= failedLoginRefresh {
+		status.FailedLogins, status.FailedLoginsKnown = countFailedLogins()
+		status.FailedLoginsAt = time.Now()
+	}
+
+	m.mu.Lock()
+	m.status = status
+	m.mu.Unlock()
+}
+
+// sessionRows shows who is logged in and the failed logins of the last
+// hour on the System view
+func (d *Dashboard) sessionRows() []string {
+	status := d.logins.Status()
+	rows := []string{"", fmt.Sprintf("[--Users (%d)--](fg:magenta)", len(status.Sessions))}
+	for i, s := range status.Sessions {
+		if i == maxSessions {
+			rows = append(rows, fmt.Sprintf("... %d more", len(status.Sessions)-maxSessions))
+			break
+		}
+		from := s.Host
+		if from == "" {
+			from = s.Terminal
+		}
+		line := fmt.Sprintf("%-7s %-15s %4s", truncateString(s.User, 7), truncateString(from, 15), formatAge(s.Idle))
+		if s.Host != "" {
+			line = fmt.Sprintf("[%s](fg:cyan)", line) // remote session
+		}
+		rows = append(rows, line)
+	}
+
+	switch {
+	case status.FailedLoginsAt.IsZero():
+		rows = append(rows, "Failed logins: checking...")
+	case !status.FailedLoginsKnown:
+		rows = append(rows, "Failed logins: n/a (journal)")
+	case status.FailedLogins > 0:
+		rows = append(rows, fmt.Sprintf("Failed logins 1h: [%d](fg:red)", status.FailedLogins))
+	default:
+		rows = append(rows, "Failed logins 1h: [0](fg:green)")
+	}
+	return rows
+}
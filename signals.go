@@ -0,0 +1,99 @@
+package main
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"syscall"
+)
+
+// handleSignal stops the monitor on SIGTERM and SIGINT, so the deferred
+// cleanup restores the terminal and releases the GPIO lines, and
+// reloads the config on SIGHUP. It reports false when the monitor
+// should exit.
+func (d *Dashboard) handleSignal(sig os.Signal) bool {
+	if sig != syscall.SIGHUP {
+		log.Printf("Stopping on %v", sig)
+		sdNotify("STOPPING=1")
+		return false
+	}
+	d.reloadConfig()
+	return true
+}
+
+// reloadConfig rereads the config file and the command-line flags over
+// it. The alerts, key and button bindings, warning thresholds and the
+// report and screenshot settings change in place, keeping the history,
+// alert states and connections. Any other change needs the display,
+// GPIO lines or listeners set up again, so the monitor restarts in place
+// and keeps its PID. A file that does not load leaves the running config
+// alone.
+func (d *Dashboard) reloadConfig() {
+	path := d.opts.configPath
+	cfg, err := loadConfig(path)
+	if err == nil {
+		err = d.opts.apply(cfg)
+	}
+	if err != nil {
+		log.Printf("Config reload failed: %v", err)
+		d.showMessage("Config", "Reload failed", fitString(err.Error(), 24))
+		return
+	}
+	cfg.applyRunMode()
+
+	sdReloading()
+	if changed := d.config.restartNeeded(cfg); len(changed) > 0 {
+		log.Printf("Reloading the config from %s: restarting for %s", path, strings.Join(changed, ", "))
+		d.restart = true // the restarted monitor sends READY=1
+		return
+	}
+	d.applyConfig(cfg)
+	log.Printf("Reloaded the config from %s", path)
+	sdNotify("READY=1")
+	d.redraw()
+}
+
+// restartNeeded returns the sections of next, by their name in the
+// file, that differ from c in settings a reload cannot apply in place
+func (c *Config) restartNeeded(next *Config) []string {
+	cur, nxt := *c, *next
+	for _, cfg := range []*Config{&cur, &nxt} {
+		cfg.TempWarn, cfg.SDWriteWarn = 0, 0
+		cfg.Keys = KeysConfig{}
+		cfg.GPIO.Actions = nil
+		cfg.Report, cfg.Screenshot = ReportConfig{}, ScreenshotConfig{}
+		// Outputs hold GPIO lines claimed at start
+		cfg.Alerts = AlertsConfig{Enabled: cfg.Alerts.Enabled, Outputs: cfg.Alerts.Outputs}
+	}
+
+	var changed []string
+	a, b := reflect.ValueOf(cur), reflect.ValueOf(nxt)
+	for i := 0; i < a.NumField(); i++ {
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("yaml"), ",")
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// applyConfig switches to cfg, which differs from the running config
+// only in what restartNeeded lets through
+func (d *Dashboard) applyConfig(cfg *Config) {
+	if cfg.Alerts.Enabled {
+		alerts := newAlertEngine(cfg.Alerts)
+		alerts.carryOver(d.alerts)
+		d.alerts = alerts
+	}
+	d.keys, d.keyPrefix = cfg.Keys.bindings(), ""
+	d.tempTracker.threshold = cfg.TempWarn
+
+	d.config.TempWarn = cfg.TempWarn
+	d.config.SDWriteWarn = cfg.SDWriteWarn
+	d.config.Keys = cfg.Keys
+	d.config.GPIO.Actions = cfg.GPIO.Actions
+	d.config.Report = cfg.Report
+	d.config.Screenshot = cfg.Screenshot
+	d.config.Alerts = cfg.Alerts
+}
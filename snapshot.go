@@ -0,0 +1,30 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// StatsSnapshot holds the most recent SystemStats for readers outside
+// the UI goroutine, such as the HTTP exporters.
+type StatsSnapshot struct {
+	mu    sync.RWMutex
+	stats SystemStats
+	at    time.Time
+}
+
+// Set stores stats as the latest sample
+func (s *StatsSnapshot) Set(stats SystemStats) {
+	s.mu.Lock()
+	s.stats = stats
+	s.at = time.Now()
+	s.mu.Unlock()
+}
+
+// Get returns the latest sample and when it was taken. The zero time
+// means no sample has been collected yet.
+func (s *StatsSnapshot) Get() (SystemStats, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stats, s.at
+}
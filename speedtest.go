@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// iperf3Port is the default port of an iperf3 server
+const iperf3Port = 5201
+
+// SpeedTestConfig selects where the speed test measures against. An
+// iperf3 server is used when set, the HTTP endpoints otherwise.
+type SpeedTestConfig struct {
+	IPerf3      string        `yaml:"iperf3"`       // host or host:port of an iperf3 server
+	DownloadURL string        `yaml:"download_url"` // fetched for the download test
+	UploadURL   string        `yaml:"upload_url"`   // receives a POST for the upload test
+	Duration    time.Duration `yaml:"duration"`     // per direction
+}
+
+func (c SpeedTestConfig) validate() error {
+	if c.Duration <= 0 {
+		return fmt.Errorf("speedtest.duration must be positive")
+	}
+	if c.IPerf3 != "" {
+		return nil
+	}
+	for key, raw := range map[string]string{"download_url": c.DownloadURL, "upload_url": c.UploadURL} {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("speedtest.%s must be an http(s) URL, got %q", key, raw)
+		}
+	}
+	return nil
+}
+
+// SpeedTestResult is the outcome of the last speed test
+type SpeedTestResult struct {
+	Running  bool
+	Down, Up float64 // bits per second
+	Err      error
+	At       time.Time // when the test finished
+}
+
+// SpeedTest runs one test at a time in the background
+type SpeedTest struct {
+	cfg SpeedTestConfig
+
+	mu     sync.Mutex
+	result SpeedTestResult
+}
+
+func newSpeedTest(cfg SpeedTestConfig) *SpeedTest {
+	return &SpeedTest{cfg: cfg}
+}
+
+// Result returns the state of the running or last test
+func (s *SpeedTest) Result() SpeedTestResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.result
+}
+
+// Start measures download and then upload throughput in a goroutine.
+// It does nothing while a test is running.
+func (s *SpeedTest) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.result.Running {
+		return
+	}
+	s.result = SpeedTestResult{Running: true}
+
+	go func() {
+		var result SpeedTestResult
+		if s.cfg.IPerf3 != "" {
+			result.Down, result.Up, result.Err = s.runIPerf3()
+		} else {
+			result.Down, result.Up, result.Err = s.runHTTP()
+		}
+		result.At = time.Now()
+		if result.Err != nil {
+			log.Printf("Speed test failed: %v", result.Err)
+		} else {
+			log.Printf("Speed test: down %s, up %s", formatBitrate(result.Down), formatBitrate(result.Up))
+		}
+
+		s.mu.Lock()
+		s.result = result
+		s.mu.Unlock()
+	}()
+}
+
+// runIPerf3 runs the iperf3 client twice, the reverse run measuring
+// the download
+func (s *SpeedTest) runIPerf3() (down, up float64, err error) {
+	host, port := s.cfg.IPerf3, iperf3Port
+	if h, p, err := net.SplitHostPort(s.cfg.IPerf3); err == nil {
+		host = h
+		port, _ = strconv.Atoi(p)
+	}
+	if down, err = s.iperf3(host, port, true); err != nil {
+		return 0, 0, err
+	}
+	if up, err = s.iperf3(host, port, false); err != nil {
+		return 0, 0, err
+	}
+	return down, up, nil
+}
+
+// iperf3 returns the throughput measured by the receiver
+func (s *SpeedTest) iperf3(host string, port int, reverse bool) (float64, error) {
+	seconds := int(s.cfg.Duration.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	args := []string{"-c", host, "-p", strconv.Itoa(port), "-t", strconv.Itoa(seconds), "-J"}
+	if reverse {
+		args = append(args, "-R")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Duration+30*time.Second)
+	defer cancel()
+	// iperf3 exits non-zero on errors but still prints its JSON report
+	out, runErr := exec.CommandContext(ctx, "iperf3", args...).Output()
+
+	var report struct {
+		End struct {
+			SumReceived struct {
+				BitsPerSecond float64 `json:"bits_per_second"`
+			} `json:"sum_received"`
+		} `json:"end"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		if runErr != nil {
+			return 0, fmt.Errorf("iperf3: %w", runErr)
+		}
+		return 0, fmt.Errorf("iperf3: %w", err)
+	}
+	if report.Error != "" {
+		return 0, fmt.Errorf("iperf3: %s", report.Error)
+	}
+	return report.End.SumReceived.BitsPerSecond, nil
+}
+
+// runHTTP downloads from and uploads to the configured URLs for the
+// test duration each
+func (s *SpeedTest) runHTTP() (down, up float64, err error) {
+	if down, err = s.httpDownload(); err != nil {
+		return 0, 0, err
+	}
+	if up, err = s.httpUpload(); err != nil {
+		return 0, 0, err
+	}
+	return down, up, nil
+}
+
+// httpDownload reads the download URL until it ends or the duration is
+// up and returns the bits per second received
+func (s *SpeedTest) httpDownload() (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Duration)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.DownloadURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("download: %s", resp.Status)
+	}
+
+	// Running out of time ends the copy with an error, the bytes read so
+	// far still count
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil && ctx.Err() == nil {
+		return 0, fmt.Errorf("download: %w", err)
+	}
+	return bitsPerSecond(n, time.Since(start)), nil
+}
+
+// httpUpload posts random data to the upload URL for the duration and
+// returns the bits per second sent
+func (s *SpeedTest) httpUpload() (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Duration)
+	defer cancel()
+
+	chunk := make([]byte, 64*1024)
+	rand.Read(chunk)
+	body := &countingReader{ctx: ctx, chunk: chunk}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.UploadURL, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	} else if ctx.Err() == nil {
+		return 0, fmt.Errorf("upload: %w", err)
+	}
+	return bitsPerSecond(body.Count(), time.Since(start)), nil
+}
+
+// countingReader repeats chunk until ctx ends and counts what was read
+type countingReader struct {
+	ctx   context.Context
+	chunk []byte
+
+	mu sync.Mutex
+	n  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunk)
+	r.mu.Lock()
+	r.n += int64(n)
+	r.mu.Unlock()
+	return n, nil
+}
+
+// Count returns the bytes read so far
+func (r *countingReader) Count() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.n
+}
+
+func bitsPerSecond(n int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(n) * 8 / elapsed.Seconds()
+}
+
+// formatBitrate prints a bit rate in Mbit/s, or kbit/s when slow
+func formatBitrate(bps float64) string {
+	if bps < 1e6 {
+		return fmt.Sprintf("%.0f kbit/s", bps/1e3)
+	}
+	return fmt.Sprintf("%.1f Mbit/s", bps/1e6)
+}
+
+// startSpeedTest runs the speed test from the Network view
+func (d *Dashboard) startSpeedTest() {
+	d.speedTest.Start()
+	d.UpdateStats()
+	d.Render()
+}
+
+// speedTestRows shows the state of the speed test on the Network view
+func speedTestRows(s *SpeedTest) []string {
+	result := s.Result()
+	switch {
+	case result.Running:
+		return []string{"Speed test: [running...](fg:yellow)"}
+	case result.Err != nil:
+		return []string{"Speed test: [failed](fg:red)", truncateString(result.Err.Error(), 27)}
+	case !result.At.IsZero():
+		return []string{fmt.Sprintf("Test ↓%.1f ↑%.1f Mbit/s", result.Down/1e6, result.Up/1e6)}
+	}
+	return []string{"Speed test: [press t](fg:yellow)"}
+}
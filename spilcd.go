@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/warthog618/go-gpiocdev"
+)
+
+const (
+	// spiIocWrMaxSpeedHz is SPI_IOC_WR_MAX_SPEED_HZ from linux/spi/spidev.h
+	spiIocWrMaxSpeedHz = 0x40046b04
+	// spiChunk stays within the default spidev bufsiz of 4096 bytes
+	spiChunk = 4096
+)
+
+// Commands shared by the ST7789 and ILI9341 controllers
+const (
+	lcdSWRESET = 0x01
+	lcdSLPOUT  = 0x11
+	lcdNORON   = 0x13
+	lcdINVON   = 0x21
+	lcdDISPOFF = 0x28
+	lcdDISPON  = 0x29
+	lcdCASET   = 0x2a
+	lcdRASET   = 0x2b
+	lcdRAMWR   = 0x2c
+	lcdMADCTL  = 0x36
+	lcdCOLMOD  = 0x3a
+)
+
+// lcdMADCTL values per rotation. The ILI9341 panels are wired BGR.
+var (
+	st7789Rotation  = map[int]byte{0: 0x00, 90: 0x60, 180: 0xc0, 270: 0xa0}
+	ili9341Rotation = map[int]byte{0: 0x48, 90: 0x28, 180: 0x88, 270: 0xe8}
+)
+
+// spiPanel drives an ST7789 or ILI9341 controller through spidev with a
+// GPIO line for data/command, as on the Waveshare 1.3" LCD HAT
+type spiPanel struct {
+	f         *os.File
+	dc        *gpiocdev.Line
+	reset     *gpiocdev.Line
+	backlight *gpiocdev.Line
+	width     int
+	height    int
+	xOffset   int // RAM column of the first visible pixel
+	yOffset   int // RAM row of the first visible pixel
+	buf       []byte
+}
+
+func openSPIPanel(cfg DisplayConfig) (*spiPanel, error) {
+	p := &spiPanel{width: cfg.Width, height: cfg.Height}
+	if cfg.Backend == displayILI9341 && cfg.Width == 240 && cfg.Height == 240 {
+		p.width, p.height = 240, 320 // the 240x240 default is for the ST7789
+		if cfg.Rotation == 90 || cfg.Rotation == 270 {
+			p.width, p.height = 320, 240
+		}
+	}
+
+	device := cfg.Device
+	if device == "" {
+		device = "/dev/spidev0.0"
+	}
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open %s (dtparam=spi=on?): %w", device, err)
+	}
+	p.f = f
+
+	speed := uint32(cfg.SPISpeed)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), spiIocWrMaxSpeedHz, uintptr(unsafe.Pointer(&speed))); errno != 0 {
+		p.Close()
+		return nil, fmt.Errorf("set SPI speed: %w", errno)
+	}
+
+	if p.dc, err = requestOutputLine(cfg.DCPin, 0); err != nil {
+		p.Close()
+		return nil, err
+	}
+	if cfg.ResetPin >= 0 {
+		if p.reset, err = requestOutputLine(cfg.ResetPin, 0); err != nil {
+			p.Close()
+			return nil, err
+		}
+	}
+	if cfg.BacklightPin >= 0 {
+		if p.backlight, err = requestOutputLine(cfg.BacklightPin, 0); err != nil {
+			p.Close()
+			return nil, err
+		}
+	}
+
+	if err := p.init(cfg); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("initialize %s: %w", cfg.Backend, err)
+	}
+	p.buf = make([]byte, p.width*p.height*2)
+	return p, nil
+}
+
+// init resets the controller and sets 16-bit color and the rotation
+func (p *spiPanel) init(cfg DisplayConfig) error {
+	if p.reset != nil {
+		p.reset.SetValue(1)
+		time.Sleep(10 * time.Millisecond)
+		p.reset.SetValue(0)
+		time.Sleep(10 * time.Millisecond)
+		p.reset.SetValue(1)
+		time.Sleep(120 * time.Millisecond)
+	}
+
+	if err := p.command(lcdSWRESET); err != nil {
+		return err
+	}
+	time.Sleep(150 * time.Millisecond)
+	p.command(lcdSLPOUT)
+	time.Sleep(120 * time.Millisecond)
+	p.command(lcdCOLMOD, 0x55) // RGB565
+
+	if cfg.Backend == displayST7789 {
+		p.command(lcdMADCTL, st7789Rotation[cfg.Rotation])
+		p.command(lcdINVON) // the IPS panels show inverted colors otherwise
+		// The controller has 240x320 of RAM; a 240x240 panel sits at the
+		// start, so the flipped rotations need an offset
+		switch {
+		case cfg.Rotation == 180 && p.height < 320:
+			p.yOffset = 320 - p.height
+		case cfg.Rotation == 270 && p.width < 320:
+			p.xOffset = 320 - p.width
+		}
+	} else {
+		p.command(lcdMADCTL, ili9341Rotation[cfg.Rotation])
+	}
+	p.command(lcdNORON)
+	if err := p.command(lcdDISPON); err != nil {
+		return err
+	}
+
+	if p.backlight != nil {
+		p.backlight.SetValue(1)
+	}
+	return nil
+}
+
+// command sends cmd followed by its parameter bytes
+func (p *spiPanel) command(cmd byte, data ...byte) error {
+	p.dc.SetValue(0)
+	if _, err := p.f.Write([]byte{cmd}); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return p.write(data)
+}
+
+// write sends pixel or parameter data in spidev sized chunks
+func (p *spiPanel) write(data []byte) error {
+	p.dc.SetValue(1)
+	for len(data) > 0 {
+		n := len(data)
+		if n > spiChunk {
+			n = spiChunk
+		}
+		if _, err := p.f.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func (p *spiPanel) Bounds() image.Rectangle {
+	return image.Rect(0, 0, p.width, p.height)
+}
+
+// Draw sends the whole frame as big-endian RGB565
+func (p *spiPanel) Draw(img *image.RGBA) error {
+	i := 0
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			c := img.RGBAAt(x, y)
+			v := rgb565(c.R, c.G, c.B)
+			p.buf[i] = byte(v >> 8)
+			p.buf[i+1] = byte(v)
+			i += 2
+		}
+	}
+
+	x0, x1 := p.xOffset, p.xOffset+p.width-1
+	y0, y1 := p.yOffset, p.yOffset+p.height-1
+	p.command(lcdCASET, byte(x0>>8), byte(x0), byte(x1>>8), byte(x1))
+	p.command(lcdRASET, byte(y0>>8), byte(y0), byte(y1>>8), byte(y1))
+	if err := p.command(lcdRAMWR); err != nil {
+		return err
+	}
+	return p.write(p.buf)
+}
+
+// SetBacklight switches the backlight line, when it is wired
+func (p *spiPanel) SetBacklight(on bool) error {
+	if p.backlight == nil {
+		return nil
+	}
+	value := 0
+	if on {
+		value = 1
+	}
+	return p.backlight.SetValue(value)
+}
+
+// Close switches the panel and its backlight off and releases the lines
+func (p *spiPanel) Close() error {
+	if p.dc != nil {
+		p.command(lcdDISPOFF)
+	}
+	for _, line := range []*gpiocdev.Line{p.backlight, p.reset, p.dc} {
+		if line != nil {
+			line.SetValue(0)
+			line.Close()
+		}
+	}
+	return p.f.Close()
+}
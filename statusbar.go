@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"time"
+	"unicode/utf8"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// statusBar is the line under every view: the time, host name, IP
+// address and CPU temperature, so the screen doubles as a desk clock
+func (d *Dashboard) statusBar(stats SystemStats) string {
+	clock := time.Now().Format("15:04")
+	temp := formatTemperature(stats.Temperature)
+	ip := stats.IPAddress
+	if ip == "" {
+		ip = "no IP"
+	}
+
+	// The host name gets what is left of the 30 columns
+	host := ""
+	if width := viewColumnWidth - len(clock) - len(ip) - utf8.RuneCountInString(temp) - 3; width >= 3 {
+		host = truncateString(d.statsHost(), width) + " "
+	}
+	return fmt.Sprintf("[%s](fg:white) [%s](fg:cyan)%s [%s](fg:%s)", clock, host, ip, temp, d.alertColor("temp", "green"))
+}
+
+// statusLine draws the status bar on one terminal line, without the
+// border a termui Block would take
+type statusLine struct {
+	ui.Block
+	text string
+}
+
+func (s *statusLine) Draw(buf *ui.Buffer) {
+	for i, cell := range ui.ParseStyles(s.text, ui.NewStyle(currentTheme.term(currentTheme.Text))) {
+		p := image.Pt(s.Min.X+i, s.Min.Y)
+		if p.X >= s.Max.X {
+			break
+		}
+		buf.SetCell(cell, p)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// DiskIOStats is collected by the collector package
+type DiskIOStats = collector.DiskIOStats
+
+// diskRate is the throughput of one device per second
+type diskRate struct {
+	readBytes  float64
+	writeBytes float64
+	readOps    float64
+	writeOps   float64
+}
+
+// diskIOHistory keeps the read and write throughput of one device
+type diskIOHistory struct {
+	read  *History
+	write *History
+}
+
+// recordDiskIO updates the per-device rates and throughput history
+func (d *Dashboard) recordDiskIO(devices []DiskIOStats, seconds float64) {
+	rates := make(map[string]diskRate, len(devices))
+	prev := make(map[string]DiskIOStats, len(devices))
+	for _, dev := range devices {
+		p, ok := d.prevDiskIO[dev.Name]
+		if ok && seconds > 0 && dev.ReadBytes >= p.ReadBytes && dev.WriteBytes >= p.WriteBytes {
+			rates[dev.Name] = diskRate{
+				readBytes:  float64(dev.ReadBytes-p.ReadBytes) / seconds,
+				writeBytes: float64(dev.WriteBytes-p.WriteBytes) / seconds,
+				readOps:    float64(dev.ReadCount-p.ReadCount) / seconds,
+				writeOps:   float64(dev.WriteCount-p.WriteCount) / seconds,
+			}
+		}
+		prev[dev.Name] = dev
+
+		history, ok := d.diskHistory[dev.Name]
+		if !ok {
+			history = diskIOHistory{read: NewHistory(historySize), write: NewHistory(historySize)}
+			d.diskHistory[dev.Name] = history
+		}
+		history.read.Add(rates[dev.Name].readBytes)
+		history.write.Add(rates[dev.Name].writeBytes)
+	}
+	d.diskRates = rates
+	d.prevDiskIO = prev
+}
+
+func (d *Dashboard) updateStorageView(stats SystemStats) {
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+	if len(stats.DiskIO) == 0 {
+		d.mainList.Rows = []string{"", "No block devices found"}
+		return
+	}
+
+	var rows []string
+	for _, dev := range stats.DiskIO {
+		rate := d.diskRates[dev.Name]
+		history := d.diskHistory[dev.Name]
+		rows = append(rows,
+			fmt.Sprintf("[--%s--](fg:cyan)", dev.Name),
+			fmt.Sprintf("Rd: %8s/s %6.0f IOPS", formatBytes(uint64(rate.readBytes)), rate.readOps),
+			fmt.Sprintf("Wr: %8s/s %6.0f IOPS", formatBytes(uint64(rate.writeBytes)), rate.writeOps),
+			fmt.Sprintf("Rd %s", getSparkline(history.read.Last(sparkWidth), 0, "green")),
+			fmt.Sprintf("Wr %s", getSparkline(history.write.Last(sparkWidth), 0, "red")),
+			fmt.Sprintf("Total R/W: %s/%s", formatBytes(dev.ReadBytes), formatBytes(dev.WriteBytes)),
+			"",
+		)
+	}
+	d.mainList.Rows = rows
+}
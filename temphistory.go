@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// tempWindow is the span min/avg/max are computed over
+	tempWindow = time.Hour
+	// tempHysteresis is how far the temperature must fall below temp_warn
+	// before another crossing counts as a new event
+	tempHysteresis = 3.0
+	// overheatEvents is how many recent overheat events are remembered
+	overheatEvents = 5
+)
+
+// tempSample is one temperature reading
+type tempSample struct {
+	at    time.Time
+	value float64
+}
+
+// OverheatEvent is one rise above temp_warn
+type OverheatEvent struct {
+	Start time.Time
+	Peak  float64
+	End   time.Time // zero while still above the threshold
+}
+
+// TempTracker keeps the readings of the last tempWindow and the times
+// the temperature crossed the warning threshold. The sparkline only
+// covers a few minutes, too short to catch a closed case heating up.
+type TempTracker struct {
+	threshold float64 // 0 disables the events
+	samples   []tempSample
+	events    []OverheatEvent // newest last
+	count     int             // events since start, including forgotten ones
+	above     bool
+}
+
+func NewTempTracker(threshold float64) *TempTracker {
+	return &TempTracker{threshold: threshold}
+}
+
+// Add records a reading. Readings of 0 mean the sensor could not be
+// read and are ignored.
+func (t *TempTracker) Add(at time.Time, value float64) {
+	if value <= 0 {
+		return
+	}
+	t.samples = append(t.samples, tempSample{at, value})
+	cut := 0
+	for cut < len(t.samples) && at.Sub(t.samples[cut].at) > tempWindow {
+		cut++
+	}
+	t.samples = t.samples[cut:]
+
+	if t.threshold <= 0 {
+		return
+	}
+	switch {
+	case !t.above && value > t.threshold:
+		t.above = true
+		t.count++
+		t.events = append(t.events, OverheatEvent{Start: at, Peak: value})
+		if len(t.events) > overheatEvents {
+			t.events = t.events[1:]
+		}
+		log.Printf("Overheat: %.1f°C above %.0f°C", value, t.threshold)
+	case t.above && value > t.events[len(t.events)-1].Peak:
+		t.events[len(t.events)-1].Peak = value
+	case t.above && value < t.threshold-tempHysteresis:
+		t.above = false
+		e := &t.events[len(t.events)-1]
+		e.End = at
+		log.Printf("Overheat over after %s, peak %.1f°C", e.End.Sub(e.Start).Round(time.Second), e.Peak)
+	}
+}
+
+// Range returns the minimum, average and maximum over tempWindow; ok is
+// false before the first reading
+func (t *TempTracker) Range() (min, avg, max float64, ok bool) {
+	if len(t.samples) == 0 {
+		return 0, 0, 0, false
+	}
+	min, max = t.samples[0].value, t.samples[0].value
+	sum := 0.0
+	for _, s := range t.samples {
+		if s.value < min {
+			min = s.value
+		}
+		if s.value > max {
+			max = s.value
+		}
+		sum += s.value
+	}
+	return min, sum / float64(len(t.samples)), max, true
+}
+
+// Span is how much time the kept readings cover, up to tempWindow
+func (t *TempTracker) Span() time.Duration {
+	if len(t.samples) == 0 {
+		return 0
+	}
+	return t.samples[len(t.samples)-1].at.Sub(t.samples[0].at)
+}
+
+// tempRangeRows shows min/avg/max and the overheat events under the
+// temperature on the System view
+func (d *Dashboard) tempRangeRows() []string {
+	min, avg, max, ok := d.tempTracker.Range()
+	if !ok {
+		return nil
+	}
+	span := "1h"
+	if d.tempTracker.Span() < tempWindow-time.Minute {
+		span = formatAge(d.tempTracker.Span())
+	}
+	rows := []string{fmt.Sprintf("%-3s %.0f/%.0f/%.0f°C min/avg/max", span, min, avg, max)}
+
+	t := d.tempTracker
+	if t.threshold <= 0 {
+		return rows
+	}
+	if t.count == 0 {
+		return append(rows, fmt.Sprintf("Over %.0f°C: [never](fg:green)", t.threshold))
+	}
+	last := t.events[len(t.events)-1]
+	color := "yellow"
+	if t.above {
+		color = "red"
+	}
+	rows = append(rows, fmt.Sprintf("Over %.0f°C: [%dx, last %s](fg:%s)",
+		t.threshold, t.count, last.Start.Format("15:04"), color))
+	return rows
+}
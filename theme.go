@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+const themeDefault = "default"
+
+// themeBar is the markup colour of the usage bars, so a theme can give
+// them a colour of their own
+const themeBar = "bar"
+
+// themeColorNames are the colour names used in the view markup. "black"
+// is also the background of the LCD and web page.
+var themeColorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white", themeBar}
+
+// ThemeConfig selects a built-in theme and optionally recolours some of
+// its colour names with "#rrggbb" values
+type ThemeConfig struct {
+	Name   string            `yaml:"name"`
+	Colors map[string]string `yaml:"colors"` // colour name -> "#rrggbb"
+}
+
+// themeColor is one colour as shown in the terminal and on pixel displays
+type themeColor struct {
+	Term ui.Color   // 0-7 use the terminal's own palette, above from the 256 colour one
+	RGB  color.RGBA // LCD and web page
+}
+
+// Theme maps the colour names of the view markup to the colours shown,
+// and names the colours of the boxes around the view and the menu
+type Theme struct {
+	Colors map[string]themeColor
+	Border string
+	Menu   string
+	Title  string
+	Text   string
+}
+
+// themes are the built-in themes. The default one keeps the terminal's
+// own colours; the others pick exact colours from the 256 colour palette.
+var themes = map[string]Theme{
+	themeDefault: {
+		Colors: map[string]themeColor{
+			"black":   {ui.ColorBlack, color.RGBA{0, 0, 0, 255}},
+			"red":     {ui.ColorRed, color.RGBA{235, 64, 52, 255}},
+			"green":   {ui.ColorGreen, color.RGBA{76, 210, 76, 255}},
+			"yellow":  {ui.ColorYellow, color.RGBA{240, 210, 60, 255}},
+			"blue":    {ui.ColorBlue, color.RGBA{80, 120, 240, 255}},
+			"magenta": {ui.ColorMagenta, color.RGBA{215, 90, 215, 255}},
+			"cyan":    {ui.ColorCyan, color.RGBA{60, 205, 225, 255}},
+			"white":   {ui.ColorWhite, color.RGBA{235, 235, 235, 255}},
+			themeBar:  {ui.ColorGreen, color.RGBA{76, 210, 76, 255}},
+		},
+		Border: "cyan", Menu: "yellow", Title: "white", Text: "white",
+	},
+	// Dimmer colours for a screen next to the bed
+	"dark": rgbTheme(map[string]color.RGBA{
+		"black":   {0, 0, 0, 255},
+		"red":     {175, 0, 0, 255},
+		"green":   {0, 135, 0, 255},
+		"yellow":  {175, 135, 0, 255},
+		"blue":    {0, 0, 175, 255},
+		"magenta": {135, 0, 135, 255},
+		"cyan":    {0, 135, 135, 255},
+		"white":   {168, 168, 168, 255},
+		themeBar:  {0, 95, 135, 255},
+	}, "blue", "yellow", "white", "white"),
+	// Dark text on white, for light terminals and transflective LCDs
+	"light": rgbTheme(map[string]color.RGBA{
+		"black":   {255, 255, 255, 255},
+		"red":     {175, 0, 0, 255},
+		"green":   {0, 135, 0, 255},
+		"yellow":  {175, 95, 0, 255},
+		"blue":    {0, 0, 175, 255},
+		"magenta": {135, 0, 135, 255},
+		"cyan":    {0, 95, 135, 255},
+		"white":   {28, 28, 28, 255},
+		themeBar:  {0, 95, 175, 255},
+	}, "cyan", "magenta", "white", "white"),
+	// Saturated colours on black, readable in sunlight
+	"high-contrast": rgbTheme(map[string]color.RGBA{
+		"black":   {0, 0, 0, 255},
+		"red":     {255, 0, 0, 255},
+		"green":   {0, 255, 0, 255},
+		"yellow":  {255, 255, 0, 255},
+		"blue":    {95, 135, 255, 255},
+		"magenta": {255, 0, 255, 255},
+		"cyan":    {0, 255, 255, 255},
+		"white":   {255, 255, 255, 255},
+		themeBar:  {255, 255, 0, 255},
+	}, "white", "yellow", "yellow", "white"),
+	// The Okabe-Ito palette: good and bad states differ in lightness and
+	// not only in red against green
+	"colorblind": rgbTheme(map[string]color.RGBA{
+		"black":   {0, 0, 0, 255},
+		"red":     {213, 94, 0, 255},
+		"green":   {86, 180, 233, 255},
+		"yellow":  {240, 228, 66, 255},
+		"blue":    {0, 114, 178, 255},
+		"magenta": {204, 121, 167, 255},
+		"cyan":    {0, 158, 115, 255},
+		"white":   {235, 235, 235, 255},
+		themeBar:  {86, 180, 233, 255},
+	}, "cyan", "yellow", "white", "white"),
+}
+
+// currentTheme is the theme applied by applyTheme
+var currentTheme = themes[themeDefault]
+
+// rgbTheme builds a theme whose terminal colours are the nearest ones of
+// the 256 colour palette
+func rgbTheme(colors map[string]color.RGBA, border, menu, title, text string) Theme {
+	t := Theme{Colors: make(map[string]themeColor), Border: border, Menu: menu, Title: title, Text: text}
+	for name, rgb := range colors {
+		t.Colors[name] = themeColor{xterm256(rgb), rgb}
+	}
+	return t
+}
+
+// themeNames lists the built-in themes for the flag help and errors
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// theme returns the selected theme with the colours from the config
+// file applied
+func (c ThemeConfig) theme() (Theme, error) {
+	base, ok := themes[c.Name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q (%s)", c.Name, strings.Join(themeNames(), ", "))
+	}
+	t := base
+	t.Colors = make(map[string]themeColor, len(base.Colors))
+	for name, tc := range base.Colors {
+		t.Colors[name] = tc
+	}
+	for name, value := range c.Colors {
+		if _, ok := t.Colors[name]; !ok {
+			return Theme{}, fmt.Errorf("theme.colors: unknown colour %q (%s)", name, strings.Join(themeColorNames, ", "))
+		}
+		rgb, err := parseHexColor(value)
+		if err != nil {
+			return Theme{}, fmt.Errorf("theme.colors.%s: %w", name, err)
+		}
+		t.Colors[name] = themeColor{xterm256(rgb), rgb}
+	}
+	return t, nil
+}
+
+func (c ThemeConfig) validate() error {
+	_, err := c.theme()
+	return err
+}
+
+// parseHexColor parses "#rrggbb"
+func parseHexColor(s string) (color.RGBA, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("%q is not a #rrggbb colour", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("%q is not a #rrggbb colour", s)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
+
+// xterm256 returns the nearest colour of the 6x6x6 cube or the grey ramp
+// of the 256 colour palette
+func xterm256(c color.RGBA) ui.Color {
+	levels := [6]int{0, 95, 135, 175, 215, 255}
+	nearest := func(v uint8) int {
+		best := 0
+		for i, level := range levels {
+			if abs(int(v)-level) < abs(int(v)-levels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	distance := func(r, g, b int) int {
+		dr, dg, db := int(c.R)-r, int(c.G)-g, int(c.B)-b
+		return dr*dr + dg*dg + db*db
+	}
+
+	r, g, b := nearest(c.R), nearest(c.G), nearest(c.B)
+	cube := distance(levels[r], levels[g], levels[b])
+
+	// The grey ramp runs from 8 to 238 in steps of 10
+	grey := ((int(c.R)+int(c.G)+int(c.B))/3 - 3) / 10
+	if grey < 0 {
+		grey = 0
+	} else if grey > 23 {
+		grey = 23
+	}
+	if v := 8 + 10*grey; distance(v, v, v) < cube {
+		return ui.Color(232 + grey)
+	}
+	return ui.Color(16 + 36*r + 6*g + b)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// applyTheme makes t the colours of the view markup for every display
+// backend. It must be called before the display is created.
+func applyTheme(t Theme) {
+	currentTheme = t
+	lcdPalette = t.palette()
+	for name, tc := range t.Colors {
+		ui.StyleParserColorMap[name] = tc.Term
+	}
+}
+
+// palette maps the terminal colours of the theme to pixel colours
+func (t Theme) palette() map[ui.Color]color.RGBA {
+	palette := make(map[ui.Color]color.RGBA, len(t.Colors))
+	for _, tc := range t.Colors {
+		palette[tc.Term] = tc.RGB
+	}
+	// Names sharing a terminal colour, like green and the bars of the
+	// default theme, share the pixel colour of the basic name
+	for _, name := range themeColorNames {
+		if tc, ok := t.Colors[name]; ok && name != themeBar {
+			palette[tc.Term] = tc.RGB
+		}
+	}
+	return palette
+}
+
+// term returns the terminal colour of a colour name
+func (t Theme) term(name string) ui.Color {
+	return t.Colors[name].Term
+}
+
+// rgb returns the pixel colour of a colour name
+func (t Theme) rgb(name string) color.RGBA {
+	return t.Colors[name].RGB
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// Supported UPS HATs
+const (
+	upsPiSugar = "pisugar" // PiSugar 2/3 through pisugar-server
+	upsPiJuice = "pijuice" // PiJuice HAT microcontroller on I2C
+	upsX728    = "x728"    // Geekworm X728, MAX17043 fuel gauge on I2C
+)
+
+// upsShutdownReadings is how many low readings in a row trigger the
+// shutdown, so a single bad read of the gauge does not power off the Pi
+const upsShutdownReadings = 3
+
+// BatteryStatus is collected by the collector package
+type BatteryStatus = collector.BatteryStatus
+
+// UPSConfig selects the UPS HAT and the safe shutdown threshold
+type UPSConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	Type            string        `yaml:"type"`      // pisugar, pijuice, x728
+	Device          string        `yaml:"device"`    // I2C bus of pijuice and x728
+	Address         int           `yaml:"address"`   // 0 uses the default address of the type
+	Server          string        `yaml:"server"`    // pisugar-server TCP address
+	PowerPin        int           `yaml:"power_pin"` // x728: BCM pin that goes high when external power is lost
+	Interval        time.Duration `yaml:"interval"`
+	ShutdownPercent float64       `yaml:"shutdown_percent"` // 0 disables the shutdown
+	ShutdownCommand string        `yaml:"shutdown_command"`
+}
+
+func (c UPSConfig) validate() error {
+	switch c.Type {
+	case upsPiSugar, upsPiJuice, upsX728:
+	default:
+		return fmt.Errorf("ups.type: unknown type %q (%s, %s, %s)", c.Type, upsPiSugar, upsPiJuice, upsX728)
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("ups.interval must be positive")
+	}
+	if c.ShutdownPercent < 0 || c.ShutdownPercent >= 100 {
+		return fmt.Errorf("ups.shutdown_percent must be between 0 and 99")
+	}
+	if c.ShutdownPercent > 0 && c.ShutdownCommand == "" {
+		return fmt.Errorf("ups.shutdown_command is required with shutdown_percent")
+	}
+	return nil
+}
+
+// upsReader reads the battery of one kind of HAT
+type upsReader interface {
+	read() (BatteryStatus, error)
+	Close() error
+}
+
+// UPSMonitor polls the HAT in the background and shuts the Pi down when
+// the battery runs low without external power
+type UPSMonitor struct {
+	cfg    UPSConfig
+	reader upsReader
+
+	mu           sync.Mutex
+	status       *BatteryStatus // nil until the first successful read
+	err          error
+	low          int  // low readings in a row
+	shuttingDown bool // the shutdown command has been run
+}
+
+func newUPSMonitor(cfg UPSConfig) (*UPSMonitor, error) {
+	device := cfg.Device
+	if device == "" {
+		device = "/dev/i2c-1"
+	}
+
+	var reader upsReader
+	var err error
+	switch cfg.Type {
+	case upsPiSugar:
+		reader = &piSugar{addr: cfg.Server}
+	case upsPiJuice:
+		reader, err = newPiJuice(device, cfg.Address)
+	case upsX728:
+		reader, err = newX728(device, cfg.Address, cfg.PowerPin)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("UPS %s, shutdown below %.0f%%", cfg.Type, cfg.ShutdownPercent)
+	return &UPSMonitor{cfg: cfg, reader: reader}, nil
+}
+
+// Status returns the latest battery reading, nil before the first one
+func (m *UPSMonitor) Status() *BatteryStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Run reads the battery every interval until stop is closed
+func (m *UPSMonitor) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.check()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *UPSMonitor) check() {
+	status, err := m.reader.read()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		if m.err == nil {
+			log.Printf("UPS read failed: %v", err)
+		}
+		m.err = err
+		return
+	}
+	if m.err != nil {
+		log.Printf("UPS readable again")
+	}
+	m.err = nil
+	if m.status != nil && m.status.Plugged != status.Plugged {
+		if status.Plugged {
+			log.Printf("UPS: external power restored at %.0f%%", status.Percent)
+		} else {
+			log.Printf("UPS: running on battery at %.0f%%", status.Percent)
+		}
+	}
+	m.status = &status
+
+	if m.cfg.ShutdownPercent <= 0 || m.shuttingDown {
+		return
+	}
+	if status.Plugged || status.Percent > m.cfg.ShutdownPercent {
+		m.low = 0
+		return
+	}
+	m.low++
+	if m.low < upsShutdownReadings {
+		return
+	}
+	m.shuttingDown = true
+	log.Printf("UPS: battery at %.0f%% without external power, running %q", status.Percent, m.cfg.ShutdownCommand)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if out, err := exec.CommandContext(ctx, "sh", "-c", m.cfg.ShutdownCommand).CombinedOutput(); err != nil {
+			log.Printf("UPS shutdown command failed: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+	}()
+}
+
+// Close releases the I2C device and GPIO line of the HAT
+func (m *UPSMonitor) Close() {
+	m.reader.Close()
+}
+
+// upsRows shows the battery under the fan on the System view
+func (m *UPSMonitor) upsRows() []string {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.status == nil {
+		if m.err != nil {
+			return []string{"[Batt: unreadable](fg:red)"}
+		}
+		return nil
+	}
+
+	s := m.status
+	rows := []string{fmt.Sprintf("Batt: %3.0f%% %s", s.Percent, getBar(s.Percent, 10))}
+	state, color := "on battery", "yellow"
+	switch {
+	case m.shuttingDown:
+		state, color = "shutting down", "red"
+	case s.Charging:
+		state, color = "charging", "green"
+	case s.Plugged:
+		state, color = "plugged in", "green"
+	case m.cfg.ShutdownPercent > 0 && s.Percent <= m.cfg.ShutdownPercent+10:
+		color = "red"
+	}
+	if s.Voltage > 0 {
+		state += fmt.Sprintf(" %.2fV", s.Voltage)
+	}
+	rows = append(rows, fmt.Sprintf("      [%s](fg:%s)", state, color))
+	if m.err != nil {
+		rows[1] += " [stale](fg:red)"
+	}
+	return rows
+}
+
+// piSugar asks pisugar-server, which owns the I2C bus of the PiSugar
+type piSugar struct {
+	addr string
+}
+
+func (p *piSugar) read() (BatteryStatus, error) {
+	conn, err := net.DialTimeout("tcp", p.addr, 3*time.Second)
+	if err != nil {
+		return BatteryStatus{}, fmt.Errorf("pisugar-server: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+
+	r := bufio.NewReader(conn)
+	get := func(key string) (string, error) {
+		if _, err := fmt.Fprintf(conn, "get %s\n", key); err != nil {
+			return "", err
+		}
+		// Replies look like "battery: 85.3"
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, key+":") {
+			return "", fmt.Errorf("pisugar-server: unexpected reply %q", line)
+		}
+		return strings.TrimSpace(strings.TrimPrefix(line, key+":")), nil
+	}
+
+	var s BatteryStatus
+	value, err := get("battery")
+	if err != nil {
+		return s, err
+	}
+	if s.Percent, err = strconv.ParseFloat(value, 64); err != nil {
+		return s, fmt.Errorf("pisugar-server: battery %q", value)
+	}
+	if value, err = get("battery_v"); err == nil {
+		s.Voltage, _ = strconv.ParseFloat(value, 64)
+	}
+	if value, err = get("battery_power_plugged"); err == nil {
+		s.Plugged = value == "true"
+	}
+	if value, err = get("battery_charging"); err == nil {
+		s.Charging = value == "true"
+	}
+	return s, nil
+}
+
+func (p *piSugar) Close() error { return nil }
+
+// piJuice reads the status registers of the PiJuice microcontroller
+type piJuice struct {
+	dev *i2cDevice
+}
+
+func newPiJuice(device string, addr int) (*piJuice, error) {
+	if addr == 0 {
+		addr = 0x14
+	}
+	dev, err := openI2C(device, addr)
+	if err != nil {
+		return nil, fmt.Errorf("pijuice: %w", err)
+	}
+	return &piJuice{dev: dev}, nil
+}
+
+// readReg reads n bytes of a register; the PiJuice appends a checksum
+// byte, 0xff XOR all data bytes
+func (p *piJuice) readReg(reg byte, n int) ([]byte, error) {
+	buf, err := p.dev.readReg(reg, n+1)
+	if err != nil {
+		return nil, err
+	}
+	sum := byte(0xff)
+	for _, b := range buf[:n] {
+		sum ^= b
+	}
+	if sum != buf[n] {
+		return nil, fmt.Errorf("pijuice: checksum mismatch in register 0x%02x", reg)
+	}
+	return buf[:n], nil
+}
+
+func (p *piJuice) read() (BatteryStatus, error) {
+	var s BatteryStatus
+	status, err := p.readReg(0x40, 1)
+	if err != nil {
+		return s, err
+	}
+	charge, err := p.readReg(0x41, 1)
+	if err != nil {
+		return s, err
+	}
+	s.Percent = float64(charge[0])
+
+	// Bits 2-3 are the battery state, 1 and 2 charging from the USB
+	// input or the GPIO 5V; bits 4-5 and 6-7 are those inputs, 2 weak
+	// and 3 present
+	battery := status[0] >> 2 & 3
+	s.Charging = battery == 1 || battery == 2
+	s.Plugged = status[0]>>4&3 >= 2 || status[0]>>6&3 >= 2
+	if v, err := p.readReg(0x49, 2); err == nil {
+		s.Voltage = float64(binary.LittleEndian.Uint16(v)) / 1000
+	}
+	return s, nil
+}
+
+func (p *piJuice) Close() error { return p.dev.Close() }
+
+// x728 reads the MAX17043 fuel gauge and the power loss pin of the X728
+type x728 struct {
+	dev   *i2cDevice
+	power *gpiocdev.Line // nil when the pin could not be requested
+}
+
+func newX728(device string, addr, pin int) (*x728, error) {
+	if addr == 0 {
+		addr = 0x36
+	}
+	dev, err := openI2C(device, addr)
+	if err != nil {
+		return nil, fmt.Errorf("x728: %w", err)
+	}
+	x := &x728{dev: dev}
+	x.power, err = gpiocdev.RequestLine(gpioChip, pin+gpioOffset,
+		gpiocdev.WithConsumer(gpioConsumer),
+		gpiocdev.AsInput)
+	if err != nil {
+		log.Printf("Warning: x728 power loss pin unavailable, assuming external power: request GPIO%d on %s: %v", pin, gpioChip, err)
+	}
+	return x, nil
+}
+
+func (x *x728) read() (BatteryStatus, error) {
+	var s BatteryStatus
+	vcell, err := x.dev.readReg(0x02, 2)
+	if err != nil {
+		return s, fmt.Errorf("x728: %w", err)
+	}
+	soc, err := x.dev.readReg(0x04, 2)
+	if err != nil {
+		return s, fmt.Errorf("x728: %w", err)
+	}
+	// VCELL is 12 bits of 1.25mV, SOC is percent in 1/256 steps
+	s.Voltage = float64(binary.BigEndian.Uint16(vcell)>>4) * 1.25 / 1000
+	s.Percent = clamp(float64(soc[0])+float64(soc[1])/256, 0, 100)
+
+	s.Plugged = true
+	if x.power != nil {
+		if v, err := x.power.Value(); err == nil {
+			s.Plugged = v == 0
+		}
+	}
+	// The X728 has no charge status output; it charges whenever it is
+	// plugged in and not full
+	s.Charging = s.Plugged && s.Percent < 99
+	return s, nil
+}
+
+func (x *x728) Close() error {
+	if x.power != nil {
+		x.power.Close()
+	}
+	return x.dev.Close()
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	usbDevicesDir = "/sys/bus/usb/devices"
+	// usbRecent is how long a (re)connected device stays highlighted
+	usbRecent = 10 * time.Minute
+	// usbEvents is how many connect and disconnect events are kept
+	usbEvents = 6
+)
+
+// usbIDFiles are the USB ID lists shipped by usbutils and hwdata, used
+// for devices that do not report their own names
+var usbIDFiles = []string{
+	"/usr/share/misc/usb.ids",
+	"/usr/share/hwdata/usb.ids",
+	"/var/lib/usbutils/usb.ids",
+}
+
+// USBDevice is one device from /sys/bus/usb/devices
+type USBDevice struct {
+	Port       string // sysfs name, e.g. "1-1.2" for bus 1, port 1, hub port 2
+	DevNum     string // changes every time the device is enumerated
+	VendorID   string
+	ProductID  string
+	Product    string
+	Speed      int    // Mbit/s
+	MaxPower   string // configured draw, e.g. "500mA"
+	PowerState string // runtime PM status: active or suspended
+	Hub        bool
+}
+
+// Name is the product name, or the vendor and product IDs
+func (u USBDevice) Name() string {
+	if u.Product != "" {
+		return u.Product
+	}
+	return u.VendorID + ":" + u.ProductID
+}
+
+// listUSBDevices reads every device except the root hubs of the host
+// controllers. Interfaces, whose names contain a colon, are skipped.
+func listUSBDevices() []USBDevice {
+	dirs, _ := filepath.Glob(filepath.Join(usbDevicesDir, "[0-9]*-*"))
+	sort.Strings(dirs)
+
+	var devices []USBDevice
+	for _, dir := range dirs {
+		port := filepath.Base(dir)
+		if strings.Contains(port, ":") {
+			continue
+		}
+		u := USBDevice{
+			Port:       port,
+			DevNum:     readSysfs(filepath.Join(dir, "devnum")),
+			VendorID:   readSysfs(filepath.Join(dir, "idVendor")),
+			ProductID:  readSysfs(filepath.Join(dir, "idProduct")),
+			Product:    readSysfs(filepath.Join(dir, "product")),
+			MaxPower:   readSysfs(filepath.Join(dir, "bMaxPower")),
+			PowerState: readSysfs(filepath.Join(dir, "power", "runtime_status")),
+			Hub:        readSysfs(filepath.Join(dir, "bDeviceClass")) == "09",
+		}
+		u.Speed, _ = strconv.Atoi(readSysfs(filepath.Join(dir, "speed")))
+		devices = append(devices, u)
+	}
+	return devices
+}
+
+// usbOverCurrent returns the hub ports that reported over-current, with
+// their counts, e.g. "usb1-port1" -> 2
+func usbOverCurrent() map[string]int {
+	counts := make(map[string]int)
+	paths, _ := filepath.Glob(filepath.Join(usbDevicesDir, "*", "*-port*", "over_current_count"))
+	for _, path := range paths {
+		if n, _ := strconv.Atoi(readSysfs(path)); n > 0 {
+			counts[filepath.Base(filepath.Dir(path))] = n
+		}
+	}
+	return counts
+}
+
+// loadUSBIDs reads the first usb.ids found, keyed by "vendor" and
+// "vendor:product" in lower case hex
+func loadUSBIDs() map[string]string {
+	for _, path := range usbIDFiles {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		names := make(map[string]string)
+		vendor := ""
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			// "0781  SanDisk Corp." followed by "\t5583  Ultra Fit";
+			// the device classes at the end start with "C "
+			switch {
+			case strings.HasPrefix(line, "C "):
+				vendor = ""
+			case len(line) > 6 && line[0] != '\t' && line[0] != '#' && line[4] == ' ':
+				vendor = line[:4]
+				names[vendor] = strings.TrimSpace(line[4:])
+			case vendor != "" && len(line) > 7 && line[0] == '\t' && line[1] != '\t' && line[5] == ' ':
+				names[vendor+":"+line[1:5]] = strings.TrimSpace(line[5:])
+			}
+		}
+		f.Close()
+		if len(names) > 0 {
+			return names
+		}
+	}
+	return map[string]string{}
+}
+
+// usbEvent is a device appearing or disappearing
+type usbEvent struct {
+	at        time.Time
+	port      string
+	name      string
+	connected bool
+}
+
+// USBTracker notices devices that come and go between samples. Devices
+// dropping off and coming back, often with a new device number, are a
+// typical sign of an undersized power supply.
+type USBTracker struct {
+	primed    bool              // false until the devices present at start are known
+	devNums   map[string]string // port -> devnum of the device there
+	names     map[string]string // port -> name, for the disconnect events
+	changedAt map[string]time.Time
+	events    []usbEvent // newest last
+	ids       map[string]string
+}
+
+func NewUSBTracker() *USBTracker {
+	return &USBTracker{
+		devNums:   make(map[string]string),
+		names:     make(map[string]string),
+		changedAt: make(map[string]time.Time),
+	}
+}
+
+// Observe compares the devices present now with the last call. It only
+// reads the device numbers, the names only for devices that are new.
+func (t *USBTracker) Observe(now time.Time) {
+	dirs, _ := filepath.Glob(filepath.Join(usbDevicesDir, "[0-9]*-*"))
+	present := make(map[string]bool)
+	for _, dir := range dirs {
+		port := filepath.Base(dir)
+		if strings.Contains(port, ":") {
+			continue
+		}
+		present[port] = true
+		devNum := readSysfs(filepath.Join(dir, "devnum"))
+		old, known := t.devNums[port]
+		if known && old == devNum {
+			continue
+		}
+		t.devNums[port] = devNum
+		t.names[port] = t.deviceName(dir)
+		if !t.primed {
+			continue
+		}
+		if known {
+			// Gone and back between two samples
+			t.addEvent(usbEvent{at: now, port: port, name: t.names[port]})
+		}
+		t.changedAt[port] = now
+		t.addEvent(usbEvent{at: now, port: port, name: t.names[port], connected: true})
+	}
+	for port := range t.devNums {
+		if !present[port] {
+			t.addEvent(usbEvent{at: now, port: port, name: t.names[port]})
+			delete(t.devNums, port)
+		}
+	}
+	t.primed = true
+}
+
+func (t *USBTracker) addEvent(e usbEvent) {
+	if e.connected {
+		log.Printf("USB connected on %s: %s", e.port, e.name)
+	} else {
+		log.Printf("USB disconnected from %s: %s", e.port, e.name)
+	}
+	t.events = append(t.events, e)
+	if len(t.events) > usbEvents {
+		t.events = t.events[1:]
+	}
+}
+
+// deviceName reads the product string of the device in dir, falling back
+// to usb.ids
+func (t *USBTracker) deviceName(dir string) string {
+	if product := readSysfs(filepath.Join(dir, "product")); product != "" {
+		return product
+	}
+	return t.lookup(readSysfs(filepath.Join(dir, "idVendor")), readSysfs(filepath.Join(dir, "idProduct")))
+}
+
+// lookup names a device from usb.ids, loading the list on first use
+func (t *USBTracker) lookup(vendor, product string) string {
+	if t.ids == nil {
+		t.ids = loadUSBIDs()
+	}
+	if name := t.ids[vendor+":"+product]; name != "" {
+		return name
+	}
+	if name := t.ids[vendor]; name != "" {
+		return name
+	}
+	return vendor + ":" + product
+}
+
+// formatUSBSpeed shows the link speed, e.g. "480M" or "5G"
+func formatUSBSpeed(mbps int) string {
+	if mbps >= 1000 {
+		return fmt.Sprintf("%dG", mbps/1000)
+	}
+	return fmt.Sprintf("%dM", mbps)
+}
+
+func (d *Dashboard) updateUSBView(stats SystemStats) {
+	d.mainList.Title = d.viewTitle("[A/B:Switch]")
+
+	devices := listUSBDevices()
+	if len(devices) == 0 {
+		d.mainList.Rows = []string{"", "No USB devices", "", "Nothing is listed in", "/sys/bus/usb/devices."}
+		return
+	}
+
+	now := time.Now()
+	t := d.usbTracker
+	var rows []string
+	for _, u := range devices {
+		if u.Product == "" {
+			u.Product = t.lookup(u.VendorID, u.ProductID)
+		}
+		color := "cyan"
+		if u.Hub {
+			color = "white"
+		}
+		if at, ok := t.changedAt[u.Port]; ok && now.Sub(at) < usbRecent {
+			color = "yellow"
+		}
+		rows = append(rows, fmt.Sprintf("%-6s [%s](fg:%s)", u.Port, truncateString(u.Name(), 21), color))
+
+		info := fmt.Sprintf(" %s:%s %4s %5s", u.VendorID, u.ProductID, formatUSBSpeed(u.Speed), u.MaxPower)
+		if u.PowerState == "suspended" {
+			info += " [idle](fg:white)"
+		}
+		rows = append(rows, info)
+	}
+
+	if overCurrent := usbOverCurrent(); len(overCurrent) > 0 {
+		ports := make([]string, 0, len(overCurrent))
+		for port := range overCurrent {
+			ports = append(ports, port)
+		}
+		sort.Strings(ports)
+		rows = append(rows, "", "[--Over-current--](fg:red)")
+		for _, port := range ports {
+			rows = append(rows, fmt.Sprintf("[%-12s %3dx](fg:red)", port, overCurrent[port]))
+		}
+	}
+
+	if len(t.events) > 0 {
+		rows = append(rows, "", "[--Recent--](fg:yellow)")
+		for i := len(t.events) - 1; i >= 0; i-- {
+			e := t.events[i]
+			sign, color := "-", "red"
+			if e.connected {
+				sign, color = "+", "green"
+			}
+			rows = append(rows, fmt.Sprintf("%s [%s %-6s %s](fg:%s)",
+				e.at.Format("15:04"), sign, e.port, truncateString(e.name, 13), color))
+		}
+	}
+	d.mainList.Rows = rows
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// ThrottleStatus is the decoded result of vcgencmd get_throttled
+type ThrottleStatus = collector.ThrottleStatus
+
+// throttleRows returns the warning rows for the system view
+func throttleRows(t ThrottleStatus) []string {
+	if !t.Known {
+		return nil
+	}
+	current, past := t.Current(), t.Past()
+	if len(current) == 0 && len(past) == 0 {
+		return []string{"[Power: OK](fg:green)"}
+	}
+
+	var rows []string
+	if len(current) > 0 {
+		rows = append(rows, fmt.Sprintf("[! %s](fg:red)", strings.Join(current, ",")))
+	}
+	if len(past) > 0 {
+		rows = append(rows, fmt.Sprintf("[Was: %s](fg:yellow)", strings.Join(past, ",")))
+	}
+	return rows
+}
+
+// MinMax tracks the lowest and highest value seen
+type MinMax struct {
+	Min, Max float64
+	set      bool
+}
+
+// Update records v; zero readings are ignored as "not available"
+func (m *MinMax) Update(v float64) {
+	if v == 0 {
+		return
+	}
+	if !m.set || v < m.Min {
+		m.Min = v
+	}
+	if !m.set || v > m.Max {
+		m.Max = v
+	}
+	m.set = true
+}
+
+// clockRows returns the voltage and clock rows for the system view
+func (d *Dashboard) clockRows(stats SystemStats) []string {
+	if stats.CoreVolts == 0 && stats.ARMClock == 0 {
+		return nil
+	}
+	rows := []string{
+		fmt.Sprintf("ARM:  %4d MHz %4.0f-%.0f", stats.ARMClock/1000000, d.armClockRange.Min/1e6, d.armClockRange.Max/1e6),
+		fmt.Sprintf("Core: %4d MHz %4.0f-%.0f", stats.CoreClock/1000000, d.coreClockRange.Min/1e6, d.coreClockRange.Max/1e6),
+	}
+	if stats.SDRAMClock > 0 {
+		rows = append(rows, fmt.Sprintf("SDRAM: %d MHz", stats.SDRAMClock/1000000))
+	}
+	rows = append(rows, fmt.Sprintf("Volt: %.3fV %.3f-%.3f", stats.CoreVolts, d.voltRange.Min, d.voltRange.Max))
+	return rows
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vpnRefresh is how often the VPN tools are asked again. wg and
+// tailscale are too slow to run on every sample.
+const vpnRefresh = 5 * time.Second
+
+// VPNPeer is one remote end of a tunnel
+type VPNPeer struct {
+	Name      string // WireGuard public key or Tailscale host name
+	Endpoint  string // host:port the traffic goes to, empty when unknown
+	Handshake time.Time
+	Online    bool
+	RxBytes   uint64
+	TxBytes   uint64
+}
+
+// VPNTunnel is a WireGuard interface, the Tailscale node or an OpenVPN
+// client
+type VPNTunnel struct {
+	Kind  string // WireGuard, Tailscale or OpenVPN
+	Name  string // interface name
+	State string // e.g. "Running", empty when up without further detail
+	Err   string // why the peers are unknown, e.g. wg needs root
+	Peers []VPNPeer
+}
+
+// VPNMonitor asks wg, tailscale and the OpenVPN configuration for the
+// tunnels in the background and keeps the result for the Network view
+type VPNMonitor struct {
+	mu      sync.Mutex
+	tunnels []VPNTunnel
+	checked time.Time
+	running bool
+}
+
+// Tunnels returns the tunnels found last and starts a new check when
+// they are older than vpnRefresh. openvpn are the PIDs of running
+// openvpn processes.
+func (m *VPNMonitor) Tunnels(openvpn []int32) []VPNTunnel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.running && time.Since(m.checked) >= vpnRefresh {
+		m.running = true
+		go m.check(openvpn)
+	}
+	return m.tunnels
+}
+
+func (m *VPNMonitor) check(openvpn []int32) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tunnels := wireguardTunnels(ctx)
+	if t, ok := tailscaleTunnel(ctx); ok {
+		tunnels = append(tunnels, t)
+	}
+	for _, pid := range openvpn {
+		tunnels = append(tunnels, openVPNTunnel(pid))
+	}
+
+	m.mu.Lock()
+	m.tunnels, m.checked, m.running = tunnels, time.Now(), false
+	m.mu.Unlock()
+}
+
+// wireguardTunnels lists the wireguard interfaces from sysfs and fills
+// in their peers from "wg show all dump", which needs root
+func wireguardTunnels(ctx context.Context) []VPNTunnel {
+	var tunnels []VPNTunnel
+	index := make(map[string]int)
+	uevents, _ := filepath.Glob("/sys/class/net/*/uevent")
+	for _, path := range uevents {
+		data, err := os.ReadFile(path)
+		if err != nil || !strings.Contains(string(data), "DEVTYPE=wireguard") {
+			continue
+		}
+		name := filepath.Base(filepath.Dir(path))
+		index[name] = len(tunnels)
+		tunnels = append(tunnels, VPNTunnel{Kind: "WireGuard", Name: name})
+	}
+	if len(tunnels) == 0 {
+		return nil
+	}
+
+	out, err := exec.CommandContext(ctx, "wg", "show", "all", "dump").Output()
+	if err != nil {
+		msg := toolError("wg", err).Error()
+		if _, ok := err.(*exec.ExitError); ok {
+			msg = "wg needs root"
+		}
+		for i := range tunnels {
+			tunnels[i].Err = msg
+		}
+		return tunnels
+	}
+
+	// Interface lines have 5 fields, peer lines 9:
+	// iface pubkey psk endpoint allowed-ips handshake rx tx keepalive
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) != 9 {
+			continue
+		}
+		i, ok := index[f[0]]
+		if !ok {
+			continue
+		}
+		peer := VPNPeer{Name: f[1]}
+		if f[3] != "(none)" {
+			peer.Endpoint = f[3]
+		}
+		if sec, _ := strconv.ParseInt(f[5], 10, 64); sec > 0 {
+			peer.Handshake = time.Unix(sec, 0)
+			// WireGuard renews the session every two minutes while in use
+			peer.Online = time.Since(peer.Handshake) < 3*time.Minute
+		}
+		peer.RxBytes, _ = strconv.ParseUint(f[6], 10, 64)
+		peer.TxBytes, _ = strconv.ParseUint(f[7], 10, 64)
+		tunnels[i].Peers = append(tunnels[i].Peers, peer)
+	}
+	return tunnels
+}
+
+// tailscaleStatus is the part of "tailscale status --json" shown
+type tailscaleStatus struct {
+	BackendState string
+	Peer         map[string]struct {
+		HostName      string
+		Online        bool
+		CurAddr       string // direct endpoint, empty when relayed
+		Relay         string // DERP region used otherwise
+		RxBytes       uint64
+		TxBytes       uint64
+		LastHandshake time.Time
+	}
+}
+
+// tailscaleTunnel reports the Tailscale node. It reports false when
+// tailscale is not installed or tailscaled is not running.
+func tailscaleTunnel(ctx context.Context) (VPNTunnel, bool) {
+	out, err := exec.CommandContext(ctx, "tailscale", "status", "--json").Output()
+	if len(out) == 0 {
+		return VPNTunnel{}, false
+	}
+	var status tailscaleStatus
+	if jsonErr := json.Unmarshal(out, &status); jsonErr != nil {
+		if err != nil {
+			return VPNTunnel{}, false
+		}
+		return VPNTunnel{Kind: "Tailscale", Name: "tailscale0", Err: "bad status output"}, true
+	}
+
+	tunnel := VPNTunnel{Kind: "Tailscale", Name: "tailscale0", State: status.BackendState}
+	for _, p := range status.Peer {
+		peer := VPNPeer{
+			Name:      p.HostName,
+			Endpoint:  p.CurAddr,
+			Handshake: p.LastHandshake,
+			Online:    p.Online,
+			RxBytes:   p.RxBytes,
+			TxBytes:   p.TxBytes,
+		}
+		if peer.Endpoint == "" && p.Relay != "" {
+			peer.Endpoint = "relay " + p.Relay
+		}
+		tunnel.Peers = append(tunnel.Peers, peer)
+	}
+	// Map order is random; peers that talk come first
+	sort.Slice(tunnel.Peers, func(i, j int) bool {
+		a, b := tunnel.Peers[i], tunnel.Peers[j]
+		if a.Online != b.Online {
+			return a.Online
+		}
+		return a.Name < b.Name
+	})
+	return tunnel, true
+}
+
+// openVPNTunnel describes the openvpn process pid by the remote of its
+// command line or configuration file. OpenVPN has no handshake time
+// without its management interface, so the transfer counters come from
+// the tun interface in the view.
+func openVPNTunnel(pid int32) VPNTunnel {
+	tunnel := VPNTunnel{Kind: "OpenVPN", State: "Running"}
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		tunnel.Err = "process gone"
+		return tunnel
+	}
+	args := strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+
+	var remotes []string
+	config := ""
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--remote":
+			if i+1 < len(args) {
+				remotes = append(remotes, remoteEndpoint(args[i+1:]))
+			}
+		case "--config":
+			if i+1 < len(args) {
+				config = args[i+1]
+			}
+		case "--dev":
+			if i+1 < len(args) {
+				tunnel.Name = args[i+1]
+			}
+		}
+	}
+	if config == "" && len(args) == 2 && !strings.HasPrefix(args[1], "-") {
+		config = args[1] // openvpn client.conf
+	}
+	if config != "" {
+		if !filepath.IsAbs(config) {
+			if cwd, err := os.Readlink(fmt.Sprintf("/proc/%d/cwd", pid)); err == nil {
+				config = filepath.Join(cwd, config)
+			}
+		}
+		name, fromConfig := openVPNConfig(config)
+		if tunnel.Name == "" {
+			tunnel.Name = name
+		}
+		remotes = append(remotes, fromConfig...)
+	}
+
+	for _, r := range remotes {
+		tunnel.Peers = append(tunnel.Peers, VPNPeer{Endpoint: r})
+	}
+	return tunnel
+}
+
+// openVPNConfig reads the dev and remote lines of an OpenVPN config file
+func openVPNConfig(path string) (string, []string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil
+	}
+	defer f.Close()
+
+	dev := ""
+	var remotes []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "dev":
+			dev = fields[1]
+		case "remote":
+			remotes = append(remotes, remoteEndpoint(fields[1:]))
+		}
+	}
+	return dev, remotes
+}
+
+// remoteEndpoint joins the host and port of an OpenVPN remote option
+func remoteEndpoint(args []string) string {
+	if len(args) > 1 && !strings.HasPrefix(args[1], "-") {
+		if _, err := strconv.Atoi(args[1]); err == nil {
+			return args[0] + ":" + args[1]
+		}
+	}
+	return args[0]
+}
+
+// openVPNProcesses returns the PIDs of the running openvpn processes
+func openVPNProcesses(procs []ProcessInfo) []int32 {
+	var pids []int32
+	for _, p := range procs {
+		if p.Name == "openvpn" {
+			pids = append(pids, p.PID)
+		}
+	}
+	return pids
+}
+
+// vpnRows shows the tunnels in the Network view, nothing when there are
+// none
+func vpnRows(tunnels []VPNTunnel, ifaces []InterfaceStats) []string {
+	if len(tunnels) == 0 {
+		return nil
+	}
+	rows := []string{"", "[--VPN--](fg:green)"}
+	for _, t := range tunnels {
+		header := fmt.Sprintf("%s %s", t.Name, t.Kind)
+		if t.Name == "" {
+			header = t.Kind
+		}
+		if t.State != "" {
+			color := "green"
+			if t.State != "Running" {
+				color = "yellow"
+			}
+			header += fmt.Sprintf(" [%s](fg:%s)", t.State, color)
+		}
+		rows = append(rows, "["+truncateString(header, 28)+"](fg:cyan)")
+
+		// OpenVPN peers carry no counters; the tun interface has them
+		if t.Kind == "OpenVPN" {
+			for _, iface := range ifaces {
+				// "dev tun" lets the kernel pick tun0, tun1, ...
+				generic := (t.Name == "tun" || t.Name == "tap") && strings.HasPrefix(iface.Name, t.Name)
+				if iface.Name == t.Name || generic {
+					rows = append(rows, fmt.Sprintf(" Tx %s Rx %s", formatBytes(iface.BytesSent), formatBytes(iface.BytesRecv)))
+				}
+			}
+		}
+		if t.Err != "" {
+			rows = append(rows, " ["+truncateString(t.Err, 26)+"](fg:red)")
+		}
+		for _, p := range t.Peers {
+			rows = append(rows, vpnPeerRows(p, t.Kind)...)
+		}
+		if len(t.Peers) == 0 && t.Err == "" && t.Kind != "OpenVPN" {
+			rows = append(rows, " No peers")
+		}
+	}
+	return rows
+}
+
+// vpnPeerRows shows where a peer is, how long ago it last shook hands
+// and how much went through the tunnel
+func vpnPeerRows(p VPNPeer, kind string) []string {
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = "-"
+	}
+	if kind == "OpenVPN" {
+		return []string{" Remote: " + truncateString(endpoint, 19)}
+	}
+
+	name := p.Name
+	if kind == "WireGuard" {
+		name = truncateString(name, 8) // public keys are 44 characters
+	}
+	color := "red"
+	if p.Online {
+		color = "green"
+	}
+	handshake := "never"
+	if !p.Handshake.IsZero() {
+		handshake = formatAge(time.Since(p.Handshake))
+	}
+	return []string{
+		fmt.Sprintf(" [%s](fg:%s) %s", truncateString(name, 12), color, truncateString(endpoint, 14)),
+		fmt.Sprintf("  hs %s Tx %s Rx %s", handshake, formatBytes(p.TxBytes), formatBytes(p.RxBytes)),
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes of RFC 6455
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xa
+)
+
+const (
+	wsGUID         = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsWriteTimeout = 10 * time.Second
+	// wsMaxPayload bounds the frames a client may send; the stream only
+	// expects control frames
+	wsMaxPayload = 4096
+)
+
+// wsMessage is one message of the /ws stream. The first one carries
+// every field of /api/stats; the later ones only the fields that
+// changed, with null for a field that is gone.
+type wsMessage struct {
+	Type string                     `json:"type"` // full or delta
+	Data map[string]json.RawMessage `json:"data"`
+}
+
+// wsConn is a server side WebSocket connection
+type wsConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	mu   sync.Mutex // serializes frames from the stream and the reader
+}
+
+// upgradeWebSocket completes the opening handshake and takes over the
+// connection
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "expected a WebSocket handshake", http.StatusBadRequest)
+		return nil, errors.New("not a WebSocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported WebSocket version")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection cannot be upgraded", http.StatusInternalServerError)
+		return nil, errors.New("response does not support hijacking")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, r: rw.Reader}, nil
+}
+
+// writeFrame sends one unmasked frame, as a server does
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readFrame reads one frame from the client and unmasks it
+func (c *wsConn) readFrame() (opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode = head[0] & 0x0f
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxPayload {
+		return 0, nil, errors.New("frame too large")
+	}
+	var mask [4]byte
+	if head[1]&0x80 != 0 {
+		if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// readLoop answers pings and returns when the client closes the
+// connection or goes away
+func (c *wsConn) readLoop() {
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsPing:
+			c.writeFrame(wsPong, payload)
+		case wsClose:
+			c.writeFrame(wsClose, payload)
+			return
+		}
+	}
+}
+
+// wsHandler streams the stats of /api/stats over a WebSocket at every
+// update interval, sending only what changed after the first message
+func (d *Dashboard) wsHandler(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer ws.conn.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		ws.readLoop()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	var last map[string]json.RawMessage
+	var lastAt time.Time
+	for {
+		stats, at := d.snapshot.Get()
+		if !at.IsZero() && at.After(lastAt) {
+			lastAt = at
+			stats.AllProcesses = nil
+			fields, err := wsFields(apiStats{Timestamp: at, Hostname: d.statsHost(), SystemStats: stats})
+			if err != nil {
+				log.Printf("WebSocket: %v", err)
+				return
+			}
+			msg := wsMessage{Type: "full", Data: fields}
+			if last != nil {
+				msg = wsMessage{Type: "delta", Data: wsDelta(last, fields)}
+			}
+			last = fields
+			body, err := json.Marshal(msg)
+			if err == nil {
+				err = ws.writeFrame(wsText, body)
+			}
+			if err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// wsFields splits the JSON object of v into its top-level fields
+func wsFields(v interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+// wsDelta returns the fields of cur that differ from prev, and null for
+// the ones prev had and cur lacks
+func wsDelta(prev, cur map[string]json.RawMessage) map[string]json.RawMessage {
+	delta := make(map[string]json.RawMessage)
+	for k, v := range cur {
+		if !bytes.Equal(prev[k], v) {
+			delta[k] = v
+		}
+	}
+	for k := range prev {
+		if _, ok := cur[k]; !ok {
+			delta[k] = json.RawMessage("null")
+		}
+	}
+	return delta
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"raspi-monitor/pkg/collector"
+)
+
+// WifiStats is collected by the collector package
+type WifiStats = collector.WifiStats
+
+// signalColor rates a signal level the way most Wi-Fi tools do
+func signalColor(dBm float64) string {
+	switch {
+	case dBm >= -60:
+		return "green"
+	case dBm >= -70:
+		return "yellow"
+	}
+	return "red"
+}
+
+// wifiRows shows the wireless link of the selected interface
+func wifiRows(wifi *WifiStats) []string {
+	if !wifi.Connected && wifi.Signal == 0 {
+		return []string{"Wi-Fi: [not connected](fg:red)"}
+	}
+	rows := []string{
+		fmt.Sprintf("SSID: %s", truncateString(wifi.SSID, 22)),
+		fmt.Sprintf("Signal: [%.0f dBm](fg:%s) Q:%.0f%%", wifi.Signal, signalColor(wifi.Signal), wifi.Quality),
+	}
+	if wifi.Freq > 0 {
+		rows = append(rows, fmt.Sprintf("Ch: %d (%.1f GHz)", wifi.Channel, float64(wifi.Freq)/1000))
+	}
+	if wifi.TxBitrate > 0 || wifi.RxBitrate > 0 {
+		rows = append(rows, fmt.Sprintf("Rate: %.0f/%.0f Mbit/s", wifi.TxBitrate, wifi.RxBitrate))
+	}
+	return rows
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	wifiScanTimeout = 30 * time.Second
+	wifiJoinTimeout = 60 * time.Second
+	wpaScanWait     = 4 * time.Second // wpa_supplicant has no blocking scan
+)
+
+// WifiNetwork is one SSID found by a scan
+type WifiNetwork struct {
+	SSID    string
+	Signal  float64 // dBm, estimated from the quality with nmcli
+	Secured bool
+	Active  bool // the interface is connected to it
+}
+
+// WifiManager scans for and joins Wi-Fi networks through NetworkManager
+// (nmcli) or, on older Raspberry Pi OS releases, wpa_supplicant
+// (wpa_cli). Both run in the background; the view polls the state.
+type WifiManager struct {
+	mu       sync.Mutex
+	networks []WifiNetwork
+	scanning bool
+	scanned  time.Time
+	err      error
+	joining  string // SSID being joined
+	status   string // result of the last join
+}
+
+// State returns the last scan result and join status
+func (m *WifiManager) State() (networks []WifiNetwork, scanning bool, status string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status = m.status
+	if m.joining != "" {
+		status = "Joining " + m.joining + "..."
+	}
+	return m.networks, m.scanning, status, m.err
+}
+
+// Scanned reports whether a scan has run or is running
+func (m *WifiManager) Scanned() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.scanning || !m.scanned.IsZero()
+}
+
+// Scan lists the networks seen by iface in a goroutine
+func (m *WifiManager) Scan(iface string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.scanning {
+		return
+	}
+	m.scanning = true
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), wifiScanTimeout)
+		defer cancel()
+		var networks []WifiNetwork
+		var err error
+		if _, lookErr := exec.LookPath("nmcli"); lookErr == nil {
+			networks, err = nmcliScan(ctx, iface)
+		} else {
+			networks, err = wpaScan(ctx, iface)
+		}
+		if err != nil {
+			log.Printf("Wi-Fi scan failed: %v", err)
+		}
+
+		m.mu.Lock()
+		m.networks, m.err = networks, err
+		m.scanning = false
+		m.scanned = time.Now()
+		m.mu.Unlock()
+	}()
+}
+
+// Join connects iface to ssid in a goroutine and rescans when done
+func (m *WifiManager) Join(iface, ssid, passphrase string) {
+	m.mu.Lock()
+	if m.joining != "" {
+		m.mu.Unlock()
+		return
+	}
+	m.joining = ssid
+	m.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), wifiJoinTimeout)
+		defer cancel()
+		var err error
+		if _, lookErr := exec.LookPath("nmcli"); lookErr == nil {
+			err = nmcliJoin(ctx, iface, ssid, passphrase)
+		} else {
+			err = wpaJoin(ctx, iface, ssid, passphrase)
+		}
+
+		status := "Connected to " + ssid
+		if err != nil {
+			log.Printf("Joining Wi-Fi %q failed: %v", ssid, err)
+			status = "Join failed: " + err.Error()
+		} else {
+			log.Printf("Joined Wi-Fi %q", ssid)
+		}
+		m.mu.Lock()
+		m.joining, m.status = "", status
+		m.mu.Unlock()
+		m.Scan(iface)
+	}()
+}
+
+// nmcliScan asks NetworkManager for a fresh scan
+func nmcliScan(ctx context.Context, iface string) ([]WifiNetwork, error) {
+	out, err := exec.CommandContext(ctx, "nmcli", "-t", "-f", "IN-USE,SSID,SIGNAL,SECURITY",
+		"device", "wifi", "list", "ifname", iface, "--rescan", "yes").Output()
+	if err != nil {
+		return nil, toolError("nmcli", err)
+	}
+
+	var networks []WifiNetwork
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := splitTerse(line)
+		if len(fields) < 4 || fields[1] == "" {
+			continue
+		}
+		quality, _ := strconv.ParseFloat(fields[2], 64)
+		networks = append(networks, WifiNetwork{
+			SSID:    fields[1],
+			Signal:  quality/2 - 100,
+			Secured: fields[3] != "" && fields[3] != "--",
+			Active:  fields[0] == "*",
+		})
+	}
+	return dedupeNetworks(networks), nil
+}
+
+// splitTerse splits a line of nmcli -t output, where colons inside a
+// field are escaped with a backslash
+func splitTerse(line string) []string {
+	var fields []string
+	var field strings.Builder
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(r)
+		}
+	}
+	return append(fields, field.String())
+}
+
+func nmcliJoin(ctx context.Context, iface, ssid, passphrase string) error {
+	args := []string{"device", "wifi", "connect", ssid, "ifname", iface}
+	if passphrase != "" {
+		args = append(args, "password", passphrase)
+	}
+	if out, err := exec.CommandContext(ctx, "nmcli", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s", commandError(out, err))
+	}
+	return nil
+}
+
+// wpaScan triggers a wpa_supplicant scan and reads the results once it
+// had time to finish
+func wpaScan(ctx context.Context, iface string) ([]WifiNetwork, error) {
+	if _, err := wpaCLI(ctx, iface, "scan"); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(wpaScanWait):
+	}
+
+	out, err := wpaCLI(ctx, iface, "scan_results")
+	if err != nil {
+		return nil, err
+	}
+	active := ""
+	if status, err := wpaCLI(ctx, iface, "status"); err == nil {
+		for _, line := range strings.Split(status, "\n") {
+			if strings.HasPrefix(line, "ssid=") {
+				active = strings.TrimPrefix(line, "ssid=")
+			}
+		}
+	}
+
+	var networks []WifiNetwork
+	for _, line := range strings.Split(out, "\n") {
+		// bssid / frequency / signal level / flags / ssid
+		fields := strings.Split(line, "\t")
+		if len(fields) < 5 || fields[4] == "" {
+			continue
+		}
+		signal, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			continue // header line
+		}
+		networks = append(networks, WifiNetwork{
+			SSID:    fields[4],
+			Signal:  signal,
+			Secured: strings.Contains(fields[3], "WPA") || strings.Contains(fields[3], "WEP"),
+			Active:  fields[4] == active,
+		})
+	}
+	return dedupeNetworks(networks), nil
+}
+
+// wpaJoin adds a network to wpa_supplicant, switches to it and saves
+// the configuration so it is used after a reboot. The SSID is sent as
+// hex, which wpa_supplicant takes for any bytes; a quoted passphrase is
+// stored as is, so it cannot contain a quote.
+func wpaJoin(ctx context.Context, iface, ssid, passphrase string) error {
+	if strings.Contains(passphrase, `"`) {
+		return errors.New(`passphrase must not contain '"'`)
+	}
+	id, err := wpaCLI(ctx, iface, "add_network")
+	if err != nil {
+		return err
+	}
+	id = strings.TrimSpace(id)
+
+	steps := [][]string{{"set_network", id, "ssid", hex.EncodeToString([]byte(ssid))}}
+	if passphrase != "" {
+		steps = append(steps, []string{"set_network", id, "psk", `"` + passphrase + `"`})
+	} else {
+		steps = append(steps, []string{"set_network", id, "key_mgmt", "NONE"})
+	}
+	steps = append(steps,
+		[]string{"enable_network", id},
+		[]string{"select_network", id},
+		[]string{"save_config"},
+	)
+	for _, step := range steps {
+		out, err := wpaCLI(ctx, iface, step...)
+		if err == nil && strings.TrimSpace(out) != "OK" {
+			err = fmt.Errorf("wpa_cli %s: %s", step[0], strings.TrimSpace(out))
+		}
+		if err != nil {
+			// do not leave a half configured network behind
+			wpaCLI(context.Background(), iface, "remove_network", id)
+			return err
+		}
+	}
+	return nil
+}
+
+func wpaCLI(ctx context.Context, iface string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "wpa_cli", append([]string{"-i", iface}, args...)...).Output()
+	if err != nil {
+		return "", toolError("wpa_cli", err)
+	}
+	return string(out), nil
+}
+
+// toolError explains a missing tool instead of a bare exec error
+func toolError(tool string, err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("%s not installed", tool)
+	}
+	return fmt.Errorf("%s: %w", tool, err)
+}
+
+// dedupeNetworks keeps the strongest access point of every SSID,
+// strongest network first
+func dedupeNetworks(networks []WifiNetwork) []WifiNetwork {
+	best := make(map[string]int)
+	var list []WifiNetwork
+	for _, n := range networks {
+		if i, ok := best[n.SSID]; ok {
+			active := list[i].Active || n.Active
+			if n.Signal > list[i].Signal {
+				list[i] = n
+			}
+			list[i].Active = active
+			continue
+		}
+		best[n.SSID] = len(list)
+		list = append(list, n)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Signal > list[j].Signal })
+	return list
+}
+
+// wifiInterface returns the first wireless interface, wlan0 if none is
+// reported
+func wifiInterface(stats SystemStats) string {
+	for _, iface := range stats.Interfaces {
+		if iface.Wifi != nil {
+			return iface.Name
+		}
+	}
+	return "wlan0"
+}
+
+// rescanWifi starts a scan from the Wi-Fi view
+func (d *Dashboard) rescanWifi() {
+	stats, _ := d.snapshot.Get()
+	d.wifiManager.Scan(wifiInterface(stats))
+	d.UpdateStats()
+	d.Render()
+}
+
+// chooseWifiNetwork asks for the passphrase of the selected network,
+// or confirms joining an open one
+func (d *Dashboard) chooseWifiNetwork() {
+	if d.selectedWifi < 0 || d.selectedWifi >= len(d.wifiList) {
+		return
+	}
+	network := d.wifiList[d.selectedWifi]
+	stats, _ := d.snapshot.Get()
+	iface := wifiInterface(stats)
+
+	join := func(d *Dashboard, passphrase string) {
+		d.wifiManager.Join(iface, network.SSID, passphrase)
+		d.UpdateStats()
+		d.Render()
+	}
+	if !network.Secured {
+		d.openMenu(&Menu{
+			title:   "Join Wi-Fi",
+			message: []string{truncateString(network.SSID, 24), "Open network"},
+			options: []menuOption{
+				{label: "Cancel"},
+				{label: "Join", action: func(d *Dashboard) { join(d, "") }},
+			},
+		})
+		return
+	}
+	d.openKeyboard("Passphrase: "+truncateString(network.SSID, 12), func(d *Dashboard, passphrase string) {
+		if len(passphrase) < 8 || len(passphrase) > 63 {
+			d.showMessage("Error", "WPA passphrases have", "8 to 63 characters")
+			return
+		}
+		join(d, passphrase)
+	})
+}
+
+func (d *Dashboard) updateWifiView(stats SystemStats) {
+	if !d.wifiManager.Scanned() {
+		d.wifiManager.Scan(wifiInterface(stats))
+	}
+	networks, scanning, status, err := d.wifiManager.State()
+	if !d.reuseLists {
+		d.wifiList = networks
+		d.apStatus, d.apErr = readAPStatus(wifiInterface(stats))
+	}
+
+	state := wifiInterface(stats)
+	if scanning {
+		state += " scanning"
+	}
+	footer := []string{"", "[Enter/A: join  r/X: rescan](fg:yellow)"}
+	if status != "" {
+		footer = append(footer, truncateString(status, 27))
+	}
+
+	total := len(d.wifiList)
+	if total == 0 {
+		d.mainList.Title = d.viewTitle("0/0 " + state)
+		rows := append(d.apRows(), "", "No networks found")
+		if err != nil {
+			rows = append(rows, "", truncateString(err.Error(), 27))
+		}
+		d.mainList.Rows = append(rows, footer...)
+		return
+	}
+	if d.selectedWifi >= total {
+		d.selectedWifi = total - 1
+	}
+	d.mainList.Title = d.viewTitle(fmt.Sprintf("%d/%d %s", d.selectedWifi+1, total, state))
+
+	rows := append(d.apRows(), "", "[  SSID               dBm Sec](fg:cyan)")
+
+	visibleHeight := 23 - len(rows)
+	startIdx := d.selectedWifi - visibleHeight/2
+	if startIdx > total-visibleHeight {
+		startIdx = total - visibleHeight
+	}
+	if startIdx < 0 {
+		startIdx = 0
+	}
+	endIdx := startIdx + visibleHeight
+	if endIdx > total {
+		endIdx = total
+	}
+
+	for i := startIdx; i < endIdx; i++ {
+		n := d.wifiList[i]
+		mark, sec := " ", ""
+		if n.Active {
+			mark = "*"
+		}
+		if n.Secured {
+			sec = "WPA"
+		}
+		line := fmt.Sprintf("%s %-17s %4.0f %-3s", mark, truncateString(n.SSID, 17), n.Signal, sec)
+		if i == d.selectedWifi {
+			rows = append(rows, fmt.Sprintf("[%s](bg:white,fg:black)", line))
+		} else {
+			rows = append(rows, fmt.Sprintf("[%s](fg:%s)", line, signalColor(n.Signal)))
+		}
+	}
+	d.mainList.Rows = append(rows, footer...)
+}